@@ -7,12 +7,35 @@ import (
     "bytes"
     "strconv"
     "strings"
+    "monkey/token"
     "monkey/utils"
 )
 
 type Node interface {
     node()
     String() string
+    Pos() token.Position
+    End() token.Position
+    SetSpan(pos token.Position, end token.Position)
+}
+
+// Where the node was written, embedded by every node. The parser fills it and the nodes built
+// by hand keep the zero value
+type Span struct {
+    StartPos token.Position
+    EndPos   token.Position
+}
+
+// @Impl
+func (this *Span) Pos() token.Position { return this.StartPos }
+
+// @Impl
+func (this *Span) End() token.Position { return this.EndPos }
+
+// @Impl
+func (this *Span) SetSpan(pos token.Position, end token.Position) {
+    this.StartPos = pos
+    this.EndPos = end
 }
 
 type Statement interface {
@@ -26,6 +49,7 @@ type Expression interface {
 }
 
 type Program struct {
+    Span
     Statements []Statement
 }
 
@@ -54,7 +78,10 @@ func (this *Program) PrintStatements() {
 }
 
 type LetStatement struct {
+    Span
     Identifier string
+    NamePos token.Position // Where the identifier was written
+    Type *TypeAnnotation // Nil when the let has no annotation
     Expression Expression
 }
 
@@ -69,6 +96,7 @@ func (this *LetStatement) String() string {
     var out bytes.Buffer
     out.WriteString("let ")
     out.WriteString(this.Identifier)
+    if this.Type != nil { out.WriteString(": " + this.Type.String()) }
     out.WriteString(" = ")
     out.WriteString(this.Expression.String())
     return out.String()
@@ -79,6 +107,7 @@ func NewLetStatement() *LetStatement {
 }
 
 type ReturnStatement struct {
+    Span
     Expression Expression
 }
 
@@ -103,6 +132,7 @@ func NewReturnStatement() *ReturnStatement {
 }
 
 type ExpressionStatement struct {
+    Span
     Expression Expression
 }
 
@@ -122,7 +152,16 @@ func NewExpressionStatement() *ExpressionStatement {
 }
 
 type Identifier struct {
+    Span
     Value string
+
+    // Filled by the resolver for the names bound inside a function: how many functions out is
+    // the one that binds it (0 for its own) and the slot of the name in its environment
+    Resolved bool
+    Depth    int
+    Slot     int
+
+    Type *TypeAnnotation // Of a function parameter, nil when it has no annotation
 }
 
 // @Impl
@@ -139,6 +178,7 @@ func NewIdentifier(value string) *Identifier {
 }
 
 type IntegerLiteral struct {
+    Span
     Value int64
 }
 
@@ -156,6 +196,7 @@ func NewIntegerLiteral(value int64) *IntegerLiteral {
 }
 
 type StringLiteral struct {
+    Span
     Value string
 }
 
@@ -169,6 +210,7 @@ func (this *StringLiteral) expression() {}
 func (this *StringLiteral) String() string { return this.Value }
 
 type PrefixExpression struct {
+    Span
     Operator string
     Value Expression
 }
@@ -189,6 +231,7 @@ func NewPrefixExpression(operator string, value Expression) *PrefixExpression {
 }
 
 type InfixExpression struct {
+    Span
     Operator string
     Left Expression
     Right Expression
@@ -210,6 +253,7 @@ func NewInfixExpression(left Expression) *InfixExpression {
 }
 
 type Boolean struct {
+    Span
     Value bool
 }
 
@@ -225,6 +269,7 @@ func (this *Boolean) String() string {
 }
 
 type StatementsBlock struct {
+    Span
     Statements []Statement
 }
 
@@ -244,6 +289,7 @@ func (this *StatementsBlock) String() string {
 }
 
 type IfExpression struct {
+    Span
     Condition Expression
     ConsequenceBlock *StatementsBlock
     AlternativeBlock *StatementsBlock
@@ -272,8 +318,11 @@ func (this *IfExpression) String() string {
 }
 
 type FunctionLiteral struct {
+    Span
     Parameters []Identifier
+    ReturnType *TypeAnnotation // Nil when the function has no annotation
     Body *StatementsBlock
+    Frame []string // The names of the slots of its environment, the parameters first. Filled by the resolver
 }
 
 // @Impl
@@ -288,7 +337,11 @@ func (this *FunctionLiteral) String() string {
 
     var args = []string {}
     for _, arg := range this.Parameters {
-        args = append(args, arg.String())
+        if arg.Type != nil {
+            args = append(args, arg.String() + ": " + arg.Type.String())
+        } else {
+            args = append(args, arg.String())
+        }
     }
 
     var stms = []string {}
@@ -300,7 +353,45 @@ func (this *FunctionLiteral) String() string {
     if len(args) > 0 {
         out.WriteString(strings.Join(args, ", "))
     }
-    out.WriteString(") {")
+    out.WriteString(")")
+    if this.ReturnType != nil { out.WriteString(" -> " + this.ReturnType.String()) }
+    out.WriteString(" {")
+    if len(stms) > 0 {
+        out.WriteString(" " + strings.Join(stms, " ") + " ")
+    }
+    out.WriteString("}")
+
+    return out.String()
+}
+
+// A macro(params) { body } bound by a let of the program. It is not evaluated like the others: the
+// expansion runs its body on the quoted arguments of each call and puts the result in its place
+type MacroLiteral struct {
+    Span
+    Parameters []Identifier
+    Body *StatementsBlock
+}
+
+// @Impl
+func (this *MacroLiteral) node() {}
+
+// @Impl
+func (this *MacroLiteral) expression() {}
+
+// @Impl
+func (this *MacroLiteral) String() string {
+    var args = []string {}
+    for _, arg := range this.Parameters {
+        args = append(args, arg.String())
+    }
+
+    var stms = []string {}
+    for _, stm := range this.Body.Statements {
+        stms = append(stms, stm.String())
+    }
+
+    var out bytes.Buffer
+    out.WriteString("macro (" + strings.Join(args, ", ") + ") {")
     if len(stms) > 0 {
         out.WriteString(" " + strings.Join(stms, " ") + " ")
     }
@@ -310,6 +401,7 @@ func (this *FunctionLiteral) String() string {
 }
 
 type CallExpression struct {
+    Span
     Expression Expression
     Parameters []Expression
 }
@@ -337,6 +429,7 @@ func (this *CallExpression) String() string {
 }
 
 type MethodExpression struct {
+    Span
     Expression Expression
     Call *CallExpression
 }
@@ -359,6 +452,7 @@ func (this *MethodExpression) String() string {
 }
 
 type ArrayLiteral struct {
+    Span
     Elements []Expression
 }
 
@@ -386,6 +480,7 @@ func (this *ArrayLiteral) String() string {
 }
 
 type IndexExpression struct {
+    Span
     Left Expression
     Index Expression
 }
@@ -408,7 +503,23 @@ func (this *IndexExpression) String() string {
 }
 
 type HashLiteral struct {
+    Span
     Pairs map[Expression] Expression
+    Keys []Expression // Keys in the order they were written
+}
+
+func NewHashLiteral() *HashLiteral {
+    return &HashLiteral {
+        Pairs: make(map[Expression] Expression),
+        Keys: []Expression {},
+    }
+}
+
+func (this *HashLiteral) Set(key Expression, value Expression) {
+    if _, exists := this.Pairs[key]; !exists {
+        this.Keys = append(this.Keys, key)
+    }
+    this.Pairs[key] = value
 }
 
 // @Impl
@@ -424,8 +535,8 @@ func (this *HashLiteral) String() string {
     if len(this.Pairs) == 0 { return "{}" }
 
     var pairs = []string {}
-    for key, value := range this.Pairs {
-        var pair = key.String() + ": " + value.String()
+    for _, key := range this.Keys {
+        var pair = key.String() + ": " + this.Pairs[key].String()
         pairs = append(pairs, pair)
     }
 
@@ -435,3 +546,177 @@ func (this *HashLiteral) String() string {
 
     return out.String()
 }
+
+// match (value) { pattern => result, ... }. The arms are tried in order, the first one whose
+// pattern matches the value, and whose guard is true, gives the result
+type MatchExpression struct {
+    Span
+    Value Expression
+    Arms []*MatchArm
+}
+
+// @Impl
+func (this *MatchExpression) node() {}
+
+// @Impl
+func (this *MatchExpression) expression() {}
+
+// @Impl
+func (this *MatchExpression) String() string {
+    var arms = []string {}
+    for _, arm := range this.Arms {
+        arms = append(arms, arm.String())
+    }
+    if len(arms) == 0 { return "match (" + this.Value.String() + ") {}" }
+    return "match (" + this.Value.String() + ") { " + strings.Join(arms, ", ") + " }"
+}
+
+type MatchArm struct {
+    Span
+    Pattern Pattern
+    Guard Expression // The condition after 'if', nil when the arm has none
+    Body Expression
+}
+
+// @Impl
+func (this *MatchArm) node() {}
+
+// @Impl
+func (this *MatchArm) String() string {
+    var out = this.Pattern.String()
+    if this.Guard != nil { out += " if " + this.Guard.String() }
+    return out + " => " + this.Body.String()
+}
+
+// The left side of an arm of a match. The names it binds are only visible in the guard and the
+// result of the arm
+type Pattern interface {
+    Node
+    pattern()
+}
+
+// An integer, string or boolean that the value must be equal to. The negative integers are kept
+// as a single literal
+type LiteralPattern struct {
+    Span
+    Value Expression
+}
+
+// @Impl
+func (this *LiteralPattern) node() {}
+
+// @Impl
+func (this *LiteralPattern) pattern() {}
+
+// @Impl
+func (this *LiteralPattern) String() string { return this.Value.String() }
+
+// _ matches anything and binds nothing
+type WildcardPattern struct {
+    Span
+}
+
+// @Impl
+func (this *WildcardPattern) node() {}
+
+// @Impl
+func (this *WildcardPattern) pattern() {}
+
+// @Impl
+func (this *WildcardPattern) String() string { return "_" }
+
+// A name matches anything and is bound to the value
+type BindingPattern struct {
+    Span
+    Name string
+}
+
+// @Impl
+func (this *BindingPattern) node() {}
+
+// @Impl
+func (this *BindingPattern) pattern() {}
+
+// @Impl
+func (this *BindingPattern) String() string { return this.Name }
+
+// [first, second, ...rest] matches the arrays with one element for each pattern. With a rest the
+// array can be longer and the rest (a binding or _) takes an array of the remaining elements
+type ArrayPattern struct {
+    Span
+    Elements []Pattern
+    Rest Pattern // Nil when there is no '...'
+}
+
+// @Impl
+func (this *ArrayPattern) node() {}
+
+// @Impl
+func (this *ArrayPattern) pattern() {}
+
+// @Impl
+func (this *ArrayPattern) String() string {
+    var elements = []string {}
+    for _, element := range this.Elements {
+        elements = append(elements, element.String())
+    }
+    if this.Rest != nil { elements = append(elements, "..." + this.Rest.String()) }
+    return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// { key: pattern, ... } matches the hashes that have each key with a value that matches its
+// pattern. The other keys of the hash are ignored
+type HashPattern struct {
+    Span
+    Keys []Expression // The literals, in the order they were written
+    Values []Pattern
+}
+
+// @Impl
+func (this *HashPattern) node() {}
+
+// @Impl
+func (this *HashPattern) pattern() {}
+
+// @Impl
+func (this *HashPattern) String() string {
+    if len(this.Keys) == 0 { return "{}" }
+
+    var pairs = []string {}
+    for i, key := range this.Keys {
+        pairs = append(pairs, key.String() + ": " + this.Values[i].String())
+    }
+    return "{ " + strings.Join(pairs, ", ") + " }"
+}
+
+// The optional type written after a let name, a parameter or the '->' of a function. The
+// evaluator ignores them, they are for the type checker. The arguments are the ones between
+// '<' '>' of the generic names (array<int>) or the parameters of a function type, nil for a bare
+// 'fn' that is any function
+type TypeAnnotation struct {
+    Span
+    Name string
+    Arguments []*TypeAnnotation
+    Return *TypeAnnotation // Of a function type, nil when it has none
+}
+
+// @Impl
+func (this *TypeAnnotation) node() {}
+
+// @Impl
+func (this *TypeAnnotation) String() string {
+    var args = []string {}
+    for _, arg := range this.Arguments {
+        args = append(args, arg.String())
+    }
+
+    if this.Name != "fn" {
+        if len(args) == 0 { return this.Name }
+        return this.Name + "<" + strings.Join(args, ", ") + ">"
+    }
+
+    if this.Arguments == nil { return "fn" }
+    var out = "fn(" + strings.Join(args, ", ") + ")"
+    if this.Return != nil { out += " -> " + this.Return.String() }
+    return out
+}
@@ -0,0 +1,468 @@
+// monkey/ast/json.go
+/*
+    JSON form of the tree, for the tools outside the interpreter and to cache parsed programs.
+    Each node is an object with its "type", its "pos" and "end" and its fields, in the order they
+    were written:
+
+        { "type": "InfixExpression", "pos": { "offset": 0, "line": 1, "column": 1 }, "end": ...,
+          "operator": "+", "left": { ... }, "right": { ... } }
+
+    The hashes keep their pairs in a list, and the annotations of the resolver are written only
+    when they are set. Decoding the encoding of a tree gives the same tree
+*/
+
+package ast
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "monkey/token"
+    "monkey/utils"
+    "strconv"
+)
+
+// An object that keeps its keys in order
+type jsonObject []jsonField
+
+type jsonField struct {
+    key   string
+    value any
+}
+
+func (this jsonObject) MarshalJSON() ([]byte, error) {
+    var out bytes.Buffer
+    out.WriteString("{")
+    for i, field := range this {
+        if i > 0 { out.WriteString(",") }
+        var key, _ = json.Marshal(field.key)
+        var value, err = json.Marshal(field.value)
+        if err != nil { return nil, err }
+        out.Write(key)
+        out.WriteString(":")
+        out.Write(value)
+    }
+    out.WriteString("}")
+    return out.Bytes(), nil
+}
+
+func encodePosition(pos token.Position) jsonObject {
+    return jsonObject { { "offset", pos.Offset }, { "line", pos.Line }, { "column", pos.Column } }
+}
+
+// Encodes the node and its children as JSON
+func EncodeJSON(node Node) ([]byte, error) {
+    return json.Marshal(encodeNode(node))
+}
+
+func encodeNodes[T Node](nodes []T) []any {
+    var result = []any {}
+    for _, node := range nodes {
+        result = append(result, encodeNode(node))
+    }
+    return result
+}
+
+// The node as a jsonObject, nil for the missing ones
+func encodeNode(node Node) any {
+    if utils.IsNill(node) { return nil }
+
+    var object = jsonObject {
+        { "type", nodeType(node) },
+        { "pos", encodePosition(node.Pos()) },
+        { "end", encodePosition(node.End()) },
+    }
+    var add = func (key string, value any) { object = append(object, jsonField { key, value }) }
+
+    switch node := node.(type) {
+    case *Program:
+        add("statements", encodeNodes(node.Statements))
+    case *StatementsBlock:
+        add("statements", encodeNodes(node.Statements))
+    case *LetStatement:
+        add("name", node.Identifier)
+        add("namePos", encodePosition(node.NamePos))
+        if node.Type != nil { add("annotation", encodeNode(node.Type)) }
+        add("value", encodeNode(node.Expression))
+    case *ReturnStatement:
+        add("value", encodeNode(node.Expression))
+    case *ExpressionStatement:
+        add("expression", encodeNode(node.Expression))
+    case *Identifier:
+        add("value", node.Value)
+        if node.Type != nil { add("annotation", encodeNode(node.Type)) }
+        if node.Resolved {
+            add("resolved", true)
+            add("depth", node.Depth)
+            add("slot", node.Slot)
+        }
+    case *IntegerLiteral:
+        add("value", node.Value)
+    case *StringLiteral:
+        add("value", node.Value)
+    case *Boolean:
+        add("value", node.Value)
+    case *PrefixExpression:
+        add("operator", node.Operator)
+        add("value", encodeNode(node.Value))
+    case *InfixExpression:
+        add("operator", node.Operator)
+        add("left", encodeNode(node.Left))
+        add("right", encodeNode(node.Right))
+    case *IfExpression:
+        add("condition", encodeNode(node.Condition))
+        add("consequence", encodeNode(node.ConsequenceBlock))
+        add("alternative", encodeNode(node.AlternativeBlock))
+    case *FunctionLiteral:
+        var params = []any {}
+        for i := range node.Parameters {
+            params = append(params, encodeNode(&node.Parameters[i]))
+        }
+        add("parameters", params)
+        if node.ReturnType != nil { add("returnType", encodeNode(node.ReturnType)) }
+        add("body", encodeNode(node.Body))
+        if node.Frame != nil { add("frame", node.Frame) }
+    case *MacroLiteral:
+        var params = []any {}
+        for i := range node.Parameters {
+            params = append(params, encodeNode(&node.Parameters[i]))
+        }
+        add("parameters", params)
+        add("body", encodeNode(node.Body))
+    case *CallExpression:
+        add("function", encodeNode(node.Expression))
+        add("arguments", encodeNodes(node.Parameters))
+    case *MethodExpression:
+        add("receiver", encodeNode(node.Expression))
+        add("call", encodeNode(node.Call))
+    case *ArrayLiteral:
+        add("elements", encodeNodes(node.Elements))
+    case *IndexExpression:
+        add("left", encodeNode(node.Left))
+        add("index", encodeNode(node.Index))
+    case *HashLiteral:
+        var pairs = []any {}
+        for _, key := range node.Keys {
+            pairs = append(pairs, jsonObject { { "key", encodeNode(key) }, { "value", encodeNode(node.Pairs[key]) } })
+        }
+        add("pairs", pairs)
+    case *MatchExpression:
+        add("value", encodeNode(node.Value))
+        add("arms", encodeNodes(node.Arms))
+    case *MatchArm:
+        add("pattern", encodeNode(node.Pattern))
+        if node.Guard != nil { add("guard", encodeNode(node.Guard)) }
+        add("body", encodeNode(node.Body))
+    case *LiteralPattern:
+        add("value", encodeNode(node.Value))
+    case *WildcardPattern:
+    case *BindingPattern:
+        add("name", node.Name)
+    case *ArrayPattern:
+        add("elements", encodeNodes(node.Elements))
+        if node.Rest != nil { add("rest", encodeNode(node.Rest)) }
+    case *HashPattern:
+        var pairs = []any {}
+        for i, key := range node.Keys {
+            pairs = append(pairs, jsonObject { { "key", encodeNode(key) }, { "value", encodeNode(node.Values[i]) } })
+        }
+        add("pairs", pairs)
+    case *TypeAnnotation:
+        add("name", node.Name)
+        if node.Arguments != nil { add("arguments", encodeNodes(node.Arguments)) }
+        if node.Return != nil { add("return", encodeNode(node.Return)) }
+    default:
+        panic(fmt.Sprintf("ast: unexpected node type %T", node))
+    }
+    return object
+}
+
+// The name of the type of the node without the package: *ast.LetStatement is LetStatement
+func nodeType(node Node) string {
+    var name = fmt.Sprintf("%T", node)
+    return name[len("*ast."):]
+}
+
+// Reads the nodes of EncodeJSON back
+type decoder struct {
+    err error // The first error found, the decoding goes on with zero values after it
+}
+
+// Decodes a node encoded by EncodeJSON
+func DecodeJSON(data []byte) (Node, error) {
+    var raw any
+    var jsonDecoder = json.NewDecoder(bytes.NewReader(data))
+    jsonDecoder.UseNumber() // The integers can be bigger than what a float64 holds
+    if err := jsonDecoder.Decode(&raw); err != nil { return nil, err }
+
+    var this = &decoder {}
+    var node = this.decode(raw, "")
+    if this.err != nil { return nil, this.err }
+    return node, nil
+}
+
+// Returns a deep copy of the node, decoded from its encoding
+func Clone(node Node) Node {
+    if utils.IsNill(node) { return nil }
+    var data, err = EncodeJSON(node)
+    if err == nil {
+        var copy, errDecode = DecodeJSON(data)
+        if errDecode == nil { return copy }
+        err = errDecode
+    }
+    panic(fmt.Sprintf("ast: could not clone %T: %s", node, err))
+}
+
+func (this *decoder) fail(format string, args ...any) {
+    if this.err == nil { this.err = fmt.Errorf(format, args...) }
+}
+
+func (this *decoder) object(raw any, what string) map[string] any {
+    var object, ok = raw.(map[string] any)
+    if !ok {
+        this.fail("%s: expected an object but got %T", what, raw)
+        return map[string] any {}
+    }
+    return object
+}
+
+func (this *decoder) str(object map[string] any, key string) string {
+    var value, ok = object[key].(string)
+    if !ok { this.fail("%s: expected a string in '%s'", object["type"], key) }
+    return value
+}
+
+func (this *decoder) boolean(object map[string] any, key string) bool {
+    var value, ok = object[key].(bool)
+    if !ok { this.fail("%s: expected a boolean in '%s'", object["type"], key) }
+    return value
+}
+
+func (this *decoder) integer(value any, what string) int64 {
+    var number, ok = value.(json.Number)
+    if !ok {
+        this.fail("%s: expected a number", what)
+        return 0
+    }
+    var result, err = strconv.ParseInt(number.String(), 10, 64)
+    if err != nil { this.fail("%s: %s", what, err) }
+    return result
+}
+
+func (this *decoder) position(raw any) token.Position {
+    var object = this.object(raw, "position")
+    return token.Position {
+        Offset: int(this.integer(object["offset"], "offset")),
+        Line:   int(this.integer(object["line"], "line")),
+        Column: int(this.integer(object["column"], "column")),
+    }
+}
+
+func (this *decoder) list(raw any, what string) []any {
+    var list, ok = raw.([]any)
+    if !ok { this.fail("%s: expected a list but got %T", what, raw) }
+    return list
+}
+
+func (this *decoder) expression(raw any) Expression {
+    if raw == nil { return nil }
+    var exp, ok = this.decode(raw, "").(Expression)
+    if !ok && this.err == nil { this.fail("expected an expression but got %v", this.object(raw, "expression")["type"]) }
+    return exp
+}
+
+func (this *decoder) expressions(raw any, what string) []Expression {
+    var result = []Expression {}
+    for _, item := range this.list(raw, what) {
+        result = append(result, this.expression(item))
+    }
+    return result
+}
+
+func (this *decoder) statements(raw any) []Statement {
+    var result = []Statement {}
+    for _, item := range this.list(raw, "statements") {
+        var stm, ok = this.decode(item, "").(Statement)
+        if !ok && this.err == nil { this.fail("expected a statement but got %v", this.object(item, "statement")["type"]) }
+        result = append(result, stm)
+    }
+    return result
+}
+
+func (this *decoder) pattern(raw any) Pattern {
+    if raw == nil { return nil }
+    var pattern, ok = this.decode(raw, "").(Pattern)
+    if !ok && this.err == nil { this.fail("expected a pattern but got %v", this.object(raw, "pattern")["type"]) }
+    return pattern
+}
+
+func (this *decoder) patterns(raw any, what string) []Pattern {
+    var result = []Pattern {}
+    for _, item := range this.list(raw, what) {
+        result = append(result, this.pattern(item))
+    }
+    return result
+}
+
+func (this *decoder) block(raw any) *StatementsBlock {
+    if raw == nil { return nil }
+    var block, _ = this.decode(raw, "StatementsBlock").(*StatementsBlock)
+    return block
+}
+
+func (this *decoder) annotation(raw any) *TypeAnnotation {
+    if raw == nil { return nil }
+    var annotation, _ = this.decode(raw, "TypeAnnotation").(*TypeAnnotation)
+    return annotation
+}
+
+func (this *decoder) identifier(raw any) *Identifier {
+    var ident, _ = this.decode(raw, "Identifier").(*Identifier)
+    if ident == nil { return &Identifier {} }
+    return ident
+}
+
+func (this *decoder) parameters(raw any) []Identifier {
+    var result = []Identifier {}
+    for _, param := range this.list(raw, "parameters") {
+        result = append(result, *this.identifier(param))
+    }
+    return result
+}
+
+// Decodes the node, that has to be of the expected type when there is one
+func (this *decoder) decode(raw any, expected string) Node {
+    if this.err != nil { return nil }
+
+    var object = this.object(raw, "node")
+    var kind, _ = object["type"].(string)
+    if expected != "" && kind != expected {
+        this.fail("expected a %s but got %v", expected, object["type"])
+        return nil
+    }
+
+    var node Node
+    switch kind {
+    case "Program":
+        node = &Program { Statements: this.statements(object["statements"]) }
+    case "StatementsBlock":
+        node = &StatementsBlock { Statements: this.statements(object["statements"]) }
+    case "LetStatement":
+        node = &LetStatement {
+            Identifier: this.str(object, "name"),
+            NamePos:    this.position(object["namePos"]),
+            Type:       this.annotation(object["annotation"]),
+            Expression: this.expression(object["value"]),
+        }
+    case "ReturnStatement":
+        node = &ReturnStatement { Expression: this.expression(object["value"]) }
+    case "ExpressionStatement":
+        node = &ExpressionStatement { Expression: this.expression(object["expression"]) }
+    case "Identifier":
+        var ident = &Identifier { Value: this.str(object, "value"), Type: this.annotation(object["annotation"]) }
+        if object["resolved"] != nil {
+            ident.Resolved = this.boolean(object, "resolved")
+            ident.Depth = int(this.integer(object["depth"], "depth"))
+            ident.Slot = int(this.integer(object["slot"], "slot"))
+        }
+        node = ident
+    case "IntegerLiteral":
+        node = &IntegerLiteral { Value: this.integer(object["value"], kind) }
+    case "StringLiteral":
+        node = &StringLiteral { Value: this.str(object, "value") }
+    case "Boolean":
+        node = &Boolean { Value: this.boolean(object, "value") }
+    case "PrefixExpression":
+        node = &PrefixExpression { Operator: this.str(object, "operator"), Value: this.expression(object["value"]) }
+    case "InfixExpression":
+        node = &InfixExpression {
+            Operator: this.str(object, "operator"),
+            Left:     this.expression(object["left"]),
+            Right:    this.expression(object["right"]),
+        }
+    case "IfExpression":
+        node = &IfExpression {
+            Condition:        this.expression(object["condition"]),
+            ConsequenceBlock: this.block(object["consequence"]),
+            AlternativeBlock: this.block(object["alternative"]),
+        }
+    case "FunctionLiteral":
+        var fn = &FunctionLiteral { Parameters: this.parameters(object["parameters"]) }
+        fn.ReturnType = this.annotation(object["returnType"])
+        fn.Body = this.block(object["body"])
+        if object["frame"] != nil {
+            fn.Frame = []string {}
+            for _, name := range this.list(object["frame"], "frame") {
+                var str, _ = name.(string)
+                fn.Frame = append(fn.Frame, str)
+            }
+        }
+        node = fn
+    case "MacroLiteral":
+        node = &MacroLiteral { Parameters: this.parameters(object["parameters"]), Body: this.block(object["body"]) }
+    case "CallExpression":
+        node = &CallExpression {
+            Expression: this.expression(object["function"]),
+            Parameters: this.expressions(object["arguments"], "arguments"),
+        }
+    case "MethodExpression":
+        var call, _ = this.decode(object["call"], "CallExpression").(*CallExpression)
+        node = &MethodExpression { Expression: this.expression(object["receiver"]), Call: call }
+    case "ArrayLiteral":
+        node = &ArrayLiteral { Elements: this.expressions(object["elements"], "elements") }
+    case "IndexExpression":
+        node = &IndexExpression { Left: this.expression(object["left"]), Index: this.expression(object["index"]) }
+    case "HashLiteral":
+        var hash = NewHashLiteral()
+        for _, item := range this.list(object["pairs"], "pairs") {
+            var pair = this.object(item, "pair")
+            hash.Set(this.expression(pair["key"]), this.expression(pair["value"]))
+        }
+        node = hash
+    case "MatchExpression":
+        var match = &MatchExpression { Value: this.expression(object["value"]), Arms: []*MatchArm {} }
+        for _, item := range this.list(object["arms"], "arms") {
+            var arm, _ = this.decode(item, "MatchArm").(*MatchArm)
+            match.Arms = append(match.Arms, arm)
+        }
+        node = match
+    case "MatchArm":
+        node = &MatchArm {
+            Pattern: this.pattern(object["pattern"]),
+            Guard:   this.expression(object["guard"]),
+            Body:    this.expression(object["body"]),
+        }
+    case "LiteralPattern":
+        node = &LiteralPattern { Value: this.expression(object["value"]) }
+    case "WildcardPattern":
+        node = &WildcardPattern {}
+    case "BindingPattern":
+        node = &BindingPattern { Name: this.str(object, "name") }
+    case "ArrayPattern":
+        node = &ArrayPattern { Elements: this.patterns(object["elements"], "elements"), Rest: this.pattern(object["rest"]) }
+    case "HashPattern":
+        var hash = &HashPattern { Keys: []Expression {}, Values: []Pattern {} }
+        for _, item := range this.list(object["pairs"], "pairs") {
+            var pair = this.object(item, "pair")
+            hash.Keys = append(hash.Keys, this.expression(pair["key"]))
+            hash.Values = append(hash.Values, this.pattern(pair["value"]))
+        }
+        node = hash
+    case "TypeAnnotation":
+        var annotation = &TypeAnnotation { Name: this.str(object, "name"), Return: this.annotation(object["return"]) }
+        if object["arguments"] != nil {
+            annotation.Arguments = []*TypeAnnotation {}
+            for _, arg := range this.list(object["arguments"], "arguments") {
+                annotation.Arguments = append(annotation.Arguments, this.annotation(arg))
+            }
+        }
+        node = annotation
+    default:
+        this.fail("unknown node type %v", object["type"])
+        return nil
+    }
+
+    if this.err != nil { return nil }
+    node.SetSpan(this.position(object["pos"]), this.position(object["end"]))
+    return node
+}
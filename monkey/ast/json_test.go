@@ -0,0 +1,103 @@
+// monkey/ast/json_test.go
+
+package ast_test
+
+import (
+    "bytes"
+    goast "go/ast"
+    goparser "go/parser"
+    gotoken "go/token"
+    "monkey/ast"
+    "monkey/lexer"
+    "monkey/parser"
+    "monkey/resolver"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+// The programs written in the parser tests, the string literals of its source that parse
+func parserTestCorpus(t *testing.T) []string {
+    var file, err = goparser.ParseFile(gotoken.NewFileSet(), "../parser/parser_test.go", nil, 0)
+    if err != nil { t.Fatalf("Could not read the parser tests: %s", err) }
+
+    var corpus = []string {}
+    goast.Inspect(file, func (node goast.Node) bool {
+        var literal, isLiteral = node.(*goast.BasicLit)
+        if !isLiteral || literal.Kind != gotoken.STRING { return true }
+
+        var input, errUnquote = strconv.Unquote(literal.Value)
+        if errUnquote != nil || strings.TrimSpace(input) == "" { return true }
+        var p = parser.NewParser(lexer.NewLexer(input))
+        var program = p.ParseProgram()
+        if len(p.Errors()) == 0 && program != nil && len(program.Statements) > 0 { corpus = append(corpus, input) }
+        return true
+    })
+    return corpus
+}
+
+func roundTrip(t *testing.T, input string, program *ast.Program) {
+    t.Helper()
+    var data, err = ast.EncodeJSON(program)
+    if err != nil { t.Fatalf("Could not encode '%s': %s", input, err) }
+
+    var decoded, errDecode = ast.DecodeJSON(data)
+    if errDecode != nil { t.Fatalf("Could not decode '%s': %s\n%s", input, errDecode, data) }
+    if decoded.String() != program.String() {
+        t.Errorf("Expected '%s' to decode as\n%s\nbut got\n%s", input, program.String(), decoded.String())
+    }
+
+    // Nothing is lost, the spans and annotations included
+    var again, _ = ast.EncodeJSON(decoded)
+    if !bytes.Equal(again, data) { t.Errorf("Expected the encoding of the decoded '%s' to be\n%s\nbut got\n%s", input, data, again) }
+}
+
+func TestJSONRoundTripOfTheParserTests(t *testing.T) {
+    var corpus = parserTestCorpus(t)
+    if len(corpus) < 50 { t.Fatalf("Expected the parser tests to have at least 50 programs but found %d", len(corpus)) }
+
+    for _, input := range append(corpus, everyNode, `9223372036854775807; let f: fn = fn () {}`) {
+        roundTrip(t, input, parse(t, input))
+    }
+}
+
+func TestJSONKeepsTheResolverAnnotations(t *testing.T) {
+    var input = `let f = fn (a) { let b = a; fn () { a + b } }`
+    var program = parse(t, input)
+    resolver.Resolve(program)
+    roundTrip(t, input, program)
+
+    var data, _ = ast.EncodeJSON(program)
+    for _, expected := range []string { `"frame":["a","b"]`, `"resolved":true,"depth":1,"slot":1` } {
+        if !strings.Contains(string(data), expected) { t.Errorf("Expected the JSON to contain %s but got\n%s", expected, data) }
+    }
+}
+
+func TestJSONFormat(t *testing.T) {
+    var data, _ = ast.EncodeJSON(parse(t, `-x`).Statements[0])
+    var expected = `{"type":"ExpressionStatement","pos":{"offset":0,"line":1,"column":1},"end":{"offset":2,"line":1,"column":3},` +
+        `"expression":{"type":"PrefixExpression","pos":{"offset":0,"line":1,"column":1},"end":{"offset":2,"line":1,"column":3},` +
+        `"operator":"-","value":{"type":"Identifier","pos":{"offset":1,"line":1,"column":2},"end":{"offset":2,"line":1,"column":3},"value":"x"}}}`
+    if string(data) != expected { t.Errorf("Expected\n%s\nbut got\n%s", expected, data) }
+}
+
+func TestJSONDecodeErrors(t *testing.T) {
+    var pos = `"pos":{"offset":0,"line":1,"column":1},"end":{"offset":1,"line":1,"column":2}`
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `{"type":"Nope",` + pos + `}`,                                   "unknown node type Nope" },
+        { `{"type":"Program",` + pos + `,"statements":[{"type":"IntegerLiteral",` + pos + `,"value":1}]}`, "expected a statement but got IntegerLiteral" },
+        { `{"type":"StringLiteral",` + pos + `,"value":1}`,                "StringLiteral: expected a string in 'value'" },
+        { `{"type":"IntegerLiteral",` + pos + `,"value":99999999999999999999}`, "value out of range" },
+        { `[1]`,                                                           "node: expected an object but got []interface {}" },
+        { `{`,                                                             "unexpected EOF" },
+    }
+
+    for _, test := range tests {
+        var _, err = ast.DecodeJSON([]byte(test.input))
+        if err == nil || !strings.Contains(err.Error(), test.expected) {
+            t.Errorf("Expected decoding %s to fail with '%s' but got %v", test.input, test.expected, err)
+        }
+    }
+}
@@ -0,0 +1,136 @@
+// monkey/ast/print.go
+/*
+    Human views of the tree, to debug the parser and to teach it: an indented tree of the nodes
+    and a Graphviz digraph. Both show the fields of the JSON form (see json.go), the plain values
+    next to the type of the node and the nodes under the name of the field that holds them:
+
+        LetStatement 1:1-1:14 name="x" namePos=1:5
+          value: InfixExpression 1:9-1:14 operator="+"
+            left: IntegerLiteral 1:9-1:10 value=1
+            right: IntegerLiteral 1:13-1:14 value=2
+*/
+
+package ast
+
+import (
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+)
+
+// A node with its fields sorted out for printing
+type printedNode struct {
+    fields   []string // The type, the span and the plain fields
+    children []printedChild
+}
+
+type printedChild struct {
+    field string
+    node  printedNode
+}
+
+func isNodeObject(value any) bool {
+    var object, ok = value.(jsonObject)
+    return ok && len(object) > 0 && object[0].key == "type"
+}
+
+func positionText(object jsonObject) string {
+    var line, column = object[1].value.(int), object[2].value.(int)
+    return fmt.Sprintf("%d:%d", line, column)
+}
+
+// The value of a field that is not a node, as it is printed
+func plainText(value any) string {
+    switch value := value.(type) {
+    case string:
+        return strconv.Quote(value)
+    case jsonObject: // A position
+        return positionText(value)
+    case []string:
+        return "[" + strings.Join(value, " ") + "]"
+    default:
+        return fmt.Sprint(value)
+    }
+}
+
+func newPrintedNode(object jsonObject) printedNode {
+    var result = printedNode {}
+    var plain = []string { object[0].value.(string) }
+    plain = append(plain, positionText(object[1].value.(jsonObject)) + "-" + positionText(object[2].value.(jsonObject)))
+
+    // The fields are nodes, lists of nodes, the pairs of the hashes or plain values
+    var addFields func (prefix string, fields jsonObject)
+    addFields = func (prefix string, fields jsonObject) {
+        for _, field := range fields {
+            var name = prefix + field.key
+            switch value := field.value.(type) {
+            case nil:
+            case []any:
+                for i, item := range value {
+                    var itemName = fmt.Sprintf("%s[%d]", name, i)
+                    if isNodeObject(item) {
+                        result.children = append(result.children, printedChild { itemName, newPrintedNode(item.(jsonObject)) })
+                    } else if pair, isObject := item.(jsonObject); isObject {
+                        addFields(itemName + ".", pair)
+                    }
+                }
+            default:
+                if isNodeObject(value) {
+                    result.children = append(result.children, printedChild { name, newPrintedNode(value.(jsonObject)) })
+                } else {
+                    plain = append(plain, name + "=" + plainText(value))
+                }
+            }
+        }
+    }
+    addFields("", object[3:])
+
+    result.fields = plain
+    return result
+}
+
+func printable(node Node) printedNode {
+    return newPrintedNode(encodeNode(node).(jsonObject))
+}
+
+// Writes the node and its children indented, one node per line
+func WriteTree(out io.Writer, node Node) {
+    var write func (field string, node printedNode, level int)
+    write = func (field string, node printedNode, level int) {
+        var prefix = strings.Repeat("  ", level)
+        if field != "" { prefix += field + ": " }
+        fmt.Fprintln(out, prefix + strings.Join(node.fields, " "))
+        for _, child := range node.children {
+            write(child.field, child.node, level + 1)
+        }
+    }
+    write("", printable(node), 0)
+}
+
+func dotQuote(text string) string {
+    var replacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+    return `"` + replacer.Replace(text) + `"`
+}
+
+// Writes the node and its children as a Graphviz digraph, each edge named after its field
+func WriteDot(out io.Writer, node Node) {
+    fmt.Fprintln(out, "digraph ast {")
+    fmt.Fprintln(out, "  node [shape=box, fontname=\"monospace\"];")
+
+    var count = 0
+    var write func (node printedNode) int
+    write = func (node printedNode) int {
+        var id = count
+        count++
+        // The type goes on the first line and each field on its own
+        fmt.Fprintf(out, "  n%d [label=%s];\n", id, dotQuote(strings.Join(node.fields, "\n")))
+        for _, child := range node.children {
+            var childId = write(child.node)
+            fmt.Fprintf(out, "  n%d -> n%d [label=%s];\n", id, childId, dotQuote(child.field))
+        }
+        return id
+    }
+    write(printable(node))
+    fmt.Fprintln(out, "}")
+}
@@ -0,0 +1,77 @@
+// monkey/ast/print_test.go
+
+package ast_test
+
+import (
+    "bytes"
+    "monkey/ast"
+    "monkey/resolver"
+    "regexp"
+    "strings"
+    "testing"
+)
+
+func TestWriteTree(t *testing.T) {
+    var program = parse(t, `let f = fn (a: int) { { "k": a } }; f(1) + [2][0]`)
+    resolver.Resolve(program)
+    var out bytes.Buffer
+    ast.WriteTree(&out, program)
+
+    var expected = `Program 1:1-1:50
+  statements[0]: LetStatement 1:1-1:35 name="f" namePos=1:5
+    value: FunctionLiteral 1:9-1:35 frame=[a]
+      parameters[0]: Identifier 1:13-1:14 value="a"
+        annotation: TypeAnnotation 1:16-1:19 name="int"
+      body: StatementsBlock 1:21-1:35
+        statements[0]: ExpressionStatement 1:23-1:33
+          expression: HashLiteral 1:23-1:33
+            pairs[0].key: StringLiteral 1:25-1:28 value="k"
+            pairs[0].value: Identifier 1:30-1:31 value="a" resolved=true depth=0 slot=0
+  statements[1]: ExpressionStatement 1:37-1:50
+    expression: InfixExpression 1:37-1:50 operator="+"
+      left: CallExpression 1:37-1:41
+        function: Identifier 1:37-1:38 value="f"
+        arguments[0]: IntegerLiteral 1:39-1:40 value=1
+      right: IndexExpression 1:44-1:50
+        left: ArrayLiteral 1:44-1:47
+          elements[0]: IntegerLiteral 1:45-1:46 value=2
+        index: IntegerLiteral 1:48-1:49 value=0
+`
+    if out.String() != expected { t.Errorf("Expected the tree\n%s\nbut got\n%s", expected, out.String()) }
+}
+
+func TestWriteDot(t *testing.T) {
+    var out bytes.Buffer
+    ast.WriteDot(&out, parse(t, `puts("a b"); if (x) { 1 }`))
+    var dot = out.String()
+
+    if !strings.HasPrefix(dot, "digraph ast {\n") || !strings.HasSuffix(dot, "}\n") {
+        t.Fatalf("Expected a digraph but got\n%s", dot)
+    }
+    for _, expected := range []string {
+        `  n0 [label="Program\n1:1-1:26"];`,
+        `  n3 [label="Identifier\n1:1-1:5\nvalue=\"puts\""];`,
+        `  n4 [label="StringLiteral\n1:6-1:11\nvalue=\"a b\""];`,
+        `  n2 -> n4 [label="arguments[0]"];`,
+        `  n5 -> n6 [label="expression"];`,
+    } {
+        if !strings.Contains(dot, expected) { t.Errorf("Expected the digraph to contain\n%s\nbut got\n%s", expected, dot) }
+    }
+
+    // Each node but the root has one edge to it
+    var nodes = regexp.MustCompile(`(?m)^  n\d+ \[label=`).FindAllString(dot, -1)
+    var edges = regexp.MustCompile(`(?m)^  n\d+ -> n\d+ `).FindAllString(dot, -1)
+    if len(nodes) != 11 || len(edges) != len(nodes) - 1 {
+        t.Errorf("Expected 11 nodes and 10 edges but got %d and %d\n%s", len(nodes), len(edges), dot)
+    }
+}
+
+func TestWriteTreePrintsEveryNodeType(t *testing.T) {
+    var out bytes.Buffer
+    ast.WriteTree(&out, parse(t, everyNode))
+
+    for _, name := range nodeTypes(t) {
+        var pattern = regexp.MustCompile(`(?m)^ *(\w+(\[\d+\])?(\.\w+)?: )?` + strings.TrimPrefix(name, "*ast.") + ` \d+:\d+-\d+:\d+`)
+        if !pattern.MatchString(out.String()) { t.Errorf("Expected the tree to print a %s but got\n%s", name, out.String()) }
+    }
+}
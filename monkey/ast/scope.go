@@ -0,0 +1,48 @@
+// monkey/ast/scope.go
+/*
+    The bindings a block of statements makes in the environment it runs in. The evaluator gives
+    the program and each function body an environment, the if blocks run in the one they are in,
+    so the lets written in them bind there too:
+
+        let f = fn (x) {
+            if (x > 0) { let sign = 1 } else { let sign = -1 };
+            sign * x
+        };
+
+    The static passes (the resolver, the type checker and the language server) declare those lets
+    before walking a scope, each one with its own bindings
+*/
+
+package ast
+
+import "monkey/token"
+
+// Calls visit with each let that binds its name in the scope of the statements, in the order they
+// were written: the lets of the statements and the ones in the blocks of an if that is a statement
+// or the value of one. The bodies of the functions are scopes of their own and are not entered
+func ScopeLets(stms []Statement, visit func (let *LetStatement)) {
+    for _, stm := range stms {
+        switch stm := stm.(type) {
+        case *LetStatement:
+            visit(stm)
+            IfLets(stm.Expression, visit)
+        case *ExpressionStatement:
+            IfLets(stm.Expression, visit)
+        case *ReturnStatement:
+            IfLets(stm.Expression, visit)
+        }
+    }
+}
+
+// Calls visit with the lets of the blocks when the expression is an if, see ScopeLets
+func IfLets(exp Expression, visit func (let *LetStatement)) {
+    var ifExp, isIf = exp.(*IfExpression)
+    if !isIf { return }
+    ScopeLets(ifExp.ConsequenceBlock.Statements, visit)
+    if ifExp.AlternativeBlock != nil { ScopeLets(ifExp.AlternativeBlock.Statements, visit) }
+}
+
+// Where the name written at the position ends, the names are always on one line
+func NameEnd(pos token.Position, name string) token.Position {
+    return token.Position { Offset: pos.Offset + len(name), Line: pos.Line, Column: pos.Column + len(name) }
+}
@@ -0,0 +1,35 @@
+// monkey/ast/scope_test.go
+
+package ast_test
+
+import (
+    "monkey/ast"
+    "monkey/lexer"
+    "monkey/parser"
+    "strings"
+    "testing"
+)
+
+func TestScopeLets(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `let a = 1; let b = 2`,                                               "a b"   },
+        { `if (true) { let a = 1 } else { let b = 2 }; let c = 3`,              "a b c" },
+        { `let a = if (true) { let b = 1; b }; return if (a) { let c = 2 }`,    "a b c" },
+        { `let f = fn (x) { let y = x; y }; puts(if (1) { let z = 1 })`,        "f"     },
+        { `match (1) { _ => if (true) { let a = 1 } }`,                         ""      },
+    }
+
+    for _, test := range tests {
+        var p = parser.NewParser(lexer.NewLexer(test.input))
+        var program = p.ParseProgram()
+        if len(p.Errors()) > 0 { t.Fatalf("Unexpected parser errors: %v", p.Errors()) }
+
+        var names = []string {}
+        ast.ScopeLets(program.Statements, func (let *ast.LetStatement) { names = append(names, let.Identifier) })
+        if strings.Join(names, " ") != test.expected {
+            t.Errorf("Expected the lets of '%s' to be '%s' but got '%s'", test.input, test.expected, strings.Join(names, " "))
+        }
+    }
+}
@@ -0,0 +1,298 @@
+// monkey/ast/walk.go
+/*
+    Traversals of the tree in the spirit of go/ast: Walk with a Visitor, Inspect with a function and
+    Apply, that can replace the nodes while it goes. The children are visited in the order they
+    were written: the key of each pair of a hash before its value, the parameters of a function
+    before its return type and body
+*/
+
+package ast
+
+import (
+    "fmt"
+    "monkey/utils"
+)
+
+// Visit is called with each node. When it returns a visitor Walk goes on with the children of the
+// node using it, and calls its Visit with nil after them
+type Visitor interface {
+    Visit(node Node) Visitor
+}
+
+// The children of the node in order, without the nil ones
+func children(node Node) []Node {
+    var result = []Node {}
+    var add = func (child Node) {
+        if !utils.IsNill(child) { result = append(result, child) }
+    }
+
+    switch node := node.(type) {
+    case *Program:
+        for _, stm := range node.Statements { add(stm) }
+    case *StatementsBlock:
+        for _, stm := range node.Statements { add(stm) }
+    case *LetStatement:
+        add(node.Type)
+        add(node.Expression)
+    case *ReturnStatement:
+        add(node.Expression)
+    case *ExpressionStatement:
+        add(node.Expression)
+    case *Identifier:
+        add(node.Type)
+    case *IntegerLiteral, *StringLiteral, *Boolean:
+    case *PrefixExpression:
+        add(node.Value)
+    case *InfixExpression:
+        add(node.Left)
+        add(node.Right)
+    case *IfExpression:
+        add(node.Condition)
+        add(node.ConsequenceBlock)
+        add(node.AlternativeBlock)
+    case *FunctionLiteral:
+        for i := range node.Parameters { add(&node.Parameters[i]) }
+        add(node.ReturnType)
+        add(node.Body)
+    case *MacroLiteral:
+        for i := range node.Parameters { add(&node.Parameters[i]) }
+        add(node.Body)
+    case *CallExpression:
+        add(node.Expression)
+        for _, param := range node.Parameters { add(param) }
+    case *MethodExpression:
+        add(node.Expression)
+        add(node.Call)
+    case *ArrayLiteral:
+        for _, elem := range node.Elements { add(elem) }
+    case *IndexExpression:
+        add(node.Left)
+        add(node.Index)
+    case *HashLiteral:
+        for _, key := range node.Keys {
+            add(key)
+            add(node.Pairs[key])
+        }
+    case *MatchExpression:
+        add(node.Value)
+        for _, arm := range node.Arms { add(arm) }
+    case *MatchArm:
+        add(node.Pattern)
+        add(node.Guard)
+        add(node.Body)
+    case *LiteralPattern:
+        add(node.Value)
+    case *WildcardPattern, *BindingPattern:
+    case *ArrayPattern:
+        for _, elem := range node.Elements { add(elem) }
+        add(node.Rest)
+    case *HashPattern:
+        for i, key := range node.Keys {
+            add(key)
+            add(node.Values[i])
+        }
+    case *TypeAnnotation:
+        for _, arg := range node.Arguments { add(arg) }
+        add(node.Return)
+    default:
+        panic(fmt.Sprintf("ast: unexpected node type %T", node))
+    }
+    return result
+}
+
+// Visits the node and its children depth first
+func Walk(visitor Visitor, node Node) {
+    if visitor = visitor.Visit(node); visitor == nil { return }
+    for _, child := range children(node) {
+        Walk(visitor, child)
+    }
+    visitor.Visit(nil)
+}
+
+type inspector func (node Node) bool
+
+// @Impl
+func (this inspector) Visit(node Node) Visitor {
+    if node != nil && this(node) { return this }
+    return nil
+}
+
+// Calls visit with each node depth first, the children of a node are skipped when it returns false
+func Inspect(node Node, visit func (node Node) bool) {
+    Walk(inspector(visit), node)
+}
+
+// The place of a node during Apply
+type Cursor struct {
+    node    Node
+    parent  Node
+    replace func (node Node)
+}
+
+// The current node
+func (this *Cursor) Node() Node { return this.node }
+
+// The node that holds the current one, nil for the root
+func (this *Cursor) Parent() Node { return this.parent }
+
+// Puts the node in the place of the current one. The new node must fit the field that holds it:
+// an Expression where the parent has an expression, an *Identifier for the parameters...
+func (this *Cursor) Replace(node Node) {
+    this.replace(node)
+    this.node = node
+}
+
+// Called by Apply before and after the children of each node. When pre returns false the children
+// and post are skipped, when post returns false Apply stops
+type ApplyFunc func (cursor *Cursor) bool
+
+type applier struct {
+    pre  ApplyFunc
+    post ApplyFunc
+}
+
+// Traverses the tree like Inspect, letting pre and post replace the nodes through the cursor. The
+// children of the replacements made by pre are the ones traversed. Returns the root, that can be
+// replaced too. Either function can be nil
+func Apply(root Node, pre ApplyFunc, post ApplyFunc) Node {
+    var this = &applier { pre: pre, post: post }
+    this.apply(nil, root, func (node Node) { root = node })
+    return root
+}
+
+// Returns false when post asked to stop
+func (this *applier) apply(parent Node, node Node, replace func (node Node)) bool {
+    if utils.IsNill(node) { return true }
+
+    var cursor = &Cursor { node: node, parent: parent, replace: replace }
+    if this.pre != nil && !this.pre(cursor) { return true }
+
+    if !this.applyChildren(cursor.node) { return false }
+
+    if this.post != nil && !this.post(cursor) { return false }
+    return true
+}
+
+func (this *applier) applyExpressions(parent Node, exps []Expression) bool {
+    for i := range exps {
+        if !this.apply(parent, exps[i], func (node Node) { exps[i] = node.(Expression) }) { return false }
+    }
+    return true
+}
+
+func (this *applier) applyStatements(parent Node, stms []Statement) bool {
+    for i := range stms {
+        if !this.apply(parent, stms[i], func (node Node) { stms[i] = node.(Statement) }) { return false }
+    }
+    return true
+}
+
+func (this *applier) applyPatterns(parent Node, patterns []Pattern) bool {
+    for i := range patterns {
+        if !this.apply(parent, patterns[i], func (node Node) { patterns[i] = node.(Pattern) }) { return false }
+    }
+    return true
+}
+
+// The parameters are values, the replacement is copied once its children are done
+func (this *applier) applyParameters(parent Node, params []Identifier) bool {
+    for i := range params {
+        var replaced *Identifier
+        var ok = this.apply(parent, &params[i], func (n Node) { replaced = n.(*Identifier) })
+        if replaced != nil { params[i] = *replaced }
+        if !ok { return false }
+    }
+    return true
+}
+
+func (this *applier) applyChildren(node Node) bool {
+    switch node := node.(type) {
+    case *Program:
+        return this.applyStatements(node, node.Statements)
+    case *StatementsBlock:
+        return this.applyStatements(node, node.Statements)
+    case *LetStatement:
+        return this.apply(node, node.Type, func (n Node) { node.Type = n.(*TypeAnnotation) }) &&
+            this.apply(node, node.Expression, func (n Node) { node.Expression = n.(Expression) })
+    case *ReturnStatement:
+        return this.apply(node, node.Expression, func (n Node) { node.Expression = n.(Expression) })
+    case *ExpressionStatement:
+        return this.apply(node, node.Expression, func (n Node) { node.Expression = n.(Expression) })
+    case *Identifier:
+        return this.apply(node, node.Type, func (n Node) { node.Type = n.(*TypeAnnotation) })
+    case *IntegerLiteral, *StringLiteral, *Boolean:
+        return true
+    case *PrefixExpression:
+        return this.apply(node, node.Value, func (n Node) { node.Value = n.(Expression) })
+    case *InfixExpression:
+        return this.apply(node, node.Left, func (n Node) { node.Left = n.(Expression) }) &&
+            this.apply(node, node.Right, func (n Node) { node.Right = n.(Expression) })
+    case *IfExpression:
+        return this.apply(node, node.Condition, func (n Node) { node.Condition = n.(Expression) }) &&
+            this.apply(node, node.ConsequenceBlock, func (n Node) { node.ConsequenceBlock = n.(*StatementsBlock) }) &&
+            this.apply(node, node.AlternativeBlock, func (n Node) { node.AlternativeBlock = n.(*StatementsBlock) })
+    case *FunctionLiteral:
+        return this.applyParameters(node, node.Parameters) &&
+            this.apply(node, node.ReturnType, func (n Node) { node.ReturnType = n.(*TypeAnnotation) }) &&
+            this.apply(node, node.Body, func (n Node) { node.Body = n.(*StatementsBlock) })
+    case *MacroLiteral:
+        return this.applyParameters(node, node.Parameters) &&
+            this.apply(node, node.Body, func (n Node) { node.Body = n.(*StatementsBlock) })
+    case *CallExpression:
+        return this.apply(node, node.Expression, func (n Node) { node.Expression = n.(Expression) }) &&
+            this.applyExpressions(node, node.Parameters)
+    case *MethodExpression:
+        return this.apply(node, node.Expression, func (n Node) { node.Expression = n.(Expression) }) &&
+            this.apply(node, node.Call, func (n Node) { node.Call = n.(*CallExpression) })
+    case *ArrayLiteral:
+        return this.applyExpressions(node, node.Elements)
+    case *IndexExpression:
+        return this.apply(node, node.Left, func (n Node) { node.Left = n.(Expression) }) &&
+            this.apply(node, node.Index, func (n Node) { node.Index = n.(Expression) })
+    case *HashLiteral:
+        // The keys are the keys of the pairs map too, replacing one moves its value to the new key
+        for i := range node.Keys {
+            var key = node.Keys[i]
+            var replaceKey = func (n Node) {
+                var value = node.Pairs[node.Keys[i]]
+                delete(node.Pairs, node.Keys[i])
+                node.Keys[i] = n.(Expression)
+                node.Pairs[node.Keys[i]] = value
+            }
+            if !this.apply(node, key, replaceKey) { return false }
+            var replaceValue = func (n Node) { node.Pairs[node.Keys[i]] = n.(Expression) }
+            if !this.apply(node, node.Pairs[node.Keys[i]], replaceValue) { return false }
+        }
+        return true
+    case *MatchExpression:
+        if !this.apply(node, node.Value, func (n Node) { node.Value = n.(Expression) }) { return false }
+        for i := range node.Arms {
+            if !this.apply(node, node.Arms[i], func (n Node) { node.Arms[i] = n.(*MatchArm) }) { return false }
+        }
+        return true
+    case *MatchArm:
+        return this.apply(node, node.Pattern, func (n Node) { node.Pattern = n.(Pattern) }) &&
+            this.apply(node, node.Guard, func (n Node) { node.Guard = n.(Expression) }) &&
+            this.apply(node, node.Body, func (n Node) { node.Body = n.(Expression) })
+    case *LiteralPattern:
+        return this.apply(node, node.Value, func (n Node) { node.Value = n.(Expression) })
+    case *WildcardPattern, *BindingPattern:
+        return true
+    case *ArrayPattern:
+        if !this.applyPatterns(node, node.Elements) { return false }
+        return this.apply(node, node.Rest, func (n Node) { node.Rest = n.(Pattern) })
+    case *HashPattern:
+        for i := range node.Keys {
+            if !this.apply(node, node.Keys[i], func (n Node) { node.Keys[i] = n.(Expression) }) { return false }
+            if !this.apply(node, node.Values[i], func (n Node) { node.Values[i] = n.(Pattern) }) { return false }
+        }
+        return true
+    case *TypeAnnotation:
+        for i := range node.Arguments {
+            if !this.apply(node, node.Arguments[i], func (n Node) { node.Arguments[i] = n.(*TypeAnnotation) }) { return false }
+        }
+        return this.apply(node, node.Return, func (n Node) { node.Return = n.(*TypeAnnotation) })
+    default:
+        panic(fmt.Sprintf("ast: unexpected node type %T", node))
+    }
+}
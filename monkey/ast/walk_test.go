@@ -0,0 +1,184 @@
+// monkey/ast/walk_test.go
+
+package ast_test
+
+import (
+    "fmt"
+    goast "go/ast"
+    goparser "go/parser"
+    gotoken "go/token"
+    "io/fs"
+    "monkey/ast"
+    "monkey/lexer"
+    "monkey/parser"
+    "sort"
+    "strings"
+    "testing"
+)
+
+// Uses every kind of node
+const everyNode = `
+let x: array<int> = [1, "s", true];
+let f = fn (a: int) -> fn(int) -> int { return -a };
+let m = macro (a) { quote(unquote(a) + 1) };
+if (x[0] < 2) { f(1) } else { { "k": 1 }.len() };
+match (x) { -1 => "one", [a, ...r] if a > 0 => r, { "k": _ } => 0, y => y }`
+
+func parse(t *testing.T, input string) *ast.Program {
+    t.Helper()
+    var p = parser.NewParser(lexer.NewLexer(input))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { t.Fatalf("Unexpected parser errors: %v", p.Errors()) }
+    return program
+}
+
+// The types of the package that implement Node, read from its source so a new one cannot be
+// missed by the walkers or by everyNode
+func nodeTypes(t *testing.T) []string {
+    var files, err = goparser.ParseDir(gotoken.NewFileSet(), ".", func (info fs.FileInfo) bool {
+        return !strings.HasSuffix(info.Name(), "_test.go")
+    }, 0)
+    if err != nil { t.Fatalf("Could not parse the package: %s", err) }
+
+    var names = []string {}
+    for _, pkg := range files {
+        for _, file := range pkg.Files {
+            for _, decl := range file.Decls {
+                var fn, isFn = decl.(*goast.FuncDecl)
+                if !isFn || fn.Name.Name != "node" || fn.Recv == nil || fn.Type.Params.NumFields() > 0 { continue }
+                var receiver = fn.Recv.List[0].Type.(*goast.StarExpr).X.(*goast.Ident)
+                names = append(names, "*ast." + receiver.Name)
+            }
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+func sortedKeys(set map[string] bool) []string {
+    var keys = []string {}
+    for key := range set {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func TestEveryNodeTypeIsVisited(t *testing.T) {
+    var expected = strings.Join(nodeTypes(t), " ")
+
+    var inspected = map[string] bool {}
+    ast.Inspect(parse(t, everyNode), func (node ast.Node) bool {
+        inspected[fmt.Sprintf("%T", node)] = true
+        return true
+    })
+    if got := strings.Join(sortedKeys(inspected), " "); got != expected {
+        t.Errorf("Expected ast.Inspect to visit\n%s\nbut it visited\n%s", expected, got)
+    }
+
+    var applied = map[string] bool {}
+    ast.Apply(parse(t, everyNode), func (cursor *ast.Cursor) bool {
+        applied[fmt.Sprintf("%T", cursor.Node())] = true
+        return true
+    }, nil)
+    if got := strings.Join(sortedKeys(applied), " "); got != expected {
+        t.Errorf("Expected ast.Apply to visit\n%s\nbut it visited\n%s", expected, got)
+    }
+}
+
+func TestInspectOrder(t *testing.T) {
+    var visited = []string {}
+    ast.Inspect(parse(t, `let x: hash<string, int> = { "a": b[1] }; f(-2)`), func (node ast.Node) bool {
+        if _, isFn := node.(*ast.IndexExpression); isFn { return false } // Skips its children
+        if _, isProgram := node.(*ast.Program); !isProgram { visited = append(visited, node.String()) }
+        return true
+    })
+
+    var expected = `let x: hash<string, int> = { a: b[1] }|hash<string, int>|string|int|{ a: b[1] }|a|` +
+        `f((-2))|f((-2))|f|(-2)|2`
+    if got := strings.Join(visited, "|"); got != expected {
+        t.Errorf("Expected the nodes\n%s\nbut got\n%s", expected, got)
+    }
+}
+
+type depthVisitor struct {
+    depth *int
+    max   *int
+}
+
+// @Impl
+func (this depthVisitor) Visit(node ast.Node) ast.Visitor {
+    if node == nil {
+        *this.depth--
+        return nil
+    }
+    *this.depth++
+    *this.max = max(*this.max, *this.depth)
+    return this
+}
+
+func TestWalkCallsVisitWithNilAfterTheChildren(t *testing.T) {
+    var depth, deepest = 0, 0
+    // Program > ExpressionStatement > InfixExpression > InfixExpression > IntegerLiteral
+    ast.Walk(depthVisitor { &depth, &deepest }, parse(t, `1 + 2 * 3`))
+    if depth != 0 || deepest != 5 {
+        t.Errorf("Expected to end at depth 0 after reaching 5 but got %d and %d", depth, deepest)
+    }
+}
+
+func TestApplyReplacesNodes(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `let x = 1 + y * 2`,                  "let x = (2 + (z * 4))" },
+        { `fn (y: int) { [y, 3] }`,             "fn (z: int) { [z, 6] }" },
+        { `{ y: 1, "b": y }`,                   "{ z: 2, b: z }" },
+        { `a.push(y, [1][0])`,                  "a.push(z, [2][0])" },
+        { `if (y) { return y }`,                "if z {return z}" },
+    }
+
+    for _, test := range tests {
+        var program = parse(t, test.input)
+        ast.Apply(program, nil, func (cursor *ast.Cursor) bool {
+            switch node := cursor.Node().(type) {
+            case *ast.IntegerLiteral:
+                if _, isIndex := cursor.Parent().(*ast.IndexExpression); !isIndex { cursor.Replace(ast.NewIntegerLiteral(node.Value * 2)) }
+            case *ast.Identifier:
+                if node.Value == "y" {
+                    var renamed = ast.NewIdentifier("z")
+                    renamed.Type = node.Type
+                    cursor.Replace(renamed)
+                }
+            }
+            return true
+        })
+        if got := program.Statements[0].String(); got != test.expected {
+            t.Errorf("Expected '%s' to be rewritten as '%s' but got '%s'", test.input, test.expected, got)
+        }
+    }
+
+    // The replaced hash keys keep their values
+    var hash = parse(t, `{ y: 1 }`).Statements[0].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral)
+    ast.Apply(hash, func (cursor *ast.Cursor) bool {
+        if ident, isIdent := cursor.Node().(*ast.Identifier); isIdent && ident.Value == "y" { cursor.Replace(ast.NewIdentifier("z")) }
+        return true
+    }, nil)
+    if value := hash.Pairs[hash.Keys[0]]; value == nil || value.String() != "1" {
+        t.Errorf("Expected the new key to keep the value 1 but got %v", value)
+    }
+}
+
+func TestApplyStopsAndReplacesTheRoot(t *testing.T) {
+    var count = 0
+    ast.Apply(parse(t, `1; 2; 3; 4`), nil, func (cursor *ast.Cursor) bool {
+        if _, isInt := cursor.Node().(*ast.IntegerLiteral); isInt { count++ }
+        return count < 2
+    })
+    if count != 2 { t.Errorf("Expected ast.Apply to stop after 2 integers but it visited %d", count) }
+
+    var root = ast.Apply(parse(t, `1`), func (cursor *ast.Cursor) bool {
+        if cursor.Parent() == nil { cursor.Replace(parse(t, `2; 3`)) }
+        return true
+    }, nil)
+    if got := root.String(); got != "2;\n3;\n" { t.Errorf("Expected the new root but got %q", got) }
+}
@@ -0,0 +1,485 @@
+// monkey/checker/checker.go
+/*
+    Static type checker for the optional annotations. The types of the names without annotation
+    are inferred from their values, one statement at a time, and the values the checker cannot
+    know (the parameters without annotation, the names bound later, most builtins) are any, that
+    matches everything. A name has the type of the last let of it the checker went through, so the
+    same name can hold values of different types along a scope
+*/
+
+package checker
+
+import (
+    "fmt"
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/token"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+type Error struct {
+    Pos     token.Position
+    End     token.Position
+    Message string
+}
+
+func (this Error) String() string {
+    return fmt.Sprintf("%s: %s", this.Pos, this.Message)
+}
+
+type scope struct {
+    outer    *scope
+    names    map[string] *Type
+    function *function // Nil for the program
+}
+
+// The function being checked, for its return statements
+type function struct {
+    declared *Type   // The annotated return type, nil when there is none
+    returned []*Type // The types of its return statements
+}
+
+// Reports a part of a value that is not of the type expected of it
+type mismatch func (node ast.Node, value *Type, expected *Type)
+
+type checker struct {
+    errors []Error
+}
+
+// Checks the program and returns its errors sorted by position
+func Check(program *ast.Program) []Error {
+    var this = &checker {}
+    var global = &scope { names: map[string] *Type {} }
+    ast.ScopeLets(program.Statements, global.declareLet)
+    this.statements(global, program.Statements)
+
+    sort.SliceStable(this.errors, func (i int, j int) bool {
+        return this.errors[i].Pos.Offset < this.errors[j].Pos.Offset
+    })
+    return this.errors
+}
+
+func (this *checker) report(node ast.Node, format string, args ...any) {
+    this.errors = append(this.errors, Error { Pos: node.Pos(), End: node.End(), Message: fmt.Sprintf(format, args...) })
+}
+
+// The lets of a scope are any until the checker reaches them. A function that uses a name bound
+// later in its scope gets that one, not another of the same name from outside
+func (this *scope) declareLet(let *ast.LetStatement) {
+    if _, found := this.names[let.Identifier]; !found { this.names[let.Identifier] = Any }
+}
+
+func (this *scope) lookup(name string) (*Type, bool) {
+    for sc := this; sc != nil; sc = sc.outer {
+        if t, found := sc.names[name]; found { return t, true }
+    }
+    return nil, false
+}
+
+// The type of the annotation, any when it is wrong
+func (this *checker) annotation(annotation *ast.TypeAnnotation) *Type {
+    var t, err = fromAnnotation(annotation)
+    if err != nil {
+        this.report(annotation, "%s", err)
+        return Any
+    }
+    return t
+}
+
+// The type a builtin returns, from the signature of its doc: 'len(...) -> Integer'
+func builtinResult(name string) *Type {
+    var doc, found = evaluator.GetBuiltinDoc(name)
+    if !found { return Any }
+
+    var arrow = strings.LastIndex(doc.Signature, "->")
+    if arrow < 0 { return Any }
+    switch strings.TrimSpace(doc.Signature[arrow + 2:]) {
+    case "Integer":
+        return Int
+    case "Boolean":
+        return Bool
+    case "String":
+        return String
+    case "Array":
+        return ArrayOf(Any)
+    case "Hash":
+        return HashOf(Any, Any)
+    default:
+        return Any
+    }
+}
+
+// The signature the annotations of the function give, before checking its body
+func (this *checker) signature(fn *ast.FunctionLiteral) *Type {
+    var params = []*Type {}
+    for _, param := range fn.Parameters {
+        if param.Type != nil {
+            params = append(params, this.annotation(param.Type))
+        } else {
+            params = append(params, Any)
+        }
+    }
+
+    var result = Any
+    if fn.ReturnType != nil { result = this.annotation(fn.ReturnType) }
+    return FunctionOf(params, result)
+}
+
+// Checks the statements and returns the type of the value of the last one
+func (this *checker) statements(sc *scope, stms []ast.Statement) *Type {
+    var last = Null
+    for _, stm := range stms {
+        last = this.statement(sc, stm)
+    }
+    return last
+}
+
+func (this *checker) statement(sc *scope, stm ast.Statement) *Type {
+    switch stm := stm.(type) {
+    case *ast.LetStatement:
+        var declared *Type
+        if stm.Type != nil { declared = this.annotation(stm.Type) }
+
+        // The functions can call themselves, so their name is bound before checking them
+        if fn, isFn := stm.Expression.(*ast.FunctionLiteral); isFn && declared == nil {
+            sc.names[stm.Identifier] = this.signature(fn)
+        } else if declared != nil {
+            sc.names[stm.Identifier] = declared
+        }
+
+        if declared == nil {
+            sc.names[stm.Identifier] = this.expression(sc, stm.Expression)
+            return Null
+        }
+        this.expect(sc, stm.Expression, declared, func (node ast.Node, value *Type, expected *Type) {
+            this.report(node, "cannot use %s as %s in the let of '%s'", value, expected, stm.Identifier)
+        })
+        return Null
+    case *ast.ReturnStatement:
+        if sc.function == nil { return this.expression(sc, stm.Expression) }
+        var value *Type
+        if sc.function.declared == nil {
+            value = this.expression(sc, stm.Expression)
+        } else {
+            value = this.expect(sc, stm.Expression, sc.function.declared, this.resultMismatch(sc.function))
+        }
+        sc.function.returned = append(sc.function.returned, value)
+        return value
+    case *ast.ExpressionStatement:
+        return this.expression(sc, stm.Expression)
+    default:
+        return Any
+    }
+}
+
+// Reports the parts of the value a function gives back that are not of the type of its annotation
+func (this *checker) resultMismatch(fn *function) mismatch {
+    return func (node ast.Node, value *Type, expected *Type) {
+        if expected == fn.declared {
+            this.report(node, "cannot return %s from a function that returns %s", value, fn.declared)
+        } else {
+            this.report(node, "cannot use %s as %s in the result of a function that returns %s", value, expected, fn.declared)
+        }
+    }
+}
+
+// Checks the value of the expression against the type expected of it. The arrays, hashes, ifs and
+// matches written in place are checked part by part, so a wrong element or branch is reported where
+// it is instead of the whole value being any
+func (this *checker) expect(sc *scope, exp ast.Expression, expected *Type, report mismatch) *Type {
+    switch exp := exp.(type) {
+    case *ast.ArrayLiteral:
+        if expected.Name != "array" { break }
+        for _, element := range exp.Elements {
+            this.expect(sc, element, expected.Arguments[0], report)
+        }
+        return expected
+    case *ast.HashLiteral:
+        if expected.Name != "hash" { break }
+        for _, key := range exp.Keys {
+            this.expect(sc, key, expected.Arguments[0], report)
+            this.expect(sc, exp.Pairs[key], expected.Arguments[1], report)
+        }
+        return expected
+    case *ast.IfExpression:
+        if exp.AlternativeBlock == nil { break } // Null when the condition is false
+        return this.ifExpression(sc, exp, expected, report)
+    case *ast.MatchExpression:
+        return this.match(sc, exp, expected, report)
+    }
+
+    var value = this.expression(sc, exp)
+    if !value.AssignableTo(expected) { report(exp, value, expected) }
+    return value
+}
+
+func (this *checker) expression(sc *scope, exp ast.Expression) *Type {
+    switch exp := exp.(type) {
+    case *ast.IntegerLiteral:
+        return Int
+    case *ast.StringLiteral:
+        return String
+    case *ast.Boolean:
+        return Bool
+    case *ast.Identifier:
+        if t, found := sc.lookup(exp.Value); found { return t }
+        return Any
+    case *ast.PrefixExpression:
+        return this.prefix(sc, exp)
+    case *ast.InfixExpression:
+        return this.infix(sc, exp)
+    case *ast.IfExpression:
+        return this.ifExpression(sc, exp, nil, nil)
+    case *ast.MatchExpression:
+        return this.match(sc, exp, nil, nil)
+    case *ast.FunctionLiteral:
+        return this.function(sc, exp)
+    case *ast.CallExpression:
+        return this.call(sc, exp)
+    case *ast.MethodExpression: // The receiver is the first argument of the builtin
+        this.expression(sc, exp.Expression)
+        for _, param := range exp.Call.Parameters {
+            this.expression(sc, param)
+        }
+        return builtinResult(exp.Call.Expression.String())
+    case *ast.ArrayLiteral:
+        var elem *Type
+        for _, element := range exp.Elements {
+            elem = join(elem, this.expression(sc, element))
+        }
+        if elem == nil { elem = Any }
+        return ArrayOf(elem)
+    case *ast.HashLiteral:
+        var key, value *Type
+        for _, k := range exp.Keys {
+            key = join(key, this.expression(sc, k))
+            value = join(value, this.expression(sc, exp.Pairs[k]))
+        }
+        if key == nil { key, value = Any, Any }
+        return HashOf(key, value)
+    case *ast.IndexExpression:
+        return this.index(sc, exp)
+    default:
+        return Any
+    }
+}
+
+func (this *checker) prefix(sc *scope, exp *ast.PrefixExpression) *Type {
+    var value = this.expression(sc, exp.Value)
+    var result = Int
+    if exp.Operator == "!" {
+        result = Bool
+        if value == Bool { return result }
+    }
+    if !value.isAny() && value != Int { this.report(exp, "unknown operator: %s%s", exp.Operator, value) }
+    return result
+}
+
+func (this *checker) infix(sc *scope, exp *ast.InfixExpression) *Type {
+    var left = this.expression(sc, exp.Left)
+    var right = this.expression(sc, exp.Right)
+
+    var comparison = false
+    switch exp.Operator {
+    case "==", "!=", "<", ">":
+        comparison = true
+    }
+
+    switch {
+    case left.isAny() || right.isAny():
+        if comparison { return Bool }
+        if exp.Operator == "+" && (left == String || right == String) { return String }
+        if left == Int || right == Int { return Int }
+        return Any
+    case left.Name != right.Name:
+        this.report(exp, "type mismatch: %s %s %s", left, exp.Operator, right)
+    case left == Int:
+        if comparison { return Bool }
+        return Int
+    case left == String && exp.Operator == "+":
+        return String
+    default:
+        this.report(exp, "unknown operator: %s %s %s", left, exp.Operator, right)
+    }
+    return Any
+}
+
+// The if takes the value of the first statement of the branch it runs, as the evaluator does. When
+// a type is expected of it, each branch is checked against it, see expect
+func (this *checker) ifExpression(sc *scope, exp *ast.IfExpression, expected *Type, report mismatch) *Type {
+    var condition = this.expression(sc, exp.Condition)
+    if !condition.isAny() && condition != Bool && condition != Int {
+        this.report(exp.Condition, "the condition is %s, the if only takes a branch on bool and int", condition)
+    }
+
+    var branch = func (block *ast.StatementsBlock) *Type {
+        var first *Type
+        for i, stm := range block.Statements {
+            var t *Type
+            if expStm, isExp := stm.(*ast.ExpressionStatement); isExp && i == 0 && expected != nil {
+                t = this.expect(sc, expStm.Expression, expected, report)
+            } else {
+                t = this.statement(sc, stm)
+            }
+            if i == 0 { first = t }
+        }
+        if first == nil { return Null }
+        return first
+    }
+
+    var consequence = branch(exp.ConsequenceBlock)
+    if exp.AlternativeBlock == nil { return Any } // Null when the condition is false
+    var alternative = branch(exp.AlternativeBlock)
+    if expected != nil { return expected }
+    return join(consequence, alternative)
+}
+
+// The match takes the value of the arm that matches, each arm has a scope for the names of its
+// pattern. When a type is expected of it, each arm is checked against it, see expect
+func (this *checker) match(sc *scope, exp *ast.MatchExpression, expected *Type, report mismatch) *Type {
+    var value = this.expression(sc, exp.Value)
+
+    var result *Type
+    for _, arm := range exp.Arms {
+        var inner = &scope { outer: sc, names: map[string] *Type {}, function: sc.function }
+        this.pattern(inner, arm.Pattern, value)
+        ast.IfLets(arm.Body, inner.declareLet)
+        if arm.Guard != nil {
+            var guard = this.expression(inner, arm.Guard)
+            if !guard.isAny() && guard != Bool && guard != Int {
+                this.report(arm.Guard, "the guard is %s, the arm is only taken on bool and int", guard)
+            }
+        }
+        if expected != nil {
+            this.expect(inner, arm.Body, expected, report)
+        } else {
+            result = join(result, this.expression(inner, arm.Body))
+        }
+    }
+    if expected != nil { return expected }
+    if result == nil { return Any }
+    return result
+}
+
+// Binds the names of the pattern to the parts of a value of the type, and reports the patterns
+// that a value of the type can never match
+func (this *checker) pattern(sc *scope, pattern ast.Pattern, value *Type) {
+    var expect = func (name string) bool {
+        if value.isAny() || value.Name == name { return true }
+        var text = pattern.String()
+        if literal, isLiteral := pattern.(*ast.LiteralPattern); isLiteral && name == "string" { text = strconv.Quote(literal.String()) }
+        this.report(pattern, "the pattern %s never matches %s", text, value)
+        return false
+    }
+
+    switch pattern := pattern.(type) {
+    case *ast.BindingPattern:
+        sc.names[pattern.Name] = value
+    case *ast.LiteralPattern:
+        expect(this.expression(sc, pattern.Value).Name)
+    case *ast.ArrayPattern:
+        var elem = Any
+        if expect("array") && !value.isAny() { elem = value.Arguments[0] }
+        for _, element := range pattern.Elements {
+            this.pattern(sc, element, elem)
+        }
+        if pattern.Rest != nil { this.pattern(sc, pattern.Rest, ArrayOf(elem)) }
+    case *ast.HashPattern:
+        var valueType = Any
+        if expect("hash") && !value.isAny() { valueType = value.Arguments[1] }
+        for _, patternValue := range pattern.Values {
+            this.pattern(sc, patternValue, valueType)
+        }
+    }
+}
+
+func (this *checker) function(sc *scope, exp *ast.FunctionLiteral) *Type {
+    var signature = this.signature(exp)
+    var fn = &function {}
+    if exp.ReturnType != nil { fn.declared = signature.Return }
+
+    var inner = &scope { outer: sc, names: map[string] *Type {}, function: fn }
+    for i, param := range exp.Parameters {
+        inner.names[param.Value] = signature.Arguments[i]
+    }
+    ast.ScopeLets(exp.Body.Statements, inner.declareLet)
+    var count = len(exp.Body.Statements)
+    var last = Null
+    for i, stm := range exp.Body.Statements {
+        if expStm, isExp := stm.(*ast.ExpressionStatement); isExp && i == count - 1 && fn.declared != nil {
+            last = this.expect(inner, expStm.Expression, fn.declared, this.resultMismatch(fn))
+        } else {
+            last = this.statement(inner, stm)
+        }
+    }
+
+    // The value of the last statement is returned too, unless it is a return itself
+    var result *Type
+    for _, returned := range fn.returned {
+        result = join(result, returned)
+    }
+    if count == 0 {
+        result = join(result, Null)
+    } else if _, isReturn := exp.Body.Statements[count - 1].(*ast.ReturnStatement); !isReturn {
+        if _, isExp := exp.Body.Statements[count - 1].(*ast.ExpressionStatement); !isExp && fn.declared != nil && !last.AssignableTo(fn.declared) {
+            this.resultMismatch(fn)(exp.Body.Statements[count - 1], last, fn.declared)
+        }
+        result = join(result, last)
+    }
+
+    if fn.declared != nil { return signature }
+    return FunctionOf(signature.Arguments, result)
+}
+
+func (this *checker) call(sc *scope, exp *ast.CallExpression) *Type {
+    if exp.Expression.String() == "quote" { return Any } // Its code is not evaluated where it is written
+
+    var args = []*Type {}
+    for _, param := range exp.Parameters {
+        args = append(args, this.expression(sc, param))
+    }
+
+    if ident, isIdent := exp.Expression.(*ast.Identifier); isIdent {
+        var _, bound = sc.lookup(ident.Value)
+        if _, isBuiltin := evaluator.GetBuiltinDoc(ident.Value); !bound && isBuiltin {
+            return builtinResult(ident.Value)
+        }
+    }
+
+    var callee = this.expression(sc, exp.Expression)
+    if callee.isAny() { return Any }
+    if callee.Name != "fn" {
+        this.report(exp.Expression, "cannot call %s, it is not a function", callee)
+        return Any
+    }
+    if callee.Arguments == nil { return callee.Return }
+
+    if len(args) != len(callee.Arguments) {
+        this.report(exp, "%s takes %d arguments but got %d", callee, len(callee.Arguments), len(args))
+        return callee.Return
+    }
+    for i, arg := range args {
+        if !arg.AssignableTo(callee.Arguments[i]) {
+            this.report(exp.Parameters[i], "cannot use %s as %s in the argument %d", arg, callee.Arguments[i], i + 1)
+        }
+    }
+    return callee.Return
+}
+
+func (this *checker) index(sc *scope, exp *ast.IndexExpression) *Type {
+    var left = this.expression(sc, exp.Left)
+    var index = this.expression(sc, exp.Index)
+
+    switch left.Name {
+    case "array":
+        if !index.AssignableTo(Int) { this.report(exp.Index, "cannot index an array with %s", index) }
+        return left.Arguments[0]
+    case "hash":
+        if !index.AssignableTo(left.Arguments[0]) { this.report(exp.Index, "cannot index %s with %s", left, index) }
+        return left.Arguments[1]
+    default:
+        return Any
+    }
+}
@@ -0,0 +1,102 @@
+// monkey/checker/checker_test.go
+
+package checker
+
+import (
+    "monkey/ast"
+    "monkey/lexer"
+    "monkey/parser"
+    "strings"
+    "testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+    t.Helper()
+    var p = parser.NewParser(lexer.NewLexer(input))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { t.Fatalf("Unexpected parser errors: %v", p.Errors()) }
+    return program
+}
+
+func TestCheck(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `5 + true`,                                              "1:1: type mismatch: int + bool" },
+        { `"a" - "b"; true == false`,                              "1:1: unknown operator: string - string, 1:12: unknown operator: bool == bool" },
+        { `-"a"; !1; !true`,                                       "1:1: unknown operator: -string" },
+        { `let x = 1; let y = "a"; x + y`,                         "1:25: type mismatch: int + string" },
+        { `let x = 1; fn () { let g = fn () { x + "a" }; let x = "s"; g() }`, "" },
+        { `let n: int = "a"`,                                      "1:14: cannot use string as int in the let of 'n'" },
+        { `let n: string = 1 + 2 * 3`,                             "1:17: cannot use int as string in the let of 'n'" },
+        { `let b: boo = 1`,                                        "1:8: unknown type 'boo'" },
+        { `let xs: array<int, int> = []`,                          "1:9: array takes 1 type arguments but got 2" },
+        { `let ys: array<int> = [1, 2]; let zs: array<int> = []`,   "" },
+        { `let arr: array<int> = [1, 2, "x"]`,                     "1:30: cannot use string as int in the let of 'arr'" },
+        { `let xs: array<string> = [1, 2]`,                        "1:26: cannot use int as string in the let of 'xs', 1:29: cannot use int as string in the let of 'xs'" },
+        { `let xs: array<int> = "a"`,                              "1:22: cannot use string as array<int> in the let of 'xs'" },
+        { `let h: hash<string, int> = { "a": 1, "b": 2 }`,         "" },
+        { `let h: hash<string, int> = { "a": "b" }`,               "1:35: cannot use string as int in the let of 'h'" },
+        { `let xs = [1, 2]; xs["a"]; xs[0] + 1`,                   "1:21: cannot index an array with string" },
+        { `let xs = ["a"]; xs[0] + 1`,                             "1:17: type mismatch: string + int" },
+        { `let add = fn (x: int, y: int) -> int { x + y }; add(1, "b")`,  "1:56: cannot use string as int in the argument 2" },
+        { `let add = fn (x: int, y: int) -> int { x + y }; add(1)`,       "1:49: fn(int, int) -> int takes 2 arguments but got 1" },
+        { `let add = fn (x: int, y: int) -> int { x + y }; add(1, 2) + "s"`, "1:49: type mismatch: int + string" },
+        { `let f = fn (x: int) -> bool { x }`,                     "1:31: cannot return int from a function that returns bool" },
+        { `let f = fn (x: int) -> bool { if (x > 1) { return "big" }; true }`, "1:51: cannot return string from a function that returns bool" },
+        { `let f = fn (b: bool) -> int { if (b) { 1 } else { "x" } }`, "1:51: cannot return string from a function that returns int" },
+        { `let f = fn (b: bool) -> array<int> { return [1, "x"] }`, "1:49: cannot use string as int in the result of a function that returns array<int>" },
+        { `let x: int = if (true) { 1 } else { "x" }`,             "1:37: cannot use string as int in the let of 'x'" },
+        { `let f = fn (x) { x * 2 }; f(1) + 1; f("a")`,            "" },
+        { `let f = fn () { "a" }; f() + 1`,                        "1:24: type mismatch: string + int" },
+        { `let fact = fn (n: int) -> int { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact("a")`, "1:83: cannot use string as int in the argument 1" },
+        { `let x = 1; x(2)`,                                       "1:12: cannot call int, it is not a function" },
+        { `let apply = fn (f: fn(int) -> int, x: int) -> int { f(x) }; apply(fn (x) { x }, 1)`, "" },
+        { `let apply = fn (f: fn(int) -> int) { f(1) }; apply(fn (s: string) { s })`, "1:52: cannot use fn(string) -> string as fn(int) -> int in the argument 1" },
+        { `let g: fn = len; g(1, 2)`,                              "" },
+        { `if ("yes") { 1 }; if (1) { 2 }`,                        "1:5: the condition is string, the if only takes a branch on bool and int" },
+        { `len("abc") + 1; len("abc") + "d"`,                      "1:17: type mismatch: int + string" },
+        { `[1].len() + "a"`,                                       "1:1: type mismatch: int + string" },
+        { `let len = fn (x) { "s" }; len(1) + 1`,                  "1:27: type mismatch: string + int" },
+        { `let f = fn () { later() + 1 }; let later = fn () { "s" };`, "" },
+        { `let x: int = args; let y: any = 1; y + "s"`,            "" },
+        { `let s: string = match (1) { 1 => "one", n => "many" }`,  "" },
+        { `let s: string = match (1) { 1 => "one", n => n }`,        "1:46: cannot use int as string in the let of 's'" },
+        { `let s: string = match ("a") { "a" => "one", n => n }`,    "" },
+        { `match ([1, 2]) { [a, ...r] => a + r }`,                   "1:31: type mismatch: int + array<int>" },
+        { `match ({ "k": "v" }) { { "k": v } => v - 1 }`,            "1:38: type mismatch: string - int" },
+        { `match (1) { "a" => 1, [a] => a, { "k": v } => v, n if "s" => n }`, "1:13: the pattern \"a\" never matches int, 1:23: the pattern [a] never matches int, 1:33: the pattern { k: v } never matches int, 1:55: the guard is string, the arm is only taken on bool and int" },
+    }
+
+    for _, test := range tests {
+        var errors = []string {}
+        for _, err := range Check(parse(t, test.input)) {
+            errors = append(errors, err.String())
+        }
+        if got := strings.Join(errors, ", "); got != test.expected {
+            t.Errorf("Expected the errors of '%s' to be '%s' but got '%s'", test.input, test.expected, got)
+        }
+    }
+}
+
+func TestAssignable(t *testing.T) {
+    var tests = []struct {
+        from *Type; to *Type; expected bool
+    } {
+        { Int,                                   Any,                                  true  },
+        { Any,                                   String,                               true  },
+        { Int,                                   String,                               false },
+        { ArrayOf(Any),                          ArrayOf(Int),                         true  },
+        { HashOf(String, Int),                   HashOf(String, Bool),                 false },
+        { FunctionOf([]*Type { Any }, Int),      FunctionOf([]*Type { Int }, Int),     true  },
+        { FunctionOf([]*Type { Int }, Int),      FunctionOf([]*Type { Int, Int }, Int), false },
+        { FunctionOf(nil, Any),                  FunctionOf([]*Type { Int }, Int),     true  },
+        { FunctionOf([]*Type { Int }, String),   FunctionOf(nil, Int),                 false },
+    }
+
+    for _, test := range tests {
+        if got := test.from.AssignableTo(test.to); got != test.expected {
+            t.Errorf("Expected %s assignable to %s to be %t but got %t", test.from, test.to, test.expected, got)
+        }
+    }
+}
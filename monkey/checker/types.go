@@ -0,0 +1,126 @@
+// monkey/checker/types.go
+
+package checker
+
+import (
+    "fmt"
+    "monkey/ast"
+    "strings"
+)
+
+// A static type. The arguments are the element of the arrays, the key and value of the hashes and
+// the parameters of the functions, nil for the functions whose parameters are not known
+type Type struct {
+    Name      string // int, bool, string, null, array, hash, fn or any
+    Arguments []*Type
+    Return    *Type // Of the functions
+}
+
+var (
+    Any    = &Type { Name: "any" }
+    Int    = &Type { Name: "int" }
+    Bool   = &Type { Name: "bool" }
+    String = &Type { Name: "string" }
+    Null   = &Type { Name: "null" }
+)
+
+var simpleTypes = map[string] *Type { "any": Any, "int": Int, "bool": Bool, "string": String, "null": Null }
+
+func ArrayOf(elem *Type) *Type {
+    return &Type { Name: "array", Arguments: []*Type { elem } }
+}
+
+func HashOf(key *Type, value *Type) *Type {
+    return &Type { Name: "hash", Arguments: []*Type { key, value } }
+}
+
+func FunctionOf(params []*Type, result *Type) *Type {
+    return &Type { Name: "fn", Arguments: params, Return: result }
+}
+
+func (this *Type) String() string {
+    var args = []string {}
+    for _, arg := range this.Arguments {
+        args = append(args, arg.String())
+    }
+
+    switch this.Name {
+    case "array", "hash":
+        return this.Name + "<" + strings.Join(args, ", ") + ">"
+    case "fn":
+        if this.Arguments == nil { return "fn" }
+        return "fn(" + strings.Join(args, ", ") + ") -> " + this.Return.String()
+    default:
+        return this.Name
+    }
+}
+
+func (this *Type) isAny() bool {
+    return this.Name == "any"
+}
+
+// Tells if a value of the type can be used where the other one is expected. Any goes everywhere
+// and takes anything, also inside the arrays, hashes and functions
+func (this *Type) AssignableTo(other *Type) bool {
+    if this.isAny() || other.isAny() { return true }
+    if this.Name != other.Name { return false }
+    if this.Name != "fn" {
+        for i := range this.Arguments {
+            if !this.Arguments[i].AssignableTo(other.Arguments[i]) { return false }
+        }
+        return true
+    }
+
+    if this.Arguments != nil && other.Arguments != nil {
+        if len(this.Arguments) != len(other.Arguments) { return false }
+        for i := range this.Arguments {
+            if !other.Arguments[i].AssignableTo(this.Arguments[i]) { return false }
+        }
+    }
+    return this.Return.AssignableTo(other.Return)
+}
+
+// The type of a value that is either of the two, any when they differ
+func join(a *Type, b *Type) *Type {
+    if a == nil { return b }
+    if b == nil { return a }
+    if a.String() == b.String() { return a }
+    return Any
+}
+
+// The type the annotation names, with an error for the unknown names or the wrong number of arguments
+func fromAnnotation(annotation *ast.TypeAnnotation) (*Type, error) {
+    var args = []*Type {}
+    for _, arg := range annotation.Arguments {
+        var argType, err = fromAnnotation(arg)
+        if err != nil { return nil, err }
+        args = append(args, argType)
+    }
+
+    var expectArgs = func (count int) error {
+        if len(args) == count { return nil }
+        return fmt.Errorf("%s takes %d type arguments but got %d", annotation.Name, count, len(args))
+    }
+
+    switch annotation.Name {
+    case "array":
+        if err := expectArgs(1); err != nil { return nil, err }
+        return ArrayOf(args[0]), nil
+    case "hash":
+        if err := expectArgs(2); err != nil { return nil, err }
+        return HashOf(args[0], args[1]), nil
+    case "fn":
+        var result = Any
+        if annotation.Return != nil {
+            var err error
+            if result, err = fromAnnotation(annotation.Return); err != nil { return nil, err }
+        }
+        if annotation.Arguments == nil { return FunctionOf(nil, result), nil }
+        return FunctionOf(args, result), nil
+    }
+
+    var simple, found = simpleTypes[annotation.Name]
+    if !found { return nil, fmt.Errorf("unknown type '%s'", annotation.Name) }
+    if err := expectArgs(0); err != nil { return nil, err }
+    return simple, nil
+}
@@ -0,0 +1,61 @@
+// monkey/cmd_check.go
+/*
+    Type checks monkey scripts with their optional annotations, without running them. Exits with
+    an error when there are type errors
+*/
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "monkey/checker"
+    "monkey/lexer"
+    "monkey/parser"
+    "os"
+)
+
+func checkCommand(args []string) int {
+    var flags = flag.NewFlagSet("check", flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), "Usage: monkey check [files or directories...]")
+        flags.PrintDefaults()
+    }
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+
+    var paths = flags.Args()
+    if len(paths) == 0 { paths = []string { "." } }
+    var files, err = collectScripts(paths, ".mk")
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Could not read the scripts: %s\n", err)
+        return exitError
+    }
+
+    var code = exitOk
+    for _, file := range files {
+        var source, errRead = os.ReadFile(file)
+        if errRead != nil {
+            fmt.Fprintf(os.Stderr, "Could not read %s: %s\n", file, errRead)
+            code = exitError
+            continue
+        }
+
+        var p = parser.NewParser(lexer.NewLexer(string(source)))
+        var program = p.ParseProgram()
+        if len(p.Errors()) > 0 {
+            for _, msg := range p.Errors() {
+                fmt.Fprintf(os.Stderr, "%s: syntax error: %s\n", file, msg)
+            }
+            code = exitError
+            continue
+        }
+
+        for _, typeErr := range checker.Check(program) {
+            fmt.Printf("%s:%s\n", file, typeErr)
+            code = exitError
+        }
+    }
+    return code
+}
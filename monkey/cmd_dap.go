@@ -0,0 +1,27 @@
+// monkey/cmd_dap.go
+/*
+    Starts the debug adapter, the editors talk to it through stdin and stdout
+*/
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "monkey/dap"
+    "os"
+)
+
+func dapCommand(args []string) int {
+    var flags = flag.NewFlagSet("dap", flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), "Usage: monkey dap")
+        fmt.Fprintln(flags.Output(), "Speaks the Debug Adapter Protocol over stdin and stdout")
+    }
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+
+    dap.NewServer(os.Stdin, os.Stdout).Serve()
+    return exitOk
+}
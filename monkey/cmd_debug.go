@@ -0,0 +1,96 @@
+// monkey/cmd_debug.go
+/*
+    Runs a script under the step debugger. It stops before the first statement so the breakpoints
+    can be set first
+*/
+
+package main
+
+import (
+    "bufio"
+    "errors"
+    "flag"
+    "fmt"
+    "monkey/debugger"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "monkey/readline"
+    "os"
+)
+
+func debugCommand(args []string) int {
+    var options = defaultRunOptions()
+
+    var flags = flag.NewFlagSet("debug", flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), "Usage: monkey debug [flags] <file.mk> [args...]")
+        fmt.Fprintln(flags.Output(), "Type help when the program stops to see the commands")
+        flags.PrintDefaults()
+    }
+    flags.StringVar(&options.root, "root", options.root, "directory the file builtins can use")
+    flags.BoolVar(&options.readOnly, "read-only", false, "blocks write_file")
+    flags.BoolVar(&options.sandbox, "sandbox", false, "disables files, stdin, env and exit for the script")
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+    if flags.NArg() < 1 {
+        flags.Usage()
+        return exitUsage
+    }
+
+    var path = flags.Arg(0)
+    var source, err = os.ReadFile(path)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Could not read the script: %s\n", err)
+        return exitError
+    }
+
+    var parser = parser.NewParser(lexer.NewLexer(string(source)))
+    var program = parser.ParseProgram()
+    if len(parser.Errors()) > 0 {
+        for _, err := range parser.Errors() {
+            fmt.Fprintf(os.Stderr, "%s: syntax error: %s\n", path, err)
+        }
+        return exitError
+    }
+
+    var interpreter = evaluator.NewInterpreter()
+    interpreter.Capabilities = options.capabilities()
+    var env = newScriptEnvironment(flags.Args()[1:])
+    if err := interpreter.ExpandMacros(program, env); err != nil {
+        fmt.Fprintf(os.Stderr, "%s:%s\n", path, err)
+        return exitError
+    }
+    // The commands are read from the terminal, the script can still read stdin with read_line. Both
+    // read through the same buffer so neither one takes the input of the other
+    var stdin = bufio.NewReader(os.Stdin)
+    interpreter.In = stdin
+    var editor = readline.NewEditorReading(stdin)
+    var readLine = func (prompt string) (string, error) {
+        var line, err = editor.ReadLine(prompt)
+        if errors.Is(err, readline.ErrInterrupted) { return "", nil } // Ctrl-C only drops the line
+        if err == nil { editor.History.Add(line) }
+        return line, err
+    }
+
+    var session = debugger.NewDebugger(interpreter)
+    debugger.NewTerminal(session, path, string(source), readLine, os.Stdout)
+
+    var result, errRun = session.Run(program, env, true)
+    if errRun != nil {
+        fmt.Println(errRun)
+        return exitError
+    }
+    if code, exited := evaluator.ExitCode(result); exited {
+        fmt.Printf("The program exited with code %d\n", code)
+        return code
+    }
+    if errObj, isErr := result.(*object.Error); isErr {
+        fmt.Fprintf(os.Stderr, "%s: runtime error: %s\n", path, errObj.Message)
+        return exitError
+    }
+    fmt.Println("The program ended")
+    return exitOk
+}
@@ -0,0 +1,132 @@
+// monkey/cmd_fmt.go
+/*
+    Formats monkey scripts. Prints the result, rewrites the files with -w or only lists the
+    files that are not formatted with --check, that exits with an error when there are any
+*/
+
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "io/fs"
+    "monkey/format"
+    "monkey/parser"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+type fmtOptions struct {
+    format format.Options
+    write  bool
+    check  bool
+}
+
+// Expands the directories to the files inside them with the suffix, .mk for all the scripts
+func collectScripts(paths []string, suffix string) ([]string, error) {
+    var files = []string {}
+    for _, path := range paths {
+        var info, err = os.Stat(path)
+        if err != nil { return nil, err }
+        if !info.IsDir() {
+            files = append(files, path)
+            continue
+        }
+
+        err = filepath.WalkDir(path, func (file string, entry fs.DirEntry, err error) error {
+            if err != nil { return err }
+            if !entry.IsDir() && strings.HasSuffix(file, suffix) {
+                files = append(files, file)
+            }
+            return nil
+        })
+        if err != nil { return nil, err }
+    }
+    return files, nil
+}
+
+// Formats one source. Returns if it was already formatted and false on syntax errors
+func formatSource(source string, name string, options fmtOptions, out io.Writer) (bool, bool) {
+    var formatted, err = format.Source(source, options.format)
+    if err != nil {
+        var syntaxErr *parser.SyntaxError
+        if errors.As(err, &syntaxErr) {
+            for _, msg := range syntaxErr.Messages {
+                fmt.Fprintf(os.Stderr, "%s: syntax error: %s\n", name, msg)
+            }
+        }
+        return false, false
+    }
+
+    var unchanged = formatted == source
+    switch {
+    case options.check:
+        if !unchanged { fmt.Fprintln(out, name) }
+    case options.write:
+        if !unchanged {
+            if err := os.WriteFile(name, []byte(formatted), 0644); err != nil {
+                fmt.Fprintf(os.Stderr, "Could not write %s: %s\n", name, err)
+                return unchanged, false
+            }
+        }
+    default:
+        fmt.Fprint(out, formatted)
+    }
+    return unchanged, true
+}
+
+func fmtCommand(args []string) int {
+    var options = fmtOptions { format: format.DefaultOptions() }
+
+    var flags = flag.NewFlagSet("fmt", flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), "Usage: monkey fmt [flags] [files or directories...]")
+        fmt.Fprintln(flags.Output(), "Without files formats stdin to stdout")
+        flags.PrintDefaults()
+    }
+    flags.IntVar(&options.format.IndentWidth, "indent", options.format.IndentWidth, "spaces of each indentation level, or the width of a tab with -tabs")
+    flags.BoolVar(&options.format.UseTabs, "tabs", false, "indents with tabs")
+    flags.IntVar(&options.format.LineWidth, "width", options.format.LineWidth, "lines longer than it are broken")
+    flags.BoolVar(&options.write, "w", false, "writes the result back to the files")
+    flags.BoolVar(&options.check, "check", false, "lists the files that are not formatted and fails when there are any")
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+
+    if flags.NArg() == 0 {
+        if options.write {
+            fmt.Fprintln(os.Stderr, "Cannot use -w when formatting stdin")
+            return exitUsage
+        }
+        var source, err = io.ReadAll(os.Stdin)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Could not read stdin: %s\n", err)
+            return exitError
+        }
+        var unchanged, ok = formatSource(string(source), "stdin", options, os.Stdout)
+        if !ok || (options.check && !unchanged) { return exitError }
+        return exitOk
+    }
+
+    var files, err = collectScripts(flags.Args(), ".mk")
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Could not read the scripts: %s\n", err)
+        return exitError
+    }
+
+    var code = exitOk
+    for _, file := range files {
+        var source, errRead = os.ReadFile(file)
+        if errRead != nil {
+            fmt.Fprintf(os.Stderr, "Could not read %s: %s\n", file, errRead)
+            code = exitError
+            continue
+        }
+        var unchanged, ok = formatSource(string(source), file, options, os.Stdout)
+        if !ok || (options.check && !unchanged) { code = exitError }
+    }
+    return code
+}
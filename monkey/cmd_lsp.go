@@ -0,0 +1,28 @@
+// monkey/cmd_lsp.go
+/*
+    Starts the language server, the editors talk to it through stdin and stdout
+*/
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "monkey/lsp"
+    "os"
+)
+
+func lspCommand(args []string) int {
+    var flags = flag.NewFlagSet("lsp", flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), "Usage: monkey lsp")
+        fmt.Fprintln(flags.Output(), "Speaks the Language Server Protocol over stdin and stdout")
+    }
+    flags.Bool("stdio", true, "uses stdin and stdout, the only transport (accepted for the editors that pass it)")
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+
+    if lsp.NewServer(os.Stdin, os.Stdout).Serve() != 0 { return exitError }
+    return exitOk
+}
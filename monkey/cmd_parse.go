@@ -0,0 +1,94 @@
+// monkey/cmd_parse.go
+/*
+    Prints the tree the parser builds from a script, for the tools outside the interpreter. By
+    default it is the program with a parenthesis around each operation, with -json it is the
+    encoding of ast.EncodeJSON, with -tree an indented tree of the nodes and with -dot a Graphviz
+    digraph of them, to render with `dot -Tsvg`
+*/
+
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "monkey/ast"
+    "monkey/lexer"
+    "monkey/parser"
+    "os"
+)
+
+func parseCommand(args []string) int {
+    var asJSON, asTree, asDot bool
+
+    var flags = flag.NewFlagSet("parse", flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), "Usage: monkey parse [flags] <file.mk | ->")
+        flags.PrintDefaults()
+    }
+    flags.BoolVar(&asJSON, "json", false, "prints the tree as JSON, with the type and the span of each node")
+    flags.BoolVar(&asTree, "tree", false, "prints the tree indented, one node per line with its fields")
+    flags.BoolVar(&asDot, "dot", false, "prints the tree as a Graphviz digraph")
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+    var formats = 0
+    for _, set := range []bool { asJSON, asTree, asDot } {
+        if set { formats++ }
+    }
+    if formats > 1 {
+        fmt.Fprintln(flags.Output(), "Only one of -json, -tree and -dot can be given")
+        return exitUsage
+    }
+    if flags.NArg() != 1 {
+        flags.Usage()
+        return exitUsage
+    }
+
+    var path = flags.Arg(0)
+    var source []byte
+    var err error
+    if path == "-" {
+        path = "stdin"
+        source, err = io.ReadAll(os.Stdin)
+    } else {
+        source, err = os.ReadFile(path)
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Could not read the script: %s\n", err)
+        return exitError
+    }
+
+    var p = parser.NewParser(lexer.NewLexer(string(source)))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 {
+        for _, msg := range p.Errors() {
+            fmt.Fprintf(os.Stderr, "%s: syntax error: %s\n", path, msg)
+        }
+        return exitError
+    }
+
+    switch {
+    case asTree:
+        ast.WriteTree(os.Stdout, program)
+        return exitOk
+    case asDot:
+        ast.WriteDot(os.Stdout, program)
+        return exitOk
+    case !asJSON:
+        fmt.Print(program.String())
+        return exitOk
+    }
+
+    var data, errEncode = ast.EncodeJSON(program)
+    if errEncode != nil {
+        fmt.Fprintf(os.Stderr, "Could not encode the tree: %s\n", errEncode)
+        return exitError
+    }
+    var indented bytes.Buffer
+    json.Indent(&indented, data, "", "  ")
+    fmt.Println(indented.String())
+    return exitOk
+}
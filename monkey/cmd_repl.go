@@ -0,0 +1,37 @@
+// monkey/cmd_repl.go
+/*
+    Flags of the eval REPL
+*/
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "monkey/repl"
+)
+
+func evalReplCommand(args []string) int {
+    var sessionPath string
+
+    var flags = flag.NewFlagSet("eval", flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), "Usage: monkey eval [--session <file.mk>]")
+        flags.PrintDefaults()
+    }
+    flags.StringVar(&sessionPath, "session", "", "replays the file on start and makes it the default of :save and :restore")
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+    if flags.NArg() > 0 {
+        flags.Usage()
+        return exitUsage
+    }
+
+    if sessionPath == "" {
+        repl.Execute("eval")
+    } else {
+        repl.ExecuteSession(sessionPath)
+    }
+    return exitOk
+}
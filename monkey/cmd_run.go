@@ -0,0 +1,234 @@
+// monkey/cmd_run.go
+/*
+    Runs monkey scripts from files, from the -e argument or from stdin. 'monkey run' and 'monkey -e'
+    take the flags of the capabilities, the script piped into a bare 'monkey' runs without them
+*/
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io"
+    "monkey/coverage"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "monkey/profiler"
+    "monkey/resolver"
+    "os"
+)
+
+type runOptions struct {
+    root      string
+    readOnly  bool
+    sandbox   bool
+    warnings  bool   // Prints the warnings of the resolver to stderr
+    trace     bool   // Prints the evaluation to stderr
+    profile   bool   // Prints the profile report to stderr
+    pprof     string // File to write the profile for 'go tool pprof'
+    cover     bool   // Prints the coverage summary to stderr
+    coverHTML string // File to write the annotated source
+    coverLCOV string // File to write the LCOV tracefile
+}
+
+func defaultRunOptions() runOptions {
+    return runOptions { root: "." }
+}
+
+// For the script piped into a bare 'monkey', that has no way to pass the flags
+func pipedRunOptions() runOptions {
+    return runOptions { root: ".", sandbox: true }
+}
+
+func (this runOptions) capabilities() evaluator.Capabilities {
+    if this.sandbox {
+        return evaluator.Capabilities {}
+    }
+    return evaluator.Capabilities {
+        FileRoot: this.root,
+        ReadOnly: this.readOnly,
+        Stdin:    true,
+        Env:      true,
+        Exit:     true,
+    }
+}
+
+func newScriptEnvironment(scriptArgs []string) *object.Environment {
+    var env = object.NewEnvironment()
+
+    var args = []object.Object {}
+    for _, arg := range scriptArgs {
+        args = append(args, &object.String { Value: arg })
+    }
+    env.Set("args", &object.Array { Elements: args })
+
+    return env
+}
+
+// Parses and evaluates the script and returns the exit code for the process. The name is only
+// used on the error messages
+func runSource(source string, name string, scriptArgs []string, options runOptions, printResult bool) int {
+    var parser = parser.NewParser(lexer.NewLexer(source))
+    var program = parser.ParseProgram()
+    if len(parser.Errors()) > 0 {
+        for _, err := range parser.Errors() {
+            fmt.Fprintf(os.Stderr, "%s: syntax error: %s\n", name, err)
+        }
+        return exitError
+    }
+
+    var interpreter = evaluator.NewInterpreter()
+    interpreter.Capabilities = options.capabilities()
+
+    var env = newScriptEnvironment(scriptArgs)
+    if err := interpreter.ExpandMacros(program, env); err != nil {
+        fmt.Fprintf(os.Stderr, "%s:%s\n", name, err)
+        return exitError
+    }
+
+    var warnings = resolver.Resolve(program, "args")
+    if options.warnings {
+        for _, warning := range warnings {
+            fmt.Fprintf(os.Stderr, "%s:%s: warning: %s\n", name, warning.Pos, warning.Message)
+        }
+    }
+
+    var observers = evaluator.Observers {}
+    if options.trace { observers = append(observers, evaluator.NewTracer(os.Stderr)) }
+    var profile *profiler.Profiler
+    if options.profile || options.pprof != "" {
+        profile = profiler.NewProfiler(program)
+        observers = append(observers, profile)
+    }
+    var cover *coverage.Coverage
+    if options.cover || options.coverHTML != "" || options.coverLCOV != "" {
+        cover = coverage.NewCoverage(name, source, program)
+        observers = append(observers, cover)
+    }
+    interpreter.Observer = evaluator.Observe(observers...)
+
+    if profile != nil { profile.Start() }
+    var result = interpreter.Eval(program, env)
+    if profile != nil {
+        profile.Stop()
+        if !writeProfile(profile, name, options) { return exitError }
+    }
+    if cover != nil && !writeCoverage(options, cover) { return exitError }
+
+    if code, exited := evaluator.ExitCode(result); exited { return code }
+    if errObj, isErr := result.(*object.Error); isErr {
+        fmt.Fprintf(os.Stderr, "%s: runtime error: %s\n", name, errObj.Message)
+        return exitError
+    }
+
+    if printResult && result != nil && result.Type() != object.NullType {
+        fmt.Println(result.Inspect())
+    }
+    return exitOk
+}
+
+// Creates the file and writes it, the errors are printed
+func writeOutput(path string, what string, write func (out io.Writer) error) bool {
+    var file, err = os.Create(path)
+    if err == nil {
+        err = write(file)
+        if errClose := file.Close(); err == nil { err = errClose }
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Could not write the %s: %s\n", what, err)
+        return false
+    }
+    return true
+}
+
+func writeProfile(profile *profiler.Profiler, name string, options runOptions) bool {
+    if options.profile { profile.WriteReport(os.Stderr) }
+    if options.pprof == "" { return true }
+    return writeOutput(options.pprof, "profile", func (out io.Writer) error { return profile.WritePprof(out, name) })
+}
+
+// Writes the reports the options ask for, of all the files together
+func writeCoverage(options runOptions, files ...*coverage.Coverage) bool {
+    if options.cover { coverage.WriteSummary(os.Stderr, files...) }
+    if options.coverHTML != "" {
+        var write = func (out io.Writer) error { return coverage.WriteHTML(out, files...) }
+        if !writeOutput(options.coverHTML, "coverage", write) { return false }
+    }
+    if options.coverLCOV != "" {
+        var write = func (out io.Writer) error { return coverage.WriteLCOV(out, files...) }
+        if !writeOutput(options.coverLCOV, "coverage", write) { return false }
+    }
+    return true
+}
+
+func runStdin(options runOptions, scriptArgs []string) int {
+    var source, err = io.ReadAll(os.Stdin)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Could not read the script from stdin: %s\n", err)
+        return exitError
+    }
+    return runSource(string(source), "stdin", scriptArgs, options, false)
+}
+
+// The flags of run, that -e takes too
+func newRunFlags(name string, usage string, options *runOptions) *flag.FlagSet {
+    var flags = flag.NewFlagSet(name, flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), usage)
+        flags.PrintDefaults()
+    }
+    flags.StringVar(&options.root, "root", options.root, "directory the file builtins can use")
+    flags.BoolVar(&options.readOnly, "read-only", false, "blocks write_file")
+    flags.BoolVar(&options.sandbox, "sandbox", false, "disables files, stdin, env and exit for the script")
+    flags.BoolVar(&options.warnings, "warnings", false, "prints the undefined, shadowed and unused names before running")
+    flags.BoolVar(&options.trace, "trace", false, "prints each step of the evaluation to stderr")
+    flags.BoolVar(&options.profile, "profile", false, "prints the time spent in each function to stderr")
+    flags.StringVar(&options.pprof, "pprof", "", "writes the profile to the file, to read with 'go tool pprof'")
+    flags.BoolVar(&options.cover, "cover", false, "prints the statements and branches that ran to stderr")
+    flags.StringVar(&options.coverHTML, "cover-html", "", "writes the source annotated with the coverage to the HTML file")
+    flags.StringVar(&options.coverLCOV, "cover-lcov", "", "writes the coverage to the LCOV file")
+    return flags
+}
+
+// monkey -e [flags] <code> [args...] runs the code and prints its result
+func evalCodeCommand(args []string) int {
+    var options = defaultRunOptions()
+    var flags = newRunFlags("-e", "Usage: monkey -e [flags] <code> [args...]", &options)
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+
+    if flags.NArg() < 1 {
+        fmt.Fprintln(os.Stderr, "Missing the code after -e")
+        return exitUsage
+    }
+    return runSource(flags.Arg(0), "-e", flags.Args()[1:], options, true)
+}
+
+func runCommand(args []string) int {
+    var options = defaultRunOptions()
+    var flags = newRunFlags("run", "Usage: monkey run [flags] <file.mk | -> [args...]", &options)
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+
+    if flags.NArg() < 1 {
+        flags.Usage()
+        return exitUsage
+    }
+
+    var path = flags.Arg(0)
+    var scriptArgs = flags.Args()[1:]
+    if path == "-" {
+        return runStdin(options, scriptArgs)
+    }
+
+    var source, err = os.ReadFile(path)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Could not read the script: %s\n", err)
+        return exitError
+    }
+    return runSource(string(source), path, scriptArgs, options, false)
+}
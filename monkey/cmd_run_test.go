@@ -0,0 +1,52 @@
+// monkey/cmd_run_test.go
+
+package main
+
+import (
+    "monkey/evaluator"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestRunWritesReportsOnExit(t *testing.T) {
+    var lcov = filepath.Join(t.TempDir(), "cover.lcov")
+    var options = defaultRunOptions()
+    options.sandbox = false
+    options.coverLCOV = lcov
+
+    var source = "let f = fn (n) {\n    if (n > 1) { exit(n) }\n    n\n};\nf(1);\nf(7);\nputs(\"not reached\");\n"
+    var code = runSource(source, "exit.mk", []string {}, options, false)
+    if code != 7 {
+        t.Fatalf("Expected the exit code to be %d but got %d instead", 7, code)
+    }
+
+    var report, err = os.ReadFile(lcov)
+    if err != nil {
+        t.Fatalf("Expected the coverage to be written on exit: %s", err)
+    }
+    // The line of exit ran, the puts after it did not
+    for _, expected := range []string { "SF:exit.mk", "DA:2,", "DA:7,0" } {
+        if !strings.Contains(string(report), expected) {
+            t.Errorf("Expected the coverage to contain %q but got:\n%s", expected, report)
+        }
+    }
+}
+
+func TestRunCapabilitiesOfEachMode(t *testing.T) {
+    if code := evalCodeCommand([]string { "exit(3)" }); code != 3 {
+        t.Errorf("Expected -e to exit with 3 but got %d", code)
+    }
+    if code := evalCodeCommand([]string { "-sandbox", "exit(3)" }); code != exitError {
+        t.Errorf("Expected -e -sandbox to fail on exit but got %d", code)
+    }
+    if code := evalCodeCommand([]string { "-sandbox" }); code != exitUsage {
+        t.Errorf("Expected -e without code to print the usage but got %d", code)
+    }
+
+    // The script piped into a bare monkey can not pass -sandbox, so it has no capabilities
+    if capabilities := pipedRunOptions().capabilities(); capabilities != (evaluator.Capabilities {}) {
+        t.Errorf("Expected no capabilities for the piped scripts but got %+v", capabilities)
+    }
+}
@@ -0,0 +1,84 @@
+// monkey/cmd_testing.go
+/*
+    Runs the tests written in monkey, the test_ functions of the *_test.mk files. The directories
+    are searched recursively and the files given by name run whatever their name is
+*/
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "monkey/tester"
+    "os"
+    "regexp"
+)
+
+func testCommand(args []string) int {
+    var options = defaultRunOptions()
+    var filter string
+    var verbose bool
+
+    var flags = flag.NewFlagSet("test", flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), "Usage: monkey test [flags] [files or directories...]")
+        flags.PrintDefaults()
+    }
+    flags.StringVar(&filter, "run", "", "runs only the tests whose name matches the regular expression")
+    flags.BoolVar(&verbose, "v", false, "lists the tests that pass too")
+    flags.StringVar(&options.root, "root", options.root, "directory the file builtins can use")
+    flags.BoolVar(&options.readOnly, "read-only", false, "blocks write_file")
+    flags.BoolVar(&options.sandbox, "sandbox", false, "disables files, stdin and env for the tests")
+    flags.BoolVar(&options.cover, "cover", false, "prints the statements and branches that ran to stderr")
+    flags.StringVar(&options.coverHTML, "cover-html", "", "writes the source annotated with the coverage to the HTML file")
+    flags.StringVar(&options.coverLCOV, "cover-lcov", "", "writes the coverage to the LCOV file")
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+
+    var runner = &tester.Runner {
+        Out:          os.Stdout,
+        ProgramOut:   os.Stdout,
+        Capabilities: options.capabilities(),
+        Verbose:      verbose,
+        Cover:        options.cover || options.coverHTML != "" || options.coverLCOV != "",
+    }
+    if filter != "" {
+        var regex, err = regexp.Compile(filter)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Invalid -run expression: %s\n", err)
+            return exitUsage
+        }
+        runner.Filter = regex
+    }
+
+    var paths = flags.Args()
+    if len(paths) == 0 { paths = []string { "." } }
+    var files, err = collectScripts(paths, tester.FileSuffix)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Could not read the tests: %s\n", err)
+        return exitError
+    }
+
+    var code = exitOk
+    for _, file := range files {
+        var source, errRead = os.ReadFile(file)
+        if errRead != nil {
+            fmt.Fprintf(os.Stderr, "Could not read %s: %s\n", file, errRead)
+            code = exitError
+            continue
+        }
+        var results, errRun = runner.RunFile(file, string(source))
+        if errRun != nil {
+            fmt.Fprintln(os.Stderr, errRun)
+            code = exitError
+            continue
+        }
+        for _, result := range results {
+            if !result.Passed() { code = exitError }
+        }
+    }
+
+    if runner.Cover && !writeCoverage(options, runner.Coverage...) { return exitError }
+    return code
+}
@@ -0,0 +1,92 @@
+// monkey/cmd_vet.go
+/*
+    Lints monkey scripts with the rules of the vet package. The config comes from -config or from
+    the .monkeyvet.json of the current directory when there is one. Exits with an error when there
+    are findings
+*/
+
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "monkey/parser"
+    "monkey/vet"
+    "os"
+)
+
+const defaultVetConfig = ".monkeyvet.json"
+
+func vetCommand(args []string) int {
+    var configPath string
+    var listRules bool
+
+    var flags = flag.NewFlagSet("vet", flag.ContinueOnError)
+    flags.Usage = func () {
+        fmt.Fprintln(flags.Output(), "Usage: monkey vet [flags] [files or directories...]")
+        flags.PrintDefaults()
+    }
+    flags.StringVar(&configPath, "config", "", "JSON file turning the rules on or off, "+defaultVetConfig+" by default")
+    flags.BoolVar(&listRules, "rules", false, "lists the rules and exits")
+    if err := flags.Parse(args); err != nil {
+        return exitUsage
+    }
+
+    if listRules {
+        for _, rule := range vet.Rules() {
+            fmt.Printf("%-20s %s\n", rule.Name, rule.Summary)
+        }
+        return exitOk
+    }
+
+    var config = vet.DefaultConfig()
+    if configPath == "" {
+        if _, err := os.Stat(defaultVetConfig); err == nil { configPath = defaultVetConfig }
+    }
+    if configPath != "" {
+        var loaded, err = vet.LoadConfig(configPath)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Could not load the config: %s\n", err)
+            return exitUsage
+        }
+        config = loaded
+    }
+
+    var paths = flags.Args()
+    if len(paths) == 0 { paths = []string { "." } }
+    var files, err = collectScripts(paths, ".mk")
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Could not read the scripts: %s\n", err)
+        return exitError
+    }
+
+    var code = exitOk
+    for _, file := range files {
+        var source, errRead = os.ReadFile(file)
+        if errRead != nil {
+            fmt.Fprintf(os.Stderr, "Could not read %s: %s\n", file, errRead)
+            code = exitError
+            continue
+        }
+
+        var findings, errCheck = vet.Check(string(source), config)
+        if errCheck != nil {
+            var syntaxErr *parser.SyntaxError
+            if errors.As(errCheck, &syntaxErr) {
+                for _, msg := range syntaxErr.Messages {
+                    fmt.Fprintf(os.Stderr, "%s: syntax error: %s\n", file, msg)
+                }
+            } else {
+                fmt.Fprintf(os.Stderr, "%s: %s\n", file, errCheck)
+            }
+            code = exitError
+            continue
+        }
+        for _, finding := range findings {
+            fmt.Printf("%s:%s\n", file, finding)
+            code = exitError
+        }
+    }
+    return code
+}
@@ -0,0 +1,120 @@
+// monkey/coverage/coverage.go
+/*
+    Statement and branch coverage of monkey scripts. As an evaluator.Observer it counts how many
+    times each statement of the program runs, and which way each if goes. The if without else
+    still has two branches, the second one is taken when the condition is false. The counts are
+    keyed by the positions of the source, so the reports can point to the lines
+*/
+
+package coverage
+
+import (
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/object"
+    "monkey/token"
+    "sort"
+)
+
+type Statement struct {
+    Pos   token.Position
+    End   token.Position
+    Count int
+}
+
+type Branch struct {
+    Pos      token.Position // Of the block, or of the if for the missing else
+    End      token.Position
+    If       token.Position // The if the branch belongs to
+    Index    int            // 0 for the consequence and 1 for the alternative
+    Implicit bool           // The else that was not written
+    Count    int
+}
+
+type Coverage struct {
+    evaluator.BaseObserver
+    File       string
+    Source     string
+
+    statements map[ast.Node] *Statement
+    conditions map[ast.Node] [2]*Branch // By the condition of their if
+}
+
+func NewCoverage(file string, source string, program *ast.Program) *Coverage {
+    var this = &Coverage {
+        File:       file,
+        Source:     source,
+        statements: map[ast.Node] *Statement {},
+        conditions: map[ast.Node] [2]*Branch {},
+    }
+    this.collect(program)
+    return this
+}
+
+// Finds the statements, the blocks are not statements of their own, and the conditions of the ifs
+func (this *Coverage) collect(program *ast.Program) {
+    ast.Inspect(program, func (node ast.Node) bool {
+        switch node := node.(type) {
+        case *ast.StatementsBlock:
+        case ast.Statement:
+            this.statements[node] = &Statement { Pos: node.Pos(), End: node.End() }
+        case *ast.IfExpression:
+            var consequence = &Branch { Pos: node.ConsequenceBlock.Pos(), End: node.ConsequenceBlock.End(), If: node.Pos() }
+            var alternative = &Branch { Pos: node.Pos(), End: node.End(), If: node.Pos(), Index: 1, Implicit: true }
+            if node.AlternativeBlock != nil {
+                alternative.Pos, alternative.End, alternative.Implicit = node.AlternativeBlock.Pos(), node.AlternativeBlock.End(), false
+            }
+            this.conditions[node.Condition] = [2]*Branch { consequence, alternative }
+        }
+        return true
+    })
+}
+
+// @Impl
+func (this *Coverage) Enter(node ast.Node, env *object.Environment) {
+    if stm, found := this.statements[node]; found { stm.Count++ }
+}
+
+// @Impl
+func (this *Coverage) Exit(node ast.Node, result object.Object) {
+    var branches, found = this.conditions[node]
+    if !found { return }
+
+    // The same rule of the evaluator, the other conditions do not take any branch
+    switch result.(type) {
+    case *object.Boolean, *object.Integer:
+        if evaluator.IsTruthy(result) {
+            branches[0].Count++
+        } else {
+            branches[1].Count++
+        }
+    }
+}
+
+func comparePositions(a token.Position, b token.Position) bool {
+    if a.Line != b.Line { return a.Line < b.Line }
+    return a.Column < b.Column
+}
+
+// The statements in the order of the source
+func (this *Coverage) Statements() []*Statement {
+    var stms = make([]*Statement, 0, len(this.statements))
+    for _, stm := range this.statements {
+        stms = append(stms, stm)
+    }
+    sort.Slice(stms, func (i int, j int) bool { return comparePositions(stms[i].Pos, stms[j].Pos) })
+    return stms
+}
+
+// The branches in the order of the source, the two of each if together
+func (this *Coverage) Branches() []*Branch {
+    var branches = make([]*Branch, 0, len(this.conditions) * 2)
+    for _, pair := range this.conditions {
+        branches = append(branches, pair[0], pair[1])
+    }
+    sort.Slice(branches, func (i int, j int) bool {
+        if branches[i].If != branches[j].If { return comparePositions(branches[i].If, branches[j].If) }
+        return branches[i].Index < branches[j].Index
+    })
+    return branches
+}
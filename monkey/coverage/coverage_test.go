@@ -0,0 +1,140 @@
+// monkey/coverage/coverage_test.go
+
+package coverage
+
+import (
+    "bytes"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+const source = `let sign = fn (n) {
+    if (n < 0) { return -1 };
+    if (n == 0) { 0 } else { 1 }
+};
+let unused = fn () { puts("never") };
+sign(5);
+sign(-3);`
+
+func runCoverage(t *testing.T, input string) *Coverage {
+    t.Helper()
+    var p = parser.NewParser(lexer.NewLexer(input))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { t.Fatalf("Unexpected parser errors: %v", p.Errors()) }
+
+    var cover = NewCoverage("sign.mk", input, program)
+    var interpreter = evaluator.NewInterpreter()
+    interpreter.Observer = cover
+    interpreter.Eval(program, object.NewEnvironment())
+    return cover
+}
+
+func TestStatementsAndBranches(t *testing.T) {
+    var cover = runCoverage(t, source)
+
+    var statements = []string {}
+    for _, stm := range cover.Statements() {
+        statements = append(statements, stm.Pos.String() + "=" + strconv.Itoa(stm.Count))
+    }
+    var expected = "1:1=1 2:5=2 2:18=1 3:5=1 3:19=0 3:30=1 5:1=1 5:22=0 6:1=1 7:1=1"
+    if got := strings.Join(statements, " "); got != expected {
+        t.Errorf("Expected the statements %s but got %s", expected, got)
+    }
+
+    var tests = []struct {
+        line int; index int; implicit bool; count int
+    } {
+        { 2, 0, false, 1 },
+        { 2, 1, true,  1 },
+        { 3, 0, false, 0 },
+        { 3, 1, false, 1 },
+    }
+    var branches = cover.Branches()
+    if len(branches) != len(tests) { t.Fatalf("Expected %d branches but got %d", len(tests), len(branches)) }
+    for i, test := range tests {
+        var branch = branches[i]
+        if branch.If.Line != test.line || branch.Index != test.index || branch.Implicit != test.implicit || branch.Count != test.count {
+            t.Errorf("Expected the branch %d to be %+v but got %+v", i, test, *branch)
+        }
+    }
+}
+
+func TestConditionWithoutBranch(t *testing.T) {
+    // The evaluator takes no branch when the condition is not a boolean or an integer
+    var cover = runCoverage(t, `if ("yes") { 1 } else { 2 };`)
+    for _, branch := range cover.Branches() {
+        if branch.Count != 0 { t.Errorf("Expected no branch taken but got %+v", *branch) }
+    }
+}
+
+func TestSummary(t *testing.T) {
+    var first = runCoverage(t, source + "\nlet never = fn () {\n    1\n};")
+    var second = runCoverage(t, "let x = 1;")
+    second.File = "x.mk"
+
+    // The return of line 3 did not run because of its branch, the puts of line 5 because unused
+    // was never called
+    var out bytes.Buffer
+    WriteSummary(&out, first, second)
+    var expected = `sign.mk: 75.0% of statements (9/12), 75.0% of branches (3/4)
+    not run: lines 9
+    partly run: lines 5
+    not taken: the if of line 3
+x.mk: 100.0% of statements (1/1), no branches
+total: 76.9% of statements (10/13), 75.0% of branches (3/4)
+`
+    if out.String() != expected { t.Errorf("Expected the summary\n%s\nbut got\n%s", expected, out.String()) }
+}
+
+func TestLCOV(t *testing.T) {
+    var cover = runCoverage(t, source + "\nlet never = fn (x) { if (x) { 1 } };")
+
+    var out bytes.Buffer
+    if err := WriteLCOV(&out, cover); err != nil { t.Fatal(err) }
+    var expected = `TN:
+SF:sign.mk
+BRDA:2,0,0,1
+BRDA:2,0,1,1
+BRDA:3,1,0,0
+BRDA:3,1,1,1
+BRDA:8,2,0,-
+BRDA:8,2,1,-
+BRF:6
+BRH:3
+DA:1,1
+DA:2,2
+DA:3,1
+DA:5,1
+DA:6,1
+DA:7,1
+DA:8,1
+LF:7
+LH:7
+end_of_record
+`
+    if out.String() != expected { t.Errorf("Expected the LCOV\n%s\nbut got\n%s", expected, out.String()) }
+}
+
+func TestHTML(t *testing.T) {
+    var cover = runCoverage(t, source + "\nlet f = fn () {\n    1\n};")
+
+    var out bytes.Buffer
+    if err := WriteHTML(&out, cover); err != nil { t.Fatal(err) }
+    var page = out.String()
+
+    var tests = []string {
+        `<h2>sign.mk</h2>`,
+        `<tr class="run"><td class="number">2</td><td class="count">2</td><td class="source">    if (n &lt; 0) { return -1 };</td></tr>`,
+        `<tr class="partial"><td class="number">3</td>`,
+        `<tr><td class="number">4</td><td class="count"></td><td class="source">};</td></tr>`,
+        `<tr class="not-run"><td class="number">9</td><td class="count">0</td><td class="source">    1</td></tr>`,
+    }
+    for _, expected := range tests {
+        if !strings.Contains(page, expected) { t.Errorf("Expected the page to contain %s\n%s", expected, page) }
+    }
+}
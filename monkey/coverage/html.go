@@ -0,0 +1,69 @@
+// monkey/coverage/html.go
+/*
+    The annotated source as one HTML page, a section for each file. Each line shows how many
+    times it ran and its color tells if it ran (green), did not run (red) or ran but left some
+    statement or branch out (yellow)
+*/
+
+package coverage
+
+import (
+    "fmt"
+    "html"
+    "io"
+    "strings"
+)
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Monkey coverage</title>
+<style>
+    body { font-family: sans-serif; margin: 2em; }
+    table { border-collapse: collapse; font-family: monospace; font-size: 13px; }
+    td { padding: 0 0.8em; white-space: pre; }
+    td.number, td.count { color: #888; text-align: right; }
+    tr.run td.source { background: #d7f5d7; }
+    tr.not-run td.source { background: #f8d0d0; }
+    tr.partial td.source { background: #f8efc0; }
+</style>
+</head>
+<body>
+`
+
+// Writes the page with the files in the given order
+func WriteHTML(out io.Writer, files ...*Coverage) error {
+    var page strings.Builder
+    page.WriteString(htmlHeader)
+
+    for _, file := range files {
+        fmt.Fprintf(&page, "<h2>%s</h2>\n<p>%s</p>\n<table>\n", html.EscapeString(file.File), html.EscapeString(file.totals().String()))
+
+        var lines = file.lines()
+        for i, text := range strings.Split(file.Source, "\n") {
+            var class, count = "", ""
+            if line, found := lines[i + 1]; found && line.code {
+                switch {
+                case !line.run:
+                    class = "not-run"
+                case line.notRun || line.notTaken:
+                    class = "partial"
+                default:
+                    class = "run"
+                }
+                count = fmt.Sprintf("%d", line.count)
+            } else if found && line.notTaken {
+                class = "partial"
+            }
+            if class != "" { class = " class=\"" + class + "\"" }
+            fmt.Fprintf(&page, "<tr%s><td class=\"number\">%d</td><td class=\"count\">%s</td><td class=\"source\">%s</td></tr>\n",
+                class, i + 1, count, html.EscapeString(text))
+        }
+        page.WriteString("</table>\n")
+    }
+
+    page.WriteString("</body>\n</html>\n")
+    var _, err = io.WriteString(out, page.String())
+    return err
+}
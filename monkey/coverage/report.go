@@ -0,0 +1,179 @@
+// monkey/coverage/report.go
+/*
+    The text summary and the LCOV file (the tracefile of lcov/genhtml, also read by most editors
+    and CI services). The HTML view is in html.go
+*/
+
+package coverage
+
+import (
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// What the statements and branches that start at a line tell about it
+type lineCoverage struct {
+    count    int  // The times the most run statement of the line ran
+    code     bool // Some statement starts at the line
+    run      bool // Some statement ran
+    notRun   bool // Some statement did not run
+    notTaken bool // Some branch of an if of the line was not taken
+}
+
+func (this *Coverage) lines() map[int] *lineCoverage {
+    var lines = map[int] *lineCoverage {}
+    var line = func (number int) *lineCoverage {
+        if lines[number] == nil { lines[number] = &lineCoverage {} }
+        return lines[number]
+    }
+    for _, stm := range this.statements {
+        var current = line(stm.Pos.Line)
+        current.count = max(current.count, stm.Count)
+        current.code = true
+        current.run = current.run || stm.Count > 0
+        current.notRun = current.notRun || stm.Count == 0
+    }
+    for _, branches := range this.conditions {
+        for _, branch := range branches {
+            if branch.Count == 0 { line(branch.If.Line).notTaken = true }
+        }
+    }
+    return lines
+}
+
+type totals struct {
+    statements, statementsRun int
+    branches, branchesTaken   int
+}
+
+func (this *Coverage) totals() totals {
+    var result totals
+    for _, stm := range this.statements {
+        result.statements++
+        if stm.Count > 0 { result.statementsRun++ }
+    }
+    for _, branches := range this.conditions {
+        for _, branch := range branches {
+            result.branches++
+            if branch.Count > 0 { result.branchesTaken++ }
+        }
+    }
+    return result
+}
+
+func (this totals) add(other totals) totals {
+    return totals {
+        this.statements + other.statements, this.statementsRun + other.statementsRun,
+        this.branches + other.branches, this.branchesTaken + other.branchesTaken,
+    }
+}
+
+func ratio(part int, total int, what string) string {
+    if total == 0 { return "no " + what }
+    return fmt.Sprintf("%.1f%% of %s (%d/%d)", float64(part) * 100 / float64(total), what, part, total)
+}
+
+func (this totals) String() string {
+    return ratio(this.statementsRun, this.statements, "statements") + ", " + ratio(this.branchesTaken, this.branches, "branches")
+}
+
+// The sorted numbers as ranges: 1, 3-5, 8
+func lineRanges(numbers []int) string {
+    sort.Ints(numbers)
+    var ranges = []string {}
+    for i := 0; i < len(numbers); {
+        var j = i
+        for j + 1 < len(numbers) && numbers[j + 1] == numbers[j] + 1 { j++ }
+        if i == j {
+            ranges = append(ranges, strconv.Itoa(numbers[i]))
+        } else {
+            ranges = append(ranges, fmt.Sprintf("%d-%d", numbers[i], numbers[j]))
+        }
+        i = j + 1
+    }
+    return strings.Join(ranges, ", ")
+}
+
+// Writes a line with the totals of each file, the lines where nothing ran, the ones that ran in
+// part, like a function never called written on the line of its let, and the branches not taken.
+// A line that ran in part because of a branch is only told by the branch:
+//
+//     lib.mk: 83.3% of statements (10/12), 75.0% of branches (3/4)
+//         not run: lines 4, 7-8
+//         partly run: lines 10
+//         not taken: the else of line 2
+func WriteSummary(out io.Writer, files ...*Coverage) {
+    var all totals
+    for _, file := range files {
+        var fileTotals = file.totals()
+        all = all.add(fileTotals)
+        fmt.Fprintf(out, "%s: %s\n", file.File, fileTotals)
+
+        var notRun, partlyRun = []int {}, []int {}
+        for number, line := range file.lines() {
+            switch {
+            case line.code && !line.run:
+                notRun = append(notRun, number)
+            case line.notRun && !line.notTaken:
+                partlyRun = append(partlyRun, number)
+            }
+        }
+        if len(notRun) > 0 { fmt.Fprintf(out, "    not run: lines %s\n", lineRanges(notRun)) }
+        if len(partlyRun) > 0 { fmt.Fprintf(out, "    partly run: lines %s\n", lineRanges(partlyRun)) }
+
+        var notTaken = []string {}
+        for _, branch := range file.Branches() {
+            if branch.Count > 0 { continue }
+            notTaken = append(notTaken, fmt.Sprintf("the %s of line %d", [2]string { "if", "else" }[branch.Index], branch.If.Line))
+        }
+        if len(notTaken) > 0 { fmt.Fprintf(out, "    not taken: %s\n", strings.Join(notTaken, ", ")) }
+    }
+    if len(files) > 1 { fmt.Fprintf(out, "total: %s\n", all) }
+}
+
+// Writes the LCOV records of the files: a DA for each line with statements and a BRDA for each
+// branch. The branches of an if that never ran are '-', as lcov expects
+func WriteLCOV(out io.Writer, files ...*Coverage) error {
+    var records strings.Builder
+    for _, file := range files {
+        fmt.Fprintln(&records, "TN:")
+        fmt.Fprintf(&records, "SF:%s\n", file.File)
+
+        // The branches come in pairs, the consequence and the alternative of each if
+        var branches = file.Branches()
+        var taken = 0
+        for i := 0; i < len(branches); i += 2 {
+            var pair = branches[i:i + 2]
+            for _, branch := range pair {
+                var count = "-"
+                if pair[0].Count + pair[1].Count > 0 { count = strconv.Itoa(branch.Count) }
+                if branch.Count > 0 { taken++ }
+                fmt.Fprintf(&records, "BRDA:%d,%d,%d,%s\n", branch.If.Line, i / 2, branch.Index, count)
+            }
+        }
+        fmt.Fprintf(&records, "BRF:%d\n", len(branches))
+        fmt.Fprintf(&records, "BRH:%d\n", taken)
+
+        var lines = file.lines()
+        var numbers = make([]int, 0, len(lines))
+        for number := range lines {
+            numbers = append(numbers, number)
+        }
+        sort.Ints(numbers)
+        var found, hit = 0, 0
+        for _, number := range numbers {
+            if !lines[number].code { continue }
+            found++
+            fmt.Fprintf(&records, "DA:%d,%d\n", number, lines[number].count)
+            if lines[number].count > 0 { hit++ }
+        }
+        fmt.Fprintf(&records, "LF:%d\n", found)
+        fmt.Fprintf(&records, "LH:%d\n", hit)
+        fmt.Fprintln(&records, "end_of_record")
+    }
+    var _, err = io.WriteString(out, records.String())
+    return err
+}
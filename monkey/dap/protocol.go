@@ -0,0 +1,119 @@
+// monkey/dap/protocol.go
+/*
+    The parts of the Debug Adapter Protocol the adapter uses. The messages are JSON objects framed
+    by a Content-Length header, like the ones of the language server
+*/
+
+package dap
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/textproto"
+    "strconv"
+)
+
+// The only thread, monkey runs one program at a time
+const threadID = 1
+
+type request struct {
+    Seq       int             `json:"seq"`
+    Type      string          `json:"type"`
+    Command   string          `json:"command"`
+    Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type response struct {
+    Seq        int    `json:"seq"`
+    Type       string `json:"type"`
+    RequestSeq int    `json:"request_seq"`
+    Success    bool   `json:"success"`
+    Command    string `json:"command"`
+    Message    string `json:"message,omitempty"`
+    Body       any    `json:"body,omitempty"`
+}
+
+type event struct {
+    Seq   int    `json:"seq"`
+    Type  string `json:"type"`
+    Event string `json:"event"`
+    Body  any    `json:"body,omitempty"`
+}
+
+type LaunchArguments struct {
+    Program     string   `json:"program"`
+    Args        []string `json:"args"`
+    StopOnEntry bool     `json:"stopOnEntry"`
+    NoDebug     bool     `json:"noDebug"`
+}
+
+type Source struct {
+    Name string `json:"name,omitempty"`
+    Path string `json:"path,omitempty"`
+}
+
+type SourceBreakpoint struct {
+    Line      int    `json:"line"`
+    Condition string `json:"condition,omitempty"`
+}
+
+type SetBreakpointsArguments struct {
+    Source      Source             `json:"source"`
+    Breakpoints []SourceBreakpoint `json:"breakpoints"`
+}
+
+type Breakpoint struct {
+    ID       int    `json:"id,omitempty"`
+    Verified bool   `json:"verified"`
+    Line     int    `json:"line,omitempty"`
+    Message  string `json:"message,omitempty"`
+}
+
+type StackFrame struct {
+    ID     int     `json:"id"`
+    Name   string  `json:"name"`
+    Source *Source `json:"source,omitempty"`
+    Line   int     `json:"line"`
+    Column int     `json:"column"`
+}
+
+type Scope struct {
+    Name               string `json:"name"`
+    VariablesReference int    `json:"variablesReference"`
+    Expensive          bool   `json:"expensive"`
+}
+
+type Variable struct {
+    Name               string `json:"name"`
+    Value              string `json:"value"`
+    Type               string `json:"type,omitempty"`
+    VariablesReference int    `json:"variablesReference"`
+}
+
+type EvaluateArguments struct {
+    Expression string `json:"expression"`
+    FrameID    int    `json:"frameId"`
+    Context    string `json:"context"`
+}
+
+// Reads the body of the next message
+func readMessage(in *bufio.Reader) ([]byte, error) {
+    var header, err = textproto.NewReader(in).ReadMIMEHeader()
+    if err != nil { return nil, err }
+
+    var length, errLength = strconv.Atoi(header.Get("Content-Length"))
+    if errLength != nil { return nil, fmt.Errorf("invalid Content-Length: %w", errLength) }
+
+    var body = make([]byte, length)
+    if _, err = io.ReadFull(in, body); err != nil { return nil, err }
+    return body, nil
+}
+
+func writeMessage(out io.Writer, value any) error {
+    var body, err = json.Marshal(value)
+    if err != nil { return err }
+    _, err = fmt.Fprintf(out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+    return err
+}
@@ -0,0 +1,430 @@
+// monkey/dap/server.go
+/*
+    Debug adapter for the editors. The requests are handled on the goroutine of Serve and the
+    program runs on its own one, blocked inside the debugger while it is stopped. The resume
+    requests wake it up through a channel
+*/
+
+package dap
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "monkey/ast"
+    "monkey/debugger"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+type Server struct {
+    in  *bufio.Reader
+    out io.Writer
+
+    mutex sync.Mutex // Of the writes and the state the program goroutine also uses
+    seq   int
+
+    program     *ast.Program
+    path        string
+    args        []string
+    stopOnEntry bool
+    noDebug     bool
+
+    debugger   *debugger.Debugger
+    running    bool
+    stopped    bool          // While the program waits in the debugger
+    resume     chan struct {}
+    done       chan struct {} // Closed when the program ends
+    references []any         // Environments and objects of the variables, valid while stopped
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+    return &Server {
+        in:     bufio.NewReader(in),
+        out:    out,
+        resume: make(chan struct {}),
+        done:   make(chan struct {}),
+    }
+}
+
+func (this *Server) send(message any) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.seq++
+    switch msg := message.(type) {
+    case *response:
+        msg.Seq = this.seq
+    case *event:
+        msg.Seq = this.seq
+    }
+    writeMessage(this.out, message)
+}
+
+func (this *Server) sendEvent(name string, body any) {
+    this.send(&event { Type: "event", Event: name, Body: body })
+}
+
+// Writes what the program prints as output events
+type outputWriter struct {
+    server   *Server
+    category string
+}
+
+func (this outputWriter) Write(data []byte) (int, error) {
+    this.server.sendEvent("output", map[string] any { "category": this.category, "output": string(data) })
+    return len(data), nil
+}
+
+// Handles the requests until the client disconnects or closes the input
+func (this *Server) Serve() {
+    defer this.finish()
+    for {
+        var body, err = readMessage(this.in)
+        if err != nil { return }
+
+        var req request
+        if err := json.Unmarshal(body, &req); err != nil || req.Type != "request" { continue }
+
+        var result, errHandle = this.handle(req)
+        var resp = &response { Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: errHandle == nil, Body: result }
+        if errHandle != nil { resp.Message = errHandle.Error() }
+        this.send(resp)
+
+        switch req.Command {
+        case "initialize":
+            this.sendEvent("initialized", nil)
+        case "configurationDone":
+            this.start()
+        case "disconnect", "terminate":
+            return
+        }
+    }
+}
+
+func decode[T any](arguments json.RawMessage) (T, error) {
+    var value T
+    if len(arguments) == 0 { return value, nil }
+    var err = json.Unmarshal(arguments, &value)
+    return value, err
+}
+
+func (this *Server) handle(req request) (any, error) {
+    switch req.Command {
+    case "initialize":
+        return map[string] any {
+            "supportsConfigurationDoneRequest": true,
+            "supportsConditionalBreakpoints":   true,
+            "supportsEvaluateForHovers":        true,
+            "supportsTerminateRequest":         true,
+        }, nil
+    case "launch":
+        var args, err = decode[LaunchArguments](req.Arguments)
+        if err != nil { return nil, err }
+        return nil, this.launch(args)
+    case "setBreakpoints":
+        var args, err = decode[SetBreakpointsArguments](req.Arguments)
+        if err != nil { return nil, err }
+        return this.setBreakpoints(args), nil
+    case "setExceptionBreakpoints", "configurationDone", "disconnect", "terminate":
+        return nil, nil
+    case "threads":
+        return map[string] any { "threads": []map[string] any { { "id": threadID, "name": "main" } } }, nil
+    case "continue":
+        return map[string] any { "allThreadsContinued": true }, this.resumeWith((*debugger.Debugger).Continue)
+    case "next":
+        return nil, this.resumeWith((*debugger.Debugger).StepOver)
+    case "stepIn":
+        return nil, this.resumeWith((*debugger.Debugger).StepIn)
+    case "stepOut":
+        return nil, this.resumeWith((*debugger.Debugger).StepOut)
+    case "pause":
+        if this.debugger == nil || !this.running { return nil, errors.New("the program is not running") }
+        this.debugger.Pause()
+        return nil, nil
+    case "stackTrace":
+        return this.stackTrace()
+    case "scopes":
+        var args, err = decode[struct { FrameID int `json:"frameId"` }](req.Arguments)
+        if err != nil { return nil, err }
+        return this.scopes(args.FrameID)
+    case "variables":
+        var args, err = decode[struct { VariablesReference int `json:"variablesReference"` }](req.Arguments)
+        if err != nil { return nil, err }
+        return this.variables(args.VariablesReference)
+    case "evaluate":
+        var args, err = decode[EvaluateArguments](req.Arguments)
+        if err != nil { return nil, err }
+        return this.evaluate(args)
+    default:
+        return nil, fmt.Errorf("unsupported request %s", req.Command)
+    }
+}
+
+func (this *Server) launch(args LaunchArguments) error {
+    if args.Program == "" { return errors.New("missing the program to debug") }
+
+    var source, err = os.ReadFile(args.Program)
+    if err != nil { return fmt.Errorf("could not read the program: %w", err) }
+
+    var p = parser.NewParser(lexer.NewLexer(string(source)))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { return fmt.Errorf("syntax error: %s", strings.Join(p.Errors(), "; ")) }
+
+    var interpreter = evaluator.NewInterpreter()
+    interpreter.Out = outputWriter { server: this, category: "stdout" }
+    // Stdin carries the protocol so the script cannot read it
+    interpreter.Capabilities = evaluator.Capabilities { FileRoot: filepath.Dir(args.Program), Env: true }
+    // The macros are only needed by the expansion, the program runs later on its own environment
+    if err := interpreter.ExpandMacros(program, object.NewEnvironment()); err != nil { return err }
+
+    this.program = program
+    this.path, _ = filepath.Abs(args.Program)
+    this.args = args.Args
+    this.stopOnEntry = args.StopOnEntry
+    this.noDebug = args.NoDebug
+    this.debugger = debugger.NewDebugger(interpreter)
+    this.debugger.OnStop = this.onStop
+    return nil
+}
+
+func (this *Server) setBreakpoints(args SetBreakpointsArguments) any {
+    var result = []Breakpoint {}
+    if this.debugger == nil { return map[string] any { "breakpoints": result } }
+
+    var path, _ = filepath.Abs(args.Source.Path)
+    var isProgram = path == this.path
+    if isProgram { this.debugger.ClearBreakpoints() }
+
+    for _, requested := range args.Breakpoints {
+        if !isProgram {
+            result = append(result, Breakpoint { Verified: false, Line: requested.Line, Message: "only the launched program can have breakpoints" })
+            continue
+        }
+        var bp, err = this.debugger.SetBreakpoint(requested.Line, requested.Condition)
+        if err != nil {
+            result = append(result, Breakpoint { Verified: false, Line: requested.Line, Message: err.Error() })
+            continue
+        }
+        result = append(result, Breakpoint { ID: bp.ID, Verified: true, Line: bp.Line })
+    }
+    return map[string] any { "breakpoints": result }
+}
+
+// Starts the program once the client has sent the breakpoints
+func (this *Server) start() {
+    if this.debugger == nil || this.running { return }
+    this.running = true
+
+    var env = object.NewEnvironment()
+    var args = []object.Object {}
+    for _, arg := range this.args {
+        args = append(args, &object.String { Value: arg })
+    }
+    env.Set("args", &object.Array { Elements: args })
+
+    go func () {
+        defer close(this.done)
+        var result object.Object
+        var err error
+        if this.noDebug {
+            result = this.debugger.Interpreter.Eval(this.program, env)
+        } else {
+            result, err = this.debugger.Run(this.program, env, this.stopOnEntry)
+        }
+
+        var exitCode = 0
+        if errObj, isErr := result.(*object.Error); isErr {
+            this.sendEvent("output", map[string] any { "category": "stderr", "output": "runtime error: " + errObj.Message + "\n" })
+            exitCode = 1
+        }
+        if err != nil { exitCode = 1 }
+        this.sendEvent("exited", map[string] any { "exitCode": exitCode })
+        this.sendEvent("terminated", nil)
+    }()
+}
+
+// Runs on the program goroutine, it waits there until a resume request
+func (this *Server) onStop(stop debugger.Stop) {
+    var reason = stop.Reason
+    var description = ""
+    switch stop.Reason {
+    case debugger.StopReturn:
+        reason = "step"
+        description = "Returned " + debugger.Inspect(stop.Result)
+    case debugger.StopBreakpoint:
+        if stop.Error != "" { description = "The condition failed: " + stop.Error }
+    }
+
+    this.mutex.Lock()
+    this.stopped = true
+    this.references = nil
+    this.mutex.Unlock()
+
+    var body = map[string] any { "reason": reason, "threadId": threadID, "allThreadsStopped": true }
+    if description != "" { body["description"] = description }
+    if stop.Breakpoint != nil { body["hitBreakpointIds"] = []int { stop.Breakpoint.ID } }
+    this.sendEvent("stopped", body)
+
+    <-this.resume
+}
+
+func (this *Server) isStopped() bool {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    return this.stopped
+}
+
+// Sets how the program continues and wakes it up
+func (this *Server) resumeWith(mode func (*debugger.Debugger)) error {
+    if !this.isStopped() { return errors.New("the program is not stopped") }
+
+    mode(this.debugger)
+    this.mutex.Lock()
+    this.stopped = false
+    this.mutex.Unlock()
+    this.resume <- struct {} {}
+    return nil
+}
+
+// Ends the program if it still runs, waiting for it so its events are sent
+func (this *Server) finish() {
+    if !this.running { return }
+    this.debugger.Abort()
+    if this.isStopped() {
+        this.mutex.Lock()
+        this.stopped = false
+        this.mutex.Unlock()
+        this.resume <- struct {} {}
+    }
+    <-this.done
+}
+
+func (this *Server) stackTrace() (any, error) {
+    if !this.isStopped() { return nil, errors.New("the program is not stopped") }
+
+    var frames = []StackFrame {}
+    var source = &Source { Name: filepath.Base(this.path), Path: this.path }
+    for i, frame := range this.debugger.Stack() {
+        var column = 1
+        if frame.Statement != nil { column = frame.Statement.Pos().Column }
+        frames = append(frames, StackFrame { ID: i + 1, Name: frame.Name, Source: source, Line: frame.Line(), Column: column })
+    }
+    return map[string] any { "stackFrames": frames, "totalFrames": len(frames) }, nil
+}
+
+func (this *Server) frame(frameID int) (*debugger.Frame, error) {
+    if !this.isStopped() { return nil, errors.New("the program is not stopped") }
+
+    var stack = this.debugger.Stack()
+    if frameID < 1 || frameID > len(stack) { return nil, fmt.Errorf("no frame %d", frameID) }
+    return stack[frameID - 1], nil
+}
+
+// The variables reference of the value, 0 when it has nothing to expand
+func (this *Server) reference(value any) int {
+    switch x := value.(type) {
+    case *object.Array:
+        if len(x.Elements) == 0 { return 0 }
+    case *object.Hash:
+        if len(x.Order) == 0 { return 0 }
+    case *object.Environment:
+    default:
+        return 0
+    }
+
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.references = append(this.references, value)
+    return len(this.references)
+}
+
+// One scope for each environment of the frame, from the innermost to the global one
+func (this *Server) scopes(frameID int) (any, error) {
+    var frame, err = this.frame(frameID)
+    if err != nil { return nil, err }
+
+    var scopes = []Scope {}
+    for env := frame.Env; env != nil; env = env.Outer() {
+        var name = "Closure"
+        switch {
+        case env.Outer() == nil:
+            name = "Globals"
+        case len(scopes) == 0:
+            name = "Locals"
+        }
+        scopes = append(scopes, Scope { Name: name, VariablesReference: this.reference(env) })
+    }
+    return map[string] any { "scopes": scopes }, nil
+}
+
+func display(value object.Object) string {
+    if str, isString := value.(*object.String); isString { return strconv.Quote(str.Value) }
+    return debugger.Inspect(value)
+}
+
+func (this *Server) variable(name string, value object.Object) Variable {
+    return Variable {
+        Name:               name,
+        Value:              display(value),
+        Type:               evaluator.GetMsgTypeFor(value.Type()),
+        VariablesReference: this.reference(value),
+    }
+}
+
+func (this *Server) variables(reference int) (any, error) {
+    if !this.isStopped() { return nil, errors.New("the program is not stopped") }
+
+    this.mutex.Lock()
+    var valid = reference >= 1 && reference <= len(this.references)
+    var target any
+    if valid { target = this.references[reference - 1] }
+    this.mutex.Unlock()
+    if !valid { return nil, fmt.Errorf("no variables %d", reference) }
+
+    var variables = []Variable {}
+    switch x := target.(type) {
+    case *object.Environment:
+        for _, name := range x.Names() {
+            var value, _ = x.Get(name)
+            variables = append(variables, this.variable(name, value))
+        }
+    case *object.Array:
+        for i, elem := range x.Elements {
+            variables = append(variables, this.variable("[" + strconv.Itoa(i) + "]", elem))
+        }
+    case *object.Hash:
+        for _, pair := range x.OrderedPairs() {
+            variables = append(variables, this.variable(display(pair.OriginalKey), pair.Value))
+        }
+    }
+    return map[string] any { "variables": variables }, nil
+}
+
+func (this *Server) evaluate(args EvaluateArguments) (any, error) {
+    var frameIndex = 0
+    if args.FrameID > 0 {
+        if _, err := this.frame(args.FrameID); err != nil { return nil, err }
+        frameIndex = args.FrameID - 1
+    } else if !this.isStopped() {
+        return nil, errors.New("the program is not stopped")
+    }
+
+    var result, err = this.debugger.Evaluate(args.Expression, frameIndex)
+    if err != nil { return nil, err }
+    if errObj, isErr := result.(*object.Error); isErr { return nil, errors.New(errObj.Message) }
+
+    return map[string] any {
+        "result":             display(result),
+        "type":               evaluator.GetMsgTypeFor(result.Type()),
+        "variablesReference": this.reference(result),
+    }, nil
+}
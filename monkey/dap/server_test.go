@@ -0,0 +1,267 @@
+// monkey/dap/server_test.go
+
+package dap
+
+import (
+    "bufio"
+    "encoding/json"
+    "io"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+const factSource = `let fact = fn (n) {
+    if (n < 2) { return 1 };
+    n * fact(n - 1)
+};
+let xs = [1, "two", { "k": 3 }];
+let x = fact(4);
+puts(x);`
+
+type received struct {
+    Type       string          `json:"type"`
+    Event      string          `json:"event"`
+    RequestSeq int             `json:"request_seq"`
+    Success    bool            `json:"success"`
+    Message    string          `json:"message"`
+    Body       json.RawMessage `json:"body"`
+}
+
+type testClient struct {
+    t        *testing.T
+    requests *io.PipeWriter
+    messages chan received
+    pending  []received
+    seq      int
+    path     string // Of the launched program
+}
+
+func newTestClient(t *testing.T) *testClient {
+    var requestsReader, requestsWriter = io.Pipe()
+    var responsesReader, responsesWriter = io.Pipe()
+    var client = &testClient { t: t, requests: requestsWriter, messages: make(chan received, 100) }
+
+    go func () {
+        NewServer(requestsReader, responsesWriter).Serve()
+        responsesWriter.Close()
+    }()
+    go func () {
+        var reader = bufio.NewReader(responsesReader)
+        for {
+            var body, err = readMessage(reader)
+            if err != nil {
+                close(client.messages)
+                return
+            }
+            var msg received
+            json.Unmarshal(body, &msg)
+            client.messages <- msg
+        }
+    }()
+    return client
+}
+
+func (this *testClient) send(command string, arguments any) int {
+    this.seq++
+    writeMessage(this.requests, map[string] any { "seq": this.seq, "type": "request", "command": command, "arguments": arguments })
+    return this.seq
+}
+
+// Waits for the first message that matches, the others are kept for the next calls
+func (this *testClient) waitFor(description string, match func (msg received) bool) received {
+    this.t.Helper()
+    for i, msg := range this.pending {
+        if match(msg) {
+            this.pending = append(this.pending[:i], this.pending[i + 1:]...)
+            return msg
+        }
+    }
+    var timeout = time.After(5 * time.Second)
+    for {
+        select {
+        case msg, open := <-this.messages:
+            if !open { this.t.Fatalf("The adapter ended while waiting for %s", description) }
+            if match(msg) { return msg }
+            this.pending = append(this.pending, msg)
+        case <-timeout:
+            this.t.Fatalf("Timeout waiting for %s", description)
+        }
+    }
+}
+
+// Sends the request and returns the body of its successful response
+func (this *testClient) request(command string, arguments any, body any) {
+    this.t.Helper()
+    var seq = this.send(command, arguments)
+    var resp = this.waitFor("the response to " + command, func (msg received) bool {
+        return msg.Type == "response" && msg.RequestSeq == seq
+    })
+    if !resp.Success { this.t.Fatalf("The request %s failed: %s", command, resp.Message) }
+    if body != nil { json.Unmarshal(resp.Body, body) }
+}
+
+func (this *testClient) event(name string, body any) {
+    this.t.Helper()
+    var msg = this.waitFor("the event " + name, func (msg received) bool {
+        return msg.Type == "event" && msg.Event == name
+    })
+    if body != nil { json.Unmarshal(msg.Body, body) }
+}
+
+type stoppedBody struct {
+    Reason string `json:"reason"`
+}
+
+type stackBody struct {
+    StackFrames []StackFrame `json:"stackFrames"`
+}
+
+func (this *testClient) stack() []string {
+    this.t.Helper()
+    var stack stackBody
+    this.request("stackTrace", map[string] any { "threadId": threadID }, &stack)
+    var frames = []string {}
+    for _, frame := range stack.StackFrames {
+        frames = append(frames, frame.Name + ":" + strconv.Itoa(frame.Line))
+    }
+    return frames
+}
+
+func writeProgram(t *testing.T) string {
+    var path = filepath.Join(t.TempDir(), "fact.mk")
+    if err := os.WriteFile(path, []byte(factSource), 0644); err != nil { t.Fatal(err) }
+    return path
+}
+
+func (this *testClient) launch(path string, stopOnEntry bool, lines ...int) {
+    this.t.Helper()
+    this.path = path
+    this.request("initialize", map[string] any { "adapterID": "monkey" }, nil)
+    this.event("initialized", nil)
+    this.request("launch", map[string] any { "program": path, "stopOnEntry": stopOnEntry }, nil)
+
+    var breakpoints = []map[string] any {}
+    for _, line := range lines {
+        breakpoints = append(breakpoints, map[string] any { "line": line })
+    }
+    var result struct { Breakpoints []Breakpoint `json:"breakpoints"` }
+    this.request("setBreakpoints", map[string] any { "source": map[string] any { "path": path }, "breakpoints": breakpoints }, &result)
+    for _, bp := range result.Breakpoints {
+        if !bp.Verified { this.t.Errorf("Expected the breakpoint at %d to be verified: %s", bp.Line, bp.Message) }
+    }
+    this.request("configurationDone", nil, nil)
+}
+
+func TestBreakpointsAndStepping(t *testing.T) {
+    var client = newTestClient(t)
+    client.launch(writeProgram(t), false, 2)
+
+    var stopped stoppedBody
+    client.event("stopped", &stopped)
+    if stopped.Reason != "breakpoint" { t.Errorf("Expected to stop on the breakpoint but got %s", stopped.Reason) }
+    if got := strings.Join(client.stack(), " "); got != "fact:2 main:6" {
+        t.Errorf("Expected the stack fact:2 main:6 but got %s", got)
+    }
+
+    var cleared struct { Breakpoints []Breakpoint `json:"breakpoints"` }
+    client.request("setBreakpoints", map[string] any { "source": map[string] any { "path": "other.mk" }, "breakpoints": []any { map[string] any { "line": 1 } } }, &cleared)
+    if len(cleared.Breakpoints) != 1 || cleared.Breakpoints[0].Verified {
+        t.Errorf("Expected the breakpoint of another file to be rejected but got %+v", cleared.Breakpoints)
+    }
+    client.request("setBreakpoints", map[string] any { "source": map[string] any { "path": client.path }, "breakpoints": []any {} }, nil)
+
+    client.request("next", map[string] any { "threadId": threadID }, nil)
+    client.event("stopped", &stopped)
+    if got := strings.Join(client.stack(), " "); got != "fact:3 main:6" {
+        t.Errorf("Expected to step to line 3 but got %s", got)
+    }
+
+    client.request("stepIn", map[string] any { "threadId": threadID }, nil)
+    client.event("stopped", nil)
+    if got := strings.Join(client.stack(), " "); got != "fact:2 fact:3 main:6" {
+        t.Errorf("Expected to step into the recursive call but got %s", got)
+    }
+
+    client.request("stepOut", map[string] any { "threadId": threadID }, nil)
+    client.event("stopped", &stopped)
+    if got := strings.Join(client.stack(), " "); got != "fact:3 main:6" || stopped.Reason != "step" {
+        t.Errorf("Expected to step out to the call on line 3 but got %s (%s)", got, stopped.Reason)
+    }
+
+    client.request("continue", map[string] any { "threadId": threadID }, nil)
+    client.event("terminated", nil)
+    client.request("disconnect", nil, nil)
+}
+
+func TestVariablesAndEvaluate(t *testing.T) {
+    var client = newTestClient(t)
+    var path = writeProgram(t)
+    client.launch(path, false, 7)
+    client.event("stopped", nil)
+
+    var scopes struct { Scopes []Scope `json:"scopes"` }
+    client.request("scopes", map[string] any { "frameId": 1 }, &scopes)
+    if len(scopes.Scopes) != 1 || scopes.Scopes[0].Name != "Globals" {
+        t.Fatalf("Expected only the global scope but got %+v", scopes.Scopes)
+    }
+
+    var variables struct { Variables []Variable `json:"variables"` }
+    client.request("variables", map[string] any { "variablesReference": scopes.Scopes[0].VariablesReference }, &variables)
+    var byName = map[string] Variable {}
+    for _, variable := range variables.Variables { byName[variable.Name] = variable }
+    if byName["x"].Value != "24" || byName["fact"].Value != "fn (n)" || byName["xs"].VariablesReference == 0 {
+        t.Fatalf("Unexpected variables %+v", variables.Variables)
+    }
+
+    client.request("variables", map[string] any { "variablesReference": byName["xs"].VariablesReference }, &variables)
+    var items = []string {}
+    for _, variable := range variables.Variables { items = append(items, variable.Name + "=" + variable.Value) }
+    if got := strings.Join(items, " "); got != `[0]=1 [1]="two" [2]={ k: 3 }` {
+        t.Errorf("Expected the elements of xs but got %s", got)
+    }
+
+    var evaluated struct { Result string `json:"result"` }
+    client.request("evaluate", map[string] any { "expression": "fact(3) + x", "frameId": 1 }, &evaluated)
+    if evaluated.Result != "30" { t.Errorf("Expected fact(3) + x to be 30 but got %s", evaluated.Result) }
+
+    var seq = client.send("evaluate", map[string] any { "expression": "missing", "frameId": 1 })
+    var resp = client.waitFor("the failed evaluate", func (msg received) bool { return msg.Type == "response" && msg.RequestSeq == seq })
+    if resp.Success || !strings.Contains(resp.Message, "identifier not found") {
+        t.Errorf("Expected the evaluation to fail but got %+v", resp)
+    }
+
+    client.request("continue", map[string] any { "threadId": threadID }, nil)
+    var output struct { Output string `json:"output"` }
+    client.event("output", &output)
+    if output.Output != "24\n" { t.Errorf("Expected the output of puts but got %q", output.Output) }
+
+    var exited struct { ExitCode int `json:"exitCode"` }
+    client.event("exited", &exited)
+    if exited.ExitCode != 0 { t.Errorf("Expected exit code 0 but got %d", exited.ExitCode) }
+    client.event("terminated", nil)
+    client.request("disconnect", nil, nil)
+}
+
+func TestStopOnEntryAndErrors(t *testing.T) {
+    var client = newTestClient(t)
+    client.launch(writeProgram(t), true)
+    var stopped stoppedBody
+    client.event("stopped", &stopped)
+    if stopped.Reason != "entry" { t.Errorf("Expected to stop on entry but got %s", stopped.Reason) }
+    client.request("disconnect", nil, nil)
+
+    client = newTestClient(t)
+    client.request("initialize", nil, nil)
+    var seq = client.send("launch", map[string] any { "program": filepath.Join(t.TempDir(), "missing.mk") })
+    var resp = client.waitFor("the failed launch", func (msg received) bool { return msg.Type == "response" && msg.RequestSeq == seq })
+    if resp.Success { t.Errorf("Expected the launch of a missing program to fail") }
+
+    seq = client.send("continue", map[string] any { "threadId": threadID })
+    resp = client.waitFor("the failed continue", func (msg received) bool { return msg.Type == "response" && msg.RequestSeq == seq })
+    if resp.Success { t.Errorf("Expected continue to fail when nothing is stopped") }
+    client.request("disconnect", nil, nil)
+}
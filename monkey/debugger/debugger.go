@@ -0,0 +1,336 @@
+// monkey/debugger/debugger.go
+/*
+    Step debugger for monkey scripts. It follows the evaluation as an evaluator.Observer and
+    stops on the breakpoints and after the steps, calling OnStop. The program stays paused until
+    OnStop returns, so the front ends choose how to resume it before returning. The breakpoints,
+    Pause and Abort can be used from another goroutine while the program runs
+*/
+
+package debugger
+
+import (
+    "errors"
+    "fmt"
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "sort"
+    "strings"
+    "sync"
+    "sync/atomic"
+)
+
+const (
+    runContinue = iota
+    runStepIn
+    runStepOver
+    runStepOut
+)
+
+const (
+    StopEntry      = "entry"
+    StopBreakpoint = "breakpoint"
+    StopStep       = "step"
+    StopReturn     = "return"
+    StopPause      = "pause"
+)
+
+type Breakpoint struct {
+    ID        int
+    Line      int
+    Condition string // Monkey expression, it stops only when the result is truthy. Empty always stops
+    Hits      int
+
+    condition ast.Expression
+}
+
+// A call of a user function, or the script itself for the outermost one
+type Frame struct {
+    Name      string
+    Function  *object.Function // nil for the script
+    Env       *object.Environment
+    Statement ast.Statement    // The statement that runs now or is about to
+}
+
+func (this *Frame) Line() int {
+    if this.Statement == nil { return 0 }
+    return this.Statement.Pos().Line
+}
+
+type Stop struct {
+    Reason     string
+    Breakpoint *Breakpoint   // When the Reason is StopBreakpoint
+    Error      string        // When the condition of the breakpoint failed
+    Result     object.Object // What the function returned when the Reason is StopReturn
+}
+
+// Raised from the hook to unwind the evaluation when the session is aborted
+type abortSignal struct {}
+
+var ErrAborted = errors.New("the program was stopped by the debugger")
+
+type Debugger struct {
+    evaluator.BaseObserver
+    Interpreter *evaluator.Interpreter
+    OnStop      func(stop Stop)
+
+    mutex       sync.Mutex           // Of the breakpoints
+    breakpoints map[int] *Breakpoint // By line
+    nextID      int
+    stack       []*Frame             // The innermost is the last
+    mode        int
+    depth       int                  // Of the stack when the step started
+    pausing     atomic.Bool
+    aborted     atomic.Bool
+}
+
+func NewDebugger(interpreter *evaluator.Interpreter) *Debugger {
+    return &Debugger {
+        Interpreter: interpreter,
+        OnStop:      func (stop Stop) {},
+        breakpoints: map[int] *Breakpoint {},
+        nextID:      1,
+    }
+}
+
+func parseExpression(source string) (ast.Expression, error) {
+    var p = parser.NewParser(lexer.NewLexer(source))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { return nil, errors.New(strings.Join(p.Errors(), "; ")) }
+
+    if len(program.Statements) != 1 { return nil, errors.New("expected a single expression") }
+    var stm, isExpression = program.Statements[0].(*ast.ExpressionStatement)
+    if !isExpression { return nil, errors.New("expected an expression") }
+    return stm.Expression, nil
+}
+
+// Sets a breakpoint on the line, replacing the one that was there
+func (this *Debugger) SetBreakpoint(line int, condition string) (*Breakpoint, error) {
+    if line < 1 { return nil, fmt.Errorf("invalid line %d", line) }
+
+    var bp = &Breakpoint { Line: line, Condition: strings.TrimSpace(condition) }
+    if bp.Condition != "" {
+        var exp, err = parseExpression(bp.Condition)
+        if err != nil { return nil, fmt.Errorf("invalid condition: %w", err) }
+        bp.condition = exp
+    }
+
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    bp.ID = this.nextID
+    this.nextID++
+    this.breakpoints[line] = bp
+    return bp, nil
+}
+
+func (this *Debugger) ClearBreakpoint(line int) bool {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    var _, found = this.breakpoints[line]
+    delete(this.breakpoints, line)
+    return found
+}
+
+func (this *Debugger) ClearBreakpoints() {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.breakpoints = map[int] *Breakpoint {}
+}
+
+func (this *Debugger) HasBreakpoint(line int) bool {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    var _, found = this.breakpoints[line]
+    return found
+}
+
+// Sorted by line
+func (this *Debugger) Breakpoints() []*Breakpoint {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    var result = []*Breakpoint {}
+    for _, bp := range this.breakpoints {
+        result = append(result, bp)
+    }
+    sort.Slice(result, func (i int, j int) bool { return result[i].Line < result[j].Line })
+    return result
+}
+
+// The frames from the innermost to the script
+func (this *Debugger) Stack() []*Frame {
+    var result = make([]*Frame, 0, len(this.stack))
+    for i := len(this.stack) - 1; i >= 0; i-- {
+        result = append(result, this.stack[i])
+    }
+    return result
+}
+
+// The ways to resume the program, to call while it is stopped
+
+func (this *Debugger) Continue() {
+    this.mode = runContinue
+}
+
+// Stops on the next statement, inside the called functions too
+func (this *Debugger) StepIn() {
+    this.mode = runStepIn
+}
+
+// Stops on the next statement of the current function, or on its call when it returns
+func (this *Debugger) StepOver() {
+    this.mode = runStepOver
+    this.depth = len(this.stack)
+}
+
+// Stops on the call of the current function when it returns
+func (this *Debugger) StepOut() {
+    this.mode = runStepOut
+    this.depth = len(this.stack)
+}
+
+// Stops the running program on its next statement
+func (this *Debugger) Pause() {
+    this.pausing.Store(true)
+}
+
+// Ends the program, Run returns ErrAborted
+func (this *Debugger) Abort() {
+    this.aborted.Store(true)
+}
+
+// Evaluates the expression in the environment of the frame, 0 is the innermost. The hooks are
+// off meanwhile so it does not stop on the breakpoints
+func (this *Debugger) Evaluate(source string, frame int) (object.Object, error) {
+    if frame < 0 || frame >= len(this.stack) { return nil, fmt.Errorf("no frame %d", frame) }
+
+    var exp, err = parseExpression(source)
+    if err != nil { return nil, err }
+    return this.evaluate(exp, this.stack[len(this.stack) - 1 - frame].Env), nil
+}
+
+func (this *Debugger) evaluate(exp ast.Expression, env *object.Environment) object.Object {
+    var observer = this.Interpreter.Observer
+    this.Interpreter.Observer = nil
+    defer func () { this.Interpreter.Observer = observer }()
+    return this.Interpreter.Eval(exp, env)
+}
+
+// Runs the program under the debugger. With stopOnEntry it stops before the first statement
+func (this *Debugger) Run(program *ast.Program, env *object.Environment, stopOnEntry bool) (result object.Object, err error) {
+    this.stack = []*Frame { { Name: "main", Env: env } }
+    this.mode = runContinue
+    this.aborted.Store(false)
+    this.pausing.Store(false)
+    if stopOnEntry { this.mode = runStepIn }
+
+    this.Interpreter.Observer = this
+    defer func () {
+        this.Interpreter.Observer = nil
+        this.stack = nil
+        if recovered := recover(); recovered != nil {
+            if _, isAbort := recovered.(abortSignal); !isAbort { panic(recovered) }
+            result, err = nil, ErrAborted
+        }
+    }()
+
+    return this.Interpreter.Eval(program, env), nil
+}
+
+// Whether the breakpoint of the line stops the program, its condition runs in the env
+func (this *Debugger) hitBreakpoint(line int, env *object.Environment) (*Breakpoint, string, bool) {
+    this.mutex.Lock()
+    var bp, found = this.breakpoints[line]
+    this.mutex.Unlock()
+    if !found { return nil, "", false }
+    if bp.condition == nil { return bp, "", true }
+
+    var result = this.evaluate(bp.condition, env)
+    if errObj, isErr := result.(*object.Error); isErr { return bp, errObj.Message, true }
+    return bp, "", evaluator.IsTruthy(result)
+}
+
+func (this *Debugger) stop(stop Stop) {
+    if stop.Breakpoint != nil { stop.Breakpoint.Hits++ }
+    this.mode = runContinue
+    this.pausing.Store(false)
+    this.OnStop(stop)
+    if this.aborted.Load() { panic(abortSignal {}) }
+}
+
+// @Impl
+func (this *Debugger) Enter(node ast.Node, env *object.Environment) {
+    switch stm := node.(type) {
+    case *ast.LetStatement:
+        this.statement(stm, env)
+    case *ast.ReturnStatement:
+        this.statement(stm, env)
+    case *ast.ExpressionStatement:
+        this.statement(stm, env)
+    }
+}
+
+// Before each let, return and expression statement
+func (this *Debugger) statement(stm ast.Statement, env *object.Environment) {
+    if this.aborted.Load() { panic(abortSignal {}) }
+
+    var frame = this.stack[len(this.stack) - 1]
+    var previousLine = frame.Line()
+    var isFirst = frame.Statement == nil
+    frame.Statement = stm
+    frame.Env = env
+
+    // The breakpoints stop once per line of a frame, not on each statement written in it
+    var line = stm.Pos().Line
+    if line != previousLine {
+        if bp, errCondition, hit := this.hitBreakpoint(line, env); hit {
+            this.stop(Stop { Reason: StopBreakpoint, Breakpoint: bp, Error: errCondition })
+            return
+        }
+    }
+
+    if this.pausing.Load() {
+        this.stop(Stop { Reason: StopPause })
+        return
+    }
+
+    var depth = len(this.stack)
+    switch this.mode {
+    case runStepIn:
+        var reason = StopStep
+        if isFirst && depth == 1 { reason = StopEntry }
+        this.stop(Stop { Reason: reason })
+    case runStepOver:
+        if depth <= this.depth { this.stop(Stop { Reason: StopStep }) }
+    case runStepOut:
+        if depth < this.depth { this.stop(Stop { Reason: StopStep }) }
+    }
+}
+
+// Like object.Inspect but the functions only show their parameters instead of the whole body
+func Inspect(value object.Object) string {
+    if fn, isFn := value.(*object.Function); isFn {
+        var params = []string {}
+        for _, param := range fn.Parameters {
+            params = append(params, param.Value)
+        }
+        return "fn (" + strings.Join(params, ", ") + ")"
+    }
+    return value.Inspect()
+}
+
+// @Impl
+func (this *Debugger) Call(fn *object.Function, site ast.Node, env *object.Environment) {
+    this.stack = append(this.stack, &Frame { Name: evaluator.FunctionName(fn, site), Function: fn, Env: env })
+}
+
+// @Impl
+func (this *Debugger) Return(fn *object.Function, result object.Object) {
+    this.stack = this.stack[:len(this.stack) - 1]
+
+    // Stepping out of the function stops on the call, that is still running in the caller
+    if (this.mode == runStepOut || this.mode == runStepOver) && len(this.stack) < this.depth {
+        this.stop(Stop { Reason: StopReturn, Result: result })
+    }
+}
@@ -0,0 +1,148 @@
+// monkey/debugger/debugger_test.go
+
+package debugger
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "strings"
+    "testing"
+)
+
+const factSource = `let fact = fn (n) {
+    if (n < 2) { return 1 };
+    n * fact(n - 1)
+};
+let x = fact(4);
+puts(x);`
+
+// Runs the source stopping on entry. Each stop is recorded as "reason:line:frame" and resumed by
+// the next action of the list, continuing when there are no more
+func runWithActions(t *testing.T, source string, setup func (d *Debugger), actions ...func (d *Debugger)) ([]string, object.Object) {
+    t.Helper()
+    var p = parser.NewParser(lexer.NewLexer(source))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { t.Fatalf("Unexpected parser errors: %v", p.Errors()) }
+
+    var interpreter = evaluator.NewInterpreter()
+    interpreter.Out = io.Discard
+    var debugger = NewDebugger(interpreter)
+    if setup != nil { setup(debugger) }
+
+    var stops = []string {}
+    debugger.OnStop = func (stop Stop) {
+        var frame = debugger.Stack()[0]
+        stops = append(stops, fmt.Sprintf("%s:%d:%s", stop.Reason, frame.Line(), frame.Name))
+        if len(actions) == 0 {
+            debugger.Continue()
+            return
+        }
+        actions[0](debugger)
+        actions = actions[1:]
+    }
+
+    var result, err = debugger.Run(program, object.NewEnvironment(), true)
+    if err != nil { t.Fatalf("Unexpected error: %s", err) }
+    return stops, result
+}
+
+func checkStops(t *testing.T, got []string, expected ...string) {
+    t.Helper()
+    if strings.Join(got, " ") != strings.Join(expected, " ") {
+        t.Errorf("Expected the stops\n%v\nbut got\n%v", expected, got)
+    }
+}
+
+func TestStepping(t *testing.T) {
+    var stepIn = (*Debugger).StepIn
+    var stepOver = (*Debugger).StepOver
+    var stepOut = (*Debugger).StepOut
+
+    var stops, _ = runWithActions(t, factSource, nil, stepOver, stepOver, stepOver)
+    checkStops(t, stops, "entry:1:main", "step:5:main", "step:6:main")
+
+    stops, _ = runWithActions(t, factSource, nil, stepOver, stepIn, stepIn, stepIn, stepIn)
+    checkStops(t, stops, "entry:1:main", "step:5:main", "step:2:fact", "step:3:fact", "step:2:fact", "step:3:fact")
+
+    stops, _ = runWithActions(t, factSource, nil, stepOver, stepIn, stepIn, stepIn, stepOut, stepOut)
+    checkStops(t, stops, "entry:1:main", "step:5:main", "step:2:fact", "step:3:fact", "step:2:fact", "return:3:fact", "return:5:main")
+}
+
+func TestBreakpoints(t *testing.T) {
+    var setup = func (d *Debugger) {
+        if _, err := d.SetBreakpoint(2, ""); err != nil { t.Fatal(err) }
+    }
+    var stops, result = runWithActions(t, factSource, setup, (*Debugger).Continue)
+    checkStops(t, stops, "entry:1:main", "breakpoint:2:fact", "breakpoint:2:fact", "breakpoint:2:fact", "breakpoint:2:fact")
+    if result == nil || result.Type() == object.ErrorType {
+        t.Errorf("Expected the program to end without errors but got %v", result)
+    }
+
+    var conditional = func (d *Debugger) {
+        if _, err := d.SetBreakpoint(2, "n == 2"); err != nil { t.Fatal(err) }
+    }
+    var values = []string {}
+    var inspect = func (d *Debugger) {
+        for frame := range d.Stack() {
+            var value, _ = d.Evaluate("n", frame)
+            values = append(values, value.Inspect())
+        }
+        d.Continue()
+    }
+    stops, _ = runWithActions(t, factSource, conditional, (*Debugger).Continue, inspect)
+    checkStops(t, stops, "entry:1:main", "breakpoint:2:fact")
+    if got := strings.Join(values, " "); got != "2 3 4 ERROR: identifier not found: n" {
+        t.Errorf("Expected n on each frame to be 2 3 4 and missing on main but got %q", got)
+    }
+
+    if _, err := NewDebugger(evaluator.NewInterpreter()).SetBreakpoint(1, "n =="); err == nil {
+        t.Errorf("Expected an invalid condition to be rejected")
+    }
+}
+
+func TestBreakpointOncePerLine(t *testing.T) {
+    var source = "let a = 1; let b = 2;\nlet c = a + b;"
+    var setup = func (d *Debugger) { d.SetBreakpoint(1, "") }
+    var stops, _ = runWithActions(t, source, setup, (*Debugger).Continue)
+    checkStops(t, stops, "breakpoint:1:main")
+}
+
+func TestTerminal(t *testing.T) {
+    var p = parser.NewParser(lexer.NewLexer(factSource))
+    var program = p.ParseProgram()
+
+    var commands = []string { "break 2 if n == 1", "continue", "stack", "print n + 100", "frame 3", "env", "quit" }
+    var readLine = func (prompt string) (string, error) {
+        if len(commands) == 0 { return "", io.EOF }
+        var line = commands[0]
+        commands = commands[1:]
+        return line, nil
+    }
+
+    var out bytes.Buffer
+    var interpreter = evaluator.NewInterpreter()
+    interpreter.Out = io.Discard
+    var debugger = NewDebugger(interpreter)
+    NewTerminal(debugger, "fact.mk", factSource, readLine, &out)
+
+    var _, err = debugger.Run(program, object.NewEnvironment(), true)
+    if err != ErrAborted { t.Errorf("Expected quit to abort the program but got %v", err) }
+
+    for _, expected := range []string {
+        "Stopped before the program at fact.mk:1 in main",
+        "Breakpoint 1 at fact.mk:2\n",
+        "Breakpoint 1 at fact.mk:2 in fact",
+        "> #0 fact at fact.mk:2\n  #1 fact at fact.mk:3\n  #2 fact at fact.mk:3\n  #3 fact at fact.mk:3\n  #4 main at fact.mk:5\n",
+        "101\n",
+        "Scope 0:\n    n = 4\nGlobal:\n    fact = fn (n)\n",
+    } {
+        if !strings.Contains(out.String(), expected) {
+            t.Errorf("Expected the output to contain %q but got\n%s", expected, out.String())
+        }
+    }
+}
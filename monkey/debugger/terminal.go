@@ -0,0 +1,293 @@
+// monkey/debugger/terminal.go
+/*
+    Terminal front end of the debugger. Each time the program stops it prints where and reads
+    commands until one of them resumes the program
+*/
+
+package debugger
+
+import (
+    "fmt"
+    "io"
+    "monkey/object"
+    "strconv"
+    "strings"
+)
+
+type terminalCommand struct {
+    names []string
+    usage string
+    help  string
+    run   func (this *Terminal, arg string) bool // Returns true to resume the program
+}
+
+var terminalCommands []terminalCommand
+
+// Filled on init because help reads the table itself
+func init() {
+    terminalCommands = []terminalCommand {
+        { []string { "break", "b" },     "break <line> [if <expr>]", "Sets a breakpoint, with a condition it stops when it is truthy", (*Terminal).breakCommand },
+        { []string { "clear" },          "clear [line]",             "Removes the breakpoint of the line, or all of them",             (*Terminal).clearCommand },
+        { []string { "breakpoints" },    "breakpoints",              "Lists the breakpoints",                                          (*Terminal).breakpointsCommand },
+        { []string { "continue", "c" },  "continue",                 "Runs until the next breakpoint",                                 (*Terminal).continueCommand },
+        { []string { "step", "s" },      "step",                     "Runs to the next statement, entering the calls",                 (*Terminal).stepCommand },
+        { []string { "next", "n" },      "next",                     "Runs to the next statement, over the calls",                     (*Terminal).nextCommand },
+        { []string { "out", "o" },       "out",                      "Runs until the current function returns",                        (*Terminal).outCommand },
+        { []string { "stack", "bt" },    "stack",                    "Prints the call stack",                                          (*Terminal).stackCommand },
+        { []string { "frame", "f" },     "frame <n>",                "Selects the frame the other commands inspect",                   (*Terminal).frameCommand },
+        { []string { "env", "e" },       "env",                      "Prints the bindings of the frame and of its outer environments", (*Terminal).envCommand },
+        { []string { "print", "p" },     "print <expr>",             "Evaluates the expression in the frame",                          (*Terminal).printCommand },
+        { []string { "list", "l" },      "list",                     "Prints the source around the line of the frame",                 (*Terminal).listCommand },
+        { []string { "help", "h" },      "help",                     "Shows this help",                                                (*Terminal).helpCommand },
+        { []string { "quit", "q" },      "quit",                     "Ends the program",                                               (*Terminal).quitCommand },
+    }
+}
+
+type Terminal struct {
+    Debugger *Debugger
+    ReadLine func(prompt string) (string, error)
+    Out      io.Writer
+
+    name     string   // Of the script, for the locations
+    lines    []string // Of the source
+    frame    int      // Selected, 0 is the innermost
+}
+
+func NewTerminal(debugger *Debugger, name string, source string, readLine func(prompt string) (string, error), out io.Writer) *Terminal {
+    var terminal = &Terminal {
+        Debugger: debugger,
+        ReadLine: readLine,
+        Out:      out,
+        name:     name,
+        lines:    strings.Split(source, "\n"),
+    }
+    debugger.OnStop = terminal.stopped
+    return terminal
+}
+
+func (this *Terminal) sourceLine(line int) string {
+    if line < 1 || line > len(this.lines) { return "" }
+    return strings.TrimRight(this.lines[line - 1], "\r")
+}
+
+func (this *Terminal) selected() *Frame {
+    return this.Debugger.Stack()[this.frame]
+}
+
+func (this *Terminal) printLocation(frame *Frame) {
+    fmt.Fprintf(this.Out, "%s:%d in %s\n", this.name, frame.Line(), frame.Name)
+    fmt.Fprintf(this.Out, "%5d | %s\n", frame.Line(), this.sourceLine(frame.Line()))
+}
+
+func (this *Terminal) stopped(stop Stop) {
+    this.frame = 0
+    switch stop.Reason {
+    case StopBreakpoint:
+        fmt.Fprintf(this.Out, "Breakpoint %d at ", stop.Breakpoint.ID)
+        if stop.Error != "" {
+            fmt.Fprintf(this.Out, "(its condition failed: %s) ", stop.Error)
+        }
+    case StopEntry:
+        fmt.Fprint(this.Out, "Stopped before the program at ")
+    case StopReturn:
+        fmt.Fprintf(this.Out, "Returned %s to ", Inspect(stop.Result))
+    default:
+        fmt.Fprint(this.Out, "Stopped at ")
+    }
+    this.printLocation(this.selected())
+
+    for {
+        var line, err = this.ReadLine("(debug) ")
+        if err != nil { // The end of the input also ends the program
+            this.Debugger.Abort()
+            return
+        }
+
+        line = strings.TrimSpace(line)
+        if line == "" { continue }
+        if resume := this.runCommand(line); resume { return }
+    }
+}
+
+func findTerminalCommand(name string) (terminalCommand, bool) {
+    for _, command := range terminalCommands {
+        for _, alias := range command.names {
+            if alias == name { return command, true }
+        }
+    }
+    return terminalCommand {}, false
+}
+
+func (this *Terminal) runCommand(line string) bool {
+    var name, arg, _ = strings.Cut(line, " ")
+    arg = strings.TrimSpace(arg)
+
+    var command, found = findTerminalCommand(name)
+    if !found {
+        fmt.Fprintf(this.Out, "Unknown command %s. Use help to see the commands\n", name)
+        return false
+    }
+    return command.run(this, arg)
+}
+
+func (this *Terminal) breakCommand(arg string) bool {
+    var lineText, condition, _ = strings.Cut(arg, " ")
+    var line, err = strconv.Atoi(lineText)
+    if err != nil {
+        fmt.Fprintln(this.Out, "Usage: break <line> [if <expr>]")
+        return false
+    }
+
+    condition = strings.TrimSpace(condition)
+    if condition != "" {
+        var expression, hasIf = strings.CutPrefix(condition, "if ")
+        if !hasIf {
+            fmt.Fprintln(this.Out, "Usage: break <line> [if <expr>]")
+            return false
+        }
+        condition = expression
+    }
+
+    var bp, errSet = this.Debugger.SetBreakpoint(line, condition)
+    if errSet != nil {
+        fmt.Fprintf(this.Out, "Could not set the breakpoint: %s\n", errSet)
+        return false
+    }
+    fmt.Fprintf(this.Out, "Breakpoint %d at %s:%d\n", bp.ID, this.name, bp.Line)
+    return false
+}
+
+func (this *Terminal) clearCommand(arg string) bool {
+    if arg == "" {
+        this.Debugger.ClearBreakpoints()
+        fmt.Fprintln(this.Out, "Removed all the breakpoints")
+        return false
+    }
+
+    var line, err = strconv.Atoi(arg)
+    if err != nil {
+        fmt.Fprintln(this.Out, "Usage: clear [line]")
+        return false
+    }
+    if !this.Debugger.ClearBreakpoint(line) {
+        fmt.Fprintf(this.Out, "No breakpoint at line %d\n", line)
+        return false
+    }
+    fmt.Fprintf(this.Out, "Removed the breakpoint at line %d\n", line)
+    return false
+}
+
+func (this *Terminal) breakpointsCommand(arg string) bool {
+    var breakpoints = this.Debugger.Breakpoints()
+    if len(breakpoints) == 0 {
+        fmt.Fprintln(this.Out, "No breakpoints")
+        return false
+    }
+    for _, bp := range breakpoints {
+        fmt.Fprintf(this.Out, "%d: %s:%d", bp.ID, this.name, bp.Line)
+        if bp.Condition != "" { fmt.Fprintf(this.Out, " if %s", bp.Condition) }
+        fmt.Fprintf(this.Out, " (hit %d times)\n", bp.Hits)
+    }
+    return false
+}
+
+func (this *Terminal) continueCommand(arg string) bool {
+    this.Debugger.Continue()
+    return true
+}
+
+func (this *Terminal) stepCommand(arg string) bool {
+    this.Debugger.StepIn()
+    return true
+}
+
+func (this *Terminal) nextCommand(arg string) bool {
+    this.Debugger.StepOver()
+    return true
+}
+
+func (this *Terminal) outCommand(arg string) bool {
+    this.Debugger.StepOut()
+    return true
+}
+
+func (this *Terminal) stackCommand(arg string) bool {
+    for i, frame := range this.Debugger.Stack() {
+        var marker = " "
+        if i == this.frame { marker = ">" }
+        fmt.Fprintf(this.Out, "%s #%d %s at %s:%d\n", marker, i, frame.Name, this.name, frame.Line())
+    }
+    return false
+}
+
+func (this *Terminal) frameCommand(arg string) bool {
+    var index, err = strconv.Atoi(arg)
+    if err != nil || index < 0 || index >= len(this.Debugger.Stack()) {
+        fmt.Fprintf(this.Out, "Usage: frame <n>, with n from 0 to %d\n", len(this.Debugger.Stack()) - 1)
+        return false
+    }
+    this.frame = index
+    this.printLocation(this.selected())
+    return false
+}
+
+func (this *Terminal) envCommand(arg string) bool {
+    var level = 0
+    for env := this.selected().Env; env != nil; env = env.Outer() {
+        var title = "Scope " + strconv.Itoa(level)
+        if env.Outer() == nil { title = "Global" }
+        fmt.Fprintf(this.Out, "%s:\n", title)
+
+        var names = env.Names()
+        if len(names) == 0 { fmt.Fprintln(this.Out, "    (empty)") }
+        for _, name := range names {
+            var value, _ = env.Get(name)
+            fmt.Fprintf(this.Out, "    %s = %s\n", name, Inspect(value))
+        }
+        level++
+    }
+    return false
+}
+
+func (this *Terminal) printCommand(arg string) bool {
+    if arg == "" {
+        fmt.Fprintln(this.Out, "Usage: print <expr>")
+        return false
+    }
+
+    var result, err = this.Debugger.Evaluate(arg, this.frame)
+    if err != nil {
+        fmt.Fprintf(this.Out, "Invalid expression: %s\n", err)
+        return false
+    }
+    if errObj, isErr := result.(*object.Error); isErr {
+        fmt.Fprintf(this.Out, "ERROR: %s\n", errObj.Message)
+        return false
+    }
+    fmt.Fprintln(this.Out, Inspect(result))
+    return false
+}
+
+func (this *Terminal) listCommand(arg string) bool {
+    var current = this.selected().Line()
+    for line := max(current - 5, 1); line <= min(current + 5, len(this.lines)); line++ {
+        var marker = " "
+        if line == current { marker = ">" }
+        if this.Debugger.HasBreakpoint(line) && line != current { marker = "*" }
+        fmt.Fprintf(this.Out, "%s%4d | %s\n", marker, line, this.sourceLine(line))
+    }
+    return false
+}
+
+func (this *Terminal) helpCommand(arg string) bool {
+    for _, command := range terminalCommands {
+        var names = strings.Join(command.names, ", ")
+        fmt.Fprintf(this.Out, "  %-26s %-14s %s\n", command.usage, "(" + names + ")", command.help)
+    }
+    return false
+}
+
+func (this *Terminal) quitCommand(arg string) bool {
+    this.Debugger.Abort()
+    return true
+}
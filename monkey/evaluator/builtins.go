@@ -5,6 +5,8 @@ package evaluator
 import (
     "monkey/object"
     "fmt"
+    "slices"
+    "sort"
     "strings"
 )
 
@@ -14,6 +16,19 @@ func getNumArgsError(expected int, got int) *object.Error {
     }
 }
 
+func getNumArgsRangeError(min int, max int, got int) *object.Error {
+    return &object.Error {
+        Message: fmt.Sprintf("wrong number of arguments. expected=%d to %d but got=%d", min, max, got),
+    }
+}
+
+func getArgumentTypeError(funcName string, expected string, obj object.Object) *object.Error {
+    var gotType = GetMsgTypeFor(obj.Type())
+    return &object.Error {
+        Message: fmt.Sprintf("argument to %s must be %s, got %s", funcName, expected, gotType),
+    }
+}
+
 func getTypeNotSupportedError(funcName string, obj object.Object) *object.Error {
     var unsupportedType = GetMsgTypeFor(obj.Type())
     return &object.Error {
@@ -21,8 +36,8 @@ func getTypeNotSupportedError(funcName string, obj object.Object) *object.Error
     }
 }
 
-// Returns the length of an array or string
-var Len = func (args ...object.Object) object.Object {
+// Returns the length of an array, string or the number of pairs of a hash
+var Len = func (rt object.Runtime, args ...object.Object) object.Object {
     if len(args) != 1 {
         return getNumArgsError(1, len(args))
     }
@@ -34,13 +49,15 @@ var Len = func (args ...object.Object) object.Object {
         return &object.Integer { Value: int64(len(obj.Value)) }
     case *object.Array:
         return &object.Integer { Value: int64(len(obj.Elements)) }
+    case *object.Hash:
+        return &object.Integer { Value: int64(len(obj.Pairs)) }
     default:
         return getTypeNotSupportedError("len", obj)
     }
 }
 
 // Returns the first element of an array or string
-var First = func (args ...object.Object) object.Object {
+var First = func (rt object.Runtime, args ...object.Object) object.Object {
     if len(args) != 1 {
         return getNumArgsError(1, len(args))
     }
@@ -63,7 +80,7 @@ var First = func (args ...object.Object) object.Object {
 }
 
 // Returns the last element of an array or a string
-var Last = func (args ...object.Object) object.Object {
+var Last = func (rt object.Runtime, args ...object.Object) object.Object {
     if len(args) != 1 {
         return getNumArgsError(1, len(args))
     }
@@ -86,7 +103,7 @@ var Last = func (args ...object.Object) object.Object {
 }
 
 // Returns a copy from the source array without the first element
-var Rest = func (args ...object.Object) object.Object {
+var Rest = func (rt object.Runtime, args ...object.Object) object.Object {
     if len(args) != 1 {
         return getNumArgsError(1, len(args))
     }
@@ -113,7 +130,7 @@ var Rest = func (args ...object.Object) object.Object {
     }
 }
 
-var Push = func (args ...object.Object) object.Object {
+var Push = func (rt object.Runtime, args ...object.Object) object.Object {
     if len(args) != 2 {
         return getNumArgsError(2, len(args))
     }
@@ -127,31 +144,79 @@ var Push = func (args ...object.Object) object.Object {
     return arr
 }
 
-var Puts = func (args ...object.Object) object.Object {
-    if len(args) != 1 {
-        return getNumArgsError(2, len(args))
+var builtins = map[string] object.BuiltinFunction {
+    "len":     Len,
+    "first":   First,
+    "last":    Last,
+    "rest":    Rest,
+    "push":    Push,
+    "map":     Map,
+    "filter":  Filter,
+    "reduce":  Reduce,
+    "each":    Each,
+    "sort":    Sort,
+    "reverse": Reverse,
+    "zip":     Zip,
+    "range":   Range,
+    "flatten": Flatten,
+    "any":     Any,
+    "all":     All,
+    "find":    Find,
+    "keys":    Keys,
+    "values":  Values,
+    "entries": Entries,
+    "has":     Has,
+    "delete":  Delete,
+    "merge":   Merge,
+
+    "json_parse":     JsonParse,
+    "json_stringify": JsonStringify,
+
+    "assert":       Assert,
+    "assert_eq":    AssertEq,
+    "assert_error": AssertError,
+
+    "quote":   Quote,
+    "unquote": Unquote,
+}
+
+// The builtins that can be called as methods of each type, receiver.name(args) is the same as
+// name(receiver, args). They are only sugar over the builtins, the table is what the REPL
+// completes after a '.' and what the evaluator checks the method calls against
+var methods = map[object.ObjectType] []string {
+    object.ArrayType: {
+        "all", "any", "each", "filter", "find", "first", "flatten", "last", "len", "map", "push",
+        "reduce", "rest", "reverse", "sort", "zip",
+    },
+    object.StringType: { "first", "last", "len", "rest", "reverse" },
+    object.HashType:   { "delete", "entries", "has", "keys", "len", "merge", "values" },
+}
+
+// Returns the names of all the builtins sorted, including the ones bound to an Interpreter
+func BuiltinNames() []string {
+    var names = make([]string, 0, len(builtins) + len(ioBuiltins))
+    for name := range builtins {
+        names = append(names, name)
     }
+    for name := range ioBuiltins {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
 
-    fmt.Println(args[0].Inspect())
+// Returns the sorted names of the methods of the type
+func MethodNames(objType object.ObjectType) []string {
+    return methods[objType]
+}
 
-    return ObjNull
+func hasMethod(objType object.ObjectType, name string) bool {
+    return slices.Contains(methods[objType], name)
 }
 
 func GetBuiltin(name string) (*object.Builtin, bool) {
-    switch name {
-    case "len":
-        return &object.Builtin { Function: Len }, true
-    case "first":
-        return &object.Builtin { Function: First }, true
-    case "last":
-        return &object.Builtin { Function: Last }, true
-    case "rest":
-        return &object.Builtin { Function: Rest }, true
-    case "push":
-        return &object.Builtin { Function: Push }, true
-    case "puts":
-        return &object.Builtin { Function: Puts }, true
-    }
-
-    return nil, false
+    var fn, ok = builtins[name]
+    if !ok { return nil, false }
+
+    return &object.Builtin { Function: fn }, true
 }
@@ -0,0 +1,339 @@
+// monkey/evaluator/builtins_array.go
+/*
+    Higher-order and collection builtins. They run natively over the elements instead of
+    recursing with rest (that copies the array on every call) and use the object.Runtime
+    to call back into the user functions
+*/
+
+package evaluator
+
+import (
+    "monkey/object"
+    "sort"
+)
+
+// Checks the common (array, function) arguments of the higher-order builtins
+func getArrayAndCallable(funcName string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+    if len(args) != 2 {
+        return nil, nil, getNumArgsError(2, len(args))
+    }
+    var arr, okArr = args[0].(*object.Array)
+    if !okArr {
+        return nil, nil, getArgumentTypeError(funcName, "Array", args[0])
+    }
+    if !isCallable(args[1]) {
+        return nil, nil, getArgumentTypeError(funcName, "Function", args[1])
+    }
+    return arr, args[1], nil
+}
+
+func newArray(elements []object.Object) *object.Array {
+    return &object.Array { Elements: elements }
+}
+
+// Returns a new array with the results of calling fn on each element
+var Map = func (rt object.Runtime, args ...object.Object) object.Object {
+    var arr, fn, err = getArrayAndCallable("map", args)
+    if err != nil { return err }
+
+    var result = make([]object.Object, 0, len(arr.Elements))
+    for _, elem := range arr.Elements {
+        var value = rt.Apply(fn, elem)
+        if isError(value) { return value }
+        result = append(result, value)
+    }
+    return newArray(result)
+}
+
+// Returns a new array with only the elements where fn returned a truthy value
+var Filter = func (rt object.Runtime, args ...object.Object) object.Object {
+    var arr, fn, err = getArrayAndCallable("filter", args)
+    if err != nil { return err }
+
+    var result = []object.Object {}
+    for _, elem := range arr.Elements {
+        var check = rt.Apply(fn, elem)
+        if isError(check) { return check }
+        if isTruthyObject(check) {
+            result = append(result, elem)
+        }
+    }
+    return newArray(result)
+}
+
+// Folds the array with fn(acc, elem). Without the initial value the first element is used as it
+var Reduce = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) != 2 && len(args) != 3 {
+        return getNumArgsRangeError(2, 3, len(args))
+    }
+    var arr, fn, err = getArrayAndCallable("reduce", args[:2])
+    if err != nil { return err }
+
+    var elements = arr.Elements
+    var acc object.Object
+    if len(args) == 3 {
+        acc = args[2]
+    } else {
+        if len(elements) == 0 { return ObjNull }
+        acc = elements[0]
+        elements = elements[1:]
+    }
+
+    for _, elem := range elements {
+        acc = rt.Apply(fn, acc, elem)
+        if isError(acc) { return acc }
+    }
+    return acc
+}
+
+// Calls fn on each element for its side effects
+var Each = func (rt object.Runtime, args ...object.Object) object.Object {
+    var arr, fn, err = getArrayAndCallable("each", args)
+    if err != nil { return err }
+
+    for _, elem := range arr.Elements {
+        var result = rt.Apply(fn, elem)
+        if isError(result) { return result }
+    }
+    return ObjNull
+}
+
+// Orders integers, strings and chars. Returns an error for any other combination
+func compareObjects(left object.Object, right object.Object) (int, *object.Error) {
+    if left.Type() != right.Type() {
+        return 0, getMismatchError(left, "<", right)
+    }
+    switch x := left.(type) {
+    case *object.Integer:
+        var y = right.(*object.Integer)
+        if x.Value < y.Value { return -1, nil }
+        if x.Value > y.Value { return 1, nil }
+        return 0, nil
+    case *object.String:
+        var y = right.(*object.String)
+        if x.Value < y.Value { return -1, nil }
+        if x.Value > y.Value { return 1, nil }
+        return 0, nil
+    case *object.Char:
+        var y = right.(*object.Char)
+        return int(x.Value) - int(y.Value), nil
+    default:
+        return 0, getUnknownOperatorError(left, "<", right)
+    }
+}
+
+// Returns a sorted copy of the array. The optional comparator fn(a, b) returns a Boolean telling
+// if a comes before b or an Integer lower than zero for the same thing
+var Sort = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) != 1 && len(args) != 2 {
+        return getNumArgsRangeError(1, 2, len(args))
+    }
+    var arr, okArr = args[0].(*object.Array)
+    if !okArr {
+        return getArgumentTypeError("sort", "Array", args[0])
+    }
+    if len(args) == 2 && !isCallable(args[1]) {
+        return getArgumentTypeError("sort", "Function", args[1])
+    }
+
+    var sorted = make([]object.Object, len(arr.Elements))
+    copy(sorted, arr.Elements)
+
+    var sortErr object.Object // sort.SliceStable cannot stop, so keep the first error and skip the rest
+    sort.SliceStable(sorted, func (i int, j int) bool {
+        if sortErr != nil { return false }
+
+        if len(args) == 1 {
+            var cmp, err = compareObjects(sorted[i], sorted[j])
+            if err != nil { sortErr = err; return false }
+            return cmp < 0
+        }
+
+        var result = rt.Apply(args[1], sorted[i], sorted[j])
+        switch x := result.(type) {
+        case *object.Boolean:
+            return x.Value
+        case *object.Integer:
+            return x.Value < 0
+        case *object.Error:
+            sortErr = x
+            return false
+        default:
+            sortErr = getArgumentTypeError("sort comparator result", "Boolean or Integer", result)
+            return false
+        }
+    })
+    if sortErr != nil { return sortErr }
+
+    return newArray(sorted)
+}
+
+// Returns a reversed copy of an array or string
+var Reverse = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) != 1 {
+        return getNumArgsError(1, len(args))
+    }
+
+    switch obj := args[0].(type) {
+    case *object.Array:
+        var reversed = make([]object.Object, len(obj.Elements))
+        for i, elem := range obj.Elements {
+            reversed[len(obj.Elements) - 1 - i] = elem
+        }
+        return newArray(reversed)
+    case *object.String:
+        // By runes, reversing the bytes would break the multi-byte characters
+        var runes = []rune(obj.Value)
+        var reversed = make([]rune, len(runes))
+        for i, r := range runes {
+            reversed[len(runes) - 1 - i] = r
+        }
+        return &object.String { Value: string(reversed) }
+    default:
+        return getTypeNotSupportedError("reverse", obj)
+    }
+}
+
+// Groups the elements with the same index of each array. Stops at the shortest one
+var Zip = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) < 2 {
+        return getNumArgsError(2, len(args))
+    }
+
+    var arrays = []*object.Array {}
+    var size = -1
+    for _, arg := range args {
+        var arr, okArr = arg.(*object.Array)
+        if !okArr {
+            return getArgumentTypeError("zip", "Array", arg)
+        }
+        if size == -1 || len(arr.Elements) < size {
+            size = len(arr.Elements)
+        }
+        arrays = append(arrays, arr)
+    }
+
+    var result = make([]object.Object, 0, size)
+    for i := 0; i < size; i++ {
+        var group = make([]object.Object, 0, len(arrays))
+        for _, arr := range arrays {
+            group = append(group, arr.Elements[i])
+        }
+        result = append(result, newArray(group))
+    }
+    return newArray(result)
+}
+
+// range(end), range(start, end) or range(start, end, step). The end is not included
+var Range = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) < 1 || len(args) > 3 {
+        return getNumArgsRangeError(1, 3, len(args))
+    }
+
+    var values = []int64 {}
+    for _, arg := range args {
+        var integer, okInt = arg.(*object.Integer)
+        if !okInt {
+            return getArgumentTypeError("range", "Integer", arg)
+        }
+        values = append(values, integer.Value)
+    }
+
+    var start, end, step int64 = 0, 0, 1
+    switch len(values) {
+    case 1:
+        end = values[0]
+    case 2:
+        start, end = values[0], values[1]
+    case 3:
+        start, end, step = values[0], values[1], values[2]
+    }
+    if step == 0 {
+        return &object.Error { Message: "range step cannot be zero" }
+    }
+
+    var result = []object.Object {}
+    for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+        result = append(result, &object.Integer { Value: i })
+    }
+    return newArray(result)
+}
+
+func flattenElements(elements []object.Object, depth int64) []object.Object {
+    var result = []object.Object {}
+    for _, elem := range elements {
+        var inner, isArray = elem.(*object.Array)
+        if isArray && depth > 0 {
+            result = append(result, flattenElements(inner.Elements, depth - 1)...)
+        } else {
+            result = append(result, elem)
+        }
+    }
+    return result
+}
+
+// Unwraps the nested arrays one level deep, or as many levels as the optional depth
+var Flatten = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) != 1 && len(args) != 2 {
+        return getNumArgsRangeError(1, 2, len(args))
+    }
+    var arr, okArr = args[0].(*object.Array)
+    if !okArr {
+        return getArgumentTypeError("flatten", "Array", args[0])
+    }
+
+    var depth int64 = 1
+    if len(args) == 2 {
+        var integer, okInt = args[1].(*object.Integer)
+        if !okInt {
+            return getArgumentTypeError("flatten", "Integer", args[1])
+        }
+        depth = integer.Value
+    }
+
+    return newArray(flattenElements(arr.Elements, depth))
+}
+
+// Returns the index of the first element where fn is truthy or -1 when none is found
+func findIndex(rt object.Runtime, arr *object.Array, fn object.Object) (int, object.Object) {
+    for i, elem := range arr.Elements {
+        var check = rt.Apply(fn, elem)
+        if isError(check) { return -1, check }
+        if isTruthyObject(check) { return i, nil }
+    }
+    return -1, nil
+}
+
+// Returns true if fn is truthy for at least one element
+var Any = func (rt object.Runtime, args ...object.Object) object.Object {
+    var arr, fn, err = getArrayAndCallable("any", args)
+    if err != nil { return err }
+
+    var index, errFind = findIndex(rt, arr, fn)
+    if errFind != nil { return errFind }
+    return objFromBool(index != -1)
+}
+
+// Returns true if fn is truthy for every element
+var All = func (rt object.Runtime, args ...object.Object) object.Object {
+    var arr, fn, err = getArrayAndCallable("all", args)
+    if err != nil { return err }
+
+    for _, elem := range arr.Elements {
+        var check = rt.Apply(fn, elem)
+        if isError(check) { return check }
+        if !isTruthyObject(check) { return ObjFalse }
+    }
+    return ObjTrue
+}
+
+// Returns the first element where fn is truthy or null
+var Find = func (rt object.Runtime, args ...object.Object) object.Object {
+    var arr, fn, err = getArrayAndCallable("find", args)
+    if err != nil { return err }
+
+    var index, errFind = findIndex(rt, arr, fn)
+    if errFind != nil { return errFind }
+    if index == -1 { return ObjNull }
+    return arr.Elements[index]
+}
@@ -0,0 +1,109 @@
+// monkey/evaluator/builtins_array_test.go
+
+package evaluator
+
+import (
+    "monkey/object"
+    "monkey/test_utils"
+    "testing"
+)
+
+func TestBuiltinHigherOrder(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        // map
+        { `map([1, 2, 3], fn (x) { x * 2 })`,                       "[2, 4, 6]"    },
+        { `map([], fn (x) { x * 2 })`,                              "[]"           },
+        { `map(["a", "bc"], len)`,                                  "[1, 2]"       },
+        { `let add = 10; map([1, 2], fn (x) { x + add })`,          "[11, 12]"     },
+
+        // filter
+        { `filter([1, 2, 3, 4], fn (x) { x > 2 })`,                 "[3, 4]"       },
+        { `filter([1, 2, 3], fn (x) { false })`,                    "[]"           },
+
+        // reduce
+        { `reduce([1, 2, 3, 4], fn (acc, x) { acc + x })`,          "10"           },
+        { `reduce([1, 2, 3], fn (acc, x) { acc * x }, 10)`,         "60"           },
+        { `reduce([], fn (acc, x) { acc + x })`,                    "null"         },
+        { `reduce([], fn (acc, x) { acc + x }, 0)`,                 "0"            },
+
+        // each
+        { `let arr = []; each([1, 2], fn (x) { push(arr, x * 3) }); arr`, "[3, 6]" },
+
+        // sort
+        { `sort([3, 1, 2])`,                                        "[1, 2, 3]"    },
+        { `sort(["b", "c", "a"])`,                                  "[a, b, c]"    },
+        { `sort([3, 1, 2], fn (a, b) { a > b })`,                   "[3, 2, 1]"    },
+        { `sort([3, 1, 2], fn (a, b) { a - b })`,                   "[1, 2, 3]"    },
+        { `let arr = [2, 1]; sort(arr); arr`,                       "[2, 1]"       },
+
+        // reverse
+        { `reverse([1, 2, 3])`,                                     "[3, 2, 1]"    },
+        { `reverse("abc")`,                                         "cba"          },
+        { `reverse("añb€")`,                                        "€bña"         },
+
+        // zip
+        { `zip([1, 2, 3], ["a", "b"])`,                             "[[1, a], [2, b]]" },
+
+        // range
+        { `range(3)`,                                               "[0, 1, 2]"    },
+        { `range(2, 5)`,                                            "[2, 3, 4]"    },
+        { `range(10, 0, -3)`,                                       "[10, 7, 4, 1]" },
+        { `range(0)`,                                               "[]"           },
+
+        // flatten
+        { `flatten([1, [2, [3]], [], 4])`,                          "[1, 2, [3], 4]" },
+        { `flatten([1, [2, [3]]], 2)`,                              "[1, 2, 3]"    },
+
+        // any, all and find
+        { `any([1, 2, 3], fn (x) { x > 2 })`,                       "true"         },
+        { `any([], fn (x) { true })`,                               "false"        },
+        { `all([1, 2, 3], fn (x) { x > 0 })`,                       "true"         },
+        { `all([1, 2, 3], fn (x) { x > 1 })`,                       "false"        },
+        { `find([1, 2, 3], fn (x) { x > 1 })`,                      "2"            },
+        { `find([1, 2, 3], fn (x) { x > 5 })`,                      "null"         },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        if test_utils.CheckForEvalError(t, evaluated) { continue }
+
+        if evaluated.Inspect() != test.expected {
+            t.Errorf("Expected '%s' to evaluate to '%s' but got '%s' instead",
+                test.input, test.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestBuiltinHigherOrderErrors(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `map(1, fn (x) { x })`,                  "argument to map must be Array, got Integer"             },
+        { `filter([1], 2)`,                        "argument to filter must be Function, got Integer"       },
+        { `map([1], fn (x, y) { x })`,             "Expected function call to have 2 parameters but found 1 instead" },
+        { `map([1, true], fn (x) { x + 1 })`,      "type mismatch: Boolean + Integer"                        },
+        { `sort([true, false])`,                    "unknown operator: Boolean < Boolean"                     },
+        { `range(1, 2, 0)`,                        "range step cannot be zero"                               },
+        { `reduce([1])`,                           "wrong number of arguments. expected=2 to 3 but got=1"    },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        var errObj, ok = evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("Expected evaluated object to be of object.Error. Got %T instead", evaluated)
+            continue
+        }
+        if errObj.Message != test.expected {
+            t.Errorf("Expected error object message to be '%s' but got '%s' instead", test.expected, errObj.Message)
+        }
+    }
+}
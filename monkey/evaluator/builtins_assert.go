@@ -0,0 +1,230 @@
+// monkey/evaluator/builtins_assert.go
+/*
+    Builtins to write tests in monkey, used by 'monkey test'. A failed assertion returns an error,
+    so it ends the test function like any other error. The message of assert_eq shows where the
+    values differ: the elements and fields of the arrays and hashes, the lines of the texts
+*/
+
+package evaluator
+
+import (
+    "fmt"
+    "monkey/object"
+    "strconv"
+    "strings"
+)
+
+// The most elements and fields assert_eq lists, the rest are counted
+const maxDifferences = 10
+
+func getAssertionError(funcName string, args []object.Object, messageIndex int, details string) *object.Error {
+    var message = funcName + " failed"
+    if len(args) > messageIndex { message += ": " + args[messageIndex].Inspect() }
+    if details != "" { message += "\n" + details }
+    return &object.Error { Message: message }
+}
+
+// Same type and same value, the arrays and hashes element by element. The functions are only
+// equal to themselves
+func objectsEqual(a object.Object, b object.Object) bool {
+    if a.Type() != b.Type() { return false }
+
+    switch a := a.(type) {
+    case *object.Integer:
+        return a.Value == b.(*object.Integer).Value
+    case *object.Boolean:
+        return a.Value == b.(*object.Boolean).Value
+    case *object.String:
+        return a.Value == b.(*object.String).Value
+    case *object.Char:
+        return a.Value == b.(*object.Char).Value
+    case *object.Null:
+        return true
+    case *object.Error:
+        return a.Message == b.(*object.Error).Message
+    case *object.Array:
+        var other = b.(*object.Array)
+        if len(a.Elements) != len(other.Elements) { return false }
+        for i := range a.Elements {
+            if !objectsEqual(a.Elements[i], other.Elements[i]) { return false }
+        }
+        return true
+    case *object.Hash:
+        var other = b.(*object.Hash)
+        if len(a.Pairs) != len(other.Pairs) { return false }
+        for key, pair := range a.Pairs {
+            var otherPair, found = other.Pairs[key]
+            if !found || !objectsEqual(pair.Value, otherPair.Value) { return false }
+        }
+        return true
+    default:
+        return a == b
+    }
+}
+
+// The lines of both texts marked with '-' when they are only in the expected one, with '+' when
+// they are only in the actual one and with ' ' when they are in both. It keeps the longest
+// common subsequence of lines
+func diffLines(expected []string, actual []string) []string {
+    var common = make([][]int, len(expected) + 1)
+    for i := range common {
+        common[i] = make([]int, len(actual) + 1)
+    }
+    for i := len(expected) - 1; i >= 0; i-- {
+        for j := len(actual) - 1; j >= 0; j-- {
+            if expected[i] == actual[j] {
+                common[i][j] = common[i + 1][j + 1] + 1
+            } else {
+                common[i][j] = max(common[i + 1][j], common[i][j + 1])
+            }
+        }
+    }
+
+    var diff = []string {}
+    var i, j = 0, 0
+    for i < len(expected) || j < len(actual) {
+        switch {
+        case i < len(expected) && j < len(actual) && expected[i] == actual[j]:
+            diff = append(diff, "  " + expected[i])
+            i, j = i + 1, j + 1
+        case j == len(actual) || (i < len(expected) && common[i + 1][j] >= common[i][j + 1]):
+            diff = append(diff, "- " + expected[i])
+            i++
+        default:
+            diff = append(diff, "+ " + actual[j])
+            j++
+        }
+    }
+    return diff
+}
+
+func describeValue(value object.Object, withType bool) string {
+    if withType { return fmt.Sprintf("%s (%s)", value.Inspect(), GetMsgTypeFor(value.Type())) }
+    return value.Inspect()
+}
+
+// The elements and fields where the values differ, each one with its path from the values like
+// [1]["a"]. The arrays and hashes are compared part by part, the other values as a whole
+func differences(path string, expected object.Object, actual object.Object) []string {
+    if objectsEqual(expected, actual) { return nil }
+
+    switch expected := expected.(type) {
+    case *object.Array:
+        var actual, isArray = actual.(*object.Array)
+        if !isArray { break }
+        var lines = []string {}
+        for i := 0; i < max(len(expected.Elements), len(actual.Elements)); i++ {
+            var at = fmt.Sprintf("%s[%d]", path, i)
+            switch {
+            case i >= len(actual.Elements):
+                lines = append(lines, fmt.Sprintf("at %s: missing, expected %s", at, expected.Elements[i].Inspect()))
+            case i >= len(expected.Elements):
+                lines = append(lines, fmt.Sprintf("at %s: unexpected %s", at, actual.Elements[i].Inspect()))
+            default:
+                lines = append(lines, differences(at, expected.Elements[i], actual.Elements[i])...)
+            }
+        }
+        return lines
+    case *object.Hash:
+        var actual, isHash = actual.(*object.Hash)
+        if !isHash { break }
+        var keyPath = func (pair object.HashPair) string {
+            if key, isString := pair.OriginalKey.(*object.String); isString { return path + "[" + strconv.Quote(key.Value) + "]" }
+            return path + "[" + pair.OriginalKey.Inspect() + "]"
+        }
+        var lines = []string {}
+        for _, key := range expected.Order {
+            var pair = expected.Pairs[key]
+            if other, found := actual.Pairs[key]; found {
+                lines = append(lines, differences(keyPath(pair), pair.Value, other.Value)...)
+            } else {
+                lines = append(lines, fmt.Sprintf("at %s: missing, expected %s", keyPath(pair), pair.Value.Inspect()))
+            }
+        }
+        for _, key := range actual.Order {
+            if _, found := expected.Pairs[key]; !found {
+                var pair = actual.Pairs[key]
+                lines = append(lines, fmt.Sprintf("at %s: unexpected %s", keyPath(pair), pair.Value.Inspect()))
+            }
+        }
+        return lines
+    }
+
+    var withType = expected.Type() != actual.Type() || expected.Inspect() == actual.Inspect()
+    return []string {
+        fmt.Sprintf("at %s: expected %s, actual %s", path, describeValue(expected, withType), describeValue(actual, withType)),
+    }
+}
+
+// How the values differ: the elements and fields that differ for the arrays and hashes, a diff of
+// the lines when the Inspect of any of them has several
+func describeDifference(expected object.Object, actual object.Object) string {
+    var expectedText, actualText = expected.Inspect(), actual.Inspect()
+    if expectedText == actualText || expected.Type() != actual.Type() {
+        return fmt.Sprintf("    expected: %s (%s)\n    actual:   %s (%s)",
+            expectedText, GetMsgTypeFor(expected.Type()), actualText, GetMsgTypeFor(actual.Type()))
+    }
+    if expected.Type() == object.ArrayType || expected.Type() == object.HashType {
+        var lines = differences("", expected, actual)
+        if len(lines) > maxDifferences {
+            lines = append(lines[:maxDifferences], fmt.Sprintf("and %d more", len(lines) - maxDifferences))
+        }
+        return fmt.Sprintf("    expected: %s\n    actual:   %s\n    %s", expectedText, actualText, strings.Join(lines, "\n    "))
+    }
+    if !strings.Contains(expectedText, "\n") && !strings.Contains(actualText, "\n") {
+        return fmt.Sprintf("    expected: %s\n    actual:   %s", expectedText, actualText)
+    }
+
+    var diff = diffLines(strings.Split(expectedText, "\n"), strings.Split(actualText, "\n"))
+    return "    - expected, + actual\n    " + strings.Join(diff, "\n    ")
+}
+
+// assert(condition, message?) fails when the condition is not truthy
+var Assert = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) < 1 || len(args) > 2 {
+        return getNumArgsRangeError(1, 2, len(args))
+    }
+    if !isTruthyObject(args[0]) {
+        return getAssertionError("assert", args, 1, fmt.Sprintf("    condition: %s", args[0].Inspect()))
+    }
+    return ObjNull
+}
+
+// assert_eq(actual, expected, message?) fails when the values are not equal
+var AssertEq = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) < 2 || len(args) > 3 {
+        return getNumArgsRangeError(2, 3, len(args))
+    }
+    if !objectsEqual(args[1], args[0]) {
+        return getAssertionError("assert_eq", args, 2, describeDifference(args[1], args[0]))
+    }
+    return ObjNull
+}
+
+// assert_error(fn, expected?, message?) calls fn without arguments and fails when it does not
+// return an error, or when the message of the error does not contain the expected text. It
+// returns the message of the error
+var AssertError = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) < 1 || len(args) > 3 {
+        return getNumArgsRangeError(1, 3, len(args))
+    }
+    if !isCallable(args[0]) {
+        return getArgumentTypeError("assert_error", "a function", args[0])
+    }
+
+    var result = rt.Apply(args[0])
+    if _, exited := ExitCode(result); exited { return result }
+    var errObj, isErr = result.(*object.Error)
+    if !isErr {
+        return getAssertionError("assert_error", args, 2, fmt.Sprintf("    expected an error but got: %s", result.Inspect()))
+    }
+    if len(args) > 1 {
+        var expected, errArg = getStringArgument("assert_error", args[1])
+        if errArg != nil { return errArg }
+        if !strings.Contains(errObj.Message, expected) {
+            return getAssertionError("assert_error", args, 2,
+                fmt.Sprintf("    expected an error with: %s\n    actual error:        %s", expected, errObj.Message))
+        }
+    }
+    return &object.String { Value: errObj.Message }
+}
@@ -0,0 +1,71 @@
+// monkey/evaluator/builtins_assert_test.go
+
+package evaluator
+
+import (
+    "monkey/object"
+    "monkey/test_utils"
+    "testing"
+)
+
+func TestBuiltinAssertions(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `assert(1 < 2)`,                                  "null"                                               },
+        { `assert(1 > 2)`,                                  "assert failed\n    condition: false"                },
+        { `assert(0, "zero")`,                              "assert failed: zero\n    condition: 0"              },
+        { `assert_eq([1, { "a": "x" }], [1, { "a": "x" }])`, "null"                                              },
+        { `assert_eq({ "a": 1, "b": 2 }, { "b": 2, "a": 1 })`, "null"                                            },
+        { `assert_eq(1 + 1, 3)`,                            "assert_eq failed\n    expected: 3\n    actual:   2" },
+        { `assert_eq("1", 1, "types")`,                     "assert_eq failed: types\n    expected: 1 (Integer)\n    actual:   1 (String)" },
+        { `assert_eq([1, 2], [1, 3])`,                      "assert_eq failed\n    expected: [1, 3]\n    actual:   [1, 2]\n    at [1]: expected 3, actual 2" },
+        { `assert_eq([1, 2, 3], [1])`,                      "assert_eq failed\n    expected: [1]\n    actual:   [1, 2, 3]\n    at [1]: unexpected 2\n    at [2]: unexpected 3" },
+        { `assert_eq([[1, "2"]], [[1, 2, 3]])`,             "assert_eq failed\n    expected: [[1, 2, 3]]\n    actual:   [[1, 2]]\n    at [0][1]: expected 2 (Integer), actual 2 (String)\n    at [0][2]: missing, expected 3" },
+        { `assert_eq({ "a": [1], "c": 1 }, { "a": [2], "b": 1 })`, "assert_eq failed\n    expected: { a: [2], b: 1 }\n    actual:   { a: [1], c: 1 }\n    at [\"a\"][0]: expected 2, actual 1\n    at [\"b\"]: missing, expected 1\n    at [\"c\"]: unexpected 1" },
+        { "assert_eq(\"a\nb\nc\", \"a\nc\nd\")",            "assert_eq failed\n    - expected, + actual\n      a\n    + b\n      c\n    - d" },
+        { `let f = fn () { 1 }; assert_eq(f, f)`,           "null"                                               },
+        { `assert_error(fn () { 1 + "a" })`,                "type mismatch: Integer + String"                    },
+        { `assert_error(fn () { 1 + "a" }, "mismatch")`,    "type mismatch: Integer + String"                    },
+        { `assert_error(fn () { 1 }, "", "no error")`,      "assert_error failed: no error\n    expected an error but got: 1" },
+        { `assert_error(fn () { len(1) }, "mismatch")`,     "assert_error failed\n    expected an error with: mismatch\n    actual error:        argument to len not supported, got Integer" },
+        { `assert_error(1)`,                                "argument to assert_error must be a function, got Integer" },
+        { `assert_eq(1)`,                                   "wrong number of arguments. expected=2 to 3 but got=1" },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        var got = evaluated.Inspect()
+        if errObj, isErr := evaluated.(*object.Error); isErr {
+            got = errObj.Message
+        }
+        if got != test.expected {
+            t.Errorf("Expected '%s' to evaluate to\n%s\nbut got\n%s", test.input, test.expected, got)
+        }
+    }
+}
+
+func TestDiffLines(t *testing.T) {
+    var tests = []struct {
+        expected []string; actual []string; diff string
+    } {
+        { []string { "a", "b" }, []string { "a", "b" }, "  a|  b"           },
+        { []string { "a" },      []string { "b" },      "- a|+ b"           },
+        { []string {},           []string { "x" },      "+ x"               },
+        { []string { "a", "b", "c" }, []string { "b", "c", "d" }, "- a|  b|  c|+ d" },
+    }
+
+    for _, test := range tests {
+        var got = ""
+        for i, line := range diffLines(test.expected, test.actual) {
+            if i > 0 { got += "|" }
+            got += line
+        }
+        if got != test.diff {
+            t.Errorf("Expected the diff of %q and %q to be %q but got %q", test.expected, test.actual, test.diff, got)
+        }
+    }
+}
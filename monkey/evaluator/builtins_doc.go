@@ -0,0 +1,63 @@
+// monkey/evaluator/builtins_doc.go
+/*
+    Signatures and one line descriptions of the builtins, for the tools that show them to the
+    user (the language server hover) and the arity checks of the linter. The '?' marks the
+    optional arguments and the '...' the variadic ones
+*/
+
+package evaluator
+
+type BuiltinDoc struct {
+    Signature string
+    Summary   string
+}
+
+var builtinDocs = map[string] BuiltinDoc {
+    "len":     { "len(value: String | Array | Hash) -> Integer", "Number of characters, elements or pairs" },
+    "first":   { "first(value: Array | String)", "First element or character, null when empty" },
+    "last":    { "last(value: Array | String)", "Last element or character, null when empty" },
+    "rest":    { "rest(value: Array | String)", "Copy without the first element or character, empty when there are less than two" },
+    "push":    { "push(arr: Array, value) -> Array", "Appends the value to the array itself and returns it" },
+    "map":     { "map(arr: Array, fn(elem)) -> Array", "New array with the results of fn on each element" },
+    "filter":  { "filter(arr: Array, fn(elem)) -> Array", "New array with the elements where fn is truthy" },
+    "reduce":  { "reduce(arr: Array, fn(acc, elem), initial?)", "Folds the array, the first element is the initial value when there is none" },
+    "each":    { "each(arr: Array, fn(elem))", "Calls fn on each element for its side effects" },
+    "sort":    { "sort(arr: Array, fn(a, b)?) -> Array", "Sorted copy, the comparator returns a Boolean or an Integer lower than zero when a comes first" },
+    "reverse": { "reverse(value: Array | String)", "Reversed copy" },
+    "zip":     { "zip(a: Array, b: Array, ...) -> Array", "Groups the elements with the same index, stops at the shortest array" },
+    "range":   { "range(end) | range(start, end, step?) -> Array", "Integers from start up to end, not including it" },
+    "flatten": { "flatten(arr: Array, depth?) -> Array", "Unwraps the nested arrays, one level deep by default" },
+    "any":     { "any(arr: Array, fn(elem)) -> Boolean", "True when fn is truthy for at least one element" },
+    "all":     { "all(arr: Array, fn(elem)) -> Boolean", "True when fn is truthy for every element" },
+    "find":    { "find(arr: Array, fn(elem))", "First element where fn is truthy, null when there is none" },
+    "keys":    { "keys(hash: Hash) -> Array", "Keys in insertion order" },
+    "values":  { "values(hash: Hash) -> Array", "Values in insertion order" },
+    "entries": { "entries(hash: Hash) -> Array", "[key, value] pairs in insertion order" },
+    "has":     { "has(hash: Hash, key) -> Boolean", "True when the key is in the hash" },
+    "delete":  { "delete(hash: Hash, key) -> Hash", "Removes the key from the hash itself and returns it" },
+    "merge":   { "merge(a: Hash, others: Hash...) -> Hash", "New hash with the pairs of all of them, the later ones win on the same keys" },
+
+    "json_parse":     { "json_parse(text: String)", "Parses JSON into hashes, arrays, strings, integers and booleans" },
+    "json_stringify": { "json_stringify(value, indent?: Integer | String) -> String", "Writes the value as JSON, indented by the string or by that many spaces" },
+
+    "assert":       { "assert(condition, message?)", "Fails the test when the condition is not truthy" },
+    "assert_eq":    { "assert_eq(actual, expected, message?)", "Fails the test when the values are not equal, showing how they differ" },
+    "assert_error": { "assert_error(fn(), expected?: String, message?) -> String", "Calls fn and fails the test when it does not return an error containing expected. Returns the message of the error" },
+
+    "puts":       { "puts(values...)", "Writes each value on its own line" },
+    "print":      { "print(values...)", "Writes the values without line breaks" },
+    "read_line":  { "read_line() -> String", "Next line of stdin, null at the end. Needs stdin enabled" },
+    "read_file":  { "read_file(path: String) -> String", "Content of a file inside the file root" },
+    "write_file": { "write_file(path: String, content: String)", "Creates or replaces a file inside the file root, not in read-only mode" },
+    "list_dir":   { "list_dir(path?: String) -> Array", "Sorted names inside a directory of the file root" },
+    "env":        { "env(name: String)", "Value of the environment variable, null when it is not set" },
+    "exit":       { "exit(code?: Integer)", "Ends the program with the code, 0 by default" },
+
+    "quote":   { "quote(expression) -> Quote", "Tree of the expression without evaluating it, each unquote inside replaced by the tree of its value" },
+    "unquote": { "unquote(value)", "Inside a quote, the tree of the value in its place. Takes integers, booleans, strings, arrays, hashes and quotes" },
+}
+
+func GetBuiltinDoc(name string) (BuiltinDoc, bool) {
+    var doc, ok = builtinDocs[name]
+    return doc, ok
+}
@@ -0,0 +1,108 @@
+// monkey/evaluator/builtins_hash.go
+/*
+    Builtins to inspect and change hashes. All of them follow the insertion order of the pairs
+*/
+
+package evaluator
+
+import (
+    "monkey/object"
+)
+
+func getHashArgument(funcName string, args []object.Object, expectedArgs int) (*object.Hash, *object.Error) {
+    if len(args) != expectedArgs {
+        return nil, getNumArgsError(expectedArgs, len(args))
+    }
+    var hash, okHash = args[0].(*object.Hash)
+    if !okHash {
+        return nil, getArgumentTypeError(funcName, "Hash", args[0])
+    }
+    return hash, nil
+}
+
+func getHashKeyArgument(funcName string, obj object.Object) (object.HashKey, *object.Error) {
+    var hashable, okHashable = obj.(object.Hashable)
+    if !okHashable {
+        return object.HashKey {}, getArgumentTypeError(funcName, "Hashable", obj)
+    }
+    return hashable.HashKey(), nil
+}
+
+// Returns an array with the keys of the hash
+var Keys = func (rt object.Runtime, args ...object.Object) object.Object {
+    var hash, err = getHashArgument("keys", args, 1)
+    if err != nil { return err }
+
+    var keys = []object.Object {}
+    for _, pair := range hash.OrderedPairs() {
+        keys = append(keys, pair.OriginalKey)
+    }
+    return newArray(keys)
+}
+
+// Returns an array with the values of the hash
+var Values = func (rt object.Runtime, args ...object.Object) object.Object {
+    var hash, err = getHashArgument("values", args, 1)
+    if err != nil { return err }
+
+    var values = []object.Object {}
+    for _, pair := range hash.OrderedPairs() {
+        values = append(values, pair.Value)
+    }
+    return newArray(values)
+}
+
+// Returns an array of [key, value] arrays
+var Entries = func (rt object.Runtime, args ...object.Object) object.Object {
+    var hash, err = getHashArgument("entries", args, 1)
+    if err != nil { return err }
+
+    var entries = []object.Object {}
+    for _, pair := range hash.OrderedPairs() {
+        entries = append(entries, newArray([]object.Object { pair.OriginalKey, pair.Value }))
+    }
+    return newArray(entries)
+}
+
+// Returns true when the hash has the key
+var Has = func (rt object.Runtime, args ...object.Object) object.Object {
+    var hash, err = getHashArgument("has", args, 2)
+    if err != nil { return err }
+
+    var key, errKey = getHashKeyArgument("has", args[1])
+    if errKey != nil { return errKey }
+
+    var _, found = hash.Get(key)
+    return objFromBool(found)
+}
+
+// Removes the key from the hash, like push it changes the hash in place and returns it
+var Delete = func (rt object.Runtime, args ...object.Object) object.Object {
+    var hash, err = getHashArgument("delete", args, 2)
+    if err != nil { return err }
+
+    var key, errKey = getHashKeyArgument("delete", args[1])
+    if errKey != nil { return errKey }
+
+    hash.Delete(key)
+    return hash
+}
+
+// Returns a new hash with the pairs of all the hashes. The later ones win on repeated keys
+var Merge = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) < 1 {
+        return getNumArgsError(1, len(args))
+    }
+
+    var merged = object.NewHash()
+    for _, arg := range args {
+        var hash, okHash = arg.(*object.Hash)
+        if !okHash {
+            return getArgumentTypeError("merge", "Hash", arg)
+        }
+        for _, key := range hash.Order {
+            merged.Set(key, hash.Pairs[key])
+        }
+    }
+    return merged
+}
@@ -0,0 +1,70 @@
+// monkey/evaluator/builtins_hash_test.go
+
+package evaluator
+
+import (
+    "monkey/object"
+    "monkey/test_utils"
+    "testing"
+)
+
+func TestBuiltinHash(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        // Insertion order
+        { `{ "z": 1, "a": 2, 3: 3, true: 4 }`,                       "{ z: 1, a: 2, 3: 3, true: 4 }" },
+
+        { `keys({ "b": 1, "a": 2 })`,                                "[b, a]"                  },
+        { `values({ "b": 1, "a": 2 })`,                              "[1, 2]"                  },
+        { `entries({ "b": 1, "a": 2 })`,                             "[[b, 1], [a, 2]]"        },
+        { `keys({})`,                                                "[]"                      },
+        { `has({ "a": 1 }, "a")`,                                    "true"                    },
+        { `has({ "a": 1 }, "b")`,                                    "false"                   },
+        { `let h = { "a": 1, "b": 2, "c": 3 }; delete(h, "b"); h`,   "{ a: 1, c: 3 }"          },
+        { `delete({ "a": 1 }, "x")`,                                 "{ a: 1 }"                },
+        { `merge({ "a": 1, "b": 2 }, { "b": 3, "c": 4 })`,           "{ a: 1, b: 3, c: 4 }"    },
+        { `let h = { "a": 1 }; merge(h, { "b": 2 }); h`,             "{ a: 1 }"                },
+        { `len({ "a": 1, "b": 2 })`,                                 "2"                       },
+        { `len({})`,                                                 "0"                       },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        if test_utils.CheckForEvalError(t, evaluated) { continue }
+
+        if evaluated.Inspect() != test.expected {
+            t.Errorf("Expected '%s' to evaluate to '%s' but got '%s' instead",
+                test.input, test.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestBuiltinHashErrors(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `keys([1])`,                   "argument to keys must be Hash, got Array"       },
+        { `has({}, [1])`,                "argument to has must be Hashable, got Array"    },
+        { `merge({}, 1)`,                "argument to merge must be Hash, got Integer"    },
+        { `delete({})`,                  "wrong number of arguments. expected=2 but got=1" },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        var errObj, ok = evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("Expected evaluated object to be of object.Error. Got %T instead", evaluated)
+            continue
+        }
+        if errObj.Message != test.expected {
+            t.Errorf("Expected error object message to be '%s' but got '%s' instead", test.expected, errObj.Message)
+        }
+    }
+}
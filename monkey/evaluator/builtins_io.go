@@ -0,0 +1,229 @@
+// monkey/evaluator/builtins_io.go
+/*
+    Builtins that talk with the world outside the interpreter. They are bound to the Interpreter
+    that runs them and each one checks its Capabilities before doing anything
+*/
+
+package evaluator
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "monkey/object"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+var ioBuiltins = map[string] func (this *Interpreter, args ...object.Object) object.Object {
+    "puts":       (*Interpreter).puts,
+    "print":      (*Interpreter).print,
+    "read_line":  (*Interpreter).readLine,
+    "read_file":  (*Interpreter).readFile,
+    "write_file": (*Interpreter).writeFile,
+    "list_dir":   (*Interpreter).listDir,
+    "env":        (*Interpreter).env,
+    "exit":       (*Interpreter).exit,
+}
+
+func getNotAllowedError(funcName string, reason string) *object.Error {
+    return &object.Error {
+        Message: fmt.Sprintf("%s not allowed: %s", funcName, reason),
+    }
+}
+
+func getIOError(funcName string, err error) *object.Error {
+    return &object.Error {
+        Message: fmt.Sprintf("%s: %s", funcName, err),
+    }
+}
+
+func getStringArgument(funcName string, obj object.Object) (string, *object.Error) {
+    var str, okStr = obj.(*object.String)
+    if !okStr {
+        return "", getArgumentTypeError(funcName, "String", obj)
+    }
+    return str.Value, nil
+}
+
+// Opens the FileRoot as an os.Root, that refuses any path (even through symlinks) that escapes
+// from it. The paths of the scripts are always relative to the root
+func (this *Interpreter) openFileRoot(funcName string) (*os.Root, *object.Error) {
+    if this.Capabilities.FileRoot == "" {
+        return nil, getNotAllowedError(funcName, "no file root configured")
+    }
+    var root, err = os.OpenRoot(this.Capabilities.FileRoot)
+    if err != nil {
+        return nil, getIOError(funcName, err)
+    }
+    return root, nil
+}
+
+func cleanScriptPath(path string) string {
+    return strings.TrimPrefix(filepath.Clean("/" + path), "/")
+}
+
+// Writes the inspect of each argument on its own line
+func (this *Interpreter) puts(args ...object.Object) object.Object {
+    for _, arg := range args {
+        fmt.Fprintln(this.Out, arg.Inspect())
+    }
+    return ObjNull
+}
+
+// Writes the inspect of the arguments without adding new lines
+func (this *Interpreter) print(args ...object.Object) object.Object {
+    for _, arg := range args {
+        fmt.Fprint(this.Out, arg.Inspect())
+    }
+    return ObjNull
+}
+
+// Returns the next line of the input without the line break or null when the input ended
+func (this *Interpreter) readLine(args ...object.Object) object.Object {
+    if len(args) != 0 {
+        return getNumArgsError(0, len(args))
+    }
+    if !this.Capabilities.Stdin {
+        return getNotAllowedError("read_line", "stdin is disabled")
+    }
+
+    var line, err = this.In.ReadString('\n')
+    if err != nil {
+        if !errors.Is(err, io.EOF) { return getIOError("read_line", err) }
+        if line == "" { return ObjNull } // The last line may not end with a line break
+    }
+
+    line = strings.TrimSuffix(line, "\n")
+    line = strings.TrimSuffix(line, "\r")
+    return &object.String { Value: line }
+}
+
+// read_file(path) returns the content of the file as a string
+func (this *Interpreter) readFile(args ...object.Object) object.Object {
+    if len(args) != 1 {
+        return getNumArgsError(1, len(args))
+    }
+    var path, errPath = getStringArgument("read_file", args[0])
+    if errPath != nil { return errPath }
+
+    var root, errRoot = this.openFileRoot("read_file")
+    if errRoot != nil { return errRoot }
+    defer root.Close()
+
+    var file, err = root.Open(cleanScriptPath(path))
+    if err != nil { return getIOError("read_file", err) }
+    defer file.Close()
+
+    var content, errRead = io.ReadAll(file)
+    if errRead != nil { return getIOError("read_file", errRead) }
+
+    return &object.String { Value: string(content) }
+}
+
+// write_file(path, content) creates or replaces the file with the content
+func (this *Interpreter) writeFile(args ...object.Object) object.Object {
+    if len(args) != 2 {
+        return getNumArgsError(2, len(args))
+    }
+    var path, errPath = getStringArgument("write_file", args[0])
+    if errPath != nil { return errPath }
+    var content, errContent = getStringArgument("write_file", args[1])
+    if errContent != nil { return errContent }
+
+    if this.Capabilities.ReadOnly {
+        return getNotAllowedError("write_file", "read-only mode")
+    }
+    var root, errRoot = this.openFileRoot("write_file")
+    if errRoot != nil { return errRoot }
+    defer root.Close()
+
+    var file, err = root.Create(cleanScriptPath(path))
+    if err != nil { return getIOError("write_file", err) }
+    defer file.Close()
+
+    if _, err = file.WriteString(content); err != nil {
+        return getIOError("write_file", err)
+    }
+    return ObjNull
+}
+
+// list_dir(path?) returns the sorted names inside the directory, the file root when there is no path
+func (this *Interpreter) listDir(args ...object.Object) object.Object {
+    if len(args) > 1 {
+        return getNumArgsRangeError(0, 1, len(args))
+    }
+    var path = ""
+    if len(args) == 1 {
+        var value, errPath = getStringArgument("list_dir", args[0])
+        if errPath != nil { return errPath }
+        path = value
+    }
+
+    var root, errRoot = this.openFileRoot("list_dir")
+    if errRoot != nil { return errRoot }
+    defer root.Close()
+
+    var dirPath = cleanScriptPath(path)
+    if dirPath == "" { dirPath = "." }
+
+    var dir, err = root.Open(dirPath)
+    if err != nil { return getIOError("list_dir", err) }
+    defer dir.Close()
+
+    var entries, errRead = dir.ReadDir(-1)
+    if errRead != nil { return getIOError("list_dir", errRead) }
+
+    var names = []object.Object {}
+    for _, entry := range entries { // ReadDir already sorts them by name
+        names = append(names, &object.String { Value: entry.Name() })
+    }
+    return newArray(names)
+}
+
+// env(name) returns the value of the environment variable or null when it is not set
+func (this *Interpreter) env(args ...object.Object) object.Object {
+    if len(args) != 1 {
+        return getNumArgsError(1, len(args))
+    }
+    var name, errName = getStringArgument("env", args[0])
+    if errName != nil { return errName }
+
+    if !this.Capabilities.Env {
+        return getNotAllowedError("env", "environment variables are disabled")
+    }
+
+    var value, found = os.LookupEnv(name)
+    if !found { return ObjNull }
+    return &object.String { Value: value }
+}
+
+// Tells if the result comes from a call to exit and the code the program has to end with
+func ExitCode(obj object.Object) (int, bool) {
+    var errObj, isErr = obj.(*object.Error)
+    if !isErr || !errObj.Exit { return 0, false }
+    return errObj.Code, true
+}
+
+// exit(code?) ends the program with the code, 0 when there is none. It returns an error that
+// unwinds the evaluation, so the host can still write its reports before ending the process
+func (this *Interpreter) exit(args ...object.Object) object.Object {
+    if len(args) > 1 {
+        return getNumArgsRangeError(0, 1, len(args))
+    }
+    var code int64 = 0
+    if len(args) == 1 {
+        var integer, okInt = args[0].(*object.Integer)
+        if !okInt {
+            return getArgumentTypeError("exit", "Integer", args[0])
+        }
+        code = integer.Value
+    }
+
+    if !this.Capabilities.Exit {
+        return getNotAllowedError("exit", "exit is disabled")
+    }
+
+    return &object.Error { Message: fmt.Sprintf("exit with code %d", code), Exit: true, Code: int(code) }
+}
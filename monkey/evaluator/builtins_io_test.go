@@ -0,0 +1,138 @@
+// monkey/evaluator/builtins_io_test.go
+
+package evaluator
+
+import (
+    "bytes"
+    "monkey/object"
+    "monkey/test_utils"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func evalWithInterpreter(t *testing.T, interpreter *Interpreter, input string) object.Object {
+    var program, parser = getParsedProgram(input)
+    if test_utils.CheckForParserErrors(t, parser) { return nil }
+    return interpreter.Eval(program, object.NewEnvironment())
+}
+
+func TestBuiltinPutsAndPrintWriteToOut(t *testing.T) {
+    var out bytes.Buffer
+    var interpreter = NewInterpreter()
+    interpreter.Out = &out
+
+    var evaluated = evalWithInterpreter(t, interpreter, `puts("Hello", 1); print("a", [2]); print("b")`)
+    if test_utils.CheckForEvalError(t, evaluated) { return }
+
+    var expected = "Hello\n1\na[2]b"
+    if out.String() != expected {
+        t.Errorf("Expected output to be %q but got %q instead", expected, out.String())
+    }
+}
+
+func TestBuiltinReadLine(t *testing.T) {
+    var interpreter = NewInterpreter()
+    interpreter.SetInput(strings.NewReader("first\r\nsecond"))
+    interpreter.Capabilities.Stdin = true
+
+    var evaluated = evalWithInterpreter(t, interpreter, `[read_line(), read_line(), read_line()]`)
+    if test_utils.CheckForEvalError(t, evaluated) { return }
+
+    var expected = "[first, second, null]"
+    if evaluated.Inspect() != expected {
+        t.Errorf("Expected '%s' but got '%s' instead", expected, evaluated.Inspect())
+    }
+}
+
+func TestBuiltinFiles(t *testing.T) {
+    var root = t.TempDir()
+    os.WriteFile(filepath.Join(root, "data.txt"), []byte("file content"), 0644)
+    os.Mkdir(filepath.Join(root, "sub"), 0755)
+
+    var interpreter = NewInterpreter()
+    interpreter.Capabilities.FileRoot = root
+
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `read_file("data.txt")`,                          "file content"             },
+        { `write_file("sub/new.txt", "abc"); read_file("sub/new.txt")`, "abc"          },
+        { `list_dir()`,                                     "[data.txt, sub]"          },
+        { `list_dir("sub")`,                                "[new.txt]"                },
+        // Paths cannot leave the root
+        { `read_file("../../data.txt")`,                    "file content"             },
+        { `read_file("/data.txt")`,                         "file content"             },
+    }
+
+    for _, test := range tests {
+        var evaluated = evalWithInterpreter(t, interpreter, test.input)
+        if evaluated.Inspect() != test.expected {
+            t.Errorf("Expected '%s' to evaluate to '%s' but got '%s' instead", test.input, test.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestBuiltinCapabilitiesDisabled(t *testing.T) {
+    var root = t.TempDir()
+    var tests = []struct {
+        input string; capabilities Capabilities; expected string
+    } {
+        { `read_file("x")`,       Capabilities {},                                "read_file not allowed: no file root configured"  },
+        { `list_dir()`,           Capabilities {},                                "list_dir not allowed: no file root configured"   },
+        { `write_file("x", "y")`, Capabilities { FileRoot: root, ReadOnly: true }, "write_file not allowed: read-only mode"          },
+        { `read_line()`,          Capabilities {},                                "read_line not allowed: stdin is disabled"        },
+        { `env("HOME")`,          Capabilities {},                                "env not allowed: environment variables are disabled" },
+        { `exit(1)`,              Capabilities {},                                "exit not allowed: exit is disabled"              },
+    }
+
+    for _, test := range tests {
+        var interpreter = NewInterpreter()
+        interpreter.Capabilities = test.capabilities
+
+        var evaluated = evalWithInterpreter(t, interpreter, test.input)
+        var errObj, ok = evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("Expected evaluated object to be of object.Error. Got %T instead", evaluated)
+            continue
+        }
+        if errObj.Message != test.expected {
+            t.Errorf("Expected error object message to be '%s' but got '%s' instead", test.expected, errObj.Message)
+        }
+    }
+}
+
+func TestBuiltinEnvAndExit(t *testing.T) {
+    t.Setenv("MONKEY_TEST_VAR", "banana")
+
+    var interpreter = NewInterpreter()
+    interpreter.Capabilities = Capabilities { Env: true, Exit: true }
+
+    var evaluated = evalWithInterpreter(t, interpreter, `[env("MONKEY_TEST_VAR"), env("MONKEY_NOT_SET_VAR")]`)
+    if evaluated.Inspect() != "[banana, null]" {
+        t.Errorf("Expected '%s' but got '%s' instead", "[banana, null]", evaluated.Inspect())
+    }
+
+    // The exit unwinds the whole evaluation, even from a function or assert_error
+    var tests = []struct {
+        input string; expected int
+    } {
+        { `exit(3); puts("not reached")`,                    3 },
+        { `exit()`,                                          0 },
+        { `let f = fn () { exit(2); 1 }; [f(), 5]`,          2 },
+        { `assert_error(fn () { exit(4) }); 1`,              4 },
+    }
+
+    for _, test := range tests {
+        var evaluated = evalWithInterpreter(t, interpreter, test.input)
+        var code, exited = ExitCode(evaluated)
+        if !exited {
+            t.Errorf("Expected '%s' to exit but got %s", test.input, evaluated.Inspect())
+            continue
+        }
+        if code != test.expected {
+            t.Errorf("Expected exit code to be %d but got %d instead", test.expected, code)
+        }
+    }
+}
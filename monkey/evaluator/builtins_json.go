@@ -0,0 +1,184 @@
+// monkey/evaluator/builtins_json.go
+/*
+    Bridge between the monkey objects and JSON text. Objects become hashes (keeping the key order
+    of the text), arrays become arrays and null becomes ObjNull. The monkey language only has
+    integers, so numbers with a fraction or exponent are reported as errors
+*/
+
+package evaluator
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "monkey/object"
+    "strings"
+)
+
+func getJsonError(funcName string, format string, args ...any) *object.Error {
+    return &object.Error { Message: funcName + ": " + fmt.Sprintf(format, args...) }
+}
+
+// Reads the next value of the decoder. Done token by token because decoding into a go map
+// would lose the order of the keys
+func decodeJsonValue(decoder *json.Decoder) (object.Object, error) {
+    var tk, err = decoder.Token()
+    if err != nil { return nil, err }
+
+    switch value := tk.(type) {
+    case nil:
+        return ObjNull, nil
+    case bool:
+        return objFromBool(value), nil
+    case string:
+        return &object.String { Value: value }, nil
+    case json.Number:
+        var integer, errInt = value.Int64()
+        if errInt != nil {
+            return nil, fmt.Errorf("number %s is not supported, only integers are", value.String())
+        }
+        return &object.Integer { Value: integer }, nil
+    case json.Delim:
+        switch value {
+        case '[':
+            var elements = []object.Object {}
+            for decoder.More() {
+                var elem, errElem = decodeJsonValue(decoder)
+                if errElem != nil { return nil, errElem }
+                elements = append(elements, elem)
+            }
+            if _, err = decoder.Token(); err != nil { return nil, err } // Consumes the ]
+            return newArray(elements), nil
+        case '{':
+            var hash = object.NewHash()
+            for decoder.More() {
+                var keyTk, errKey = decoder.Token()
+                if errKey != nil { return nil, errKey }
+                var key = &object.String { Value: keyTk.(string) }
+
+                var pairValue, errValue = decodeJsonValue(decoder)
+                if errValue != nil { return nil, errValue }
+
+                hash.Set(key.HashKey(), object.HashPair { OriginalKey: key, Value: pairValue })
+            }
+            if _, err = decoder.Token(); err != nil { return nil, err } // Consumes the }
+            return hash, nil
+        }
+    }
+
+    return nil, fmt.Errorf("unexpected token %v", tk)
+}
+
+// json_parse(str) returns the monkey object described by the JSON text
+var JsonParse = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) != 1 {
+        return getNumArgsError(1, len(args))
+    }
+    var str, okStr = args[0].(*object.String)
+    if !okStr {
+        return getArgumentTypeError("json_parse", "String", args[0])
+    }
+
+    var decoder = json.NewDecoder(strings.NewReader(str.Value))
+    decoder.UseNumber()
+
+    var obj, err = decodeJsonValue(decoder)
+    if err != nil {
+        return getJsonError("json_parse", "%s", err)
+    }
+
+    // Only one value is allowed in the text
+    if _, errExtra := decoder.Token(); errExtra != io.EOF {
+        return getJsonError("json_parse", "unexpected data after the JSON value")
+    }
+
+    return obj
+}
+
+func writeJsonString(out *bytes.Buffer, value string) {
+    var encoder = json.NewEncoder(out)
+    encoder.SetEscapeHTML(false)
+    encoder.Encode(value)
+    out.Truncate(out.Len() - 1) // Encode always ends with a new line
+}
+
+// Writes the compact JSON of the object. The visiting set holds the arrays and hashes that are
+// being written, finding one of them again means the value has a cycle
+func encodeJsonValue(out *bytes.Buffer, obj object.Object, visiting map[object.Object]bool) *object.Error {
+    switch x := obj.(type) {
+    case *object.Null:
+        out.WriteString("null")
+    case *object.Boolean, *object.Integer:
+        out.WriteString(x.Inspect())
+    case *object.String:
+        writeJsonString(out, x.Value)
+    case *object.Char:
+        writeJsonString(out, string(x.Value))
+    case *object.Array:
+        if visiting[x] { return getJsonError("json_stringify", "cycle detected in Array") }
+        visiting[x] = true
+        defer delete(visiting, x)
+
+        out.WriteString("[")
+        for i, elem := range x.Elements {
+            if i > 0 { out.WriteString(",") }
+            if err := encodeJsonValue(out, elem, visiting); err != nil { return err }
+        }
+        out.WriteString("]")
+    case *object.Hash:
+        if visiting[x] { return getJsonError("json_stringify", "cycle detected in Hash") }
+        visiting[x] = true
+        defer delete(visiting, x)
+
+        // JSON keys are always strings, so 1 and "1" would give the same key twice
+        var keys = map[string]bool {}
+        out.WriteString("{")
+        for i, pair := range x.OrderedPairs() {
+            if i > 0 { out.WriteString(",") }
+            var key = pair.OriginalKey.Inspect()
+            if str, isString := pair.OriginalKey.(*object.String); isString { key = str.Value }
+            if keys[key] { return getJsonError("json_stringify", "duplicate key %q in Hash", key) }
+            keys[key] = true
+            writeJsonString(out, key)
+            out.WriteString(":")
+            if err := encodeJsonValue(out, pair.Value, visiting); err != nil { return err }
+        }
+        out.WriteString("}")
+    default:
+        return getJsonError("json_stringify", "value of type %s cannot be serialized", GetMsgTypeFor(obj.Type()))
+    }
+    return nil
+}
+
+// json_stringify(value, indent?) returns the JSON text of the value. The indent is the number of
+// spaces or the string used for each level
+var JsonStringify = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) != 1 && len(args) != 2 {
+        return getNumArgsRangeError(1, 2, len(args))
+    }
+
+    var indent = ""
+    if len(args) == 2 {
+        switch x := args[1].(type) {
+        case *object.Integer:
+            indent = strings.Repeat(" ", int(max(x.Value, 0)))
+        case *object.String:
+            indent = x.Value
+        default:
+            return getArgumentTypeError("json_stringify", "Integer or String", args[1])
+        }
+    }
+
+    var compact bytes.Buffer
+    var err = encodeJsonValue(&compact, args[0], make(map[object.Object]bool))
+    if err != nil { return err }
+
+    if indent == "" {
+        return &object.String { Value: compact.String() }
+    }
+
+    var indented bytes.Buffer
+    json.Indent(&indented, compact.Bytes(), "", indent)
+    return &object.String { Value: indented.String() }
+}
@@ -0,0 +1,102 @@
+// monkey/evaluator/builtins_json_test.go
+
+package evaluator
+
+import (
+    "monkey/object"
+    "monkey/test_utils"
+    "testing"
+)
+
+func TestBuiltinJsonParse(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `1`,                                  "1"                               },
+        { `-42`,                                "-42"                             },
+        { `"hello"`,                            "hello"                           },
+        { `true`,                               "true"                            },
+        { `null`,                               "null"                            },
+        { `[1, "two", [false]]`,                "[1, two, [false]]"               },
+        { `{ "z": 1, "a": { "b": null } }`,     "{ z: 1, a: { b: null } }"        },
+        { `{}`,                                 "{}"                              },
+    }
+
+    for _, test := range tests {
+        var evaluated = JsonParse(NewInterpreter(), &object.String { Value: test.input })
+        if test_utils.CheckForEvalError(t, evaluated) { continue }
+
+        if evaluated.Inspect() != test.expected {
+            t.Errorf("Expected '%s' to parse to '%s' but got '%s' instead", test.input, test.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestBuiltinJsonParseErrors(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `1.5`,       "json_parse: number 1.5 is not supported, only integers are" },
+        { `[1, 2`,     "json_parse: unexpected end of JSON input"                   },
+        { `1 2`,       "json_parse: unexpected data after the JSON value"           },
+    }
+
+    for _, test := range tests {
+        var evaluated = JsonParse(NewInterpreter(), &object.String { Value: test.input })
+        var errObj, ok = evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("Expected evaluated object to be of object.Error. Got %T instead", evaluated)
+            continue
+        }
+        if errObj.Message != test.expected {
+            t.Errorf("Expected error object message to be '%s' but got '%s' instead", test.expected, errObj.Message)
+        }
+    }
+}
+
+func TestBuiltinJsonStringify(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `json_stringify(1)`,                                  `1`                               },
+        { `json_stringify("a<b")`,                              `"a<b"`                           },
+        { `json_stringify(first("abc"))`,                       `"a"`                             },
+        { `json_stringify([1, true, puts])`,                    "json_stringify: value of type Builtin cannot be serialized" },
+        { `json_stringify({ "b": [1, 2], "a": {}, 3: false })`, `{"b":[1,2],"a":{},"3":false}`    },
+        { `json_stringify({ 1: "a", "1": "b" })`,               `json_stringify: duplicate key "1" in Hash` },
+        { `json_stringify({ "t": [{ true: 1, "true": 2 }] })`,  `json_stringify: duplicate key "true" in Hash` },
+        { `json_stringify({ "a": [1] }, 2)`,                    "{\n  \"a\": [\n    1\n  ]\n}"    },
+        { `json_stringify([1], "\t")`,                          "[\n\\t1\n]"                      },
+        { `json_stringify(fn (x) { x })`,                       "json_stringify: value of type Function cannot be serialized" },
+        { `let a = [1]; push(a, a); json_stringify(a)`,         "json_stringify: cycle detected in Array" },
+        { `let a = [1]; json_stringify([a, a])`,                `[[1],[1]]`                       },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        var got = evaluated.Inspect()
+        if errObj, isErr := evaluated.(*object.Error); isErr {
+            got = errObj.Message
+        }
+        if got != test.expected {
+            t.Errorf("Expected '%s' to evaluate to '%s' but got '%s' instead", test.input, test.expected, got)
+        }
+    }
+}
+
+func TestBuiltinJsonRoundTrip(t *testing.T) {
+    var input = `{"name":"monkey","tags":["a","b"],"nested":{"ok":true,"count":3,"nothing":null}}`
+
+    var parsed = JsonParse(NewInterpreter(), &object.String { Value: input })
+    if test_utils.CheckForEvalError(t, parsed) { return }
+
+    var text = JsonStringify(NewInterpreter(), parsed)
+    if test_utils.CheckForEvalError(t, text) { return }
+
+    if text.Inspect() != input {
+        t.Errorf("Expected round trip to be '%s' but got '%s' instead", input, text.Inspect())
+    }
+}
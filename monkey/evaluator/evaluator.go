@@ -39,6 +39,20 @@ func GetMsgTypeFor(objType object.ObjectType) string {
         return "Null"
     case object.StringType:
         return "String"
+    case object.CharType:
+        return "Char"
+    case object.ArrayType:
+        return "Array"
+    case object.HashType:
+        return "Hash"
+    case object.FuncType:
+        return "Function"
+    case object.BuiltinType:
+        return "Builtin"
+    case object.QuoteType:
+        return "Quote"
+    case object.MacroType:
+        return "Macro"
     default:
         return "Not Covered"
     }
@@ -77,11 +91,11 @@ func getIdentifierNotFoundError(name string) *object.Error {
     }
 }
 
-func evalStatements(statements []ast.Statement, env *object.Environment) object.Object {
+func (this *Interpreter) evalStatements(statements []ast.Statement, env *object.Environment) object.Object {
     var result object.Object = nil
 
     for _, stm := range statements {
-        result = Eval(stm, env)
+        result = this.Eval(stm, env)
 
         if isError(result) { return result }
 
@@ -91,34 +105,79 @@ func evalStatements(statements []ast.Statement, env *object.Environment) object.
     return result // Return the last evaluated statement if no early return types are found
 }
 
-func evalCallExpression(objFunc *object.Function, node *ast.CallExpression, env *object.Environment) object.Object {
-    if len(objFunc.Parameters) != len(node.Parameters) {
-        return &object.Error {
-            Message: fmt.Sprintf("Expected function call to have %d parameters but found %d instead",
-            len(objFunc.Parameters), len(node.Parameters)),
-        }
+func (this *Interpreter) evalArguments(params []ast.Expression, env *object.Environment) ([]object.Object, object.Object) {
+    var args = []object.Object {}
+    for _, param := range params {
+        var arg = this.Eval(param, env)
+        if isError(arg) { return nil, arg }
+        args = append(args, arg)
     }
+    return args, nil
+}
 
-    var funcEnv = object.NewEnclosedEnvironment(objFunc.Env)
+// Calls a user function or a builtin with already evaluated arguments. It is also the way the
+// builtins call back into the evaluator (e.g. map and filter)
+func (this *Interpreter) applyFunction(fn object.Object, args []object.Object) object.Object {
+    return this.callFunction(fn, args, nil)
+}
 
-    // Adds params with values to function env
-    for i := range objFunc.Parameters {
-        var paramName = objFunc.Parameters[i].Value
-        var paramValue = Eval(node.Parameters[i], env)
-        if isError(paramValue) { return paramValue }
-        funcEnv.Set(paramName, paramValue)
-    }
+// Same as applyFunction, the site is the call expression written in the script if there is one
+func (this *Interpreter) callFunction(fn object.Object, args []object.Object, site ast.Node) object.Object {
+    switch objFunc := fn.(type) {
+    case *object.Function:
+        if len(objFunc.Parameters) != len(args) {
+            return &object.Error {
+                Message: fmt.Sprintf("Expected function call to have %d parameters but found %d instead",
+                len(objFunc.Parameters), len(args)),
+            }
+        }
+
+        var funcEnv = object.NewEnclosedEnvironment(objFunc.Env)
+        if objFunc.Frame != nil { funcEnv = object.NewFrameEnvironment(objFunc.Env, objFunc.Frame) }
+
+        // Adds params with values to function env
+        for i, param := range objFunc.Parameters {
+            funcEnv.Set(param.Value, args[i])
+        }
+
+        if this.Observer == nil { return unwrapReturn(this.Eval(objFunc.Body, funcEnv)) }
+
+        this.Observer.Call(objFunc, site, funcEnv)
+        for i, param := range objFunc.Parameters {
+            this.Observer.Bind(param.Value, args[i], funcEnv)
+        }
+        var result = unwrapReturn(this.Eval(objFunc.Body, funcEnv))
+        this.Observer.Return(objFunc, result)
 
-    var result = Eval(objFunc.Body, funcEnv)
+        return result
 
-    return unwrapReturn(result)
+    case *object.Builtin:
+        return objFunc.Function(this, args...)
+
+    case *object.Macro:
+        return &object.Error { Message: "a macro can only be called before the evaluation, when the macros are expanded" }
+
+    default:
+        return &object.Error {
+            Message: fmt.Sprintf("Identifier is not connected to an covered function type. Found %T instead", fn),
+        }
+    }
 }
 
-func findIdentifier(name string, env *object.Environment) object.Object {
+// Reads the slot the resolver found for the identifier. The slot is empty when its let has not
+// run yet, then the name is looked up as always
+func (this *Interpreter) evalIdentifier(ident *ast.Identifier, env *object.Environment) object.Object {
+    if ident.Resolved {
+        if value, ok := env.GetSlot(ident.Depth, ident.Slot); ok { return value }
+    }
+    return this.findIdentifier(ident.Value, env)
+}
+
+func (this *Interpreter) findIdentifier(name string, env *object.Environment) object.Object {
     var value, ok = env.Get(name)
     if ok { return value }
 
-    var builtin, okBuiltin = GetBuiltin(name)
+    var builtin, okBuiltin = this.getBuiltin(name)
     if okBuiltin { return builtin }
 
     return getIdentifierNotFoundError(name)
@@ -141,8 +200,29 @@ func isTruthy(check any) bool {
     }
 }
 
-func getIndexFromExpression(expr *ast.IndexExpression, env *object.Environment) (object.Object, bool) {
-    var index = Eval(expr.Index, env)
+// Same rules of isTruthy but for the evaluated objects. Used by the builtins that take predicates
+func isTruthyObject(obj object.Object) bool {
+    switch x := obj.(type) {
+    case *object.Boolean:
+        return isTruthy(x.Value)
+    case *object.Integer:
+        return isTruthy(x.Value)
+    default:
+        return false
+    }
+}
+
+// If the condition of an if takes its consequence, for the tools that follow the evaluation
+func IsTruthy(obj object.Object) bool {
+    return isTruthyObject(obj)
+}
+
+func isCallable(obj object.Object) bool {
+    return isOfType(obj, object.FuncType) || isOfType(obj, object.BuiltinType)
+}
+
+func (this *Interpreter) getIndexFromExpression(expr *ast.IndexExpression, env *object.Environment) (object.Object, bool) {
+    var index = this.Eval(expr.Index, env)
     if isError(index) { return index, false }
 
     var indexInt, okIndexInt = index.(*object.Integer)
@@ -167,17 +247,26 @@ func isOutOfBounds[T any](arr []T, index int64) bool {
 }
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+    return NewInterpreter().Eval(node, env)
+}
+
+func (this *Interpreter) Eval(node ast.Node, env *object.Environment) object.Object {
+    if this.Observer != nil { return this.observe(node, env) }
+    return this.eval(node, env)
+}
+
+func (this *Interpreter) eval(node ast.Node, env *object.Environment) object.Object {
     switch node := node.(type) {
 
 // Statements
     case *ast.Program:
-        return evalStatements(node.Statements, env)
+        return this.evalStatements(node.Statements, env)
 
     case *ast.StatementsBlock:
-        return evalStatements(node.Statements, env)
+        return this.evalStatements(node.Statements, env)
 
     case *ast.ReturnStatement:
-        var value = Eval(node.Expression, env)
+        var value = this.Eval(node.Expression, env)
         if isError(value) { return value }
 
         switch x := value.(type) {
@@ -188,15 +277,16 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         }
 
     case *ast.LetStatement:
-        var expValue = Eval(node.Expression, env)
+        var expValue = this.Eval(node.Expression, env)
         if isError(expValue) { return expValue }
 
         env.Set(node.Identifier, expValue)
+        if this.Observer != nil { this.Observer.Bind(node.Identifier, expValue, env) }
 
         return ObjNull
 
     case *ast.ExpressionStatement:
-        return Eval(node.Expression, env)
+        return this.Eval(node.Expression, env)
 
 // Expressions
     case *ast.ArrayLiteral:
@@ -205,7 +295,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         }
 
         for _, expr := range node.Elements {
-            var element = Eval(expr, env)
+            var element = this.Eval(expr, env)
+            if isError(element) { return element }
             arr.Elements = append(arr.Elements, element)
         }
 
@@ -215,13 +306,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         switch nodeLeft := node.Left.(type) {
 
         case *ast.ArrayLiteral:
-            var index, okIndex = getIndexFromExpression(node, env)
+            var index, okIndex = this.getIndexFromExpression(node, env)
             if !okIndex { return index }
             var indexInt = index.(*object.Integer).Value
 
             if isOutOfBounds(nodeLeft.Elements, indexInt) { return ObjNull }
 
-            return Eval(nodeLeft.Elements[indexInt], env)
+            return this.Eval(nodeLeft.Elements[indexInt], env)
 
         case *ast.Identifier:
             var ident, found = env.Get(nodeLeft.Value)
@@ -239,7 +330,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
                 }
             }
 
-            var index, okIndex = getIndexFromExpression(node, env)
+            var index, okIndex = this.getIndexFromExpression(node, env)
             if !okIndex { return index }
             var indexInt = index.(*object.Integer).Value
 
@@ -248,7 +339,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
             return arr.Elements[indexInt]
 
         case *ast.HashLiteral:
-            var evaluatedIndex = Eval(node.Index, env)
+            var evaluatedIndex = this.Eval(node.Index, env)
             if isError(evaluatedIndex) { return evaluatedIndex }
 
             var hashable, okHashable = evaluatedIndex.(object.Hashable)
@@ -260,7 +351,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
             }
             var hashKey = hashable.HashKey()
 
-            var evaluatedHash = Eval(nodeLeft, env)
+            var evaluatedHash = this.Eval(nodeLeft, env)
             if isError(evaluatedHash) { return evaluatedHash }
 
             var objHash, okObjHash = evaluatedHash.(*object.Hash)
@@ -270,7 +361,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
                 }
             }
 
-            var value, ok = objHash.Pairs[hashKey]
+            var value, ok = objHash.Get(hashKey)
 
             if !ok { return ObjNull }
 
@@ -283,12 +374,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         }
 
     case *ast.HashLiteral:
-        var hash = &object.Hash {}
-        hash.Pairs = make(map[object.HashKey]object.HashPair)
+        var hash = object.NewHash()
+
+        for _, key := range node.Keys {
+            var value = node.Pairs[key]
 
-        for key, value := range node.Pairs {
             // Prepare pair key
-            var evaluatedKey = Eval(key, env)
+            var evaluatedKey = this.Eval(key, env)
             if isError(evaluatedKey) { return evaluatedKey }
             var hashableKey, okHashableKey = evaluatedKey.(object.Hashable)
             if !okHashableKey {
@@ -297,10 +389,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
             var hashKey = hashableKey.HashKey()
 
             // Prepare pair value
-            var evaluatedValue = Eval(value, env)
+            var evaluatedValue = this.Eval(value, env)
             if isError(evaluatedValue) { return evaluatedValue }
 
-            hash.Pairs[hashKey] = object.HashPair { OriginalKey: evaluatedKey, Value: evaluatedValue }
+            hash.Set(hashKey, object.HashPair { OriginalKey: evaluatedKey, Value: evaluatedValue })
         }
 
         return hash
@@ -308,7 +400,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
     case *ast.PrefixExpression:
         switch node.Operator {
         case "-":
-            var evaluated = Eval(node.Value, env)
+            var evaluated = this.Eval(node.Value, env)
             if isError(evaluated) { return evaluated }
 
             switch x := evaluated.(type) {
@@ -318,7 +410,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
                 return getUnknownOperatorError(nil, node.Operator, evaluated)
             }
         case "!":
-            var evaluated = Eval(node.Value, env)
+            var evaluated = this.Eval(node.Value, env)
             if isError(evaluated) { return evaluated }
 
             switch x := evaluated.(type) {
@@ -332,8 +424,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         }
 
     case *ast.InfixExpression:
-        var evaluatedLeft = Eval(node.Left, env)
-        var evaluatedRight =  Eval(node.Right, env)
+        var evaluatedLeft = this.Eval(node.Left, env)
+        var evaluatedRight =  this.Eval(node.Right, env)
 
         if isError(evaluatedLeft) { return evaluatedLeft }
         if isError(evaluatedRight) { return evaluatedRight }
@@ -382,24 +474,24 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 // TODO: Make IfExpression good and not this mess
 // TODO: Make if eval all needed statements (can use evalStatements)
     case *ast.IfExpression:
-        var conditionResult = Eval(node.Condition, env)
+        var conditionResult = this.Eval(node.Condition, env)
         if isError(conditionResult) { return conditionResult }
 
         switch x := conditionResult.(type) {
         case *object.Boolean:
             if isTruthy(x.Value) {
-                return Eval(node.ConsequenceBlock.Statements[0], env)
+                return this.Eval(node.ConsequenceBlock.Statements[0], env)
             } else if node.AlternativeBlock != nil {
-                return Eval(node.AlternativeBlock.Statements[0], env)
+                return this.Eval(node.AlternativeBlock.Statements[0], env)
             } else {
                 // TODO: error for missing alternative
                 return ObjNull
             }
         case *object.Integer:
             if isTruthy(x.Value) {
-                return Eval(node.ConsequenceBlock.Statements[0], env)
+                return this.Eval(node.ConsequenceBlock.Statements[0], env)
             } else if node.AlternativeBlock != nil {
-                return Eval(node.AlternativeBlock.Statements[0], env)
+                return this.Eval(node.AlternativeBlock.Statements[0], env)
             } else {
                 // TODO: error for missing alternative
                 return ObjNull
@@ -407,48 +499,54 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         }
 
     case *ast.FunctionLiteral:
-        return &object.Function { Parameters: node.Parameters, Body: node.Body, Env: env }
+        return &object.Function { Parameters: node.Parameters, Body: node.Body, Env: env, Frame: node.Frame }
+
+    case *ast.MatchExpression:
+        return this.evalMatch(node, env)
+
+    case *ast.MacroLiteral: // Only when the program was not expanded
+        return &object.Macro { Parameters: node.Parameters, Body: node.Body, Env: env }
 
     case *ast.CallExpression:
+        var fn object.Object
+
         switch exp := node.Expression.(type) {
         case *ast.Identifier: // Exp: foo(x, y, z)
-            var obj = findIdentifier(exp.Value, env)
-            if isError(obj) { return obj }
-
-            switch objFunc := obj.(type) {
-            case *object.Function:
-                return evalCallExpression(objFunc, node, env)
-            case *object.Builtin:
-                var args = []object.Object {}
-                for _, param := range node.Parameters {
-                    var arg = Eval(param, env)
-                    if isError(arg) { return arg }
-                    args = append(args, arg)
-                }
-                var fn = objFunc.Function
-                return fn(args...)
-            default:
-                return &object.Error {
-                    Message: fmt.Sprintf("Identifier is not connected to an covered function type. Found %T instead", obj),
-                }
+            if exp.Value == "quote" { return this.evalQuote(node, env) } // The argument is not evaluated
+            fn = this.evalIdentifier(exp, env)
+        case *ast.FunctionLiteral: // Exp: fn (x, y) { x + y; }(5, 6)
+            fn = this.Eval(exp, env)
+        default:
+            return &object.Error {
+                Message:fmt.Sprintf("Not covered CallExpression.Expression type: %T", node.Expression),
             }
+        }
+        if isError(fn) { return fn }
 
-        case *ast.FunctionLiteral: // Exp: fn (x, y) { x + y; }(5, 6)
-            var obj = Eval(exp, env)
-            if isError(obj) { return obj }
+        var args, errArgs = this.evalArguments(node.Parameters, env)
+        if errArgs != nil { return errArgs }
 
-            var objFunc = obj.(*object.Function)
+        return this.callFunction(fn, args, node)
 
-            return evalCallExpression(objFunc, node, env)
+    case *ast.MethodExpression: // Exp: arr.push(x) is the same as push(arr, x)
+        var receiver = this.Eval(node.Expression, env)
+        if isError(receiver) { return receiver }
 
-        default:
+        var name = node.Call.Expression.String()
+        if !hasMethod(receiver.Type(), name) {
             return &object.Error {
-                Message:fmt.Sprintf("Not covered CallExpression.Expression type: %T", node.Expression),
+                Message: fmt.Sprintf("%s has no method %s", GetMsgTypeFor(receiver.Type()), name),
             }
         }
+        var fn, _ = this.getBuiltin(name)
+
+        var args, errArgs = this.evalArguments(node.Call.Parameters, env)
+        if errArgs != nil { return errArgs }
+
+        return this.applyFunction(fn, append([]object.Object { receiver }, args...))
 
     case *ast.Identifier:
-        return findIdentifier(node.Value, env)
+        return this.evalIdentifier(node, env)
 
     case *ast.IntegerLiteral:
         return &object.Integer { Value: node.Value }
@@ -308,6 +308,8 @@ func TestLetStatements(t *testing.T) {
         { "let a = 5; let b = a; b;", 5 },
         { "let a = 5 * 5; a;", 25 },
         { "let a = 5; let b = 10; let c = a + b; c;", 15 },
+        { "let a: int = 5; let f = fn (x: int) -> int { x * 2 }; f(a);", 10 },
+        { "let a: string = 5; a;", 5 }, // The annotations are only for the checker
     }
 
     for _, test := range tests {
@@ -904,3 +906,45 @@ func TestBuiltinPuts(t *testing.T) {
 
     _ = program
 }
+
+func TestMethodCalls(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `[1, 2, 3].len()`,                                     "3"                                  },
+        { `"four".len()`,                                        "4"                                  },
+        { `let a = [3, 1, 2]; a.sort().reverse()`,               "[3, 2, 1]"                          },
+        { `[1, 2, 3].map(fn (x) { x * 2 }).len() + 1`,           "4"                                  },
+        { `{ "a": 1, "b": 2 }.keys()`,                           "[a, b]"                             },
+        { `let h = { "a": 1 }; h.has("a")`,                      "true"                               },
+        { `[1, 2].push(3)`,                                      "[1, 2, 3]"                          },
+        { `"abc".push(1)`,                                       "ERROR: String has no method push"   },
+        { `5.len()`,                                             "ERROR: Integer has no method len"   },
+        { `[1].map(1)`,                                          "ERROR: argument to map must be Function, got Integer" },
+        { `[1].foo()`,                                           "ERROR: Array has no method foo"     },
+        { `let double = fn (x) { x * 2 }; [1].double()`,         "ERROR: Array has no method double"  },
+        { `let len = fn (x) { 0 }; [1, 2].len()`,                "2"                                  },
+        { `{ "f": fn () { 1 } }.f()`,                            "ERROR: Hash has no method f"        },
+        { `missing.len()`,                                       "ERROR: identifier not found: missing" },
+        { `[1].len(undefined)`,                                  "ERROR: identifier not found: undefined" },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        if evaluated.Inspect() != test.expected {
+            t.Errorf("Expected '%s' to evaluate to '%s' but got '%s' instead",
+                test.input, test.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestEveryBuiltinHasDoc(t *testing.T) {
+    for _, name := range BuiltinNames() {
+        if _, ok := GetBuiltinDoc(name); !ok {
+            t.Errorf("Expected builtin %s to have a doc", name)
+        }
+    }
+}
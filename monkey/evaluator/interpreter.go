@@ -0,0 +1,60 @@
+// monkey/evaluator/interpreter.go
+
+package evaluator
+
+import (
+    "bufio"
+    "io"
+    "monkey/object"
+    "os"
+)
+
+// What the host allows the scripts of one interpreter to do besides writing to Out. Everything is
+// disabled on the zero value
+type Capabilities struct {
+    FileRoot string // Directory the file builtins work inside of. Empty disables the file builtins
+    ReadOnly bool   // Blocks write_file even when there is a FileRoot
+    Stdin    bool   // Allows read_line
+    Env      bool   // Allows env to read the environment variables
+    Exit     bool   // Allows exit to end the program
+}
+
+// Holds the state of one running evaluation. It is passed to the builtins as their object.Runtime
+// so they can invoke the evaluator again
+type Interpreter struct {
+    Out          io.Writer      // Where puts and print write
+    In           *bufio.Reader  // Where read_line reads from
+    Capabilities Capabilities
+    Observer     Observer       // Follows the evaluation when not nil
+
+    lastError    *object.Error  // The last one reported to the Observer
+    expanding    bool           // While a macro runs, its quotes rename the names they bind
+    generated    int            // The names generated by the expansions so far
+}
+
+func NewInterpreter() *Interpreter {
+    return &Interpreter {
+        Out: os.Stdout,
+        In:  bufio.NewReader(os.Stdin),
+    }
+}
+
+func (this *Interpreter) SetInput(in io.Reader) {
+    this.In = bufio.NewReader(in)
+}
+
+// @Impl
+func (this *Interpreter) Apply(fn object.Object, args ...object.Object) object.Object {
+    return this.applyFunction(fn, args)
+}
+
+// The builtins bound to this interpreter come first so they can use its Out, In and Capabilities
+func (this *Interpreter) getBuiltin(name string) (*object.Builtin, bool) {
+    var fn, ok = ioBuiltins[name]
+    if !ok { return GetBuiltin(name) }
+
+    var bound = func (rt object.Runtime, args ...object.Object) object.Object {
+        return fn(this, args...)
+    }
+    return &object.Builtin { Function: bound }, true
+}
@@ -0,0 +1,315 @@
+// monkey/evaluator/macro.go
+/*
+    Macros. quote(exp) gives the tree of exp without evaluating it, each unquote(value) inside it
+    replaced by the tree of the value. A macro is bound by a let of the top level:
+
+        let unless = macro (cond, then, otherwise) {
+            quote(if (!unquote(cond)) { unquote(then) } else { unquote(otherwise) })
+        };
+        unless(10 > 5, puts("no"), puts("yes"));
+
+    ExpandMacros runs between the parser and the evaluation. It removes those lets and puts in the
+    place of each call of a macro the quote its body returns, with its parameters bound to the
+    quoted arguments. The names the quotes of a macro bind, with a let, as parameters or in a
+    pattern, are renamed in the scope of their binding to names that cannot be written (tmp#1) so
+    they never capture the names of the arguments
+*/
+
+package evaluator
+
+import (
+    "fmt"
+    "maps"
+    "monkey/ast"
+    "monkey/object"
+    "monkey/utils"
+)
+
+// How many macros can be expanded one inside the result of the other, to stop the recursive ones
+const maxExpansionDepth = 100
+
+// quote and unquote are evaluated before their arguments, the builtins are only reached when they
+// are called through another name or out of place
+var Quote = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) != 1 { return getNumArgsError(1, len(args)) }
+    return &object.Error { Message: "quote can only be called by its name" }
+}
+
+var Unquote = func (rt object.Runtime, args ...object.Object) object.Object {
+    if len(args) != 1 { return getNumArgsError(1, len(args)) }
+    return &object.Error { Message: "unquote can only be called inside a quote" }
+}
+
+// The call when the node is a call to the function of the name, not the one of a method
+func callTo(cursorNode ast.Node, parent ast.Node, name string) (*ast.CallExpression, bool) {
+    if _, isMethod := parent.(*ast.MethodExpression); isMethod { return nil, false }
+    var call, isCall = cursorNode.(*ast.CallExpression)
+    if !isCall { return nil, false }
+    var ident, isIdent = call.Expression.(*ast.Identifier)
+    return call, isIdent && ident.Value == name
+}
+
+func isUnquote(node ast.Node) bool {
+    var _, found = callTo(node, nil, "unquote")
+    return found
+}
+
+// Evaluates quote(exp): a copy of the tree of exp with the unquotes replaced
+func (this *Interpreter) evalQuote(call *ast.CallExpression, env *object.Environment) object.Object {
+    if len(call.Parameters) != 1 { return getNumArgsError(1, len(call.Parameters)) }
+
+    var quoted = ast.Clone(call.Parameters[0])
+    if this.expanding { this.renameBindings(quoted) }
+
+    var failure object.Object
+    quoted = ast.Apply(quoted, func (cursor *ast.Cursor) bool {
+        if failure != nil { return false }
+        var unquote, found = callTo(cursor.Node(), cursor.Parent(), "unquote")
+        if !found { return true }
+
+        if len(unquote.Parameters) != 1 {
+            failure = getNumArgsError(1, len(unquote.Parameters))
+            return false
+        }
+        var value = this.Eval(unquote.Parameters[0], env)
+        if isError(value) {
+            failure = value
+            return false
+        }
+        var replacement, err = nodeFromObject(value, unquote)
+        if err != nil {
+            failure = err
+            return false
+        }
+        cursor.Replace(replacement)
+        return false
+    }, nil)
+    if failure != nil { return failure }
+
+    return &object.Quote { Node: quoted }
+}
+
+// The tree of an expression that evaluates to the value, with the span of the unquote for the
+// literals it makes
+func nodeFromObject(obj object.Object, site ast.Node) (ast.Expression, *object.Error) {
+    var result ast.Expression
+    switch obj := obj.(type) {
+    case *object.Quote:
+        var exp, isExp = ast.Clone(obj.Node).(ast.Expression)
+        if !isExp { return nil, &object.Error { Message: fmt.Sprintf("cannot unquote the statement %s", obj.Node) } }
+        return exp, nil
+    case *object.Integer:
+        result = &ast.IntegerLiteral { Value: obj.Value }
+    case *object.Boolean:
+        result = &ast.Boolean { Value: obj.Value }
+    case *object.String:
+        result = &ast.StringLiteral { Value: obj.Value }
+    case *object.Array:
+        var array = &ast.ArrayLiteral { Elements: []ast.Expression {} }
+        for _, element := range obj.Elements {
+            var exp, err = nodeFromObject(element, site)
+            if err != nil { return nil, err }
+            array.Elements = append(array.Elements, exp)
+        }
+        result = array
+    case *object.Hash:
+        var hash = ast.NewHashLiteral()
+        for _, pair := range obj.OrderedPairs() {
+            var key, errKey = nodeFromObject(pair.OriginalKey, site)
+            if errKey != nil { return nil, errKey }
+            var value, errValue = nodeFromObject(pair.Value, site)
+            if errValue != nil { return nil, errValue }
+            hash.Set(key, value)
+        }
+        result = hash
+    default:
+        return nil, &object.Error {
+            Message: fmt.Sprintf("cannot unquote a %s, only integers, booleans, strings, arrays, hashes and quotes",
+                GetMsgTypeFor(obj.Type())),
+        }
+    }
+    result.SetSpan(site.Pos(), site.End())
+    return result, nil
+}
+
+// The hygiene of the expansion: renames the names bound in a quote of a macro, and their uses in
+// the scope of each binding, before the unquotes put the code of the arguments. The names the
+// quote uses without binding them keep referring to the ones where the macro is called
+func (this *Interpreter) renameBindings(node ast.Node) {
+    this.renameIn(node, map[string] string {}, map[string] string {})
+}
+
+func (this *Interpreter) generateName(name string) string {
+    this.generated++
+    return fmt.Sprintf("%s#%d", name, this.generated)
+}
+
+// A copy of the names with the ones of the others added, the last ones win
+func withNames(names map[string] string, others ...map[string] string) map[string] string {
+    var result = maps.Clone(names)
+    for _, other := range others { maps.Copy(result, other) }
+    return result
+}
+
+// Renames the uses of the renamed names written under the node, the ones under an unquote and the
+// names of the methods are skipped. The later names are the lets of the enclosing blocks that are
+// not bound yet, the functions see them too because they run later
+func (this *Interpreter) renameIn(node ast.Node, renamed map[string] string, later map[string] string) {
+    if utils.IsNill(node) { return }
+    ast.Inspect(node, func (node ast.Node) bool {
+        if isUnquote(node) { return false }
+
+        switch node := node.(type) {
+        case *ast.Identifier:
+            if name, found := renamed[node.Value]; found { node.Value = name }
+        case *ast.MethodExpression:
+            this.renameIn(node.Expression, renamed, later)
+            for _, arg := range node.Call.Parameters { this.renameIn(arg, renamed, later) }
+            return false
+        case *ast.StatementsBlock:
+            this.renameStatements(node.Statements, renamed, later)
+            return false
+        case *ast.FunctionLiteral: // The parameters are seen by the body only
+            var inner = withNames(renamed, later)
+            for i := range node.Parameters {
+                var param = &node.Parameters[i]
+                inner[param.Value] = this.generateName(param.Value)
+                param.Value = inner[param.Value]
+            }
+            this.renameIn(node.Body, inner, map[string] string {})
+            return false
+        case *ast.MatchArm: // The names of the pattern are seen by the guard and the body
+            var inner = maps.Clone(renamed)
+            var bound = map[string] bool {}
+            ast.Inspect(node.Pattern, func (node ast.Node) bool {
+                var pattern, isBinding = node.(*ast.BindingPattern)
+                if !isBinding { return true }
+                if !bound[pattern.Name] { inner[pattern.Name] = this.generateName(pattern.Name) }
+                bound[pattern.Name] = true
+                pattern.Name = inner[pattern.Name]
+                return true
+            })
+            this.renameIn(node.Guard, inner, later)
+            this.renameIn(node.Body, inner, later)
+            return false
+        }
+        return true
+    })
+}
+
+// A let is seen by the statements after it. The value of the let is evaluated before it binds its
+// name, so there only the functions see it, with the lets after it
+func (this *Interpreter) renameStatements(stms []ast.Statement, renamed map[string] string, later map[string] string) {
+    var names = make([]string, len(stms))
+    for i, stm := range stms {
+        if let, isLet := stm.(*ast.LetStatement); isLet { names[i] = this.generateName(let.Identifier) }
+    }
+
+    for i, stm := range stms {
+        var following = maps.Clone(later)
+        for j := len(stms) - 1; j >= i; j-- {
+            if names[j] != "" { following[stms[j].(*ast.LetStatement).Identifier] = names[j] }
+        }
+
+        var let, isLet = stm.(*ast.LetStatement)
+        if !isLet {
+            this.renameIn(stm, renamed, following)
+            continue
+        }
+        this.renameIn(let.Expression, renamed, following)
+        renamed = withNames(renamed, map[string] string { let.Identifier: names[i] })
+        let.Identifier = names[i]
+    }
+}
+
+func expansionError(node ast.Node, format string, args ...any) error {
+    return fmt.Errorf("%s: %s", node.Pos(), fmt.Sprintf(format, args...))
+}
+
+// Binds the macros of the lets of the top level in env, removing those lets from the program, and
+// replaces each of their calls with its expansion. The error has the position of the call that
+// failed, the outermost one when the expansion of a macro calls the others
+func (this *Interpreter) ExpandMacros(program *ast.Program, env *object.Environment) error {
+    var statements = []ast.Statement {}
+    for _, stm := range program.Statements {
+        if let, isLet := stm.(*ast.LetStatement); isLet {
+            if literal, isMacro := let.Expression.(*ast.MacroLiteral); isMacro {
+                env.Set(let.Identifier, &object.Macro { Parameters: literal.Parameters, Body: literal.Body, Env: env })
+                continue
+            }
+        }
+        statements = append(statements, stm)
+    }
+    program.Statements = statements
+
+    var err error
+    ast.Inspect(program, func (node ast.Node) bool {
+        if _, isMacro := node.(*ast.MacroLiteral); isMacro && err == nil {
+            err = expansionError(node, "a macro can only be bound by a let of the top level")
+        }
+        return err == nil
+    })
+    if err != nil { return err }
+
+    _, err = this.expandCalls(program, env, 0)
+    return err
+}
+
+// Replaces the calls of the macros of env in the tree, the root included
+func (this *Interpreter) expandCalls(root ast.Node, env *object.Environment, depth int) (ast.Node, error) {
+    var err error
+    root = ast.Apply(root, func (cursor *ast.Cursor) bool {
+        if err != nil { return false }
+        if _, isMethod := cursor.Parent().(*ast.MethodExpression); isMethod { return true }
+        var call, isCall = cursor.Node().(*ast.CallExpression)
+        if !isCall { return true }
+        var ident, isIdent = call.Expression.(*ast.Identifier)
+        if !isIdent { return true }
+        var value, _ = env.Get(ident.Value)
+        var macro, isMacro = value.(*object.Macro)
+        if !isMacro { return true }
+
+        var expansion ast.Node
+        expansion, err = this.expand(call, macro, env, depth)
+        if err == nil {
+            cursor.Replace(expansion)
+        } else if depth == 0 { // At the call written in the program, the others are in the macros
+            err = expansionError(call, "%s", err)
+        }
+        return false
+    }, nil)
+    return root, err
+}
+
+func (this *Interpreter) expand(call *ast.CallExpression, macro *object.Macro, env *object.Environment, depth int) (ast.Node, error) {
+    var name = call.Expression.String()
+    if depth == maxExpansionDepth {
+        return nil, fmt.Errorf("the expansion of %s did not end after %d levels", name, maxExpansionDepth)
+    }
+    if len(call.Parameters) != len(macro.Parameters) {
+        return nil, fmt.Errorf("macro %s takes %d arguments but got %d", name, len(macro.Parameters), len(call.Parameters))
+    }
+
+    var macroEnv = object.NewEnclosedEnvironment(macro.Env)
+    for i, param := range macro.Parameters {
+        macroEnv.Set(param.Value, &object.Quote { Node: call.Parameters[i] })
+    }
+
+    var expanding, observer = this.expanding, this.Observer
+    this.expanding, this.Observer = true, nil
+    var result = unwrapReturn(this.Eval(macro.Body, macroEnv))
+    this.expanding, this.Observer = expanding, observer
+
+    if errObj, isErr := result.(*object.Error); isErr {
+        return nil, fmt.Errorf("macro %s: %s", name, errObj.Message)
+    }
+    var quote, isQuote = result.(*object.Quote)
+    if !isQuote {
+        var got = "nothing"
+        if result != nil { got = GetMsgTypeFor(result.Type()) }
+        return nil, fmt.Errorf("macro %s returned %s instead of a quote", name, got)
+    }
+
+    // The expansion can call macros too
+    return this.expandCalls(quote.Node, env, depth + 1)
+}
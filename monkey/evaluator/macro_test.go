@@ -0,0 +1,158 @@
+// monkey/evaluator/macro_test.go
+
+package evaluator
+
+import (
+    "monkey/object"
+    "monkey/test_utils"
+    "strings"
+    "testing"
+)
+
+func TestQuoteUnquote(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `quote(5)`,                                          "QUOTE(5)"                       },
+        { `quote(5 + 8)`,                                      "QUOTE((5 + 8))"                 },
+        { `quote(foobar + barfoo)`,                            "QUOTE((foobar + barfoo))"       },
+        { `quote(unquote(4 + 4))`,                             "QUOTE(8)"                       },
+        { `quote(8 + unquote(4 + 4))`,                         "QUOTE((8 + 8))"                 },
+        { `let x = 8; quote(x + unquote(x))`,                  "QUOTE((x + 8))"                 },
+        { `quote(unquote(1 == 2))`,                            "QUOTE(false)"                   },
+        { `quote(unquote("a" + "b"))`,                         "QUOTE(ab)"                      },
+        { `quote(unquote([1, { "k": true }]))`,                "QUOTE([1, { k: true }])"        },
+        { `let q = quote(4 + 4); quote(unquote(q) * 2)`,       "QUOTE(((4 + 4) * 2))"           },
+        { `quote(a.push(unquote(1)))`,                         "QUOTE(a.push(1))"               },
+
+        // The quoted code is copied, each call gets its own
+        { `let f = fn (x) { quote(unquote(x)) }; f(1); f(2)`,  "QUOTE(2)"                       },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        if test_utils.CheckForEvalError(t, evaluated) { continue }
+
+        if evaluated.Inspect() != test.expected {
+            t.Errorf("Expected '%s' to evaluate to '%s' but got '%s' instead", test.input, test.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestQuoteErrors(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `quote(unquote(fn () { 1 }))`,  "cannot unquote a Function" },
+        { `quote(unquote(1, 2))`,         "wrong number of arguments" },
+        { `quote(unquote(x))`,            "identifier not found: x"   },
+        { `unquote(1)`,                   "unquote can only be called inside a quote" },
+        { `let q = quote; q(1)`,          "quote can only be called by its name" },
+        { `let m = macro (x) { x }; m(1)`, "a macro can only be called before the evaluation" },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        var errObj, isErr = evaluated.(*object.Error)
+        if !isErr || !strings.Contains(errObj.Message, test.expected) {
+            t.Errorf("Expected '%s' to fail with '%s' but got %s", test.input, test.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestExpandMacros(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `let m = macro () { quote(1 + 2) }; m()`,                               "(1 + 2);\n" },
+        { `let m = macro (a, b) { quote(unquote(b) - unquote(a)) }; m(2 + 2, 10 - 5)`, "((10 - 5) - (2 + 2));\n" },
+        {
+            `let unless = macro (cond, then, otherwise) {
+                quote(if (!unquote(cond)) { unquote(then) } else { unquote(otherwise) })
+            };
+            unless(10 > 5, puts("not greater"), puts("greater"))`,
+            "if (!(10 > 5)) {puts(not greater)} else {puts(greater)};\n",
+        },
+
+        // The arguments are not expanded before the call, the expansion is expanded again
+        { `let one = macro () { quote(1) }; let two = macro () { quote(one() + one()) }; fn () { two() }`, "fn () { (1 + 1) };\n" },
+        { `let len = macro () { quote(0) }; a.len() + len()`,        "(a.len() + 0);\n" },
+
+        // The names bound in the quotes of a macro never capture the ones of the arguments
+        { `let swap = macro (a, b) { quote(fn (tmp) { [unquote(b), tmp] }(unquote(a))) }; swap(tmp, 1)`,
+            "fn (tmp#1) { [1, tmp#1] }(tmp);\n" },
+        { `let m = macro (x) { quote(fn () { let y = unquote(x); y }) }; m(y); m(y)`,
+            "fn () { let y#1 = y y#1 };\nfn () { let y#2 = y y#2 };\n" },
+
+        // Only the uses in the scope of a binding are renamed, the same name out of it is free
+        { `let m = macro (e) { quote(fn (x) { x * 2 }(unquote(e)) + x) }; m(1)`,
+            "(fn (x#1) { (x#1 * 2) }(1) + x);\n" },
+        { `let m = macro () { quote(fn () { let t = t + 1; t }) }; m()`,
+            "fn () { let t#1 = (t + 1) t#1 };\n" },
+        { `let m = macro () { quote(fn () { let f = fn () { f() + g() }; let g = fn () { 1 }; f }) }; m()`,
+            "fn () { let f#1 = fn () { (f#1() + g#2()) } let g#2 = fn () { 1 } f#1 };\n" },
+        { `let m = macro (v) { quote(match (unquote(v)) { [n, n] => n, _ => n }) }; m([1])`,
+            "match ([1]) { [n#1, n#1] => n#1, _ => n };\n" },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        if err := NewInterpreter().ExpandMacros(program, object.NewEnvironment()); err != nil {
+            t.Errorf("Unexpected error expanding '%s': %s", test.input, err)
+            continue
+        }
+        if program.String() != test.expected {
+            t.Errorf("Expected '%s' to expand to\n%q\nbut got\n%q", test.input, test.expected, program.String())
+        }
+    }
+}
+
+func TestExpandedMacrosRun(t *testing.T) {
+    var input = `
+    let unless = macro (cond, then, otherwise) { quote(if (!unquote(cond)) { unquote(then) } else { unquote(otherwise) }) };
+    let swap = macro (a, b) { quote(fn (tmp) { [unquote(b), tmp] }(unquote(a))) };
+    let tmp = 1;
+    let double = macro (e) { quote(fn (tmp) { tmp * 2 }(unquote(e)) + tmp) };
+    [unless(1 > 2, "a", "b"), swap(tmp, 2), double(5)]`
+
+    var program, parser = getParsedProgram(input)
+    if test_utils.CheckForParserErrors(t, parser) { return }
+
+    var interpreter = NewInterpreter()
+    var env = object.NewEnvironment()
+    if err := interpreter.ExpandMacros(program, env); err != nil { t.Fatalf("Unexpected error: %s", err) }
+
+    var evaluated = interpreter.Eval(program, env)
+    if test_utils.CheckForEvalError(t, evaluated) { return }
+    if evaluated.Inspect() != "[a, [2, 1], 11]" { t.Errorf("Expected [a, [2, 1], 11] but got %s", evaluated.Inspect()) }
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `let m = macro (x) { 1 }; m(2)`,                     "1:26: macro m returned Integer instead of a quote" },
+        { `let m = macro (x) { quote(x) }; m(1, 2)`,           "1:33: macro m takes 1 arguments but got 2" },
+        { `let m = macro () { quote(unquote(y)) }; m()`,       "1:41: macro m: identifier not found: y" },
+        { `let m = macro (x) { quote(m(unquote(x))) }; m(1)`,  "1:45: the expansion of m did not end after 100 levels" },
+        { `let f = fn () { macro (x) { x } }`,                 "1:17: a macro can only be bound by a let of the top level" },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var err = NewInterpreter().ExpandMacros(program, object.NewEnvironment())
+        if err == nil || err.Error() != test.expected {
+            t.Errorf("Expected expanding '%s' to fail with '%s' but got %v", test.input, test.expected, err)
+        }
+    }
+}
@@ -0,0 +1,113 @@
+// monkey/evaluator/match.go
+/*
+    The match expression. The arms are tried in the order they were written, each one with a new
+    environment enclosed by the one of the match where its pattern binds the names:
+
+        match (list) {
+            [] => "empty",
+            [x] if x > 0 => "one positive",
+            [first, ...rest] => rest,
+            { "name": name } => name,
+            _ => "anything else",
+        }
+
+    The first arm whose pattern matches, and whose guard is true, gives the value of the match
+*/
+
+package evaluator
+
+import (
+    "fmt"
+    "monkey/ast"
+    "monkey/object"
+)
+
+func (this *Interpreter) evalMatch(node *ast.MatchExpression, env *object.Environment) object.Object {
+    var value = this.Eval(node.Value, env)
+    if isError(value) { return value }
+
+    for _, arm := range node.Arms {
+        var armEnv = object.NewEnclosedEnvironment(env)
+        if !this.matchPattern(arm.Pattern, value, armEnv) { continue }
+
+        if arm.Guard != nil {
+            var guard = this.Eval(arm.Guard, armEnv)
+            if isError(guard) { return guard }
+            if !isTruthyObject(guard) { continue }
+        }
+        return this.Eval(arm.Body, armEnv)
+    }
+
+    return &object.Error { Message: fmt.Sprintf("no arm of the match takes %s %s", GetMsgTypeFor(value.Type()), value.Inspect()) }
+}
+
+// Tells if the value has the shape of the pattern, binding its names in env while it goes. The
+// names bound before a part that does not match stay in env, each arm has its own
+func (this *Interpreter) matchPattern(pattern ast.Pattern, value object.Object, env *object.Environment) bool {
+    switch pattern := pattern.(type) {
+    case *ast.WildcardPattern:
+        return true
+    case *ast.BindingPattern:
+        env.Set(pattern.Name, value)
+        if this.Observer != nil { this.Observer.Bind(pattern.Name, value, env) }
+        return true
+    case *ast.LiteralPattern:
+        return equalLiteral(pattern.Value, value)
+    case *ast.ArrayPattern:
+        var array, isArray = value.(*object.Array)
+        if !isArray { return false }
+        if len(array.Elements) < len(pattern.Elements) { return false }
+        if pattern.Rest == nil && len(array.Elements) != len(pattern.Elements) { return false }
+
+        for i, element := range pattern.Elements {
+            if !this.matchPattern(element, array.Elements[i], env) { return false }
+        }
+        if pattern.Rest == nil { return true }
+
+        var rest = make([]object.Object, len(array.Elements) - len(pattern.Elements))
+        copy(rest, array.Elements[len(pattern.Elements):])
+        return this.matchPattern(pattern.Rest, &object.Array { Elements: rest }, env)
+    case *ast.HashPattern:
+        var hash, isHash = value.(*object.Hash)
+        if !isHash { return false }
+
+        for i, key := range pattern.Keys {
+            var pair, found = hash.Get(hashKeyOfLiteral(key))
+            if !found || !this.matchPattern(pattern.Values[i], pair.Value, env) { return false }
+        }
+        return true
+    default:
+        return false
+    }
+}
+
+// The literals of the patterns are only integers, strings and booleans, the values of other types
+// are never equal to them
+func equalLiteral(literal ast.Expression, value object.Object) bool {
+    switch literal := literal.(type) {
+    case *ast.IntegerLiteral:
+        var integer, isInteger = value.(*object.Integer)
+        return isInteger && integer.Value == literal.Value
+    case *ast.StringLiteral:
+        var str, isString = value.(*object.String)
+        return isString && str.Value == literal.Value
+    case *ast.Boolean:
+        var boolean, isBoolean = value.(*object.Boolean)
+        return isBoolean && boolean.Value == literal.Value
+    default:
+        return false
+    }
+}
+
+func hashKeyOfLiteral(literal ast.Expression) object.HashKey {
+    switch literal := literal.(type) {
+    case *ast.IntegerLiteral:
+        return (&object.Integer { Value: literal.Value }).HashKey()
+    case *ast.StringLiteral:
+        return (&object.String { Value: literal.Value }).HashKey()
+    case *ast.Boolean:
+        return objFromBool(literal.Value).HashKey()
+    default:
+        return object.HashKey {}
+    }
+}
@@ -0,0 +1,95 @@
+// monkey/evaluator/match_test.go
+
+package evaluator
+
+import (
+    "monkey/object"
+    "monkey/test_utils"
+    "strings"
+    "testing"
+)
+
+func TestMatchExpressions(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        // Literals, the first arm that matches wins
+        { `match (2) { 1 => "one", 2 => "two", 2 => "again" }`,           "two"        },
+        { `match (-3) { 3 => "plus", -3 => "minus" }`,                    "minus"      },
+        { `match ("b") { "a" => 1, "b" => 2 }`,                           "2"          },
+        { `match (1 > 2) { true => "yes", false => "no" }`,               "no"         },
+        { `match ("1") { 1 => "int", _ => "other" }`,                     "other"      },
+        { `match (true) { 1 => "int", _ => "other" }`,                    "other"      },
+
+        // Bindings and guards
+        { `match (5) { n => n * 2 }`,                                      "10"         },
+        { `match (5) { n if n > 10 => "big", n if n > 1 => "medium", _ => "small" }`, "medium" },
+        { `let n = 1; match (5) { n => n }; n`,                            "1"          },
+        { `let limit = 3; match (5) { n if n > limit => n - limit, _ => 0 }`, "2"       },
+
+        // Arrays
+        { `match ([]) { [] => "empty", _ => "other" }`,                   "empty"      },
+        { `match ([1, 2]) { [a] => a, [a, b] => a + b }`,                  "3"          },
+        { `match ([1, 2, 3]) { [a, b] => "two", [a, ...rest] => rest }`,  "[2, 3]"     },
+        { `match ([1]) { [a, ...rest] => rest }`,                          "[]"         },
+        { `match ([1, [2, 3]]) { [1, [x, ...y]] => [x, y] }`,              "[2, [3]]"   },
+        { `match ([1, 2]) { [_, ..._] => "any" }`,                         "any"        },
+        { `match ("ab") { [a, ...r] => "array", _ => "string" }`,          "string"     },
+        { `match ([1, 2]) { [x, x] => x }`,                                "2"          },
+
+        // Hashes, the other keys are ignored
+        { `match ({ "name": "bob", "age": 3 }) { { "name": name } => name }`,          "bob" },
+        { `match ({ "age": 3 }) { { "name": name } => name, { "age": 3 } => "three" }`, "three" },
+        { `match ({ 1: [true] }) { { 1: [b] } => b }`,                     "true"       },
+        { `match ({}) { {} => "hash" }`,                                   "hash"       },
+
+        // Inside functions
+        {
+            `let sum = fn (xs) { match (xs) { [] => 0, [x, ...rest] => x + sum(rest) } };
+            sum([1, 2, 3, 4])`,
+            "10",
+        },
+        {
+            `let describe = fn (v) { let suffix = "!"; match (v) { [x] => fn () { x + suffix }(), _ => "?" } };
+            describe(["hi"])`,
+            "hi!",
+        },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        if test_utils.CheckForEvalError(t, evaluated) { continue }
+
+        if evaluated.Inspect() != test.expected {
+            t.Errorf("Expected '%s' to evaluate to '%s' but got '%s' instead", test.input, test.expected, evaluated.Inspect())
+        }
+    }
+}
+
+func TestMatchErrors(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `match (3) { 1 => "one", 2 => "two" }`,          "no arm of the match takes Integer 3" },
+        { `match ([1, 2]) { [a] => a, [a, b, c] => a }`,   "no arm of the match takes Array [1, 2]" },
+        { `match (1) { n if n > 1 => n }`,                 "no arm of the match takes Integer 1" },
+        { `match (x) { _ => 1 }`,                          "identifier not found: x" },
+        { `match (1) { n if n + "a" => n }`,               "type mismatch: Integer + String" },
+        { `match (1) { n => m }`,                          "identifier not found: m" },
+        { `match (1) { n => 2 }; n`,                       "identifier not found: n" },
+    }
+
+    for _, test := range tests {
+        var program, parser = getParsedProgram(test.input)
+        if test_utils.CheckForParserErrors(t, parser) { continue }
+
+        var evaluated = Eval(program, object.NewEnvironment())
+        var errObj, isErr = evaluated.(*object.Error)
+        if !isErr || !strings.Contains(errObj.Message, test.expected) {
+            t.Errorf("Expected '%s' to fail with '%s' but got %s", test.input, test.expected, evaluated.Inspect())
+        }
+    }
+}
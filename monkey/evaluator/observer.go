@@ -0,0 +1,126 @@
+// monkey/evaluator/observer.go
+/*
+    Lets the tools follow the evaluation (debugger, tracer, profiler...). The observer is called
+    synchronously from the evaluator, and when there is none the only cost is a nil check
+*/
+
+package evaluator
+
+import (
+    "monkey/ast"
+    "monkey/object"
+    "strings"
+)
+
+type Observer interface {
+    // Before and after each node is evaluated. The result can be an error
+    Enter(node ast.Node, env *object.Environment)
+    Exit(node ast.Node, result object.Object)
+    // When a user function starts, env is the one of its parameters. The site is the call
+    // expression, or nil when a builtin calls the function (e.g. map)
+    Call(fn *object.Function, site ast.Node, env *object.Environment)
+    // When a user function ends, the result can be an error
+    Return(fn *object.Function, result object.Object)
+    // When a let or a parameter binds a name in the env
+    Bind(name string, value object.Object, env *object.Environment)
+    // When an error appears, the node is the innermost one that produced it
+    Error(err *object.Error, node ast.Node)
+}
+
+// Does nothing, to embed in the observers that only need some of the events
+type BaseObserver struct {}
+
+// @Impl
+func (this BaseObserver) Enter(node ast.Node, env *object.Environment) {}
+
+// @Impl
+func (this BaseObserver) Exit(node ast.Node, result object.Object) {}
+
+// @Impl
+func (this BaseObserver) Call(fn *object.Function, site ast.Node, env *object.Environment) {}
+
+// @Impl
+func (this BaseObserver) Return(fn *object.Function, result object.Object) {}
+
+// @Impl
+func (this BaseObserver) Bind(name string, value object.Object, env *object.Environment) {}
+
+// @Impl
+func (this BaseObserver) Error(err *object.Error, node ast.Node) {}
+
+// Sends the events to each of the observers in order, to run several tools at once
+type Observers []Observer
+
+// @Impl
+func (this Observers) Enter(node ast.Node, env *object.Environment) {
+    for _, observer := range this { observer.Enter(node, env) }
+}
+
+// @Impl
+func (this Observers) Exit(node ast.Node, result object.Object) {
+    for _, observer := range this { observer.Exit(node, result) }
+}
+
+// @Impl
+func (this Observers) Call(fn *object.Function, site ast.Node, env *object.Environment) {
+    for _, observer := range this { observer.Call(fn, site, env) }
+}
+
+// @Impl
+func (this Observers) Return(fn *object.Function, result object.Object) {
+    for _, observer := range this { observer.Return(fn, result) }
+}
+
+// @Impl
+func (this Observers) Bind(name string, value object.Object, env *object.Environment) {
+    for _, observer := range this { observer.Bind(name, value, env) }
+}
+
+// @Impl
+func (this Observers) Error(err *object.Error, node ast.Node) {
+    for _, observer := range this { observer.Error(err, node) }
+}
+
+// Joins the observers that are not nil, the result is nil when there are none
+func Observe(observers ...Observer) Observer {
+    var result = Observers {}
+    for _, observer := range observers {
+        if observer != nil { result = append(result, observer) }
+    }
+    switch len(result) {
+    case 0:
+        return nil
+    case 1:
+        return result[0]
+    default:
+        return result
+    }
+}
+
+// The name the function was called with when the site tells it, else its parameters
+func FunctionName(fn *object.Function, site ast.Node) string {
+    if call, isCall := site.(*ast.CallExpression); isCall {
+        if ident, isIdent := call.Expression.(*ast.Identifier); isIdent { return ident.Value }
+    }
+
+    var params = []string {}
+    for _, param := range fn.Parameters {
+        params = append(params, param.Value)
+    }
+    return "fn (" + strings.Join(params, ", ") + ")"
+}
+
+// Evaluates the node reporting it to the observer
+func (this *Interpreter) observe(node ast.Node, env *object.Environment) object.Object {
+    this.Observer.Enter(node, env)
+    var result = this.eval(node, env)
+
+    // The errors are returned up to the program unchanged, so each one is reported only once. A
+    // call to exit is not a failure
+    if errObj, isErr := result.(*object.Error); isErr && !errObj.Exit && errObj != this.lastError {
+        this.lastError = errObj
+        this.Observer.Error(errObj, node)
+    }
+    this.Observer.Exit(node, result)
+    return result
+}
@@ -0,0 +1,135 @@
+// monkey/evaluator/observer_test.go
+
+package evaluator
+
+import (
+    "bytes"
+    "monkey/ast"
+    "monkey/object"
+    "strings"
+    "testing"
+)
+
+// Records the events other than Enter and Exit, and checks those two are balanced
+type recordingObserver struct {
+    events []string
+    open   []ast.Node
+    t      *testing.T
+}
+
+func (this *recordingObserver) Enter(node ast.Node, env *object.Environment) {
+    this.open = append(this.open, node)
+}
+
+func (this *recordingObserver) Exit(node ast.Node, result object.Object) {
+    if len(this.open) == 0 || this.open[len(this.open) - 1] != node {
+        this.t.Fatalf("Exit of %s without its Enter", node.String())
+    }
+    this.open = this.open[:len(this.open) - 1]
+}
+
+func (this *recordingObserver) Call(fn *object.Function, site ast.Node, env *object.Environment) {
+    this.events = append(this.events, "call " + FunctionName(fn, site))
+}
+
+func (this *recordingObserver) Return(fn *object.Function, result object.Object) {
+    this.events = append(this.events, "return " + result.Inspect())
+}
+
+func (this *recordingObserver) Bind(name string, value object.Object, env *object.Environment) {
+    this.events = append(this.events, "bind " + name)
+}
+
+func (this *recordingObserver) Error(err *object.Error, node ast.Node) {
+    this.events = append(this.events, "error at " + nodeKind(node))
+}
+
+func TestObserverEvents(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "let x = 1; x",                              "bind x"                                             },
+        { "let f = fn (a) { a * 2 }; f(3)",            "bind f, call f, bind a, return 6"                  },
+        { "fn (a, b) { a }(1, 2)",                     "call fn (a, b), bind a, bind b, return 1"          },
+        { "map([1], fn (x) { x })",                    "call fn (x), bind x, return 1"                     },
+        { "let f = fn () { 1 + true }; let y = f()",   "bind f, call f, error at InfixExpression, return ERROR: type mismatch: Integer + Boolean" },
+        { "len(1)",                                    "error at CallExpression"                           },
+    }
+
+    for _, test := range tests {
+        var program, _ = getParsedProgram(test.input)
+        var observer = &recordingObserver { t: t }
+        var interpreter = NewInterpreter()
+        interpreter.Observer = observer
+        interpreter.Eval(program, object.NewEnvironment())
+
+        if len(observer.open) != 0 { t.Errorf("Expected every Enter of %q to have its Exit", test.input) }
+        if got := strings.Join(observer.events, ", "); got != test.expected {
+            t.Errorf("Expected the events of %q to be\n%s\nbut got\n%s", test.input, test.expected, got)
+        }
+    }
+}
+
+func TestTracer(t *testing.T) {
+    var program, _ = getParsedProgram("let add = fn (a, b) { a + b }; add(1, 2)")
+    var out bytes.Buffer
+    var interpreter = NewInterpreter()
+    interpreter.Observer = NewTracer(&out)
+    var result = interpreter.Eval(program, object.NewEnvironment())
+    if result.Inspect() != "3" { t.Fatalf("Expected the tracer not to change the result but got %s", result.Inspect()) }
+
+    var expected = strings.Join([]string {
+        "Program let add = fn (a, b) { (a + b) }; add(1, 2);",
+        "  LetStatement let add = fn (a, b) { (a + b) }",
+        "    FunctionLiteral fn (a, b) { (a + b) } => fn (a, b)",
+        "    bind add = fn (a, b)",
+        "  => null",
+        "  ExpressionStatement add(1, 2)",
+        "    CallExpression add(1, 2)",
+        "      IntegerLiteral 1 => 1",
+        "      IntegerLiteral 2 => 2",
+        "      call add",
+        "      bind a = 1",
+        "      bind b = 2",
+        "      StatementsBlock (a + b)",
+        "        ExpressionStatement (a + b)",
+        "          InfixExpression (a + b)",
+        "            Identifier a => 1",
+        "            Identifier b => 2",
+        "          => 3",
+        "        => 3",
+        "      => 3",
+        "      return 3",
+        "    => 3",
+        "  => 3",
+        "=> 3",
+        "",
+    }, "\n")
+    if out.String() != expected {
+        t.Errorf("Expected the trace\n%s\nbut got\n%s", expected, out.String())
+    }
+}
+
+func TestShortSource(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "a +\n    b",                 "a + b"                                                        },
+        { strings.Repeat("é", 60),       strings.Repeat("é", 60)                                       },
+        { strings.Repeat("é", 61),       strings.Repeat("é", 57) + "..."                               },
+        { "\"" + strings.Repeat("日本", 40) + "\"", "\"" + strings.Repeat("日本", 28) + "..."        },
+    }
+
+    for _, test := range tests {
+        if got := shortSource(test.input); got != test.expected {
+            t.Errorf("Expected the short source of %q to be %q but got %q", test.input, test.expected, got)
+        }
+    }
+}
+
+func BenchmarkEvalWithoutObserver(b *testing.B) {
+    var program, _ = getParsedProgram("let fib = fn (n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)")
+    for i := 0; i < b.N; i++ {
+        NewInterpreter().Eval(program, object.NewEnvironment())
+    }
+}
@@ -0,0 +1,113 @@
+// monkey/evaluator/trace.go
+/*
+    Observer that prints the evaluation as an indented tree. Each node prints its kind and source,
+    and its result when it ends. The nodes without children fit in one line:
+
+        LetStatement let x = add(1, 2)
+          CallExpression add(1, 2)
+            Identifier add => fn (a, b)
+            IntegerLiteral 1 => 1
+            ...
+*/
+
+package evaluator
+
+import (
+    "fmt"
+    "io"
+    "monkey/ast"
+    "monkey/object"
+    "strings"
+)
+
+const traceSourceWidth = 60
+
+type Tracer struct {
+    BaseObserver
+    Out     io.Writer
+
+    depth   int
+    pending string // The line of the last node entered, until it is known if it has children
+}
+
+func NewTracer(out io.Writer) *Tracer {
+    return &Tracer { Out: out }
+}
+
+func nodeKind(node ast.Node) string {
+    return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
+
+// The source in one line and cut to the width, in characters so a letter is never split
+func shortSource(text string) string {
+    var runes = []rune(strings.Join(strings.Fields(text), " "))
+    if len(runes) > traceSourceWidth { return string(runes[:traceSourceWidth - 3]) + "..." }
+    return string(runes)
+}
+
+func shortValue(obj object.Object) string {
+    if obj == nil { return "nothing" }
+    switch x := obj.(type) {
+    case *object.Function:
+        var params = []string {}
+        for _, param := range x.Parameters {
+            params = append(params, param.Value)
+        }
+        return "fn (" + strings.Join(params, ", ") + ")"
+    case *object.String:
+        return shortSource(fmt.Sprintf("%q", x.Value))
+    case *object.Error:
+        return "ERROR: " + x.Message
+    default:
+        return shortSource(obj.Inspect())
+    }
+}
+
+func (this *Tracer) flush() {
+    if this.pending == "" { return }
+    fmt.Fprintln(this.Out, this.pending)
+    this.pending = ""
+}
+
+func (this *Tracer) line(text string) {
+    this.flush()
+    fmt.Fprintf(this.Out, "%s%s\n", strings.Repeat("  ", this.depth), text)
+}
+
+// @Impl
+func (this *Tracer) Enter(node ast.Node, env *object.Environment) {
+    this.flush()
+    this.pending = strings.Repeat("  ", this.depth) + nodeKind(node) + " " + shortSource(node.String())
+    this.depth++
+}
+
+// @Impl
+func (this *Tracer) Exit(node ast.Node, result object.Object) {
+    this.depth--
+    if this.pending != "" {
+        fmt.Fprintf(this.Out, "%s => %s\n", this.pending, shortValue(result))
+        this.pending = ""
+        return
+    }
+    this.line("=> " + shortValue(result))
+}
+
+// @Impl
+func (this *Tracer) Call(fn *object.Function, site ast.Node, env *object.Environment) {
+    this.line("call " + FunctionName(fn, site))
+}
+
+// @Impl
+func (this *Tracer) Return(fn *object.Function, result object.Object) {
+    this.line("return " + shortValue(result))
+}
+
+// @Impl
+func (this *Tracer) Bind(name string, value object.Object, env *object.Environment) {
+    this.line("bind " + name + " = " + shortValue(value))
+}
+
+// @Impl
+func (this *Tracer) Error(err *object.Error, node ast.Node) {
+    this.line("error " + err.Message)
+}
@@ -0,0 +1,54 @@
+// monkey/format/format.go
+/*
+    Pretty-prints monkey programs in one canonical style. Unlike the String of the ast nodes,
+    that is for debugging, the output keeps the comments and the blank lines between statements,
+    only has the parentheses the precedences need and parses back to the same program. Formatting
+    the output again gives the same output
+*/
+
+package format
+
+import (
+    "monkey/ast"
+    "monkey/lexer"
+    "monkey/parser"
+    "monkey/token"
+    "strings"
+)
+
+type Options struct {
+    IndentWidth int  // Spaces of each level, or the width of a tab when UseTabs is set
+    UseTabs     bool
+    LineWidth   int  // Lists, calls and blocks longer than it are broken one item per line
+}
+
+func DefaultOptions() Options {
+    return Options { IndentWidth: 4, LineWidth: 100 }
+}
+
+// Parses and formats the source keeping its comments and the shebang line. Returns a
+// *parser.SyntaxError when it does not parse
+func Source(source string, options Options) (string, error) {
+    var lx = lexer.NewLexer(source)
+    var p = parser.NewParser(lx)
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 {
+        return "", &parser.SyntaxError { Messages: p.Errors() }
+    }
+
+    var shebang = ""
+    if strings.HasPrefix(source, "#!") {
+        shebang, _, _ = strings.Cut(source, "\n")
+        shebang = strings.TrimRight(shebang, " \t\r") + "\n"
+    }
+    return shebang + Program(program, lx.Comments(), options), nil
+}
+
+// Formats the program. The comments are the ones of the lexer that read it, nil when there are none
+func Program(program *ast.Program, comments []token.Comment, options Options) string {
+    if options.IndentWidth <= 0 { options.IndentWidth = DefaultOptions().IndentWidth }
+    if options.LineWidth <= 0 { options.LineWidth = DefaultOptions().LineWidth }
+
+    var printer = &printer { options: options, comments: comments }
+    return printer.program(program)
+}
@@ -0,0 +1,176 @@
+// monkey/format/format_test.go
+
+package format
+
+import (
+    "errors"
+    "monkey/lexer"
+    "monkey/parser"
+    "testing"
+)
+
+func checkFormat(t *testing.T, input string, expected string, options Options) {
+    t.Helper()
+    var formatted, err = Source(input, options)
+    if err != nil {
+        t.Errorf("Unexpected error formatting %q: %s", input, err)
+        return
+    }
+    if formatted != expected {
+        t.Errorf("Expected %q to be formatted as\n%s\nbut got\n%s", input, expected, formatted)
+        return
+    }
+
+    var again, _ = Source(formatted, options)
+    if again != formatted {
+        t.Errorf("Expected formatting to be idempotent but the second pass of %q gave\n%s", input, again)
+    }
+}
+
+func TestFormatStatements(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "",                                    ""                                       },
+        { "let   x=1",                           "let x = 1;\n"                           },
+        { "let x = 1; x",                        "let x = 1;\nx;\n"                       },
+        { "return  a",                           "return a;\n"                            },
+        { `let s = "a  b"`,                      "let s = \"a  b\";\n"                    },
+        { "let f=fn(a,b){a+b}",                  "let f = fn (a, b) { a + b };\n"         },
+        { "let f = fn() {}",                     "let f = fn () {};\n"                    },
+        { "let h = {}; let a = []",              "let h = {};\nlet a = [];\n"             },
+        { `{"a":1,"b":[1,2]}`,                   "{ \"a\": 1, \"b\": [1, 2] };\n"         },
+        { "xs[0]; [1, 2][1]",                    "xs[0];\n[1, 2][1];\n"                   },
+        { "a.map(f).len()",                      "a.map(f).len();\n"                      },
+        { "if(a<b){a}else{b}",                   "if (a < b) { a } else { b };\n"         },
+        { "let f = fn (x) { let y = x; y }",     "let f = fn (x) {\n    let y = x;\n    y;\n};\n" },
+        { "if (x) { return 1; }",                "if (x) {\n    return 1;\n};\n"          },
+        { "let f = fn (x) { if (x) { return 1 }; return 2 }", "let f = fn (x) {\n    if (x) {\n        return 1;\n    }\n    return 2;\n};\n" },
+        { "let f = fn (x) { if (x) { 1 } else { 2 }; x }", "let f = fn (x) {\n    if (x) { 1 } else { 2 }\n    x;\n};\n" },
+
+        // The ';' stays after an if when the next statement would continue it
+        { "let f = fn (x) { if (x) { 1 }; -x }",  "let f = fn (x) {\n    if (x) { 1 };\n    -x;\n};\n" },
+        { "let f = fn (x) { if (x) { 1 }; (x + 1) * 2 }", "let f = fn (x) {\n    if (x) { 1 };\n    (x + 1) * 2;\n};\n" },
+        { "let f = fn (x) { if (x) { 1 }; [x].len() }", "let f = fn (x) {\n    if (x) { 1 };\n    [x].len();\n};\n" },
+        { "fn (x) { x }(1)",                     "fn (x) { x }(1);\n"                     },
+        { "let n:int=fn(x:int,y)->hash<string,int>{x}", "let n: int = fn (x: int, y) -> hash<string, int> { x };\n" },
+        { "let m=macro(x){quote(unquote(x)*2)}",  "let m = macro (x) { quote(unquote(x) * 2) };\n" },
+        { "let m = macro () { let y = 1; quote(y) }", "let m = macro () {\n    let y = 1;\n    quote(y);\n};\n" },
+        { "match(x){-1=>\"a\",[a,...r]if a>0=>r,{\"k\":_}=>0,}", "match (x) { -1 => \"a\", [a, ...r] if a > 0 => r, { \"k\": _ } => 0 };\n" },
+
+        // Blank lines are kept but collapsed to one
+        { "let a = 1;\n\n\n\nlet b = 2;",        "let a = 1;\n\nlet b = 2;\n"             },
+        { "let f = fn () {\n\n  1;\n\n  2 }",    "let f = fn () {\n    1;\n\n    2;\n};\n" },
+    }
+
+    for _, test := range tests {
+        checkFormat(t, test.input, test.expected, DefaultOptions())
+    }
+}
+
+func TestFormatParentheses(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "((a + b)) + c",                 "a + b + c;\n"        },
+        { "a + (b + c)",                   "a + (b + c);\n"      },
+        { "a - (b - c)",                   "a - (b - c);\n"      },
+        { "(a - b) - c",                   "a - b - c;\n"        },
+        { "(a * b) + c",                   "a * b + c;\n"        },
+        { "(a + b) * c",                   "(a + b) * c;\n"      },
+        { "a * (b / c)",                   "a * (b / c);\n"      },
+        { "(a < b) == (c > d)",            "a < b == c > d;\n"   },
+        { "-(a + b)",                      "-(a + b);\n"         },
+        { "-(-a)",                         "-(-a);\n"            },
+        { "-(-(-a))",                      "-(-(-a));\n"         },
+        { "!(!a)",                         "!!a;\n"              },
+        { "-(!a)",                         "-!a;\n"              },
+        { "!(a == b)",                     "!(a == b);\n"        },
+        { "(-a) * b",                      "-a * b;\n"           },
+        { "(a + b).len()",                 "(a + b).len();\n"    },
+        { "(-a).len()",                    "(-a).len();\n"       },
+        { "-(a.len())",                    "-a.len();\n"         },
+        { "f((a + b))",                    "f(a + b);\n"         },
+    }
+
+    for _, test := range tests {
+        checkFormat(t, test.input, test.expected, DefaultOptions())
+    }
+}
+
+func TestFormatComments(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "// only a comment",                         "// only a comment\n"                               },
+        { "#!/usr/bin/env monkey\nlet a = 1",          "#!/usr/bin/env monkey\nlet a = 1;\n"               },
+        { "// doc\nlet a = 1;   // trailing  ",        "// doc\nlet a = 1; // trailing\n"                  },
+        { "let a = 1;\n\n// b\n\nlet b = 2;",          "let a = 1;\n\n// b\n\nlet b = 2;\n"                },
+        { "let f = fn (x) { x // same\n}",             "let f = fn (x) {\n    x; // same\n};\n"            },
+        { "let f = fn () {\n  // nothing\n}",          "let f = fn () {\n    // nothing\n};\n"             },
+        { "let f = fn () {\n  1;\n  // end\n}",        "let f = fn () {\n    1;\n    // end\n};\n"         },
+        { "let xs = [1, // one\n 2]",                  "let xs = [\n    1, // one\n    2,\n];\n"           },
+        { "let h = { \"a\": 1, \"b\": 2, // b\n}",     "let h = {\n    \"a\": 1,\n    \"b\": 2, // b\n};\n" },
+        { "f(1, // one\n 2)",                          "f(\n    1, // one\n    2,\n);\n"                   },
+        { "let x = 1 + // inside\n 2;\nlet y = 3",     "let x = 1 + 2;\n// inside\nlet y = 3;\n"           },
+        { "let s = \"// not a comment\"",              "let s = \"// not a comment\";\n"                   },
+        { "match (x) { 1 => a, // one\n _ => b }",     "match (x) {\n    1 => a, // one\n    _ => b,\n};\n"      },
+    }
+
+    for _, test := range tests {
+        checkFormat(t, test.input, test.expected, DefaultOptions())
+    }
+}
+
+func TestFormatLineWidth(t *testing.T) {
+    var options = Options { IndentWidth: 2, LineWidth: 20 }
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "let xs = [1, 2, 3]",                       "let xs = [1, 2, 3];\n"                                },
+        { "let xs = [100, 200, 300, 400]",            "let xs = [\n  100,\n  200,\n  300,\n  400,\n];\n"     },
+        { "let h = { \"key\": 1, \"other\": 2 }",     "let h = {\n  \"key\": 1,\n  \"other\": 2,\n};\n"      },
+        { "each(xs, fn (x) { puts(x) })",             "each(xs, fn (x) {\n  puts(x);\n});\n"                 },
+        { "let total = add(first, second)",           "let total = add(\n  first,\n  second,\n);\n"          },
+        { "if (ok) { [10000, 20000, 30000] }",        "if (ok) {\n  [\n    10000,\n    20000,\n    30000,\n  ];\n};\n" },
+        { "match (x) { 1 => \"one\", _ => x }",       "match (x) {\n  1 => \"one\",\n  _ => x,\n};\n"       },
+        { "match (x) { [a] => f(a, 200000) }",        "match (x) {\n  [a] => f(\n    a,\n    200000,\n  ),\n};\n" },
+    }
+
+    for _, test := range tests {
+        checkFormat(t, test.input, test.expected, options)
+    }
+
+    checkFormat(t, "let f = fn (x) { let y = x; y }", "let f = fn (x) {\n\tlet y = x;\n\ty;\n};\n", Options { UseTabs: true })
+}
+
+// The formatted program has to be the same program
+func TestFormatKeepsTheProgram(t *testing.T) {
+    var input = `
+        let fib = fn(n) { if (n < 2) { return n; } ; fib(n - 1) + fib(n - 2) };
+        let xs = map(range(10), fn (x) { x * (x - 1) / 2 });
+        let h = { "a": [1, 2, { "b": -(1 - 2) }], 3: !true };
+        puts(fib(10), xs.filter(fn (x) { x > 2 }).len(), h["a"][2]);
+        let kind = fn (v) { match (v) { [] => "empty", [x, ...rest] if x > 0 => rest, { "a": [y] } => y, _ => "other" } };
+    `
+    var formatted, err = Source(input, Options { IndentWidth: 4, LineWidth: 30 })
+    if err != nil { t.Fatalf("Unexpected error: %s", err) }
+
+    var original = parser.NewParser(lexer.NewLexer(input)).ParseProgram()
+    var reparser = parser.NewParser(lexer.NewLexer(formatted))
+    var reparsed = reparser.ParseProgram()
+    if len(reparser.Errors()) > 0 {
+        t.Fatalf("The formatted program does not parse: %v\n%s", reparser.Errors(), formatted)
+    }
+    if original.String() != reparsed.String() {
+        t.Errorf("Expected the formatted program to be the same but got\n%s\ninstead of\n%s", reparsed.String(), original.String())
+    }
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+    var _, err = Source("let = 1", DefaultOptions())
+    var syntaxErr *parser.SyntaxError
+    if !errors.As(err, &syntaxErr) || len(syntaxErr.Messages) == 0 {
+        t.Errorf("Expected a SyntaxError but got %v instead", err)
+    }
+}
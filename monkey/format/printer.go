@@ -0,0 +1,509 @@
+// monkey/format/printer.go
+/*
+    Each expression is first tried on a single line. When it does not fit the width, or has
+    comments inside, its lists and blocks are broken one item per line and the children try the
+    same on their own. Comments are printed before the statement or list item that follows them,
+    or at the end of the line when they were written after one
+*/
+
+package format
+
+import (
+    "math"
+    "monkey/ast"
+    "monkey/parser"
+    "monkey/token"
+    "strings"
+)
+
+type printer struct {
+    options  Options
+    comments []token.Comment
+    next     int // First comment not printed yet
+    lastLine int // Source line of the last item or comment printed, to keep the blank lines
+}
+
+func (this *printer) indent(level int) string {
+    if this.options.UseTabs { return strings.Repeat("\t", level) }
+    return strings.Repeat(" ", level * this.options.IndentWidth)
+}
+
+// Width of the last line of the text, with the tabs as wide as the indentation
+func (this *printer) lastLineWidth(text string) int {
+    var index = strings.LastIndexByte(text, '\n')
+    var last = text[index + 1:]
+    return len(last) + strings.Count(last, "\t") * (this.options.IndentWidth - 1)
+}
+
+// Column where the text ends when it starts at the column
+func (this *printer) endColumn(column int, text string) int {
+    if strings.Contains(text, "\n") { return this.lastLineWidth(text) }
+    return column + this.lastLineWidth(text)
+}
+
+// Tells if there are comments to print between the offsets
+func (this *printer) hasComments(from int, to int) bool {
+    for i := this.next; i < len(this.comments); i++ {
+        var offset = this.comments[i].Pos.Offset
+        if offset >= to { return false }
+        if offset >= from { return true }
+    }
+    return false
+}
+
+func (this *printer) hasCommentsIn(node ast.Node) bool {
+    return this.hasComments(node.Pos().Offset, node.End().Offset)
+}
+
+// Writes a blank line when there was at least one before the line in the source, but never
+// before the first item of a block or list
+func (this *printer) blankLine(out *strings.Builder, line int, first *bool) {
+    if !*first && line > this.lastLine + 1 { out.WriteString("\n") }
+    *first = false
+}
+
+// Writes the comments that come before the offset, each on its own line
+func (this *printer) leadingComments(out *strings.Builder, before int, level int, first *bool) {
+    for this.next < len(this.comments) && this.comments[this.next].Pos.Offset < before {
+        var comment = this.comments[this.next]
+        this.blankLine(out, comment.Pos.Line, first)
+        out.WriteString(this.indent(level) + comment.Text + "\n")
+        this.lastLine = max(this.lastLine, comment.End.Line)
+        this.next++
+    }
+}
+
+// Writes the comment that was on the same line, right after the item and before the next one
+func (this *printer) trailingComment(out *strings.Builder, end token.Position, nextStart int) {
+    if this.next >= len(this.comments) { return }
+    var comment = this.comments[this.next]
+    if comment.Pos.Line != end.Line || comment.Pos.Offset < end.Offset || comment.Pos.Offset > nextStart { return }
+
+    out.WriteString(" " + comment.Text)
+    this.next++
+}
+
+// Where each item of a block or list was written. For the hashes an item goes from the key to the value
+type itemSpan struct {
+    pos token.Position
+    end token.Position
+}
+
+func nodeSpan(node ast.Node) itemSpan {
+    return itemSpan { node.Pos(), node.End() }
+}
+
+// Writes the items of a block or list, one per line. Each one goes with the comments before it,
+// the separator and the comment after it. The comments left before the end go after the last item
+func (this *printer) items(out *strings.Builder, spans []itemSpan, end token.Position, level int, render func (i int, column int) string, separator string) {
+    var first = true
+    for i, span := range spans {
+        this.leadingComments(out, span.pos.Offset, level, &first)
+        this.blankLine(out, span.pos.Line, &first)
+
+        var text = render(i, this.lastLineWidth(this.indent(level)))
+        out.WriteString(this.indent(level) + text + separator)
+        this.lastLine = max(this.lastLine, span.end.Line)
+
+        var nextStart = math.MaxInt
+        if i + 1 < len(spans) { nextStart = spans[i + 1].pos.Offset }
+        this.trailingComment(out, span.end, nextStart)
+        out.WriteString("\n")
+    }
+    this.leadingComments(out, end.Offset, level, &first)
+}
+
+func statementSpans(stms []ast.Statement) []itemSpan {
+    var spans = []itemSpan {}
+    for _, stm := range stms {
+        spans = append(spans, nodeSpan(stm))
+    }
+    return spans
+}
+
+func (this *printer) program(program *ast.Program) string {
+    var out strings.Builder
+    var end = token.Position { Offset: math.MaxInt } // Every comment left goes at the end
+    this.items(&out, statementSpans(program.Statements), end, 0, func (i int, column int) string {
+        return this.statement(program.Statements[i], 0, column)
+    }, ";")
+    return out.String()
+}
+
+// The statements of a block between braces, already broken on lines
+func (this *printer) block(block *ast.StatementsBlock, level int) string {
+    var out strings.Builder
+    out.WriteString("{\n")
+
+    this.items(&out, statementSpans(block.Statements), block.End(), level + 1, func (i int, column int) string {
+        return this.statement(block.Statements[i], level + 1, column) + blockSeparator(block.Statements, i)
+    }, "")
+
+    out.WriteString(this.indent(level) + "}")
+    return out.String()
+}
+
+// The ';' after a statement of a block. An if needs none, like in 'if (x) { return 1 } return 2',
+// unless the next statement starts with a '-', '(' or '[' that would continue it. At the top level
+// the parser always needs it
+func blockSeparator(stms []ast.Statement, i int) string {
+    var expStm, isExp = stms[i].(*ast.ExpressionStatement)
+    if !isExp { return ";" }
+    if _, isIf := expStm.Expression.(*ast.IfExpression); !isIf { return ";" }
+    if i + 1 < len(stms) && startsLikeAnOperator(stms[i + 1]) { return ";" }
+    return ""
+}
+
+// Whether the statement is printed starting with a token that is an operator too: the '-' of a
+// negation, the '(' of a group or the '[' of an array
+func startsLikeAnOperator(stm ast.Statement) bool {
+    var expStm, isExp = stm.(*ast.ExpressionStatement)
+    if !isExp { return false }
+
+    var exp = expStm.Expression
+    for {
+        var left ast.Expression
+        var leftPrecedence = parser.Call
+        switch node := exp.(type) {
+        case *ast.PrefixExpression:
+            return node.Operator == "-"
+        case *ast.ArrayLiteral:
+            return true
+        case *ast.InfixExpression:
+            left, leftPrecedence = node.Left, parser.Precedence(node.Operator)
+        case *ast.CallExpression:
+            left = node.Expression
+        case *ast.IndexExpression:
+            left = node.Left
+        case *ast.MethodExpression:
+            left = node.Expression
+        default:
+            return false
+        }
+        if needsParens(left, leftPrecedence, false) { return true }
+        exp = left
+    }
+}
+
+func (this *printer) statement(stm ast.Statement, level int, column int) string {
+    switch stm := stm.(type) {
+    case *ast.LetStatement:
+        var head = "let " + stm.Identifier + " = "
+        if stm.Type != nil { head = "let " + stm.Identifier + ": " + stm.Type.String() + " = " }
+        return head + this.expr(stm.Expression, level, column + len(head))
+    case *ast.ReturnStatement:
+        return "return " + this.expr(stm.Expression, level, column + len("return "))
+    case *ast.ExpressionStatement:
+        return this.expr(stm.Expression, level, column)
+    default:
+        return stm.String()
+    }
+}
+
+// How tight the expression binds, the literals and calls never need parentheses around them
+func precedence(node ast.Expression) int {
+    switch node := node.(type) {
+    case *ast.InfixExpression:
+        return parser.Precedence(node.Operator)
+    case *ast.PrefixExpression:
+        return parser.Prefix
+    default:
+        return parser.Call + 1
+    }
+}
+
+// The precedence a prefix operator asks of its operand. A negation of a negation keeps its
+// parentheses, -(-a) and not --a
+func prefixPrecedence(node *ast.PrefixExpression) int {
+    if inner, isPrefix := node.Value.(*ast.PrefixExpression); isPrefix && node.Operator == "-" && inner.Operator == "-" {
+        return parser.Prefix + 1
+    }
+    return parser.Prefix
+}
+
+// The parser groups the operators of the same precedence to the left, so on the right side of an
+// infix they need the parentheses too
+func needsParens(node ast.Expression, parentPrecedence int, right bool) bool {
+    var childPrecedence = precedence(node)
+    return childPrecedence < parentPrecedence || (right && childPrecedence == parentPrecedence)
+}
+
+// Prints the expression on one line when it fits from the column and on many when it does not
+func (this *printer) expr(node ast.Expression, level int, column int) string {
+    if flat, ok := this.flat(node); ok && column + len(flat) <= this.options.LineWidth {
+        return flat
+    }
+    return this.broken(node, level, column)
+}
+
+// A child of the expression, with parentheses when the precedences ask for them
+func (this *printer) operand(node ast.Expression, parentPrecedence int, right bool, level int, column int) string {
+    if needsParens(node, parentPrecedence, right) {
+        return "(" + this.expr(node, level, column + 1) + ")"
+    }
+    return this.expr(node, level, column)
+}
+
+func (this *printer) flatOperand(node ast.Expression, parentPrecedence int, right bool) (string, bool) {
+    var text, ok = this.flat(node)
+    if ok && needsParens(node, parentPrecedence, right) { return "(" + text + ")", true }
+    return text, ok
+}
+
+func (this *printer) flatList(nodes []ast.Expression) (string, bool) {
+    var items = []string {}
+    for _, node := range nodes {
+        var text, ok = this.flat(node)
+        if !ok { return "", false }
+        items = append(items, text)
+    }
+    return strings.Join(items, ", "), true
+}
+
+// A block that can stay inside a line: empty or with one expression
+func (this *printer) flatBlock(block *ast.StatementsBlock) (string, bool) {
+    if len(block.Statements) == 0 { return "{}", true }
+    if len(block.Statements) > 1 { return "", false }
+
+    var stm, isExpr = block.Statements[0].(*ast.ExpressionStatement)
+    if !isExpr { return "", false }
+    var text, ok = this.flat(stm.Expression)
+    return "{ " + text + " }", ok
+}
+
+// The parameters of a function or a macro between parentheses, with the type annotations
+func parameters(params []ast.Identifier) string {
+    var texts = []string {}
+    for _, param := range params {
+        if param.Type != nil {
+            texts = append(texts, param.Value + ": " + param.Type.String())
+        } else {
+            texts = append(texts, param.Value)
+        }
+    }
+    return "(" + strings.Join(texts, ", ") + ")"
+}
+
+// The function up to its body, with the type annotations
+func functionHead(fn *ast.FunctionLiteral) string {
+    var head = "fn " + parameters(fn.Parameters) + " "
+    if fn.ReturnType != nil { head += "-> " + fn.ReturnType.String() + " " }
+    return head
+}
+
+// The pattern of an arm of a match, with the strings quoted
+func pattern(node ast.Pattern) string {
+    var literal = func (exp ast.Expression) string {
+        if str, isString := exp.(*ast.StringLiteral); isString { return "\"" + str.Value + "\"" }
+        return exp.String()
+    }
+
+    switch node := node.(type) {
+    case *ast.LiteralPattern:
+        return literal(node.Value)
+    case *ast.ArrayPattern:
+        var elements = []string {}
+        for _, element := range node.Elements {
+            elements = append(elements, pattern(element))
+        }
+        if node.Rest != nil { elements = append(elements, "..." + pattern(node.Rest)) }
+        return "[" + strings.Join(elements, ", ") + "]"
+    case *ast.HashPattern:
+        if len(node.Keys) == 0 { return "{}" }
+        var pairs = []string {}
+        for i, key := range node.Keys {
+            pairs = append(pairs, literal(key) + ": " + pattern(node.Values[i]))
+        }
+        return "{ " + strings.Join(pairs, ", ") + " }"
+    default:
+        return node.String()
+    }
+}
+
+func (this *printer) flatArm(arm *ast.MatchArm) (string, bool) {
+    var text = pattern(arm.Pattern)
+    if arm.Guard != nil {
+        var guard, ok = this.flat(arm.Guard)
+        if !ok { return "", false }
+        text += " if " + guard
+    }
+    var body, ok = this.flat(arm.Body)
+    return text + " => " + body, ok
+}
+
+// Prints the expression on a single line. Returns false when it cannot be, because it has comments
+// inside or blocks with more than one statement
+func (this *printer) flat(node ast.Expression) (string, bool) {
+    if this.hasCommentsIn(node) { return "", false }
+
+    switch node := node.(type) {
+    case *ast.Identifier:
+        return node.Value, true
+    case *ast.IntegerLiteral, *ast.Boolean:
+        return node.String(), true
+    case *ast.StringLiteral:
+        return "\"" + node.Value + "\"", !strings.Contains(node.Value, "\n")
+    case *ast.PrefixExpression:
+        var value, ok = this.flatOperand(node.Value, prefixPrecedence(node), false)
+        return node.Operator + value, ok
+    case *ast.InfixExpression:
+        var opPrecedence = parser.Precedence(node.Operator)
+        var left, okLeft = this.flatOperand(node.Left, opPrecedence, false)
+        var right, okRight = this.flatOperand(node.Right, opPrecedence, true)
+        return left + " " + node.Operator + " " + right, okLeft && okRight
+    case *ast.ArrayLiteral:
+        var elements, ok = this.flatList(node.Elements)
+        return "[" + elements + "]", ok
+    case *ast.HashLiteral:
+        if len(node.Keys) == 0 { return "{}", true }
+        var pairs = []string {}
+        for _, key := range node.Keys {
+            var keyText, okKey = this.flat(key)
+            var valueText, okValue = this.flat(node.Pairs[key])
+            if !okKey || !okValue { return "", false }
+            pairs = append(pairs, keyText + ": " + valueText)
+        }
+        return "{ " + strings.Join(pairs, ", ") + " }", true
+    case *ast.IndexExpression:
+        var left, okLeft = this.flatOperand(node.Left, parser.Call, false)
+        var index, okIndex = this.flat(node.Index)
+        return left + "[" + index + "]", okLeft && okIndex
+    case *ast.CallExpression:
+        var callee, okCallee = this.flatOperand(node.Expression, parser.Call, false)
+        var args, okArgs = this.flatList(node.Parameters)
+        return callee + "(" + args + ")", okCallee && okArgs
+    case *ast.MethodExpression:
+        var receiver, okReceiver = this.flatOperand(node.Expression, parser.Call, false)
+        var call, okCall = this.flat(node.Call)
+        return receiver + "." + call, okReceiver && okCall
+    case *ast.FunctionLiteral:
+        var body, ok = this.flatBlock(node.Body)
+        return functionHead(node) + body, ok
+    case *ast.MacroLiteral:
+        var body, ok = this.flatBlock(node.Body)
+        return "macro " + parameters(node.Parameters) + " " + body, ok
+    case *ast.IfExpression:
+        var condition, okCondition = this.flat(node.Condition)
+        var consequence, okConsequence = this.flatBlock(node.ConsequenceBlock)
+        var text = "if (" + condition + ") " + consequence
+        if node.AlternativeBlock == nil { return text, okCondition && okConsequence }
+
+        var alternative, okAlternative = this.flatBlock(node.AlternativeBlock)
+        return text + " else " + alternative, okCondition && okConsequence && okAlternative
+    case *ast.MatchExpression:
+        var value, ok = this.flat(node.Value)
+        var arms = []string {}
+        for _, arm := range node.Arms {
+            var text, okArm = this.flatArm(arm)
+            ok = ok && okArm
+            arms = append(arms, text)
+        }
+        return "match (" + value + ") { " + strings.Join(arms, ", ") + " }", ok
+    default:
+        return node.String(), true
+    }
+}
+
+// Breaks the items of a list one per line, with a comma after each one
+func (this *printer) brokenList(open string, close string, end token.Position, spans []itemSpan, render func (i int, column int) string, level int) string {
+    var out strings.Builder
+    out.WriteString(open + "\n")
+    this.items(&out, spans, end, level + 1, render, ",")
+    out.WriteString(this.indent(level) + close)
+    return out.String()
+}
+
+func (this *printer) brokenExpressions(open string, close string, end token.Position, nodes []ast.Expression, level int) string {
+    var spans = []itemSpan {}
+    for _, node := range nodes {
+        spans = append(spans, nodeSpan(node))
+    }
+    return this.brokenList(open, close, end, spans, func (i int, column int) string {
+        return this.expr(nodes[i], level + 1, column)
+    }, level)
+}
+
+// The arguments of a call, that start at the column right after the head. When only the last one
+// does not fit and it is a function, array or hash it stays on the line of the call so
+// 'each(xs, fn (x) {' does not move the function down
+func (this *printer) arguments(call *ast.CallExpression, head string, level int, column int) string {
+    var count = len(call.Parameters)
+    if count > 0 {
+        var last = call.Parameters[count - 1]
+        var others, ok = this.flatList(call.Parameters[:count - 1])
+        if count > 1 { others += ", " }
+
+        var hugs = false
+        switch last.(type) {
+        case *ast.FunctionLiteral, *ast.ArrayLiteral, *ast.HashLiteral:
+            hugs = true
+        }
+
+        var prefix = "(" + others
+        if ok && hugs && !this.hasComments(call.Pos().Offset, last.Pos().Offset) &&
+            column + len(prefix) <= this.options.LineWidth {
+            return head + prefix + this.expr(last, level, column + len(prefix)) + ")"
+        }
+    }
+    return head + this.brokenExpressions("(", ")", call.End(), call.Parameters, level)
+}
+
+// Prints the expression on many lines, the children still go on one line when they fit
+func (this *printer) broken(node ast.Expression, level int, column int) string {
+    switch node := node.(type) {
+    case *ast.PrefixExpression:
+        return node.Operator + this.operand(node.Value, prefixPrecedence(node), false, level, column + len(node.Operator))
+    case *ast.InfixExpression:
+        var opPrecedence = parser.Precedence(node.Operator)
+        var left = this.operand(node.Left, opPrecedence, false, level, column) + " " + node.Operator + " "
+        return left + this.operand(node.Right, opPrecedence, true, level, this.endColumn(column, left))
+    case *ast.ArrayLiteral:
+        return this.brokenExpressions("[", "]", node.End(), node.Elements, level)
+    case *ast.HashLiteral:
+        if len(node.Keys) == 0 { return "{}" }
+        var spans = []itemSpan {}
+        for _, key := range node.Keys {
+            spans = append(spans, itemSpan { key.Pos(), node.Pairs[key].End() })
+        }
+        return this.brokenList("{", "}", node.End(), spans, func (i int, column int) string {
+            var key = this.expr(node.Keys[i], level + 1, column) + ": "
+            return key + this.expr(node.Pairs[node.Keys[i]], level + 1, this.endColumn(column, key))
+        }, level)
+    case *ast.IndexExpression:
+        var left = this.operand(node.Left, parser.Call, false, level, column) + "["
+        return left + this.expr(node.Index, level, this.endColumn(column, left)) + "]"
+    case *ast.CallExpression:
+        var callee = this.operand(node.Expression, parser.Call, false, level, column)
+        return this.arguments(node, callee, level, this.endColumn(column, callee))
+    case *ast.MethodExpression:
+        var receiver = this.operand(node.Expression, parser.Call, false, level, column) + "."
+        var head = receiver + node.Call.Expression.String()
+        return this.arguments(node.Call, head, level, this.endColumn(column, head))
+    case *ast.FunctionLiteral:
+        return functionHead(node) + this.block(node.Body, level)
+    case *ast.MacroLiteral:
+        return "macro " + parameters(node.Parameters) + " " + this.block(node.Body, level)
+    case *ast.IfExpression:
+        var head = "if ("
+        var text = head + this.expr(node.Condition, level, column + len(head)) + ") " + this.block(node.ConsequenceBlock, level)
+        if node.AlternativeBlock == nil { return text }
+        return text + " else " + this.block(node.AlternativeBlock, level)
+    case *ast.MatchExpression:
+        var head = "match ("
+        head += this.expr(node.Value, level, column + len(head)) + ") "
+        var spans = []itemSpan {}
+        for _, arm := range node.Arms {
+            spans = append(spans, nodeSpan(arm))
+        }
+        return head + this.brokenList("{", "}", node.End(), spans, func (i int, column int) string {
+            var arm = node.Arms[i]
+            var text = pattern(arm.Pattern)
+            if arm.Guard != nil { text += " if " + this.expr(arm.Guard, level + 1, column + len(text) + len(" if ")) }
+            text += " => "
+            return text + this.expr(arm.Body, level + 1, this.endColumn(column, text))
+        }, level)
+    default:
+        var text, _ = this.flat(node)
+        return text
+    }
+}
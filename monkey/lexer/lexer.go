@@ -9,19 +9,69 @@ import (
     "monkey/token"
     "fmt"
     "bytes"
+    "sort"
+    "strings"
 )
 
 const EOF = 0
 
+var keywords = map[string] string {
+    "true":   token.True,
+    "false":  token.False,
+    "let":    token.Let,
+    "fn":     token.Function,
+    "return": token.Return,
+    "if":     token.If,
+    "else":   token.Else,
+    "macro":  token.Macro,
+    "match":  token.Match,
+}
+
+// Returns the words reserved by the language sorted
+func Keywords() []string {
+    var words = make([]string, 0, len(keywords))
+    for word := range keywords {
+        words = append(words, word)
+    }
+    sort.Strings(words)
+    return words
+}
+
 // INFO: The current version of Lexer only supports ASCII characters. Can be updated
 // later to support utf-8 later as an exercise
 type Lexer struct {
     input string
     pos int
+    lineStarts []int // Offset where each line starts, to turn offsets into positions
+    comments []token.Comment
 }
 
 func NewLexer(input string) *Lexer {
-    return &Lexer { input: input, pos: 0 }
+    var lexer = &Lexer { input: input, pos: 0, lineStarts: []int { 0 } }
+    for i := 0; i < len(input); i++ {
+        if input[i] == '\n' { lexer.lineStarts = append(lexer.lineStarts, i + 1) }
+    }
+    lexer.skipShebang()
+    return lexer
+}
+
+// Turns an offset of the input into its line and column
+func (this *Lexer) PositionAt(offset int) token.Position {
+    var line = sort.Search(len(this.lineStarts), func (i int) bool { return this.lineStarts[i] > offset })
+    return token.Position { Offset: offset, Line: line, Column: offset - this.lineStarts[line - 1] + 1 }
+}
+
+// The comments read so far, in the order they appear
+func (this *Lexer) Comments() []token.Comment {
+    return this.comments
+}
+
+// Script files can start with a '#!' line so they can be executed directly. The whole line is ignored
+func (this *Lexer) skipShebang() {
+    if len(this.input) < 2 || this.input[0:2] != "#!" { return }
+    for this.getCh() != '\n' && this.getCh() != EOF {
+        this.nextPos()
+    }
 }
 
 func (this *Lexer) getCh() byte {
@@ -88,6 +138,24 @@ func (this *Lexer) skipWhiteSpaces() {
     }
 }
 
+// Skips the white spaces and the '//' comments between the tokens, keeping the comments
+func (this *Lexer) skipWhiteSpacesAndComments() {
+    this.skipWhiteSpaces()
+    for this.getCh() == '/' && this.getNextCh() == '/' {
+        var start = this.pos
+        for this.getCh() != '\n' && this.getCh() != EOF {
+            this.nextPos()
+        }
+        var text = strings.TrimRight(this.input[start:this.pos], " \t\r")
+        this.comments = append(this.comments, token.Comment {
+            Text: text,
+            Pos:  this.PositionAt(start),
+            End:  this.PositionAt(start + len(text)),
+        })
+        this.skipWhiteSpaces()
+    }
+}
+
 func (this *Lexer) readIdentifier() string {
     start := this.pos
     for this.hasNextCh() && isIdentLetter(this.input[this.pos + 1]) {
@@ -120,9 +188,10 @@ func (this *Lexer) readStringLiteral() string {
 }
 
 func (this *Lexer) GetNextToken() token.Token {
-    this.skipWhiteSpaces()
+    this.skipWhiteSpacesAndComments()
 
     var tk token.Token
+    var start = this.pos
 
     switch this.getCh() {
     // Operators & Comparison
@@ -131,13 +200,22 @@ func (this *Lexer) GetNextToken() token.Token {
         case '=':
             tk = token.NewTokenStr(token.Eq, "==")
             this.nextPos() // Needed for 2 characters operators
+        case '>':
+            tk = token.NewTokenStr(token.FatArrow, "=>")
+            this.nextPos() // Needed for 2 characters operators
         default:
             tk = token.NewToken(token.Assign, this.getCh())
         }
     case '+':
         tk = token.NewToken(token.Plus, this.getCh())
     case '-':
-        tk = token.NewToken(token.Minus, this.getCh())
+        switch this.getNextCh() {
+        case '>':
+            tk = token.NewTokenStr(token.Arrow, "->")
+            this.nextPos() // Needed for 2 characters operators
+        default:
+            tk = token.NewToken(token.Minus, this.getCh())
+        }
     case '!':
         switch this.getNextCh() {
         case '=':
@@ -161,7 +239,12 @@ func (this *Lexer) GetNextToken() token.Token {
     case ';':
         tk = token.NewToken(token.Semicolon, this.getCh())
     case '.':
-        tk = token.NewToken(token.Dot, this.getCh())
+        if this.getNextCh() == '.' && this.pos + 2 < len(this.input) && this.input[this.pos + 2] == '.' {
+            tk = token.NewTokenStr(token.Ellipsis, "...")
+            this.pos += 2
+        } else {
+            tk = token.NewToken(token.Dot, this.getCh())
+        }
     case ':':
         tk = token.NewToken(token.Colon, this.getCh())
     case '(':
@@ -185,22 +268,9 @@ func (this *Lexer) GetNextToken() token.Token {
         switch {
         case isIdentLetter(this.getCh()) == true:
             ident := this.readIdentifier()
-            switch ident {
-            case "true":
-                tk = token.NewTokenStr(token.True, ident)
-            case "false":
-                tk = token.NewTokenStr(token.False, ident)
-            case "let":
-                tk = token.NewTokenStr(token.Let, ident)
-            case "fn":
-                tk = token.NewTokenStr(token.Function, ident)
-            case "return":
-                tk = token.NewTokenStr(token.Return, ident)
-            case "if":
-                tk = token.NewTokenStr(token.If, ident)
-            case "else":
-                tk = token.NewTokenStr(token.Else, ident)
-            default:
+            if keywordType, isKeyword := keywords[ident]; isKeyword {
+                tk = token.NewTokenStr(keywordType, ident)
+            } else {
                 tk = token.NewTokenStr(token.Ident, ident)
             }
         case isIntNumber(this.getCh()) :
@@ -213,6 +283,8 @@ func (this *Lexer) GetNextToken() token.Token {
 
     this.nextPos()
 
+    tk.Pos = this.PositionAt(start)
+    tk.End = this.PositionAt(this.pos)
     return tk
 }
 
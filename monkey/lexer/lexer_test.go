@@ -234,7 +234,7 @@ func TestNextToken6(t *testing.T) {
 }
 
 func TestNextTokenAllTokens(t *testing.T) {
-    var input = `foo 5 = + - ! * / < > == != , ; ( ) { } fn let true false if else return`
+    var input = `foo 5 = + - ! * / < > == != , ; ( ) { } fn let true false if else return macro match`
     var lexer = NewLexer(input)
 
     var expectedTokens = []ExpectedToken {
@@ -274,6 +274,8 @@ func TestNextTokenAllTokens(t *testing.T) {
         {token.If, "if"},
         {token.Else, "else"},
         {token.Return, "return"},
+        {token.Macro, "macro"},
+        {token.Match, "match"},
     }
 
     checksForNextToken(lexer, t, expectedTokens)
@@ -331,3 +333,132 @@ func TestHashs(t *testing.T) {
 
     checksForNextToken(lexer, t, expectedTokens)
 }
+
+func TestTypeAnnotations(t *testing.T) {
+    var input = `fn (x: int) -> bool { x - 1 }`
+    var expectedTokens = []ExpectedToken {
+        { token.Function, "fn"   },
+        { token.Lparen,   "("    },
+        { token.Ident,    "x"    },
+        { token.Colon,    ":"    },
+        { token.Ident,    "int"  },
+        { token.Rparen,   ")"    },
+        { token.Arrow,    "->"   },
+        { token.Ident,    "bool" },
+        { token.Lbrace,   "{"    },
+        { token.Ident,    "x"    },
+        { token.Minus,    "-"    },
+        { token.Int,      "1"    },
+        { token.Rbrace,   "}"    },
+    }
+    var lexer = NewLexer(input)
+
+    checksForNextToken(lexer, t, expectedTokens)
+}
+
+func TestMatchTokens(t *testing.T) {
+    var input = `match (x) { [a, ...r] => r, _ => x.len() }`
+    var expectedTokens = []ExpectedToken {
+        { token.Match,    "match" },
+        { token.Lparen,   "("     },
+        { token.Ident,    "x"     },
+        { token.Rparen,   ")"     },
+        { token.Lbrace,   "{"     },
+        { token.Lbracket, "["     },
+        { token.Ident,    "a"     },
+        { token.Comma,    ","     },
+        { token.Ellipsis, "..."   },
+        { token.Ident,    "r"     },
+        { token.Rbracket, "]"     },
+        { token.FatArrow, "=>"    },
+        { token.Ident,    "r"     },
+        { token.Comma,    ","     },
+        { token.Ident,    "_"     },
+        { token.FatArrow, "=>"    },
+        { token.Ident,    "x"     },
+        { token.Dot,      "."     },
+        { token.Ident,    "len"   },
+        { token.Lparen,   "("     },
+        { token.Rparen,   ")"     },
+        { token.Rbrace,   "}"     },
+    }
+    var lexer = NewLexer(input)
+
+    checksForNextToken(lexer, t, expectedTokens)
+}
+
+func TestShebangLine(t *testing.T) {
+    var input = "#!/usr/bin/env monkey run\nlet x = 1;"
+    var expectedTokens = []ExpectedToken {
+        { token.Let,       "let" },
+        { token.Ident,     "x"   },
+        { token.Assign,    "="   },
+        { token.Int,       "1"   },
+        { token.Semicolon, ";"   },
+        { token.Eof,       ""    },
+    }
+    var lexer = NewLexer(input)
+
+    checksForNextToken(lexer, t, expectedTokens)
+}
+
+func TestComments(t *testing.T) {
+    var input = "// first\nlet x = 10 / 2; // half\n// last"
+    var expectedTokens = []ExpectedToken {
+        { token.Let,       "let" },
+        { token.Ident,     "x"   },
+        { token.Assign,    "="   },
+        { token.Int,       "10"  },
+        { token.Slash,     "/"   },
+        { token.Int,       "2"   },
+        { token.Semicolon, ";"   },
+        { token.Eof,       ""    },
+    }
+    var lexer = NewLexer(input)
+    checksForNextToken(lexer, t, expectedTokens)
+
+    var expectedComments = []struct {
+        text string; line int; column int
+    } {
+        { "// first", 1, 1  },
+        { "// half",  2, 17 },
+        { "// last",  3, 1  },
+    }
+    var comments = lexer.Comments()
+    if len(comments) != len(expectedComments) {
+        t.Fatalf("Expected %d comments but got %d instead", len(expectedComments), len(comments))
+    }
+    for i, expected := range expectedComments {
+        var comment = comments[i]
+        if comment.Text != expected.text || comment.Pos.Line != expected.line || comment.Pos.Column != expected.column {
+            t.Errorf("[%d] Expected comment %q at %d:%d but got %q at %s instead",
+                i, expected.text, expected.line, expected.column, comment.Text, comment.Pos)
+        }
+    }
+}
+
+func TestTokenPositions(t *testing.T) {
+    var input = "let s = \"ab\";\n  x == 1"
+    var expected = []struct {
+        literal string; pos string; end string; offset int
+    } {
+        { "let", "1:1",  "1:4",  0  },
+        { "s",   "1:5",  "1:6",  4  },
+        { "=",   "1:7",  "1:8",  6  },
+        { "ab",  "1:9",  "1:13", 8  },
+        { ";",   "1:13", "1:14", 12 },
+        { "x",   "2:3",  "2:4",  16 },
+        { "==",  "2:5",  "2:7",  18 },
+        { "1",   "2:8",  "2:9",  21 },
+        { "",    "2:9",  "2:9",  22 },
+    }
+
+    var lexer = NewLexer(input)
+    for i, exp := range expected {
+        var tk = lexer.GetNextToken()
+        if tk.Literal != exp.literal || tk.Pos.String() != exp.pos || tk.End.String() != exp.end || tk.Pos.Offset != exp.offset {
+            t.Errorf("[%d] Expected %q at %s-%s (offset %d) but got %q at %s-%s (offset %d) instead",
+                i, exp.literal, exp.pos, exp.end, exp.offset, tk.Literal, tk.Pos, tk.End, tk.Pos.Offset)
+        }
+    }
+}
@@ -0,0 +1,239 @@
+// monkey/lsp/analysis.go
+/*
+    Finds the bindings of a program (let statements, function parameters and the names of the
+    patterns of a match) and which binding each identifier refers to, for the navigation of the
+    editor. Every let of a name is a binding of its own, with the offset from where it is seen, so
+    each use of a name bound twice goes to the let it reads at that place
+*/
+
+package lsp
+
+import (
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/token"
+    "monkey/utils"
+    "sort"
+)
+
+const (
+    bindingLet = iota
+    bindingParameter
+    bindingPattern
+)
+
+type binding struct {
+    name     string
+    kind     int
+    pos      token.Position // Of the name
+    end      token.Position
+    visible  int            // Offset from where the code of the same scope sees it
+    let      *ast.LetStatement
+    function ast.Expression // The function or the macro of a parameter
+    arm      *ast.MatchArm  // The arm whose pattern binds the name
+}
+
+// An identifier in the source. It is the name of a binding, a reference to one or a builtin
+type occurrence struct {
+    pos     token.Position
+    end     token.Position
+    binding *binding
+    builtin string
+    isDefinition bool
+}
+
+func (this occurrence) name() string {
+    if this.binding != nil { return this.binding.name }
+    return this.builtin
+}
+
+type scope struct {
+    outer    *scope
+    bindings map[string] []*binding // In the order they were written
+    inline   bool                   // Runs with the code around it, like the arms of a match
+}
+
+type analysis struct {
+    bindings    []*binding
+    occurrences []occurrence // Sorted by offset
+}
+
+func analyze(program *ast.Program) *analysis {
+    var result = &analysis {}
+    var global = &scope { bindings: map[string] []*binding {} }
+    ast.ScopeLets(program.Statements, result.declareLet(global))
+    result.walkStatements(global, program.Statements)
+
+    sort.SliceStable(result.occurrences, func (i int, j int) bool {
+        return result.occurrences[i].pos.Offset < result.occurrences[j].pos.Offset
+    })
+    return result
+}
+
+func (this *analysis) addBinding(sc *scope, bind *binding) {
+    sc.bindings[bind.name] = append(sc.bindings[bind.name], bind)
+    this.bindings = append(this.bindings, bind)
+    this.occurrences = append(this.occurrences, occurrence {
+        pos: bind.pos, end: bind.end, binding: bind, isDefinition: true,
+    })
+}
+
+// A let is visible to the code of its scope written after it, and to all its functions
+func (this *analysis) declareLet(sc *scope) func (let *ast.LetStatement) {
+    return func (let *ast.LetStatement) {
+        this.addBinding(sc, &binding {
+            name:    let.Identifier,
+            kind:    bindingLet,
+            pos:     let.NamePos,
+            end:     ast.NameEnd(let.NamePos, let.Identifier),
+            visible: let.End().Offset,
+            let:     let,
+        })
+    }
+}
+
+// Finds the binding the name refers to from the offset. In its own scope it is the last one
+// visible before the offset, the same for the scopes an arm of a match is in. The scopes out of a
+// function are only read when the function runs, that is later, so there it is the last one
+// written before the offset or the first one of the scope
+func (this *scope) resolve(name string, offset int) *binding {
+    var deferred = false
+    for sc := this; sc != nil; sc = sc.outer {
+        var candidates = sc.bindings[name]
+        var found *binding
+        for _, bind := range candidates {
+            var from = bind.visible
+            if deferred { from = bind.pos.Offset }
+            if from <= offset { found = bind }
+        }
+        if found == nil && deferred && len(candidates) > 0 { found = candidates[0] }
+        if found != nil { return found }
+        if !sc.inline { deferred = true }
+    }
+    return nil
+}
+
+func (this *analysis) reference(sc *scope, ident *ast.Identifier) {
+    var occ = occurrence { pos: ident.Pos(), end: ident.End() }
+    occ.binding = sc.resolve(ident.Value, ident.Pos().Offset)
+    if occ.binding == nil {
+        if _, isBuiltin := evaluator.GetBuiltinDoc(ident.Value); !isBuiltin { return }
+        occ.builtin = ident.Value
+    }
+    this.occurrences = append(this.occurrences, occ)
+}
+
+func (this *analysis) walkStatements(sc *scope, stms []ast.Statement) {
+    for _, stm := range stms {
+        this.walk(sc, stm)
+    }
+}
+
+// Records the identifiers under the node. The functions, macros and matches open scopes, only the
+// unquotes of a quote refer to the names where it is written
+func (this *analysis) walk(sc *scope, node ast.Node) {
+    if utils.IsNill(node) { return }
+    ast.Inspect(node, func (node ast.Node) bool {
+        switch node := node.(type) {
+        case *ast.Identifier:
+            this.reference(sc, node)
+        case *ast.MatchExpression:
+            this.walk(sc, node.Value)
+            for _, arm := range node.Arms {
+                this.walkArm(sc, arm)
+            }
+            return false
+        case *ast.FunctionLiteral:
+            this.walkBody(sc, node, node.Parameters, node.Body)
+            return false
+        case *ast.MacroLiteral:
+            this.walkBody(sc, node, node.Parameters, node.Body)
+            return false
+        case *ast.CallExpression:
+            if node.Expression.String() == "quote" {
+                this.walkQuote(sc, node)
+                return false
+            }
+        case *ast.MethodExpression:
+            this.walk(sc, node.Expression)
+            if name, isIdent := node.Call.Expression.(*ast.Identifier); isIdent {
+                if _, isBuiltin := evaluator.GetBuiltinDoc(name.Value); isBuiltin {
+                    this.occurrences = append(this.occurrences, occurrence { pos: name.Pos(), end: name.End(), builtin: name.Value })
+                }
+            }
+            for _, param := range node.Call.Parameters {
+                this.walk(sc, param)
+            }
+            return false
+        }
+        return true
+    })
+}
+
+// The code of a quote only reaches the names where it is written through its unquotes
+func (this *analysis) walkQuote(sc *scope, quote *ast.CallExpression) {
+    ast.Inspect(quote, func (node ast.Node) bool {
+        var call, isCall = node.(*ast.CallExpression)
+        if !isCall || call.Expression.String() != "unquote" { return true }
+        for _, param := range call.Parameters {
+            this.walk(sc, param)
+        }
+        return false
+    })
+}
+
+// The parameters and the body of a function or a macro
+func (this *analysis) walkBody(sc *scope, owner ast.Expression, params []ast.Identifier, body *ast.StatementsBlock) {
+    var inner = &scope { outer: sc, bindings: map[string] []*binding {} }
+    for i := range params {
+        var param = &params[i]
+        this.addBinding(inner, &binding {
+            name:     param.Value,
+            kind:     bindingParameter,
+            pos:      param.Pos(),
+            end:      param.End(),
+            visible:  param.Pos().Offset,
+            function: owner,
+        })
+    }
+    ast.ScopeLets(body.Statements, this.declareLet(inner))
+    this.walkStatements(inner, body.Statements)
+}
+
+// The names of the pattern are seen by the guard and the result of the arm
+func (this *analysis) walkArm(sc *scope, arm *ast.MatchArm) {
+    var inner = &scope { outer: sc, bindings: map[string] []*binding {}, inline: true }
+    ast.Inspect(arm.Pattern, func (node ast.Node) bool {
+        if pattern, isBinding := node.(*ast.BindingPattern); isBinding {
+            this.addBinding(inner, &binding {
+                name:    pattern.Name,
+                kind:    bindingPattern,
+                pos:     pattern.Pos(),
+                end:     pattern.End(),
+                visible: arm.Pattern.End().Offset,
+                arm:     arm,
+            })
+        }
+        return true
+    })
+    ast.IfLets(arm.Body, this.declareLet(inner))
+    if arm.Guard != nil { this.walk(inner, arm.Guard) }
+    this.walk(inner, arm.Body)
+}
+
+// The identifier at the offset, the end of an identifier counts so the cursor can be right after it
+func (this *analysis) occurrenceAt(offset int) (occurrence, bool) {
+    for _, occ := range this.occurrences {
+        if occ.pos.Offset <= offset && offset <= occ.end.Offset { return occ, true }
+    }
+    return occurrence {}, false
+}
+
+// The definition and the references of the binding, in the order they are written
+func (this *analysis) occurrencesOf(bind *binding) []occurrence {
+    var result = []occurrence {}
+    for _, occ := range this.occurrences {
+        if occ.binding == bind { result = append(result, occ) }
+    }
+    return result
+}
@@ -0,0 +1,116 @@
+// monkey/lsp/analysis_test.go
+
+package lsp
+
+import (
+    "monkey/lexer"
+    "monkey/parser"
+    "strings"
+    "testing"
+)
+
+func analyzeSource(t *testing.T, source string) *analysis {
+    t.Helper()
+    var p = parser.NewParser(lexer.NewLexer(source))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { t.Fatalf("Unexpected parser errors for %q: %v", source, p.Errors()) }
+    return analyze(program)
+}
+
+// Offset of the n-th (from 0) appearance of the text
+func nthIndex(source string, text string, n int) int {
+    var offset = -1
+    for i := 0; i <= n; i++ {
+        var next = strings.Index(source[offset + 1:], text)
+        if next == -1 { return -1 }
+        offset += next + 1
+    }
+    return offset
+}
+
+func TestDefinitions(t *testing.T) {
+    var tests = []struct {
+        input string; name string; use int; definition int
+    } {
+        // The use and the definition are the n-th appearance of the name
+        { "let a = 1; a + 1",                                "a", 1, 0 },
+        { "let a = 1; let a = a + 1; a",                     "a", 2, 0 },
+        { "let a = 1; let a = a + 1; a",                     "a", 3, 1 },
+        { "let f = fn (x) { x * 2 }; f(1)",                  "x", 1, 0 },
+        { "let x = 1; let f = fn (x) { x }; x",              "x", 2, 1 },
+        { "let x = 1; let f = fn (x) { x }; x",              "x", 3, 0 },
+        { "let x = 1; let f = fn (y) { x + y }",             "x", 1, 0 },
+        { "let fact = fn (n) { fact(n - 1) }",               "fact", 1, 0 },
+        { "let f = fn () { g() }; let g = fn () { 1 }",      "g", 0, 1 },
+        { "if (true) { let a = 1 }; a",                      "a", 1, 0 },
+        { "let f = fn (a) { let a = a + 1; a }",             "a", 2, 0 },
+        { "let f = fn (a) { let a = a + 1; a }",             "a", 3, 1 },
+        { "let x = 1; match (x) { [x] => x, _ => x }",       "x", 1, 0 },
+        { "let x = 1; match (x) { [x] => x, _ => x }",       "x", 3, 2 },
+        { "let x = 1; match (x) { [x] => x, _ => x }",       "x", 4, 0 },
+        { "let v = 1; fn () { match (2) { v if v > 0 => v } }", "v", 2, 1 },
+    }
+
+    for _, test := range tests {
+        var result = analyzeSource(t, test.input)
+        var occ, found = result.occurrenceAt(nthIndex(test.input, test.name, test.use))
+        if !found || occ.binding == nil {
+            t.Errorf("Expected a binding for the %s at %d in %q", test.name, test.use, test.input)
+            continue
+        }
+        var expected = nthIndex(test.input, test.name, test.definition)
+        if occ.binding.pos.Offset != expected {
+            t.Errorf("Expected the %s at %d in %q to be defined at offset %d but got %d",
+                test.name, test.use, test.input, expected, occ.binding.pos.Offset)
+        }
+    }
+}
+
+func TestReferences(t *testing.T) {
+    var input = "let a = 1; let f = fn (a) { a }; let b = a + a; f(a)"
+    var result = analyzeSource(t, input)
+
+    var occ, _ = result.occurrenceAt(nthIndex(input, "a", 0))
+    var offsets = []int {}
+    for _, ref := range result.occurrencesOf(occ.binding) {
+        offsets = append(offsets, ref.pos.Offset)
+    }
+
+    var expected = []int { nthIndex(input, "a", 0), nthIndex(input, "a", 3), nthIndex(input, "a", 4), nthIndex(input, "a", 5) }
+    if len(offsets) != len(expected) {
+        t.Fatalf("Expected references at %v but got %v", expected, offsets)
+    }
+    for i := range expected {
+        if offsets[i] != expected[i] { t.Fatalf("Expected references at %v but got %v", expected, offsets) }
+    }
+}
+
+func TestBuiltinOccurrences(t *testing.T) {
+    var input = "let size = fn (a) { len(a) }; [1].push(2); let puts = 1; puts; [1].size()"
+    var result = analyzeSource(t, input)
+
+    var tests = []struct {
+        at int; builtin string; isBinding bool
+    } {
+        { nthIndex(input, "len(", 0), "len",  false },
+        { nthIndex(input, "push", 0), "push", false },
+        { nthIndex(input, "puts", 1), "",     true  }, // Shadowed by the let
+    }
+
+    // A method that is not a builtin has no occurrence, the call fails when it runs
+    if occ, found := result.occurrenceAt(nthIndex(input, "size", 1)); found {
+        t.Errorf("Expected no occurrence for the method size but got %+v", occ)
+    }
+
+    for _, test := range tests {
+        var occ, found = result.occurrenceAt(test.at)
+        if !found {
+            t.Errorf("Expected an identifier at %d", test.at)
+            continue
+        }
+        if occ.builtin != test.builtin || (occ.binding != nil) != test.isBinding {
+            t.Errorf("Expected the identifier at %d to be builtin %q (binding %t) but got %q (binding %t)",
+                test.at, test.builtin, test.isBinding, occ.builtin, occ.binding != nil)
+        }
+    }
+}
@@ -0,0 +1,157 @@
+// monkey/lsp/document.go
+/*
+    An open document: its text, the result of parsing it and the conversion between the byte
+    offsets of the lexer and the UTF-16 positions of the protocol
+*/
+
+package lsp
+
+import (
+    "monkey/ast"
+    "monkey/lexer"
+    "monkey/parser"
+    "monkey/resolver"
+    "monkey/token"
+    "unicode/utf16"
+    "unicode/utf8"
+)
+
+type document struct {
+    uri        string
+    version    int
+    text       string
+    lineStarts []int
+    program    *ast.Program   // The last version that parsed, nil until one does
+    errors     []parser.Error
+    analysis   *analysis      // Of the program
+    stale      bool           // The program is of an older text, its offsets may have moved
+    warnings   []resolver.Warning
+}
+
+// While the text has syntax errors, which is most of the time during an edit, the program and the
+// analysis of the previous version are kept so navigation keeps working on the unchanged code
+func newDocument(uri string, version int, text string, previous *document) *document {
+    var doc = &document { uri: uri, version: version, text: text, lineStarts: []int { 0 } }
+    for i := 0; i < len(text); i++ {
+        if text[i] == '\n' { doc.lineStarts = append(doc.lineStarts, i + 1) }
+    }
+
+    var parser = parser.NewParser(lexer.NewLexer(text))
+    var program = parser.ParseProgram()
+    doc.errors = parser.ErrorList()
+    if len(doc.errors) == 0 && program != nil {
+        doc.program = program
+        doc.analysis = analyze(program)
+        doc.warnings = resolver.Resolve(program)
+    } else if previous != nil && previous.program != nil {
+        doc.program = previous.program
+        doc.analysis = previous.analysis
+        doc.stale = true
+    }
+    return doc
+}
+
+// Whether the name is still written between the offsets. Always true when the program is of this
+// text, a stale one can point to code that moved or was removed
+func (this *document) spells(pos token.Position, end token.Position, name string) bool {
+    if !this.stale { return true }
+    if pos.Offset < 0 || end.Offset > len(this.text) || pos.Offset > end.Offset { return false }
+    return this.text[pos.Offset:end.Offset] == name
+}
+
+// Number of UTF-16 code units of the text
+func utf16Length(text string) int {
+    var length = 0
+    for _, r := range text {
+        length += utf16.RuneLen(r)
+    }
+    return length
+}
+
+func (this *document) line(index int) string {
+    var start = this.lineStarts[index]
+    var end = len(this.text)
+    if index + 1 < len(this.lineStarts) { end = this.lineStarts[index + 1] }
+    return this.text[start:end]
+}
+
+// Turns an offset of the text into a position of the protocol
+func (this *document) position(offset int) Position {
+    offset = min(max(offset, 0), len(this.text))
+
+    var line = 0
+    for line + 1 < len(this.lineStarts) && this.lineStarts[line + 1] <= offset {
+        line++
+    }
+    return Position { Line: line, Character: utf16Length(this.text[this.lineStarts[line]:offset]) }
+}
+
+func (this *document) rangeOf(pos token.Position, end token.Position) Range {
+    return Range { Start: this.position(pos.Offset), End: this.position(end.Offset) }
+}
+
+// Turns a position of the protocol into an offset of the text
+func (this *document) offset(pos Position) int {
+    if pos.Line < 0 { return 0 }
+    if pos.Line >= len(this.lineStarts) { return len(this.text) }
+
+    var line = this.line(pos.Line)
+    var units = 0
+    for i, r := range line {
+        if units >= pos.Character || r == '\n' { return this.lineStarts[pos.Line] + i }
+        units += utf16.RuneLen(r)
+    }
+    return this.lineStarts[pos.Line] + len(line)
+}
+
+func (this *document) diagnostics() []Diagnostic {
+    var result = []Diagnostic {}
+    for _, err := range this.errors {
+        var rng = this.rangeOf(err.Pos, err.End)
+        if rng.Start == rng.End { // The end of the input, so the mark can be seen
+            rng.Start.Character = max(rng.Start.Character - 1, 0)
+        }
+        result = append(result, Diagnostic {
+            Range: rng, Severity: severityError, Source: "monkey", Message: err.Message,
+        })
+    }
+    for _, warning := range this.warnings {
+        result = append(result, Diagnostic {
+            Range: this.rangeOf(warning.Pos, warning.End), Severity: severityWarning, Source: "monkey", Message: warning.Message,
+        })
+    }
+    return result
+}
+
+// The let statements with the ones inside the functions they bind as children
+func (this *document) symbols(stms []ast.Statement) []DocumentSymbol {
+    var result = []DocumentSymbol {}
+    for _, stm := range stms {
+        var let, isLet = stm.(*ast.LetStatement)
+        if !isLet { continue }
+
+        var symbol = DocumentSymbol {
+            Name:           let.Identifier,
+            Kind:           symbolVariable,
+            Range:          this.rangeOf(let.Pos(), let.End()),
+            SelectionRange: this.rangeOf(let.NamePos, ast.NameEnd(let.NamePos, let.Identifier)),
+        }
+        if fn, isFn := let.Expression.(*ast.FunctionLiteral); isFn {
+            symbol.Kind = symbolFunction
+            symbol.Children = this.symbols(fn.Body.Statements)
+        }
+        result = append(result, symbol)
+    }
+    return result
+}
+
+// The identifier that ends at the offset, for completion
+func (this *document) wordBefore(offset int) (string, int) {
+    var start = offset
+    for start > 0 {
+        var r, size = utf8.DecodeLastRuneInString(this.text[:start])
+        if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) { break }
+        start -= size
+    }
+    return this.text[start:offset], start
+}
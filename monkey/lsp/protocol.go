@@ -0,0 +1,167 @@
+// monkey/lsp/protocol.go
+/*
+    The parts of the Language Server Protocol the server uses. The names and JSON fields follow
+    the specification so the messages can be checked against it
+*/
+
+package lsp
+
+import "encoding/json"
+
+// JSON-RPC error codes
+const (
+    codeParseError     = -32700
+    codeInvalidRequest = -32600
+    codeMethodNotFound = -32601
+    codeInvalidParams  = -32602
+)
+
+const (
+    severityError   = 1
+    severityWarning = 2
+
+    syncFull = 1
+
+    // CompletionItemKind
+    completionFunction = 3
+    completionVariable = 6
+    completionKeyword  = 14
+
+    // SymbolKind
+    symbolFunction = 12
+    symbolVariable = 13
+)
+
+type message struct {
+    Jsonrpc string           `json:"jsonrpc"`
+    ID      *json.RawMessage `json:"id,omitempty"`
+    Method  string           `json:"method,omitempty"`
+    Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type response struct {
+    Jsonrpc string           `json:"jsonrpc"`
+    ID      *json.RawMessage `json:"id"`
+    Result  any              `json:"result"`
+    Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+    Code    int    `json:"code"`
+    Message string `json:"message"`
+}
+
+type notification struct {
+    Jsonrpc string `json:"jsonrpc"`
+    Method  string `json:"method"`
+    Params  any    `json:"params"`
+}
+
+type Position struct {
+    Line      int `json:"line"`
+    Character int `json:"character"` // In UTF-16 code units
+}
+
+type Range struct {
+    Start Position `json:"start"`
+    End   Position `json:"end"`
+}
+
+type Location struct {
+    URI   string `json:"uri"`
+    Range Range  `json:"range"`
+}
+
+type TextDocumentIdentifier struct {
+    URI string `json:"uri"`
+}
+
+type TextDocumentItem struct {
+    URI     string `json:"uri"`
+    Version int    `json:"version"`
+    Text    string `json:"text"`
+}
+
+type TextDocumentPositionParams struct {
+    TextDocument TextDocumentIdentifier `json:"textDocument"`
+    Position     Position               `json:"position"`
+}
+
+type DidOpenParams struct {
+    TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type DidChangeParams struct {
+    TextDocument struct {
+        URI     string `json:"uri"`
+        Version int    `json:"version"`
+    } `json:"textDocument"`
+    ContentChanges []struct {
+        Text string `json:"text"`
+    } `json:"contentChanges"`
+}
+
+type DidCloseParams struct {
+    TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type ReferenceParams struct {
+    TextDocumentPositionParams
+    Context struct {
+        IncludeDeclaration bool `json:"includeDeclaration"`
+    } `json:"context"`
+}
+
+type DocumentSymbolParams struct {
+    TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type FormattingParams struct {
+    TextDocument TextDocumentIdentifier `json:"textDocument"`
+    Options      struct {
+        TabSize      int  `json:"tabSize"`
+        InsertSpaces bool `json:"insertSpaces"`
+    } `json:"options"`
+}
+
+type Diagnostic struct {
+    Range    Range  `json:"range"`
+    Severity int    `json:"severity"`
+    Source   string `json:"source"`
+    Message  string `json:"message"`
+}
+
+type PublishDiagnosticsParams struct {
+    URI         string       `json:"uri"`
+    Version     int          `json:"version"`
+    Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type MarkupContent struct {
+    Kind  string `json:"kind"`
+    Value string `json:"value"`
+}
+
+type Hover struct {
+    Contents MarkupContent `json:"contents"`
+    Range    *Range        `json:"range,omitempty"`
+}
+
+type CompletionItem struct {
+    Label  string `json:"label"`
+    Kind   int    `json:"kind"`
+    Detail string `json:"detail,omitempty"`
+}
+
+type DocumentSymbol struct {
+    Name           string           `json:"name"`
+    Kind           int              `json:"kind"`
+    Range          Range            `json:"range"`
+    SelectionRange Range            `json:"selectionRange"`
+    Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+type TextEdit struct {
+    Range   Range  `json:"range"`
+    NewText string `json:"newText"`
+}
@@ -0,0 +1,328 @@
+// monkey/lsp/server.go
+/*
+    Language server for the monkey scripts, spoken over stdio with JSON-RPC messages framed by a
+    Content-Length header. The documents are parsed again on each change (full sync) and the
+    diagnostics are published right after
+*/
+
+package lsp
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/format"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/token"
+    "net/textproto"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+type Server struct {
+    in        *bufio.Reader
+    out       io.Writer
+    documents map[string] *document
+    shutdown  bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+    return &Server {
+        in:        bufio.NewReader(in),
+        out:       out,
+        documents: map[string] *document {},
+    }
+}
+
+// The header of a message could not be read, the server answers it and goes on with the next one
+var errHeader = errors.New("invalid header")
+
+// Reads the body of the next message
+func (this *Server) read() ([]byte, error) {
+    var header, err = textproto.NewReader(this.in).ReadMIMEHeader()
+    var protocolErr textproto.ProtocolError
+    if errors.As(err, &protocolErr) { return nil, fmt.Errorf("%w: %s", errHeader, protocolErr) }
+    if err != nil { return nil, err }
+
+    var length, errLength = strconv.Atoi(header.Get("Content-Length"))
+    if errLength != nil { return nil, fmt.Errorf("%w: Content-Length: %s", errHeader, errLength) }
+    if length < 0 { return nil, fmt.Errorf("%w: negative Content-Length %d", errHeader, length) }
+
+    var body = make([]byte, length)
+    if _, err = io.ReadFull(this.in, body); err != nil { return nil, err }
+    return body, nil
+}
+
+func (this *Server) write(value any) error {
+    var body, err = json.Marshal(value)
+    if err != nil { return err }
+    _, err = fmt.Fprintf(this.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+    return err
+}
+
+func (this *Server) notify(method string, params any) error {
+    return this.write(notification { Jsonrpc: "2.0", Method: method, Params: params })
+}
+
+// Handles the messages until the client sends exit or closes the input. Returns the exit code,
+// that is 1 when the client did not ask for a shutdown first
+func (this *Server) Serve() int {
+    for {
+        var body, err = this.read()
+        if errors.Is(err, errHeader) {
+            this.write(response { Jsonrpc: "2.0", Error: &responseError { Code: codeParseError, Message: err.Error() } })
+            continue
+        }
+        if err != nil { return 1 } // The client went away without exit
+
+        var msg message
+        if err := json.Unmarshal(body, &msg); err != nil {
+            this.write(response { Jsonrpc: "2.0", Error: &responseError { Code: codeParseError, Message: err.Error() } })
+            continue
+        }
+
+        if msg.Method == "exit" {
+            if this.shutdown { return 0 }
+            return 1
+        }
+
+        var result, errResponse = this.handle(msg)
+        if msg.ID == nil { continue } // Notifications have no response
+
+        this.write(response { Jsonrpc: "2.0", ID: msg.ID, Result: result, Error: errResponse })
+    }
+}
+
+func decode[T any](params json.RawMessage) (T, *responseError) {
+    var value T
+    if err := json.Unmarshal(params, &value); err != nil {
+        return value, &responseError { Code: codeInvalidParams, Message: err.Error() }
+    }
+    return value, nil
+}
+
+func (this *Server) handle(msg message) (any, *responseError) {
+    switch msg.Method {
+    case "initialize":
+        return this.initialize(), nil
+    case "initialized", "$/cancelRequest", "$/setTrace":
+        return nil, nil
+    case "shutdown":
+        this.shutdown = true
+        return nil, nil
+    case "textDocument/didOpen":
+        var params, err = decode[DidOpenParams](msg.Params)
+        if err != nil { return nil, err }
+        this.update(params.TextDocument.URI, params.TextDocument.Version, params.TextDocument.Text)
+        return nil, nil
+    case "textDocument/didChange":
+        var params, err = decode[DidChangeParams](msg.Params)
+        if err != nil { return nil, err }
+        if len(params.ContentChanges) == 0 { return nil, nil }
+        var text = params.ContentChanges[len(params.ContentChanges) - 1].Text // Full sync sends the whole text
+        this.update(params.TextDocument.URI, params.TextDocument.Version, text)
+        return nil, nil
+    case "textDocument/didClose":
+        var params, err = decode[DidCloseParams](msg.Params)
+        if err != nil { return nil, err }
+        delete(this.documents, params.TextDocument.URI)
+        this.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams { URI: params.TextDocument.URI, Diagnostics: []Diagnostic {} })
+        return nil, nil
+    case "textDocument/definition":
+        var params, err = decode[TextDocumentPositionParams](msg.Params)
+        if err != nil { return nil, err }
+        return this.definition(params), nil
+    case "textDocument/references":
+        var params, err = decode[ReferenceParams](msg.Params)
+        if err != nil { return nil, err }
+        return this.references(params), nil
+    case "textDocument/hover":
+        var params, err = decode[TextDocumentPositionParams](msg.Params)
+        if err != nil { return nil, err }
+        return this.hover(params), nil
+    case "textDocument/documentSymbol":
+        var params, err = decode[DocumentSymbolParams](msg.Params)
+        if err != nil { return nil, err }
+        return this.documentSymbols(params), nil
+    case "textDocument/completion":
+        var params, err = decode[TextDocumentPositionParams](msg.Params)
+        if err != nil { return nil, err }
+        return this.completion(params), nil
+    case "textDocument/formatting":
+        var params, err = decode[FormattingParams](msg.Params)
+        if err != nil { return nil, err }
+        return this.formatting(params), nil
+    default:
+        if msg.ID == nil { return nil, nil } // Unknown notifications are ignored
+        return nil, &responseError { Code: codeMethodNotFound, Message: "method not supported: " + msg.Method }
+    }
+}
+
+func (this *Server) initialize() any {
+    return map[string] any {
+        "capabilities": map[string] any {
+            "textDocumentSync":           syncFull,
+            "definitionProvider":         true,
+            "referencesProvider":         true,
+            "hoverProvider":              true,
+            "documentSymbolProvider":     true,
+            "documentFormattingProvider": true,
+            "completionProvider":         map[string] any { "triggerCharacters": []string { "." } },
+        },
+        "serverInfo": map[string] any { "name": "monkey-lsp" },
+    }
+}
+
+func (this *Server) update(uri string, version int, text string) {
+    var doc = newDocument(uri, version, text, this.documents[uri])
+    this.documents[uri] = doc
+    this.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams {
+        URI: uri, Version: version, Diagnostics: doc.diagnostics(),
+    })
+}
+
+// The document and the identifier under the position, when both exist
+func (this *Server) occurrenceAt(params TextDocumentPositionParams) (*document, occurrence, bool) {
+    var doc, found = this.documents[params.TextDocument.URI]
+    if !found || doc.analysis == nil { return nil, occurrence {}, false }
+
+    var occ, ok = doc.analysis.occurrenceAt(doc.offset(params.Position))
+    if !ok || !doc.spells(occ.pos, occ.end, occ.name()) { return nil, occurrence {}, false }
+    return doc, occ, true
+}
+
+func (this *Server) definition(params TextDocumentPositionParams) any {
+    var doc, occ, ok = this.occurrenceAt(params)
+    if !ok || occ.binding == nil { return nil }
+    if !doc.spells(occ.binding.pos, occ.binding.end, occ.binding.name) { return nil }
+    return Location { URI: doc.uri, Range: doc.rangeOf(occ.binding.pos, occ.binding.end) }
+}
+
+func (this *Server) references(params ReferenceParams) any {
+    var doc, occ, ok = this.occurrenceAt(params.TextDocumentPositionParams)
+    if !ok || occ.binding == nil { return []Location {} }
+
+    var locations = []Location {}
+    for _, ref := range doc.analysis.occurrencesOf(occ.binding) {
+        if ref.isDefinition && !params.Context.IncludeDeclaration { continue }
+        if !doc.spells(ref.pos, ref.end, occ.binding.name) { continue }
+        locations = append(locations, Location { URI: doc.uri, Range: doc.rangeOf(ref.pos, ref.end) })
+    }
+    return locations
+}
+
+func (this *Server) hover(params TextDocumentPositionParams) any {
+    var doc, occ, ok = this.occurrenceAt(params)
+    if !ok { return nil }
+
+    var text string
+    switch {
+    case occ.builtin != "":
+        var builtinDoc, _ = evaluator.GetBuiltinDoc(occ.builtin)
+        text = "```monkey\n" + builtinDoc.Signature + "\n```\n" + builtinDoc.Summary
+    case occ.binding.kind == bindingParameter:
+        var owner = "fn"
+        if _, isMacro := occ.binding.function.(*ast.MacroLiteral); isMacro { owner = "macro" }
+        text = "```monkey\n" + occ.binding.name + "\n```\nParameter of `" + owner + " (" + parameterList(occ.binding.function) + ")`"
+    case occ.binding.kind == bindingPattern:
+        text = "```monkey\n" + occ.binding.name + "\n```\nBound by the pattern `" + occ.binding.arm.Pattern.String() + "`"
+    default:
+        text = "```monkey\nlet " + occ.binding.name + " = " + describeValue(occ.binding) + "\n```"
+    }
+
+    var rng = doc.rangeOf(occ.pos, occ.end)
+    return Hover { Contents: MarkupContent { Kind: "markdown", Value: text }, Range: &rng }
+}
+
+func parameterList(fn interface { String() string }) string {
+    var text = fn.String()
+    var start = strings.Index(text, "(")
+    var end = strings.Index(text, ")")
+    if start == -1 || end < start { return "" }
+    return text[start + 1:end]
+}
+
+// A short form of the value of a let, the functions only show their parameters
+func describeValue(bind *binding) string {
+    var value = bind.let.Expression.String()
+    if strings.HasPrefix(value, "fn (") { return "fn (" + parameterList(bind.let.Expression) + ")" }
+    if strings.HasPrefix(value, "macro (") { return "macro (" + parameterList(bind.let.Expression) + ")" }
+    if len(value) > 60 { return value[:57] + "..." }
+    return value
+}
+
+func (this *Server) documentSymbols(params DocumentSymbolParams) any {
+    var doc, found = this.documents[params.TextDocument.URI]
+    if !found || doc.program == nil { return []DocumentSymbol {} }
+    return doc.symbols(doc.program.Statements)
+}
+
+// Identifiers of the document, builtins and keywords that start with the word before the cursor.
+// After a '.' only the methods. The identifiers come from the tokens so it also works while the
+// document does not parse
+func (this *Server) completion(params TextDocumentPositionParams) any {
+    var doc, found = this.documents[params.TextDocument.URI]
+    if !found { return []CompletionItem {} }
+
+    var offset = doc.offset(params.Position)
+    var prefix, start = doc.wordBefore(offset)
+    var items = []CompletionItem {}
+    var seen = map[string] bool {}
+    var add = func (label string, kind int, detail string) {
+        if seen[label] || !strings.HasPrefix(label, prefix) || label == prefix { return }
+        seen[label] = true
+        items = append(items, CompletionItem { Label: label, Kind: kind, Detail: detail })
+    }
+
+    if start > 0 && doc.text[start - 1] == '.' {
+        var names = []string {}
+        for _, objType := range []object.ObjectType { object.ArrayType, object.StringType, object.HashType } {
+            names = append(names, evaluator.MethodNames(objType)...)
+        }
+        sort.Strings(names)
+        for _, name := range names {
+            var builtinDoc, _ = evaluator.GetBuiltinDoc(name)
+            add(name, completionFunction, builtinDoc.Signature)
+        }
+        return items
+    }
+
+    var identifiers = []string {}
+    var lx = lexer.NewLexer(doc.text)
+    for tk := lx.GetNextToken(); tk.Type != token.Eof; tk = lx.GetNextToken() {
+        if tk.Type == token.Ident && tk.End.Offset != offset { identifiers = append(identifiers, tk.Literal) }
+    }
+    sort.Strings(identifiers)
+    for _, name := range identifiers {
+        add(name, completionVariable, "")
+    }
+    for _, name := range evaluator.BuiltinNames() {
+        var builtinDoc, _ = evaluator.GetBuiltinDoc(name)
+        add(name, completionFunction, builtinDoc.Signature)
+    }
+    for _, word := range lexer.Keywords() {
+        add(word, completionKeyword, "")
+    }
+    return items
+}
+
+func (this *Server) formatting(params FormattingParams) any {
+    var doc, found = this.documents[params.TextDocument.URI]
+    if !found { return nil }
+
+    var options = format.DefaultOptions()
+    if params.Options.TabSize > 0 { options.IndentWidth = params.Options.TabSize }
+    options.UseTabs = !params.Options.InsertSpaces
+
+    var formatted, err = format.Source(doc.text, options)
+    if err != nil || formatted == doc.text { return []TextEdit {} }
+
+    var whole = Range { Start: Position {}, End: doc.position(len(doc.text)) }
+    return []TextEdit { { Range: whole, NewText: formatted } }
+}
@@ -0,0 +1,282 @@
+// monkey/lsp/server_test.go
+
+package lsp
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/textproto"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+const testURI = "file:///test.mk"
+
+// Frames the messages as a client would send them
+func frame(messages ...string) string {
+    var out strings.Builder
+    for _, msg := range messages {
+        fmt.Fprintf(&out, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+    }
+    return out.String()
+}
+
+func request(id int, method string, params string) string {
+    return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`, id, method, params)
+}
+
+func notificationMessage(method string, params string) string {
+    return fmt.Sprintf(`{"jsonrpc":"2.0","method":%q,"params":%s}`, method, params)
+}
+
+func openMessage(text string) string {
+    var encoded, _ = json.Marshal(text)
+    return notificationMessage("textDocument/didOpen",
+        fmt.Sprintf(`{"textDocument":{"uri":%q,"version":1,"text":%s}}`, testURI, encoded))
+}
+
+func positionParams(line int, character int) string {
+    return fmt.Sprintf(`{"textDocument":{"uri":%q},"position":{"line":%d,"character":%d}}`, testURI, line, character)
+}
+
+type received struct {
+    ID     *int            `json:"id"`
+    Method string          `json:"method"`
+    Params json.RawMessage `json:"params"`
+    Result json.RawMessage `json:"result"`
+    Error  *responseError  `json:"error"`
+}
+
+// Runs the server over the messages and returns what it wrote and its exit code
+func serve(t *testing.T, messages ...string) ([]received, int) {
+    t.Helper()
+    var out bytes.Buffer
+    var code = NewServer(strings.NewReader(frame(messages...)), &out).Serve()
+
+    var result = []received {}
+    var reader = bufio.NewReader(&out)
+    for {
+        var header, err = textproto.NewReader(reader).ReadMIMEHeader()
+        if err != nil { break }
+        var length, _ = strconv.Atoi(header.Get("Content-Length"))
+        var body = make([]byte, length)
+        if _, err := io.ReadFull(reader, body); err != nil { t.Fatalf("Could not read a response: %s", err) }
+
+        var msg received
+        if err := json.Unmarshal(body, &msg); err != nil { t.Fatalf("Invalid response %s: %s", body, err) }
+        result = append(result, msg)
+    }
+    return result, code
+}
+
+func responseTo(t *testing.T, messages []received, id int, target any) {
+    t.Helper()
+    for _, msg := range messages {
+        if msg.ID == nil || *msg.ID != id { continue }
+        if msg.Error != nil { t.Fatalf("Request %d failed: %s", id, msg.Error.Message) }
+        if err := json.Unmarshal(msg.Result, target); err != nil { t.Fatalf("Invalid result of %d: %s", id, err) }
+        return
+    }
+    t.Fatalf("No response to request %d", id)
+}
+
+func TestLifecycle(t *testing.T) {
+    var messages, code = serve(t,
+        request(1, "initialize", `{"capabilities":{}}`),
+        notificationMessage("initialized", `{}`),
+        request(2, "workspace/unknown", `{}`),
+        notificationMessage("$/unknown", `{}`),
+        request(3, "shutdown", `null`),
+        notificationMessage("exit", `null`),
+    )
+    if code != 0 { t.Errorf("Expected exit code 0 after shutdown but got %d", code) }
+    if len(messages) != 3 { t.Fatalf("Expected 3 responses but got %d", len(messages)) }
+
+    var init struct {
+        Capabilities map[string] any `json:"capabilities"`
+    }
+    responseTo(t, messages, 1, &init)
+    for _, capability := range []string { "definitionProvider", "referencesProvider", "hoverProvider", "documentSymbolProvider", "completionProvider", "documentFormattingProvider" } {
+        if init.Capabilities[capability] == nil { t.Errorf("Expected the capability %s", capability) }
+    }
+    if messages[1].Error == nil || messages[1].Error.Code != codeMethodNotFound {
+        t.Errorf("Expected method not found for an unknown request but got %+v", messages[1].Error)
+    }
+
+    _, code = serve(t, notificationMessage("exit", `null`))
+    if code != 1 { t.Errorf("Expected exit code 1 without shutdown but got %d", code) }
+}
+
+func TestHeaderErrors(t *testing.T) {
+    // Each bad header is answered with an error and the messages after it are still served
+    var input = "Content-Length: -5\r\n\r\n" + "Content-Length: x\r\n\r\n" + "bad header\r\n" +
+        frame(request(1, "initialize", `{"capabilities":{}}`), request(2, "shutdown", `null`), notificationMessage("exit", `null`))
+    var out bytes.Buffer
+    var code = NewServer(strings.NewReader(input), &out).Serve()
+    if code != 0 { t.Errorf("Expected exit code 0 after shutdown but got %d", code) }
+
+    var text = out.String()
+    for _, expected := range []string { "negative Content-Length -5", "Content-Length: strconv.Atoi", "malformed MIME header: missing colon", `"id":1`, `"id":2` } {
+        if !strings.Contains(text, expected) { t.Errorf("Expected %q in the output but got\n%s", expected, text) }
+    }
+}
+
+func TestDiagnostics(t *testing.T) {
+    var messages, _ = serve(t, openMessage("let a = 1;\nlet = 2;"))
+    if len(messages) != 1 || messages[0].Method != "textDocument/publishDiagnostics" {
+        t.Fatalf("Expected the diagnostics to be published but got %+v", messages)
+    }
+
+    var params PublishDiagnosticsParams
+    json.Unmarshal(messages[0].Params, &params)
+    if params.URI != testURI || len(params.Diagnostics) == 0 {
+        t.Fatalf("Expected diagnostics for %s but got %+v", testURI, params)
+    }
+    if params.Diagnostics[0].Range.Start.Line != 1 || params.Diagnostics[0].Severity != severityError {
+        t.Errorf("Expected an error on the second line but got %+v", params.Diagnostics[0])
+    }
+
+    messages, _ = serve(t, openMessage("let a = 1;"))
+    json.Unmarshal(messages[0].Params, &params)
+    if len(params.Diagnostics) != 0 { t.Errorf("Expected no diagnostics but got %+v", params.Diagnostics) }
+}
+
+func TestNavigation(t *testing.T) {
+    var source = "let add = fn (a, b) { a + b };\nlet x = add(1, 2);\nadd(x, len(\"ab\"));"
+    var messages, _ = serve(t,
+        openMessage(source),
+        request(1, "textDocument/definition", positionParams(1, 9)),
+        request(2, "textDocument/references", `{"textDocument":{"uri":"file:///test.mk"},"position":{"line":0,"character":5},"context":{"includeDeclaration":false}}`),
+        request(3, "textDocument/hover", positionParams(2, 8)),
+        request(4, "textDocument/hover", positionParams(0, 23)),
+        request(5, "textDocument/definition", positionParams(2, 8)),
+    )
+
+    var location Location
+    responseTo(t, messages, 1, &location)
+    var expected = Range { Start: Position { 0, 4 }, End: Position { 0, 7 } }
+    if location.URI != testURI || location.Range != expected {
+        t.Errorf("Expected the definition at %+v but got %+v", expected, location)
+    }
+
+    var references []Location
+    responseTo(t, messages, 2, &references)
+    if len(references) != 2 || references[0].Range.Start != (Position { 1, 8 }) || references[1].Range.Start != (Position { 2, 0 }) {
+        t.Errorf("Expected the 2 calls of add as references but got %+v", references)
+    }
+
+    var hover Hover
+    responseTo(t, messages, 3, &hover)
+    if !strings.Contains(hover.Contents.Value, "len(") || hover.Contents.Kind != "markdown" {
+        t.Errorf("Expected the signature of len but got %+v", hover.Contents)
+    }
+    responseTo(t, messages, 4, &hover)
+    if !strings.Contains(hover.Contents.Value, "Parameter") {
+        t.Errorf("Expected the parameter a but got %+v", hover.Contents)
+    }
+
+    for _, msg := range messages {
+        if msg.ID != nil && *msg.ID == 5 && string(msg.Result) != "null" {
+            t.Errorf("Expected no definition for a builtin but got %s", msg.Result)
+        }
+    }
+}
+
+func changeMessage(version int, text string) string {
+    var encoded, _ = json.Marshal(text)
+    return notificationMessage("textDocument/didChange",
+        fmt.Sprintf(`{"textDocument":{"uri":%q,"version":%d},"contentChanges":[{"text":%s}]}`, testURI, version, encoded))
+}
+
+// While the text does not parse the last program that did is used where its code did not change
+func TestNavigationWhileEditing(t *testing.T) {
+    var source = "let add = fn (a, b) { a + b };\nlet x = add(1, 2);\n"
+    var messages, _ = serve(t,
+        openMessage(source),
+        changeMessage(2, source + "let y = add(x,"),
+        request(1, "textDocument/definition", positionParams(1, 9)),
+        request(2, "textDocument/hover", positionParams(0, 23)),
+        changeMessage(3, "let sum = fn (a, b) { a + b };\nlet x = add(1, 2);\nlet y = add(x,"),
+        request(3, "textDocument/definition", positionParams(1, 9)),
+        request(4, "textDocument/hover", positionParams(0, 5)),
+    )
+
+    var location Location
+    responseTo(t, messages, 1, &location)
+    var expected = Range { Start: Position { 0, 4 }, End: Position { 0, 7 } }
+    if location.Range != expected {
+        t.Errorf("Expected the definition at %+v while editing but got %+v", expected, location)
+    }
+
+    var hover Hover
+    responseTo(t, messages, 2, &hover)
+    if !strings.Contains(hover.Contents.Value, "Parameter") {
+        t.Errorf("Expected the parameter a while editing but got %+v", hover.Contents)
+    }
+
+    // The name of the definition was changed, the old analysis does not apply to it anymore
+    for _, msg := range messages {
+        if msg.ID != nil && *msg.ID >= 3 && string(msg.Result) != "null" {
+            t.Errorf("Expected no result on the changed code for %d but got %s", *msg.ID, msg.Result)
+        }
+    }
+}
+
+func TestDocumentSymbols(t *testing.T) {
+    var messages, _ = serve(t,
+        openMessage("let x = 1;\nlet f = fn (a) {\n    let y = a;\n    y\n};\nputs(x);"),
+        request(1, "textDocument/documentSymbol", fmt.Sprintf(`{"textDocument":{"uri":%q}}`, testURI)),
+    )
+
+    var symbols []DocumentSymbol
+    responseTo(t, messages, 1, &symbols)
+    if len(symbols) != 2 { t.Fatalf("Expected 2 symbols but got %+v", symbols) }
+    if symbols[0].Name != "x" || symbols[0].Kind != symbolVariable {
+        t.Errorf("Expected the variable x but got %+v", symbols[0])
+    }
+    if symbols[1].Name != "f" || symbols[1].Kind != symbolFunction || len(symbols[1].Children) != 1 || symbols[1].Children[0].Name != "y" {
+        t.Errorf("Expected the function f with y inside but got %+v", symbols[1])
+    }
+}
+
+func TestCompletion(t *testing.T) {
+    var labels = func (items []CompletionItem) []string {
+        var result = []string {}
+        for _, item := range items { result = append(result, item.Label) }
+        return result
+    }
+
+    var messages, _ = serve(t,
+        openMessage("let counter = 1;\nco\n[1].pu"),
+        request(1, "textDocument/completion", positionParams(1, 2)),
+        request(2, "textDocument/completion", positionParams(2, 6)),
+    )
+
+    var items []CompletionItem
+    responseTo(t, messages, 1, &items)
+    if got := strings.Join(labels(items), " "); got != "counter" {
+        t.Errorf("Expected to complete counter but got %q", got)
+    }
+
+    responseTo(t, messages, 2, &items)
+    if got := strings.Join(labels(items), " "); got != "push" {
+        t.Errorf("Expected to complete the method push but got %q", got)
+    }
+}
+
+func TestFormatting(t *testing.T) {
+    var messages, _ = serve(t,
+        openMessage("let   x=1"),
+        request(1, "textDocument/formatting", fmt.Sprintf(`{"textDocument":{"uri":%q},"options":{"tabSize":4,"insertSpaces":true}}`, testURI)),
+    )
+
+    var edits []TextEdit
+    responseTo(t, messages, 1, &edits)
+    if len(edits) != 1 || edits[0].NewText != "let x = 1;\n" || edits[0].Range.End != (Position { 0, 9 }) {
+        t.Errorf("Expected one edit of the whole document but got %+v", edits)
+    }
+}
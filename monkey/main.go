@@ -4,111 +4,85 @@ package main
 
 import (
     "fmt"
-    "monkey/evaluator"
-    "monkey/lexer"
-    "monkey/object"
-    "monkey/parser"
     "monkey/repl"
     "os"
 )
 
-func debugMain() {
-    // Return Statement
-    // input := "return a + b;"
-
-    // Identifier Expressions
-    // input := "foo;"
-
-    // Precedence
-    // input := "1 + 2 + 3;"
-    // input := "a + b;"
-    // input := "a + b + c;"
-    // input := "a + b + c + d;"
-    // input := "a + b; a + b + c; a + b + c + d;"
-    // input := "a + b * c;"
-    // input := "-a * b"
-    // input := "a + (b * c);"
-
-    // Prefix
-    // input := "-1 + 2;"
-
-    // IfElse
-    // input := "if (foo < bar) { 13 } else { 42 };"
-    // input := "if (foo < bar) { 13 };"
-
-    // function literal
-    // input := "fn (x, y) { x + y; }"
-    // input := "fn (x, y) {}"
-    // input := "fn () {}"
-
-    // Call Expression
-    // input := "add(1, 2 * 3, 4 + 5);"
-
-    // Call Expression Precedence Test
-    // input := "a + add(b * c) + d;" // => "((a + add((b * c))) + d)"
-    // input := "add(b * c);" // => "add((b * c))"
-    // input := "a + add(b * c);" // => "(a + add((b * c)))"
-    // input := "add(b * c) + d;" // => "(add((b * c)) + d)"
-    // input := "add();" // => "add()"
-
-    // Return Statement
-    // input := "return;" // => "return"
-    // input := "return 5;" // => "return 5"
-    // input := "return 5 + 10;" // => "return (5 + 10)"
-
-    // Eval int
-    // input := "5;"
-
-    // Eval Bang
-    // input := "!false;"
-
-    // Eval Return
-    // input := "9; return 3 * 7; 9;"
-    // input := "return 3 * 7;"
-
-    // Eval error
-    // input := "5 + true;"
-
-    // Eval Let Statements
-    // input := "let a = 5; a;"
-
-    // Eval builtin
-    // input := `len("hello")`
-
-    // input := `arr[1 + 1]`
-    input := "myarr.push(666)"
-
-    lexer := lexer.NewLexer(input)
-
-    parser := parser.NewParser(lexer)
-    program := parser.ParseProgram()
-
-    env := object.NewEnvironment()
-    eva := evaluator.Eval(program, env)
-
-    // pro.PrintStatements()
-    // pro.PrintStatements()
-    // s := pro.String()
-    // _ = s
-    // _ = program
+const usage = `Usage:
+    monkey run [flags] <file.mk | -> [args...]    Runs a script file. '-' reads the script from stdin
+    monkey -e [flags] <code> [args...]            Runs the code and prints its result, with the flags of run
+    monkey fmt [flags] [files...]                 Formats the scripts, see 'monkey fmt -h'
+    monkey test [flags] [files...]                Runs the test_ functions of the *_test.mk files
+    monkey vet [flags] [files...]                 Reports the likely mistakes of the scripts, see 'monkey vet -rules'
+    monkey check [files...]                       Type checks the scripts with their annotations
+    monkey parse [flags] <file.mk | ->            Prints the syntax tree of a script, or with -json, -tree or -dot
+    monkey debug [flags] <file.mk> [args...]      Runs a script in the step debugger
+    monkey lsp                                    Starts the language server on stdio
+    monkey dap                                    Starts the debug adapter on stdio
+    monkey lexer | parser | eval                  Starts the REPL of that stage
+    monkey [eval] --session <file.mk>             Starts the eval REPL replaying the saved session
+    monkey                                        Runs the script piped into stdin without the capabilities
+                                                  of the flags, or starts the eval REPL
+
+Run 'monkey run -h' to see the flags of the scripts capabilities
+`
+
+const (
+    exitOk    = 0
+    exitError = 1 // Syntax or runtime errors of the script
+    exitUsage = 2
+)
 
-    _ = eva
+func isStdinPiped() bool {
+    var info, err = os.Stdin.Stat()
+    if err != nil { return false }
+    return info.Mode() & os.ModeCharDevice == 0
 }
 
-func replMain() {
+func main() {
     if len(os.Args) < 2 {
-        fmt.Println("You did not provided the repl type. Add 'lexer', 'parser' or 'eval' as an argument.")
-        os.Exit(0)
+        if isStdinPiped() {
+            os.Exit(runStdin(pipedRunOptions(), []string {}))
+        }
+        repl.Execute("eval")
+        return
     }
-    replType := os.Args[1]
-    repl.Execute(replType)
-}
 
-func main() {
-    const debug = true // Toggle for debugging or to use the repl
-    if debug {
-        debugMain()
-    } else {
-        replMain()
+    var command = os.Args[1]
+    var args = os.Args[2:]
+
+    switch command {
+    case "run":
+        os.Exit(runCommand(args))
+    case "-e":
+        os.Exit(evalCodeCommand(args))
+    case "fmt":
+        os.Exit(fmtCommand(args))
+    case "test":
+        os.Exit(testCommand(args))
+    case "vet":
+        os.Exit(vetCommand(args))
+    case "check":
+        os.Exit(checkCommand(args))
+    case "parse":
+        os.Exit(parseCommand(args))
+    case "debug":
+        os.Exit(debugCommand(args))
+    case "dap":
+        os.Exit(dapCommand(args))
+    case "lsp":
+        os.Exit(lspCommand(args))
+    case "lexer", "parser":
+        repl.Execute(command)
+    case "eval":
+        os.Exit(evalReplCommand(args))
+    case "-session", "--session":
+        os.Exit(evalReplCommand(os.Args[1:]))
+    case "-h", "--help", "help":
+        fmt.Print(usage)
+    default:
+        fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n", command)
+        fmt.Fprint(os.Stderr, usage)
+        os.Exit(exitUsage)
     }
 }
@@ -4,12 +4,17 @@ package object
 
 import (
     "bytes"
+    "sort"
     "strings"
 )
 
+// The names are kept in a map, except the ones the resolver gave a slot in the frame of a
+// function call. Get and Set still find those by their name
 type Environment struct {
     store map[string] Object
     outer *Environment
+    slots []Object
+    frame []string // The name of each slot, shared by all the calls of the function
 }
 
 func NewEnvironment() *Environment {
@@ -25,7 +30,21 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
     return env
 }
 
+// The environment of a call of a resolved function, with a slot for each name of its frame
+func NewFrameEnvironment(outer *Environment, frame []string) *Environment {
+    return &Environment { outer: outer, slots: make([]Object, len(frame)), frame: frame }
+}
+
+func (this *Environment) slotOf(name string) int {
+    for i, slotName := range this.frame {
+        if slotName == name { return i }
+    }
+    return -1
+}
+
 func (this *Environment) Get(name string) (Object, bool) {
+    if slot := this.slotOf(name); slot >= 0 && this.slots[slot] != nil { return this.slots[slot], true }
+
     var val, ok = this.store[name]
     if !ok && this.outer != nil {
         // It will call the outer of outer of outer until its nil and ends
@@ -35,14 +54,49 @@ func (this *Environment) Get(name string) (Object, bool) {
 }
 
 func (this *Environment) Set(name string, val Object) {
+    if slot := this.slotOf(name); slot >= 0 {
+        this.slots[slot] = val
+        return
+    }
+    if this.store == nil { this.store = make(map[string]Object) }
     this.store[name] = val
 }
 
+// Reads the slot of the environment depth levels out. False when the slot is empty, or when that
+// environment has no such slot
+func (this *Environment) GetSlot(depth int, slot int) (Object, bool) {
+    var env = this
+    for ; depth > 0 && env != nil; depth-- {
+        env = env.outer
+    }
+    if env == nil || slot >= len(env.slots) || env.slots[slot] == nil { return nil, false }
+    return env.slots[slot], true
+}
+
+// The enclosing environment or nil for the global one
+func (this *Environment) Outer() *Environment {
+    return this.outer
+}
+
+// Returns the names bound directly in this environment (not the outer ones) sorted
+func (this *Environment) Names() []string {
+    var names = make([]string, 0, len(this.store) + len(this.slots))
+    for name := range this.store {
+        names = append(names, name)
+    }
+    for i, name := range this.frame {
+        if this.slots[i] != nil { names = append(names, name) }
+    }
+    sort.Strings(names)
+    return names
+}
+
 func (this *Environment) String() string {
     var out bytes.Buffer
 
     var items = []string {}
-    for key, value := range this.store {
+    for _, key := range this.Names() {
+        var value, _ = this.Get(key)
         var item string = key + ": " + value.Inspect()
         items = append(items, item)
     }
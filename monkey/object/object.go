@@ -22,6 +22,8 @@ const (
     ArrayType   = "ARRAY_TYPE"
     CharType    = "CHAR_TYPE"
     HashType    = "HASH_TYPE"
+    QuoteType   = "QUOTE_TYPE"
+    MacroType   = "MACRO_TYPE"
 )
 
 type ObjectType string
@@ -115,6 +117,8 @@ func (this *ReturnValue) Type() ObjectType {
 
 type Error struct {
     Message string
+    Exit    bool // Set by the exit builtin, the error unwinds the evaluation to end the program
+    Code    int  // The exit code when Exit is set
 }
 
 // @Impl
@@ -131,6 +135,7 @@ type Function struct {
     Parameters []ast.Identifier
     Body *ast.StatementsBlock
     Env *Environment
+    Frame []string // The slots of the environment of its calls, nil when it was not resolved
 }
 
 // @Impl
@@ -160,7 +165,45 @@ func (this *Function) Type() ObjectType {
     return FuncType
 }
 
-type BuiltinFunction func(args ...Object) Object
+// The tree of an expression that was not evaluated, made by quote. The macros return them
+type Quote struct {
+    Node ast.Node
+}
+
+// @Impl
+func (this *Quote) Inspect() string {
+    return "QUOTE(" + this.Node.String() + ")"
+}
+
+// @Impl
+func (this *Quote) Type() ObjectType {
+    return QuoteType
+}
+
+// A macro defined before the expansion, its parameters are bound to the quoted arguments of a call
+type Macro struct {
+    Parameters []ast.Identifier
+    Body *ast.StatementsBlock
+    Env *Environment
+}
+
+// @Impl
+func (this *Macro) Inspect() string {
+    return (&ast.MacroLiteral { Parameters: this.Parameters, Body: this.Body }).String()
+}
+
+// @Impl
+func (this *Macro) Type() ObjectType {
+    return MacroType
+}
+
+// The side of the evaluator a builtin can call back into, like when map or filter have to apply a
+// user function to each element of an array
+type Runtime interface {
+    Apply(fn Object, args ...Object) Object
+}
+
+type BuiltinFunction func(rt Runtime, args ...Object) Object
 
 type Builtin struct {
     Function BuiltinFunction
@@ -239,6 +282,50 @@ type HashPair struct {
 
 type Hash struct {
     Pairs map[HashKey]HashPair
+    Order []HashKey // Keys in insertion order, so the iteration and the Inspect output are deterministic
+}
+
+func NewHash() *Hash {
+    return &Hash {
+        Pairs: make(map[HashKey]HashPair),
+        Order: []HashKey {},
+    }
+}
+
+// Adds or replaces a pair. Replacing a key keeps its original position
+func (this *Hash) Set(key HashKey, pair HashPair) {
+    if _, exists := this.Pairs[key]; !exists {
+        this.Order = append(this.Order, key)
+    }
+    this.Pairs[key] = pair
+}
+
+func (this *Hash) Get(key HashKey) (HashPair, bool) {
+    var pair, ok = this.Pairs[key]
+    return pair, ok
+}
+
+// Removes the pair and returns false when the key was not found
+func (this *Hash) Delete(key HashKey) bool {
+    if _, exists := this.Pairs[key]; !exists { return false }
+
+    delete(this.Pairs, key)
+    for i, orderKey := range this.Order {
+        if orderKey == key {
+            this.Order = append(this.Order[:i], this.Order[i + 1:]...)
+            break
+        }
+    }
+    return true
+}
+
+// Returns the pairs in insertion order
+func (this *Hash) OrderedPairs() []HashPair {
+    var pairs = make([]HashPair, 0, len(this.Order))
+    for _, key := range this.Order {
+        pairs = append(pairs, this.Pairs[key])
+    }
+    return pairs
 }
 
 // @Impl
@@ -248,7 +335,7 @@ func (this *Hash) Inspect() string {
     if len(this.Pairs) == 0 { return "{}" }
 
     var pairs = []string {}
-    for _, value := range this.Pairs {
+    for _, value := range this.OrderedPairs() {
         var pair = value.OriginalKey.Inspect() + ": " + value.Value.Inspect()
         pairs = append(pairs, pair)
     }
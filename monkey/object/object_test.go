@@ -24,3 +24,60 @@ func TestStringHashKey(t *testing.T) {
         t.Errorf("Strings with different content have the same hash keys")
     }
 }
+
+func TestHashInsertionOrder(t *testing.T) {
+    var hash = NewHash()
+    var keys = []*String { { Value: "c" }, { Value: "a" }, { Value: "b" } }
+    for i, key := range keys {
+        hash.Set(key.HashKey(), HashPair { OriginalKey: key, Value: &Integer { Value: int64(i) } })
+    }
+
+    // Replacing a value keeps the key position
+    hash.Set(keys[0].HashKey(), HashPair { OriginalKey: keys[0], Value: &Integer { Value: 9 } })
+
+    var expected = "{ c: 9, a: 1, b: 2 }"
+    if hash.Inspect() != expected {
+        t.Errorf("Expected hash inspect to be '%s' but got '%s' instead", expected, hash.Inspect())
+    }
+
+    if !hash.Delete(keys[1].HashKey()) {
+        t.Errorf("Expected delete to find the key 'a'")
+    }
+    if hash.Delete(keys[1].HashKey()) {
+        t.Errorf("Expected the second delete of key 'a' to not find it")
+    }
+
+    expected = "{ c: 9, b: 2 }"
+    if hash.Inspect() != expected {
+        t.Errorf("Expected hash inspect to be '%s' but got '%s' instead", expected, hash.Inspect())
+    }
+    if len(hash.Order) != len(hash.Pairs) {
+        t.Errorf("Expected hash order to have %d keys but got %d instead", len(hash.Pairs), len(hash.Order))
+    }
+}
+
+func TestFrameEnvironment(t *testing.T) {
+    var global = NewEnvironment()
+    global.Set("g", &Integer { Value: 1 })
+    var frame = NewFrameEnvironment(global, []string { "a", "b" })
+    var inner = NewFrameEnvironment(frame, []string { "c" })
+
+    frame.Set("a", &Integer { Value: 2 })
+    frame.Set("other", &Integer { Value: 3 }) // Not in the frame, it goes to the map
+    inner.Set("c", &Integer { Value: 4 })
+
+    if value, ok := inner.GetSlot(1, 0); !ok || value.Inspect() != "2" {
+        t.Errorf("Expected the slot of a to be 2 but got %v", value)
+    }
+    if _, ok := inner.GetSlot(1, 1); ok { t.Errorf("Expected the slot of b to be empty") }
+    if _, ok := inner.GetSlot(5, 0); ok { t.Errorf("Expected no slot past the global environment") }
+    if _, ok := global.GetSlot(0, 0); ok { t.Errorf("Expected no slots in the global environment") }
+
+    for name, expected := range map[string] string { "a": "2", "c": "4", "g": "1", "other": "3" } {
+        if value, ok := inner.Get(name); !ok || value.Inspect() != expected {
+            t.Errorf("Expected %s to be %s but got %v", name, expected, value)
+        }
+    }
+    if _, ok := inner.Get("b"); ok { t.Errorf("Expected b to be unbound") }
+    if got := frame.String(); got != "ENV { a: 2, other: 3 }" { t.Errorf("Unexpected names %s", got) }
+}
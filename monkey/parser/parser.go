@@ -5,6 +5,7 @@ package parser
 import (
     "fmt"
     "strconv"
+    "strings"
     "monkey/ast"
     "monkey/lexer"
     "monkey/token"
@@ -34,6 +35,31 @@ var precedences = map[string] int {
     token.Slash:    Product,
     token.Asterisk: Product,
     token.Lparen:   Call,
+    token.Dot:      Call,
+}
+
+// Returns how tight the infix operator binds, Lowest for anything that is not an operator
+func Precedence(operator string) int {
+    if precedence, ok := precedences[operator]; ok { return precedence }
+    return Lowest
+}
+
+// A syntax error with the token where it was found
+type Error struct {
+    Message string
+    Pos     token.Position
+    End     token.Position
+}
+
+// The source does not parse. Returned by the tools that need the whole program, like the
+// formatter and the linter, with the messages of the parser
+type SyntaxError struct {
+    Messages []string
+}
+
+// @Impl
+func (this *SyntaxError) Error() string {
+    return strings.Join(this.Messages, "\n")
 }
 
 type Parser struct {
@@ -41,6 +67,7 @@ type Parser struct {
     curr token.Token
     peek token.Token
     errors []string
+    errorList []Error // The same errors with their positions
 }
 
 func NewParser(lexer *lexer.Lexer) *Parser {
@@ -83,6 +110,10 @@ func (this *Parser) Errors() []string {
     return this.errors
 }
 
+func (this *Parser) ErrorList() []Error {
+    return this.errorList
+}
+
 func (this *Parser) PrintErrors() {
     for i, err := range this.errors {
         fmt.Printf("[%d] %s\n", i, err)
@@ -91,16 +122,39 @@ func (this *Parser) PrintErrors() {
 
 func (this *Parser) addTokenError(tokenType string) {
     err := fmt.Sprintf("Expected token to be %s but got %s instead", tokenType, this.curr.Type)
-    this.errors = append(this.errors, err)
+    this.addError(err)
 }
 
 func (this *Parser) addError(msg string) {
     this.errors = append(this.errors, msg)
+    this.errorList = append(this.errorList, Error { Message: msg, Pos: this.curr.Pos, End: this.curr.End })
+}
+
+// The loops over lists and blocks stop at the end of the input too, so an unclosed one has to be
+// reported instead of looping forever
+func (this *Parser) expectClosing(tokenType string) bool {
+    if this.isCurr(tokenType) { return true }
+    this.addError("Expected " + tokenType + " but reached the end of the input")
+    return false
+}
+
+// Sets the span of the node from the start to the end of the current token, that is the last one
+// of the node when its parsing is done
+func (this *Parser) setSpan(node ast.Node, start token.Position) {
+    if utils.IsNill(node) { return }
+    node.SetSpan(start, this.curr.End)
+}
+
+// Same as setSpan but starting where the first child starts
+func (this *Parser) setSpanFrom(node ast.Node, first ast.Node) {
+    if utils.IsNill(first) { return }
+    this.setSpan(node, first.Pos())
 }
 
 func (this *Parser) parseLetStatement() *ast.LetStatement {
     // Start: Curr is token.LET
     var stm = &ast.LetStatement {}
+    var start = this.curr.Pos
     hasError := false
 
     this.next() // Jumps to the token.IDENT
@@ -112,6 +166,14 @@ func (this *Parser) parseLetStatement() *ast.LetStatement {
     }
     if !hasError {
         stm.Identifier = this.curr.Literal
+        stm.NamePos = this.curr.Pos
+        this.next() // Jumps to token.ASSIGN or to the token.COLON of the type
+    }
+
+    if !hasError && this.isCurr(token.Colon) {
+        this.next() // Jumps to the first token of the type
+        stm.Type = this.parseTypeAnnotation()
+        if stm.Type == nil { hasError = true }
         this.next() // Jumps to token.ASSIGN
     }
 
@@ -123,6 +185,7 @@ func (this *Parser) parseLetStatement() *ast.LetStatement {
     this.next() // Jumps to the first token of the expression
 
     stm.Expression = this.parseExpression(Lowest)
+    this.setSpan(stm, start)
     this.next() // Jumps to the token.SEMICOLON
 
     if hasError { return nil }
@@ -133,10 +196,12 @@ func (this *Parser) parseLetStatement() *ast.LetStatement {
 func (this *Parser) parseReturnStatement() *ast.ReturnStatement {
     // Start: Curr is token.RETURN
     var stm = &ast.ReturnStatement {}
+    var start = this.curr.Pos
 
     this.next()
 
     stm.Expression = this.parseExpression(Lowest)
+    this.setSpan(stm, start)
     this.next() // Jumps to the token.SEMICOLON
 
     return stm
@@ -149,52 +214,62 @@ func (this *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
     this.next() // Jumps inside the brackets so the expr is not viewed as an array
     indexExpr.Index = this.parseExpression(Lowest)
     this.next() // Jumps to the token.Rbracket
+    this.setSpanFrom(indexExpr, left)
     return indexExpr
 }
 
-func (this *Parser) parseMethodExpression(expr ast.Expression) ast.Expression {
+// <receiver>.<name>(<arguments>). The '.' is an infix with the precedence of a call, so any
+// expression can be the receiver and the calls chain from left to right: [1, 2].map(f).len()
+func (this *Parser) parseMethodExpression(receiver ast.Expression) ast.Expression {
+    // Start: Curr is token.Dot
     var methExpr = &ast.MethodExpression {}
+    methExpr.Expression = receiver
 
-    // Left value
-    methExpr.Expression = expr
-
-    // Separator
-    this.next() // Jumps to the token.Dot
+    if !this.isPeek(token.Ident) {
+        this.addError("Expected the method name after the '.' but got " + this.peek.Type + " instead")
+        return nil
+    }
+    this.next() // Jumps to the method name
+    var name = &ast.Identifier { Value: this.curr.Literal }
+    this.setSpan(name, this.curr.Pos)
 
-    // Right value
-    this.next() // Jumps to the first token of the call expression
-    var right = this.parseExpression(Lowest)
-    var callExpr, ok = right.(*ast.CallExpression)
-    if !ok {
+    if !this.isPeek(token.Lparen) {
         this.addError("The right value of the method expression is not a call expression")
         return nil
     }
+    this.next() // Jumps to the token.Lparen
+
+    var callExpr, ok = this.parseCallExpression(name).(*ast.CallExpression)
+    if !ok { return nil }
     methExpr.Call = callExpr
+    this.setSpanFrom(methExpr, receiver)
 
     return methExpr
 }
 
 func (this *Parser) parsePrefixOrSymbol() ast.Expression {
+    var start = this.curr.Pos
+
     switch this.curr.Type {
     case token.Bang, token.Minus:
         var pre = &ast.PrefixExpression {}
         pre.Operator = this.curr.Literal
         this.next()
         pre.Value = this.parseExpression(Prefix)
+        this.setSpan(pre, start)
         return pre
     case token.True, token.False:
-        return &ast.Boolean { Value: this.isCurr(token.True) } // Easy convert to bool trick :D
+        var boolean = &ast.Boolean { Value: this.isCurr(token.True) } // Easy convert to bool trick :D
+        this.setSpan(boolean, start)
+        return boolean
     case token.Ident:
         var identifier = &ast.Identifier { Value: this.curr.Literal }
+        this.setSpan(identifier, start)
 
         if this.isPeek(token.Lbracket) {
             return this.parseIndexExpression(identifier)
         }
 
-        if this.isPeek(token.Dot) {
-            return this.parseMethodExpression(identifier)
-        }
-
         return identifier
 
     case token.Int:
@@ -202,9 +277,13 @@ func (this *Parser) parsePrefixOrSymbol() ast.Expression {
         if err != nil {
             this.addError("Could not convert current token literal to int64")
         }
-        return &ast.IntegerLiteral { Value: intValue }
+        var integer = &ast.IntegerLiteral { Value: intValue }
+        this.setSpan(integer, start)
+        return integer
     case token.String:
-        return &ast.StringLiteral { Value: this.curr.Literal }
+        var str = &ast.StringLiteral { Value: this.curr.Literal }
+        this.setSpan(str, start)
+        return str
     case token.Lparen:
         this.next() // Jumps the token.LPAREN
         var exp = this.parseExpression(Lowest)
@@ -213,18 +292,21 @@ func (this *Parser) parsePrefixOrSymbol() ast.Expression {
             return nil
         }
         this.next() // Jumps the token.RPAREN
+        this.setSpan(exp, start) // The parens are part of the grouped expression
         return exp
     case token.Lbracket:
         var array = &ast.ArrayLiteral {}
         array.Elements = []ast.Expression {}
 
         this.next()
-        for !this.isCurr(token.Rbracket) {
+        for !this.isCurr(token.Rbracket) && !this.isCurr(token.Eof) {
             var elem = this.parseExpression(Lowest)
             array.Elements = append(array.Elements, elem)
             this.next()
             if this.isCurr(token.Comma) { this.next() }
         }
+        if !this.expectClosing(token.Rbracket) { return nil }
+        this.setSpan(array, start)
 
         if !this.isPeek(token.Lbracket) {
             return array
@@ -233,12 +315,11 @@ func (this *Parser) parsePrefixOrSymbol() ast.Expression {
         return this.parseIndexExpression(array)
 
     case token.Lbrace:
-        var hash = &ast.HashLiteral {}
-        hash.Pairs = make(map[ast.Expression] ast.Expression)
+        var hash = ast.NewHashLiteral()
 
         this.next() // Jumps to the first token inside the hash or the token.Rbrace
 
-        for !this.isCurr(token.Rbrace) {
+        for !this.isCurr(token.Rbrace) && !this.isCurr(token.Eof) {
             var key = this.parseExpression(Lowest)
 
             if !this.isPeek(token.Colon) {
@@ -251,11 +332,13 @@ func (this *Parser) parsePrefixOrSymbol() ast.Expression {
 
             var value = this.parseExpression(Lowest)
 
-            hash.Pairs[key] = value
+            hash.Set(key, value)
 
             this.next() // Normal iteration
             if this.isCurr(token.Comma) { this.next() } // If has next pair
         }
+        if !this.expectClosing(token.Rbrace) { return nil }
+        this.setSpan(hash, start)
 
         if !this.isPeek(token.Lbracket) { return hash }
 
@@ -267,6 +350,12 @@ func (this *Parser) parsePrefixOrSymbol() ast.Expression {
     case token.Function:
         return this.parseFunctionLiteral()
 
+    case token.Macro:
+        return this.parseMacroLiteral()
+
+    case token.Match:
+        return this.parseMatchExpression()
+
     default:
         this.addError("Invalid or not covered symbol or prefix to parse: " + this.curr.Type)
         return nil
@@ -275,6 +364,7 @@ func (this *Parser) parsePrefixOrSymbol() ast.Expression {
 
 func (this *Parser) parseIfExpression() ast.Expression {
     // Start: Curr is token.IF
+    var start = this.curr.Pos
     if !this.isPeek(token.Lparen) {
         this.addError("Expected token.LPAREN but got " + this.peek.Type + " instead")
         return nil
@@ -297,6 +387,7 @@ func (this *Parser) parseIfExpression() ast.Expression {
         return nil
     }
     this.next() // Jumps to token.LBRACE
+    var consequenceStart = this.curr.Pos
 
     this.next() // Jumps to the first token in the consequence block
 
@@ -307,11 +398,16 @@ func (this *Parser) parseIfExpression() ast.Expression {
         if this.isCurr(token.Semicolon) { this.next() } // Jumps the semicolon
     }
     exp.ConsequenceBlock = &ast.StatementsBlock { Statements: consequences }
+    this.setSpan(exp.ConsequenceBlock, consequenceStart)
 
-    if !this.isPeek(token.Else) { return exp }
+    if !this.isPeek(token.Else) {
+        this.setSpan(exp, start)
+        return exp
+    }
 
     this.next() // Jumps to token.ELSE
     this.next() // Jumps to token.LBRACE
+    var alternativeStart = this.curr.Pos
     this.next() // Jumps to the first token in the alternative block
 
     var alternatives = []ast.Statement {}
@@ -321,12 +417,15 @@ func (this *Parser) parseIfExpression() ast.Expression {
         if this.isCurr(token.Semicolon) { this.next() } // Jumps the semicolon
     }
     exp.AlternativeBlock = &ast.StatementsBlock { Statements: alternatives }
+    this.setSpan(exp.AlternativeBlock, alternativeStart)
+    this.setSpan(exp, start)
 
     return exp
 }
 
 func (this *Parser) parseFunctionLiteral() ast.Expression {
     // Start: Curr is token.FUNCTION
+    var start = this.curr.Pos
     if !this.isPeek(token.Lparen) {
         this.addError("Expected token.LPAREN but got " + this.peek.Type + " instead")
         return nil
@@ -334,34 +433,325 @@ func (this *Parser) parseFunctionLiteral() ast.Expression {
     this.next() // Jumps to the token.LPAREN
 
     var funLiteral = &ast.FunctionLiteral {}
-    funLiteral.Parameters = []ast.Identifier {}
+    funLiteral.Parameters = this.parseParameters()
+    if funLiteral.Parameters == nil { return nil }
+
+    if this.isPeek(token.Arrow) {
+        this.next() // Jumps to the token.ARROW
+        this.next() // Jumps to the first token of the return type
+        funLiteral.ReturnType = this.parseTypeAnnotation()
+        if funLiteral.ReturnType == nil { return nil }
+    }
 
-    this.next() // Jumps to the first token of the function arguments or the right paren if none
+    if !this.isPeek(token.Lbrace) {
+        this.addError("Expected token.LBRACE but got " + this.peek.Type + " instead")
+        return nil
+    }
+    this.next() // Jumps to the token.LBRACE
+    var bodyStart = this.curr.Pos
+
+    this.next() // Jumps to the first token in the function body
 
-    for !this.isCurr(token.Rparen) { // Parse function args
+    var body = []ast.Statement {}
+    for !this.isCurr(token.Rbrace) && !this.isCurr(token.Eof) {
+        var stm = this.parseStatement()
+        body = append(body, stm)
+        if this.isCurr(token.Semicolon) { this.next() } // Jumps the semicolon
+    }
+    if !this.expectClosing(token.Rbrace) { return nil }
+    funLiteral.Body = &ast.StatementsBlock { Statements: body }
+    this.setSpan(funLiteral.Body, bodyStart)
+    this.setSpan(funLiteral, start)
+
+    return funLiteral
+}
+
+// Parses the parameters of a function or a macro, each with its optional type. Starts on the
+// token.LPAREN and ends on the token.RPAREN, returns nil on errors
+func (this *Parser) parseParameters() []ast.Identifier {
+    var params = []ast.Identifier {}
+
+    this.next() // Jumps to the first token of the arguments or the right paren if none
+
+    for !this.isCurr(token.Rparen) && !this.isCurr(token.Eof) {
         var iden = ast.Identifier { Value: this.curr.Literal }
-        funLiteral.Parameters = append(funLiteral.Parameters, iden)
+        iden.SetSpan(this.curr.Pos, this.curr.End)
+        if this.isPeek(token.Colon) {
+            this.next() // Jumps to the token.COLON
+            this.next() // Jumps to the first token of the type
+            iden.Type = this.parseTypeAnnotation()
+            if iden.Type == nil { return nil }
+        }
+        params = append(params, iden)
         this.next()
         if this.isCurr(token.Comma) { this.next() }
     }
+    if !this.expectClosing(token.Rparen) { return nil }
+    return params
+}
+
+func (this *Parser) parseMacroLiteral() ast.Expression {
+    // Start: Curr is token.MACRO
+    var start = this.curr.Pos
+    if !this.isPeek(token.Lparen) {
+        this.addError("Expected token.LPAREN but got " + this.peek.Type + " instead")
+        return nil
+    }
+    this.next() // Jumps to the token.LPAREN
+
+    var macro = &ast.MacroLiteral {}
+    macro.Parameters = this.parseParameters()
+    if macro.Parameters == nil { return nil }
 
     if !this.isPeek(token.Lbrace) {
         this.addError("Expected token.LBRACE but got " + this.peek.Type + " instead")
         return nil
     }
     this.next() // Jumps to the token.LBRACE
+    var bodyStart = this.curr.Pos
 
-    this.next() // Jumps to the first token in the function body
+    this.next() // Jumps to the first token in the macro body
 
     var body = []ast.Statement {}
-    for !this.isCurr(token.Rbrace) {
+    for !this.isCurr(token.Rbrace) && !this.isCurr(token.Eof) {
         var stm = this.parseStatement()
         body = append(body, stm)
         if this.isCurr(token.Semicolon) { this.next() } // Jumps the semicolon
     }
-    funLiteral.Body = &ast.StatementsBlock { Statements: body }
+    if !this.expectClosing(token.Rbrace) { return nil }
+    macro.Body = &ast.StatementsBlock { Statements: body }
+    this.setSpan(macro.Body, bodyStart)
+    this.setSpan(macro, start)
 
-    return funLiteral
+    return macro
+}
+
+func (this *Parser) parseMatchExpression() ast.Expression {
+    // Start: Curr is token.MATCH
+    var start = this.curr.Pos
+    if !this.isPeek(token.Lparen) {
+        this.addError("Expected token.LPAREN but got " + this.peek.Type + " instead")
+        return nil
+    }
+    this.next() // Jumps to the token.LPAREN
+    this.next() // Jumps to the first token of the value
+
+    var match = &ast.MatchExpression {}
+    match.Value = this.parseExpression(Lowest)
+    if utils.IsNill(match.Value) { return nil }
+
+    if !this.isPeek(token.Rparen) {
+        this.addError("Expected token.RPAREN but got " + this.peek.Type + " instead")
+        return nil
+    }
+    this.next() // Jumps to the token.RPAREN
+
+    if !this.isPeek(token.Lbrace) {
+        this.addError("Expected token.LBRACE but got " + this.peek.Type + " instead")
+        return nil
+    }
+    this.next() // Jumps to the token.LBRACE
+    this.next() // Jumps to the first token of the first arm
+
+    match.Arms = []*ast.MatchArm {}
+    for !this.isCurr(token.Rbrace) && !this.isCurr(token.Eof) {
+        var arm = this.parseMatchArm()
+        if arm == nil { return nil }
+        match.Arms = append(match.Arms, arm)
+        this.next()
+        if this.isCurr(token.Comma) { this.next() } // The last arm can have a comma too
+    }
+    if !this.expectClosing(token.Rbrace) { return nil }
+    if len(match.Arms) == 0 {
+        this.addError("A match needs at least one arm")
+        return nil
+    }
+    this.setSpan(match, start)
+
+    return match
+}
+
+// Parses pattern [if guard] => result. Ends on the last token of the result
+func (this *Parser) parseMatchArm() *ast.MatchArm {
+    var start = this.curr.Pos
+    var arm = &ast.MatchArm {}
+    arm.Pattern = this.parsePattern()
+    if arm.Pattern == nil { return nil }
+
+    if this.isPeek(token.If) {
+        this.next() // Jumps to the token.IF
+        this.next() // Jumps to the first token of the guard
+        arm.Guard = this.parseExpression(Lowest)
+        if utils.IsNill(arm.Guard) { return nil }
+    }
+
+    if !this.isPeek(token.FatArrow) {
+        this.addError("Expected => after the pattern but got " + this.peek.Type + " instead")
+        return nil
+    }
+    this.next() // Jumps to the token.FATARROW
+    this.next() // Jumps to the first token of the result
+
+    arm.Body = this.parseExpression(Lowest)
+    if utils.IsNill(arm.Body) { return nil }
+    this.setSpan(arm, start)
+
+    return arm
+}
+
+// Parses a pattern of an arm, ends on its last token. Returns nil on errors
+func (this *Parser) parsePattern() ast.Pattern {
+    var start = this.curr.Pos
+
+    switch this.curr.Type {
+    case token.Int, token.String, token.True, token.False, token.Minus:
+        var literal = this.parsePatternLiteral()
+        if literal == nil { return nil }
+        var pattern = &ast.LiteralPattern { Value: literal }
+        this.setSpan(pattern, start)
+        return pattern
+    case token.Ident:
+        var pattern ast.Pattern = &ast.BindingPattern { Name: this.curr.Literal }
+        if this.curr.Literal == "_" { pattern = &ast.WildcardPattern {} }
+        this.setSpan(pattern, start)
+        return pattern
+    case token.Lbracket:
+        var array = &ast.ArrayPattern { Elements: []ast.Pattern {} }
+
+        this.next() // Jumps to the first token of the elements or the token.RBRACKET
+        for !this.isCurr(token.Rbracket) && !this.isCurr(token.Eof) {
+            if this.isCurr(token.Ellipsis) {
+                this.next() // Jumps to the name of the rest
+                if !this.isCurr(token.Ident) {
+                    this.addError("Expected a name or _ after ... but got " + this.curr.Type + " instead")
+                    return nil
+                }
+                array.Rest = this.parsePattern()
+                if !this.isPeek(token.Rbracket) {
+                    this.addError("The ...rest must be the last element of an array pattern")
+                    return nil
+                }
+                this.next() // Jumps to the token.RBRACKET
+                break
+            }
+
+            var element = this.parsePattern()
+            if element == nil { return nil }
+            array.Elements = append(array.Elements, element)
+            this.next()
+            if this.isCurr(token.Comma) { this.next() }
+        }
+        if !this.expectClosing(token.Rbracket) { return nil }
+        this.setSpan(array, start)
+        return array
+    case token.Lbrace:
+        var hash = &ast.HashPattern { Keys: []ast.Expression {}, Values: []ast.Pattern {} }
+
+        this.next() // Jumps to the first key or the token.RBRACE
+        for !this.isCurr(token.Rbrace) && !this.isCurr(token.Eof) {
+            var key = this.parsePatternLiteral()
+            if key == nil { return nil }
+
+            if !this.isPeek(token.Colon) {
+                this.addError(fmt.Sprintf("Expected ':' after hash key but found '%s' instead", this.peek.Literal))
+                return nil
+            }
+            this.next() // Jumps to the token.COLON
+            this.next() // Jumps to the first token of the pattern of the value
+
+            var value = this.parsePattern()
+            if value == nil { return nil }
+            hash.Keys = append(hash.Keys, key)
+            hash.Values = append(hash.Values, value)
+
+            this.next()
+            if this.isCurr(token.Comma) { this.next() }
+        }
+        if !this.expectClosing(token.Rbrace) { return nil }
+        this.setSpan(hash, start)
+        return hash
+    default:
+        this.addError("Invalid pattern: " + this.curr.Type)
+        return nil
+    }
+}
+
+// An integer, with its minus when it is negative, a string or a boolean
+func (this *Parser) parsePatternLiteral() ast.Expression {
+    var start = this.curr.Pos
+    var literal ast.Expression
+
+    switch this.curr.Type {
+    case token.Minus:
+        if !this.isPeek(token.Int) {
+            this.addError("Expected an integer after the - of a pattern but got " + this.peek.Type + " instead")
+            return nil
+        }
+        this.next() // Jumps to the token.INT
+        var integer, isInteger = this.parsePrefixOrSymbol().(*ast.IntegerLiteral)
+        if !isInteger { return nil }
+        integer.Value = -integer.Value
+        literal = integer
+    case token.Int, token.String, token.True, token.False:
+        literal = this.parsePrefixOrSymbol()
+    default:
+        this.addError("Invalid pattern literal: " + this.curr.Type)
+        return nil
+    }
+    this.setSpan(literal, start)
+    return literal
+}
+
+// Parses a type: a name, a name with arguments like hash<string, int>, a bare fn or a function type
+// like fn(int, int) -> bool. Ends on the last token of the type
+func (this *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+    var start = this.curr.Pos
+    var annotation = &ast.TypeAnnotation { Name: this.curr.Literal }
+
+    switch this.curr.Type {
+    case token.Function:
+        annotation.Name = "fn"
+        if !this.isPeek(token.Lparen) { break } // Any function
+
+        this.next() // Jumps to the token.LPAREN
+        this.next() // Jumps to the first parameter type or the token.RPAREN
+        annotation.Arguments = []*ast.TypeAnnotation {}
+        for !this.isCurr(token.Rparen) && !this.isCurr(token.Eof) {
+            var param = this.parseTypeAnnotation()
+            if param == nil { return nil }
+            annotation.Arguments = append(annotation.Arguments, param)
+            this.next()
+            if this.isCurr(token.Comma) { this.next() }
+        }
+        if !this.expectClosing(token.Rparen) { return nil }
+
+        if this.isPeek(token.Arrow) {
+            this.next() // Jumps to the token.ARROW
+            this.next() // Jumps to the first token of the return type
+            annotation.Return = this.parseTypeAnnotation()
+            if annotation.Return == nil { return nil }
+        }
+    case token.Ident:
+        if !this.isPeek(token.Lt) { break }
+
+        this.next() // Jumps to the '<'
+        this.next() // Jumps to the first type argument
+        annotation.Arguments = []*ast.TypeAnnotation {}
+        for !this.isCurr(token.Gt) && !this.isCurr(token.Eof) {
+            var arg = this.parseTypeAnnotation()
+            if arg == nil { return nil }
+            annotation.Arguments = append(annotation.Arguments, arg)
+            this.next()
+            if this.isCurr(token.Comma) { this.next() }
+        }
+        if !this.expectClosing(token.Gt) { return nil }
+    default:
+        this.addError("Expected a type but got " + this.curr.Type + " instead")
+        return nil
+    }
+
+    this.setSpan(annotation, start)
+    return annotation
 }
 
 func (this *Parser) makeInfix(left ast.Expression) ast.Expression {
@@ -372,6 +762,7 @@ func (this *Parser) makeInfix(left ast.Expression) ast.Expression {
     var precedence = this.currPrecedence()
     this.next() // Curr to next value
     inf.Right = this.createNewInfixGroup(precedence)
+    this.setSpanFrom(inf, left)
     return inf
 }
 
@@ -383,12 +774,14 @@ func (this *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
     this.next() // Jumps to token.RPAREN or to first token of parameters
 
     callExp.Parameters = []ast.Expression {}
-    for !this.isCurr(token.Rparen) {
+    for !this.isCurr(token.Rparen) && !this.isCurr(token.Eof) {
         var exp = this.parseExpression(Lowest)
         callExp.Parameters = append(callExp.Parameters, exp)
         this.next()
         if this.isCurr(token.Comma) { this.next() }
     }
+    if !this.expectClosing(token.Rparen) { return nil }
+    this.setSpanFrom(callExp, fn)
 
     return callExp
 }
@@ -399,6 +792,8 @@ func (this *Parser) parseInfix(expression ast.Expression) ast.Expression {
         return this.makeInfix(expression)
     case token.Lparen:
         return this.parseCallExpression(expression)
+    case token.Dot:
+        return this.parseMethodExpression(expression)
     default:
         this.addError("Invalid or not covered symbol for infix parse: " + this.curr.Type)
         return nil
@@ -427,7 +822,9 @@ func (this *Parser) parseExpression(precedence int) ast.Expression {
 
 func (this *Parser) parseExpressionStatement() *ast.ExpressionStatement {
     stm := &ast.ExpressionStatement {}
+    var start = this.curr.Pos
     stm.Expression = this.parseExpression(Lowest)
+    this.setSpan(stm, start)
     this.next()
     return stm
 }
@@ -467,5 +864,6 @@ func (this *Parser) ParseProgram() *ast.Program {
         program.Statements = append(program.Statements, stm)
         this.next() // Jumps the semicolon
     }
+    program.SetSpan(this.lex.PositionAt(0), this.curr.End)
     return program
 }
@@ -366,6 +366,96 @@ func TestParsingFunctionLiterals(t *testing.T) {
     // program.PrintStatements()
 }
 
+func TestParsingMacroLiterals(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "let m = macro () {}",                           "let m = macro () {}"                          },
+        { "let m = macro (x, y) { quote(unquote(x) + y) }", "let m = macro (x, y) { quote((unquote(x) + y)) }" },
+        { "macro (x) { let y = x; y }",                    "macro (x) { let y = x y }"                    },
+    }
+
+    for _, test := range tests {
+        var parser = NewParser(lexer.NewLexer(test.input))
+        var program = parser.ParseProgram()
+        checkParserErrors(t, parser)
+        if got := program.Statements[0].String(); got != test.expected {
+            t.Errorf("Expected '%s' to be parsed as '%s' but got '%s'", test.input, test.expected, got)
+        }
+    }
+
+    var invalid = []string { "macro x { x }", "macro (x) x", "macro (x { x }" }
+    for _, input := range invalid {
+        var parser = NewParser(lexer.NewLexer(input))
+        parser.ParseProgram()
+        if len(parser.Errors()) == 0 { t.Errorf("Expected errors parsing '%s'", input) }
+    }
+}
+
+func TestParsingMatchExpressions(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "match (x) { 1 => a }",                            "match (x) { 1 => a }"                          },
+        { "match (x + 1) { -1 => a, \"s\" => b, true => c, }", "match ((x + 1)) { -1 => a, s => b, true => c }" },
+        { "match (x) { _ => 0, y => y }",                    "match (x) { _ => 0, y => y }"                  },
+        { "match (xs) { [] => 0, [a, ...rest] => a }",       "match (xs) { [] => 0, [a, ...rest] => a }"     },
+        { "match (xs) { [[a], ..._] if a > 0 => a }",        "match (xs) { [[a], ..._] if (a > 0) => a }"    },
+        { "match (h) { { \"k\": [v], 1: _ } => v, {} => 0 }",  "match (h) { { k: [v], 1: _ } => v, {} => 0 }"  },
+        { "let y = match (x) { n => n * 2 }.len()",          "let y = match (x) { n => (n * 2) }.len()"      },
+    }
+
+    for _, test := range tests {
+        var parser = NewParser(lexer.NewLexer(test.input))
+        var program = parser.ParseProgram()
+        checkParserErrors(t, parser)
+        if got := program.Statements[0].String(); got != test.expected {
+            t.Errorf("Expected '%s' to be parsed as '%s' but got '%s'", test.input, test.expected, got)
+        }
+    }
+
+    var invalid = []string {
+        "match x { 1 => a }", "match (x) 1 => a", "match (x) {}", "match (x) { 1 a }", "match (x) { 1 => a",
+        "match (x) { x + 1 => a }", "match (x) { [...r, a] => a }", "match (x) { [...1] => a }",
+        "match (x) { { k: 1 } => a }", "match (x) { - a => a }", "match (x) { fn () {} => a }",
+    }
+    for _, input := range invalid {
+        var parser = NewParser(lexer.NewLexer(input))
+        parser.ParseProgram()
+        if len(parser.Errors()) == 0 { t.Errorf("Expected errors parsing '%s'", input) }
+    }
+}
+
+func TestParsingTypeAnnotations(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "let x: int = 1",                                "let x: int = 1"                         },
+        { "let xs: array<array<string>> = []",             "let xs: array<array<string>> = []"      },
+        { "let h: hash<string, int> = {}",                 "let h: hash<string, int> = {}"          },
+        { "fn (x: int, y) -> bool { x }",                  "fn (x: int, y) -> bool { x }"           },
+        { "let f: fn(int, int) -> fn() -> int = g",        "let f: fn(int, int) -> fn() -> int = g" },
+        { "let f: fn = g",                                 "let f: fn = g"                          },
+        { "fn (f: fn(int)) { f }",                         "fn (f: fn(int)) { f }"                  },
+    }
+
+    for _, test := range tests {
+        var parser = NewParser(lexer.NewLexer(test.input))
+        var program = parser.ParseProgram()
+        checkParserErrors(t, parser)
+        if got := program.Statements[0].String(); got != test.expected {
+            t.Errorf("Expected '%s' to be parsed as '%s' but got '%s'", test.input, test.expected, got)
+        }
+    }
+
+    var invalid = []string { "let x: = 1", "fn (x: 1) { x }", "fn () -> { 1 }", "let xs: array<int = 1" }
+    for _, input := range invalid {
+        var parser = NewParser(lexer.NewLexer(input))
+        parser.ParseProgram()
+        if len(parser.Errors()) == 0 { t.Errorf("Expected errors parsing '%s'", input) }
+    }
+}
+
 func TestParsingCallExpressions(t *testing.T) {
     var input = []string {
         "add(1, 2 * 3, 4 + 5)",
@@ -674,3 +764,95 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
         testInfixExpression(t, valueInfix, left, operator, right)
     }
 }
+
+func TestParsingHashLiteralKeepsKeyOrder(t *testing.T) {
+    var input = `{ "c": 1, "a": 2, "b": 3 }`
+    var lexer = lexer.NewLexer(input)
+    var parser = NewParser(lexer)
+    var program = parser.ParseProgram()
+
+    checkParserErrors(t, parser)
+
+    var expected = "{ c: 1, a: 2, b: 3 }"
+    if program.Statements[0].String() != expected {
+        t.Errorf("Expected hash literal to be '%s' but got '%s' instead", expected, program.Statements[0].String())
+    }
+}
+
+func TestParsingUnclosedInputReportsError(t *testing.T) {
+    var inputs = []string {
+        "fn (x) { x",
+        "fn (x",
+        "[1, 2",
+        "add(1, 2",
+        "{ 1: 2",
+        "let add = fn (a, b) { a",
+    }
+
+    for _, input := range inputs {
+        var lexer = lexer.NewLexer(input)
+        var parser = NewParser(lexer)
+        parser.ParseProgram()
+
+        if len(parser.Errors()) == 0 {
+            t.Errorf("Expected parser errors for unclosed input '%s' but got none", input)
+        }
+    }
+}
+
+func TestParsingMethodChains(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "a.len()",                      "a.len()"                      },
+        { "[1, 2].push(3)",               "[1, 2].push(3)"               },
+        { "a.sort().reverse()",           "a.sort().reverse()"           },
+        { "a.map(f).len() + 1",           "(a.map(f).len() + 1)"         },
+        { `"abc".len() * 2`,              "(abc.len() * 2)"              },
+    }
+
+    for _, test := range tests {
+        var parser = NewParser(lexer.NewLexer(test.input))
+        var program = parser.ParseProgram()
+        checkParserErrors(t, parser)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("[%s] Expected 1 statement but got %d instead", test.input, len(program.Statements))
+        }
+        if program.Statements[0].String() != test.expected {
+            t.Errorf("Expected statement to be '%s' but got '%s' instead", test.expected, program.Statements[0].String())
+        }
+    }
+}
+
+func TestParsingNodeSpans(t *testing.T) {
+    var input = "let add = fn (a, b) {\n    a + b\n};\nadd(1, (2 * 3))"
+    var parser = NewParser(lexer.NewLexer(input))
+    var program = parser.ParseProgram()
+    checkParserErrors(t, parser)
+
+    var let = program.Statements[0].(*ast.LetStatement)
+    var fn = let.Expression.(*ast.FunctionLiteral)
+    var sum = fn.Body.Statements[0].(*ast.ExpressionStatement).Expression
+    var call = program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+
+    var tests = []struct {
+        name string; node ast.Node; pos string; end string
+    } {
+        { "let",       let,                   "1:1",  "3:2"  },
+        { "fn",        fn,                    "1:11", "3:2"  },
+        { "parameter", &fn.Parameters[1],     "1:18", "1:19" },
+        { "body",      fn.Body,               "1:21", "3:2"  },
+        { "infix",     sum,                   "2:5",  "2:10" },
+        { "call",      call,                  "4:1",  "4:16" },
+        { "grouped",   call.Parameters[1],    "4:8",  "4:15" },
+        { "program",   program,               "1:1",  "4:16" },
+    }
+
+    for _, test := range tests {
+        if test.node.Pos().String() != test.pos || test.node.End().String() != test.end {
+            t.Errorf("Expected the %s to be at %s-%s but got %s-%s instead",
+                test.name, test.pos, test.end, test.node.Pos(), test.node.End())
+        }
+    }
+}
@@ -0,0 +1,196 @@
+// monkey/profiler/pprof.go
+/*
+    Writes the samples in the format of pprof (a gzipped profile.proto), so they can be read with
+    'go tool pprof'. The protocol buffer is encoded by hand, the messages are few and small
+*/
+
+package profiler
+
+import (
+    "compress/gzip"
+    "io"
+    "sort"
+)
+
+// Field numbers of the messages of profile.proto
+const (
+    profileSampleType    = 1
+    profileSample        = 2
+    profileLocation      = 4
+    profileFunction      = 5
+    profileStringTable   = 6
+    profileTimeNanos     = 9
+    profileDurationNanos = 10
+    profilePeriodType    = 11
+    profilePeriod        = 12
+
+    valueTypeType = 1
+    valueTypeUnit = 2
+
+    sampleLocationID = 1
+    sampleValue      = 2
+
+    locationID   = 1
+    locationLine = 4
+
+    lineFunctionID = 1
+    lineLine       = 2
+
+    functionID         = 1
+    functionName       = 2
+    functionSystemName = 3
+    functionFilename   = 4
+    functionStartLine  = 5
+)
+
+const (
+    wireVarint = 0
+    wireBytes  = 2
+)
+
+type protoBuffer struct {
+    data []byte
+}
+
+func (this *protoBuffer) varint(value uint64) {
+    for value >= 0x80 {
+        this.data = append(this.data, byte(value) | 0x80)
+        value >>= 7
+    }
+    this.data = append(this.data, byte(value))
+}
+
+func (this *protoBuffer) key(field int, wire int) {
+    this.varint(uint64(field << 3 | wire))
+}
+
+// The zero values are left out, as protobuf does
+func (this *protoBuffer) int64Field(field int, value int64) {
+    if value == 0 { return }
+    this.key(field, wireVarint)
+    this.varint(uint64(value))
+}
+
+func (this *protoBuffer) bytesField(field int, value []byte) {
+    this.key(field, wireBytes)
+    this.varint(uint64(len(value)))
+    this.data = append(this.data, value...)
+}
+
+func (this *protoBuffer) packedField(field int, values []int64) {
+    var packed protoBuffer
+    for _, value := range values {
+        packed.varint(uint64(value))
+    }
+    this.bytesField(field, packed.data)
+}
+
+func (this *protoBuffer) message(field int, build func (message *protoBuffer)) {
+    var message protoBuffer
+    build(&message)
+    this.bytesField(field, message.data)
+}
+
+type stringTable struct {
+    indexes map[string] int64
+    values  []string
+}
+
+// The table starts with the empty string, as pprof requires
+func newStringTable() *stringTable {
+    return &stringTable { indexes: map[string] int64 { "": 0 }, values: []string { "" } }
+}
+
+func (this *stringTable) index(value string) int64 {
+    if index, found := this.indexes[value]; found { return index }
+    this.indexes[value] = int64(len(this.values))
+    this.values = append(this.values, value)
+    return int64(len(this.values) - 1)
+}
+
+type locationKey struct {
+    function *Function
+    line     int
+}
+
+// Writes the profile with two values for each sample: the count and the time. The file is the
+// name of the script shown in the source views
+func (this *Profiler) WritePprof(out io.Writer, file string) error {
+    var table = newStringTable()
+    var profile protoBuffer
+
+    var valueType = func (field int, kind string, unit string) {
+        profile.message(field, func (message *protoBuffer) {
+            message.int64Field(valueTypeType, table.index(kind))
+            message.int64Field(valueTypeUnit, table.index(unit))
+        })
+    }
+    valueType(profileSampleType, "samples", "count")
+    valueType(profileSampleType, "cpu", "nanoseconds")
+
+    // The samples in a fixed order so the output does not depend on the map
+    var keys = make([]string, 0, len(this.samples))
+    for key := range this.samples {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    var locations = map[locationKey] int64 {}
+    var locationOrder = []locationKey {}
+    var functions = map[*Function] bool {}
+    for _, key := range keys {
+        var current = this.samples[key]
+        var ids = []int64 {}
+        for _, frame := range current.stack {
+            var location = locationKey { frame.function, frame.line }
+            var id, found = locations[location]
+            if !found {
+                id = int64(len(locations) + 1)
+                locations[location] = id
+                locationOrder = append(locationOrder, location)
+            }
+            ids = append(ids, id)
+            functions[frame.function] = true
+        }
+
+        profile.message(profileSample, func (message *protoBuffer) {
+            message.packedField(sampleLocationID, ids)
+            message.packedField(sampleValue, []int64 { int64(current.count), int64(current.time) })
+        })
+    }
+
+    for _, location := range locationOrder {
+        profile.message(profileLocation, func (message *protoBuffer) {
+            message.int64Field(locationID, locations[location])
+            message.message(locationLine, func (line *protoBuffer) {
+                line.int64Field(lineFunctionID, int64(location.function.id))
+                line.int64Field(lineLine, int64(location.line))
+            })
+        })
+    }
+
+    for _, function := range this.Functions() {
+        if !functions[function] { continue }
+        profile.message(profileFunction, func (message *protoBuffer) {
+            message.int64Field(functionID, int64(function.id))
+            message.int64Field(functionName, table.index(function.Name))
+            message.int64Field(functionSystemName, table.index(function.Name))
+            message.int64Field(functionFilename, table.index(file))
+            message.int64Field(functionStartLine, int64(function.Line))
+        })
+    }
+
+    profile.int64Field(profileTimeNanos, this.start.UnixNano())
+    profile.int64Field(profileDurationNanos, int64(this.duration))
+    valueType(profilePeriodType, "cpu", "nanoseconds")
+    profile.int64Field(profilePeriod, int64(this.Interval))
+
+    // The strings go last because the other fields add them to the table
+    for _, value := range table.values {
+        profile.bytesField(profileStringTable, []byte(value))
+    }
+
+    var compressed = gzip.NewWriter(out)
+    if _, err := compressed.Write(profile.data); err != nil { return err }
+    return compressed.Close()
+}
@@ -0,0 +1,179 @@
+// monkey/profiler/profiler.go
+/*
+    Sampling profiler for monkey programs. As an evaluator.Observer it keeps a stack of the user
+    functions that run and the line each one is at, and a goroutine records that stack at every
+    Interval. Each sample weighs the time since the previous one, so the times stay right when the
+    sampler runs late (e.g. with one CPU it only runs when the evaluation is preempted). The calls
+    are counted exactly. The functions are named by the let that binds them, else by the name they
+    are called with, else by where they are written
+*/
+
+package profiler
+
+import (
+    "fmt"
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/object"
+    "strings"
+    "sync"
+    "time"
+)
+
+const DefaultInterval = time.Millisecond
+
+type Function struct {
+    Name  string
+    Line  int           // Where it starts
+    Calls int
+    Flat  time.Duration // Running by itself
+    Cum   time.Duration // Running or waiting for its calls to return
+
+    id    int
+}
+
+type frame struct {
+    function *Function
+    line     int
+}
+
+type sample struct {
+    stack []frame // The innermost is the first
+    count int
+    time  time.Duration
+}
+
+type Profiler struct {
+    evaluator.BaseObserver
+    Interval  time.Duration
+
+    names     map[*ast.StatementsBlock] string // Of the functions bound by a let, by their body
+    functions map[*ast.StatementsBlock] *Function
+    main      *Function
+
+    mutex     sync.Mutex // Of the stack and the samples, the sampler reads them from its goroutine
+    stack     []frame    // The innermost is the last
+    samples   map[string] *sample
+    total     int
+    last      time.Time  // Of the last sample
+
+    start     time.Time
+    duration  time.Duration
+    stop      chan struct {}
+    done      chan struct {}
+}
+
+func NewProfiler(program *ast.Program) *Profiler {
+    var this = &Profiler {
+        Interval:  DefaultInterval,
+        names:     map[*ast.StatementsBlock] string {},
+        functions: map[*ast.StatementsBlock] *Function {},
+        main:      &Function { Name: "main", Line: 1, id: 1 },
+        samples:   map[string] *sample {},
+    }
+    this.nameFunctions(program)
+    return this
+}
+
+// Finds the lets that bind function literals, anywhere in the program
+func (this *Profiler) nameFunctions(program *ast.Program) {
+    ast.Inspect(program, func (node ast.Node) bool {
+        if let, isLet := node.(*ast.LetStatement); isLet {
+            if fn, isFn := let.Expression.(*ast.FunctionLiteral); isFn { this.names[fn.Body] = let.Identifier }
+        }
+        return true
+    })
+}
+
+// The profile entry of the function, one for each literal of the source
+func (this *Profiler) function(fn *object.Function, site ast.Node) *Function {
+    if function, found := this.functions[fn.Body]; found { return function }
+
+    var line = fn.Body.Pos().Line
+    var name, hasName = this.names[fn.Body]
+    if !hasName {
+        name = evaluator.FunctionName(fn, site)
+        if strings.HasPrefix(name, "fn (") { name = fmt.Sprintf("%s at line %d", name, line) }
+    }
+
+    var function = &Function { Name: name, Line: line, id: len(this.functions) + 2 }
+    this.functions[fn.Body] = function
+    return function
+}
+
+// Starts sampling, the program must be evaluated right after it with the Profiler as observer
+func (this *Profiler) Start() {
+    this.stack = []frame { { function: this.main, line: 1 } }
+    this.stop = make(chan struct {})
+    this.done = make(chan struct {})
+    this.start = time.Now()
+    this.last = this.start
+
+    go func () {
+        defer close(this.done)
+        var ticker = time.NewTicker(this.Interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case now := <-ticker.C:
+                this.takeSample(now)
+            case <-this.stop:
+                return
+            }
+        }
+    }()
+}
+
+func (this *Profiler) Stop() {
+    close(this.stop)
+    <-this.done
+    this.duration = time.Since(this.start)
+}
+
+func (this *Profiler) takeSample(now time.Time) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    var key strings.Builder
+    var stack = make([]frame, 0, len(this.stack))
+    for i := len(this.stack) - 1; i >= 0; i-- {
+        stack = append(stack, this.stack[i])
+        fmt.Fprintf(&key, "%d:%d;", this.stack[i].function.id, this.stack[i].line)
+    }
+
+    var current, found = this.samples[key.String()]
+    if !found {
+        current = &sample { stack: stack }
+        this.samples[key.String()] = current
+    }
+    current.count++
+    current.time += now.Sub(this.last)
+    this.last = now
+    this.total++
+}
+
+// @Impl
+func (this *Profiler) Enter(node ast.Node, env *object.Environment) {
+    switch node.(type) {
+    case *ast.LetStatement, *ast.ReturnStatement, *ast.ExpressionStatement:
+        this.mutex.Lock()
+        this.stack[len(this.stack) - 1].line = node.Pos().Line
+        this.mutex.Unlock()
+    }
+}
+
+// @Impl
+func (this *Profiler) Call(fn *object.Function, site ast.Node, env *object.Environment) {
+    var function = this.function(fn, site)
+    this.mutex.Lock()
+    function.Calls++
+    this.stack = append(this.stack, frame { function: function, line: function.Line })
+    this.mutex.Unlock()
+}
+
+// @Impl
+func (this *Profiler) Return(fn *object.Function, result object.Object) {
+    this.mutex.Lock()
+    this.stack = this.stack[:len(this.stack) - 1]
+    this.mutex.Unlock()
+}
@@ -0,0 +1,163 @@
+// monkey/profiler/profiler_test.go
+
+package profiler
+
+import (
+    "bytes"
+    "compress/gzip"
+    "io"
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "strings"
+    "testing"
+    "time"
+)
+
+const source = `let fib = fn (n) {
+    if (n < 2) { return n };
+    fib(n - 1) + fib(n - 2)
+};
+let twice = fn (f) { fn (x) { f(f(x)) } };
+let inc = twice(fn (x) { x + 1 });
+map([1, 2], fn (x) { inc(x) });
+fib(5);`
+
+// Takes a sample of 1ms before each statement, so the profile does not depend on the timing
+type statementSampler struct {
+    evaluator.BaseObserver
+    profile *Profiler
+}
+
+func (this statementSampler) Enter(node ast.Node, env *object.Environment) {
+    switch node.(type) {
+    case *ast.LetStatement, *ast.ReturnStatement, *ast.ExpressionStatement:
+        this.profile.takeSample(this.profile.last.Add(time.Millisecond))
+    }
+}
+
+func profileSource(t *testing.T, input string) *Profiler {
+    t.Helper()
+    var p = parser.NewParser(lexer.NewLexer(input))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { t.Fatalf("Unexpected parser errors: %v", p.Errors()) }
+
+    var profile = NewProfiler(program)
+    profile.Interval = time.Hour
+    var interpreter = evaluator.NewInterpreter()
+    // The profiler goes first so the sample sees the line of the statement
+    interpreter.Observer = evaluator.Observe(profile, statementSampler { profile: profile })
+
+    profile.Start()
+    interpreter.Eval(program, object.NewEnvironment())
+    profile.Stop()
+    return profile
+}
+
+func TestFunctionsAndCalls(t *testing.T) {
+    var profile = profileSource(t, source)
+
+    var calls = map[string] int {}
+    for _, function := range profile.Functions() {
+        calls[function.Name] = function.Calls
+    }
+    var expected = map[string] int {
+        "main": 0, "fib": 15, "twice": 1, "inc": 2, "f": 4, "fn (x) at line 7": 2,
+    }
+    for name, count := range expected {
+        if got, found := calls[name]; !found || got != count {
+            t.Errorf("Expected %s to be called %d times but got %d (found %t)", name, count, got, found)
+        }
+    }
+    if len(calls) != len(expected) { t.Errorf("Expected the functions %v but got %v", expected, calls) }
+}
+
+func TestFlatAndCumulativeTime(t *testing.T) {
+    var profile = profileSource(t, "let f = fn (x) { let y = x; y };\nlet g = fn () { f(1) };\ng();")
+
+    var byName = map[string] *Function {}
+    for _, function := range profile.Functions() {
+        byName[function.Name] = function
+    }
+
+    // Samples: 3 in main, 1 in g, 2 in f (its two statements)
+    var tests = []struct {
+        name string; flat time.Duration; cum time.Duration
+    } {
+        { "main", 3 * time.Millisecond, 6 * time.Millisecond },
+        { "g",    1 * time.Millisecond, 3 * time.Millisecond },
+        { "f",    2 * time.Millisecond, 2 * time.Millisecond },
+    }
+    for _, test := range tests {
+        var function = byName[test.name]
+        if function == nil || function.Flat != test.flat || function.Cum != test.cum {
+            t.Errorf("Expected %s to have flat %s and cum %s but got %+v", test.name, test.flat, test.cum, function)
+        }
+    }
+    if first := profile.Functions()[0]; first.Name != "main" {
+        t.Errorf("Expected the function with the most flat time first but got %s", first.Name)
+    }
+
+    var report bytes.Buffer
+    profile.WriteReport(&report)
+    if !strings.Contains(report.String(), "2ms   33.3%        2ms   33.3%         1  f (line 1)") {
+        t.Errorf("Unexpected report\n%s", report.String())
+    }
+}
+
+// Reads the fields of a protocol buffer message, as field number and raw value
+func readFields(t *testing.T, data []byte) [][2]any {
+    var fields = [][2]any {}
+    var varint = func () uint64 {
+        var value uint64
+        for shift := 0; ; shift += 7 {
+            var b = data[0]
+            data = data[1:]
+            value |= uint64(b & 0x7f) << shift
+            if b < 0x80 { return value }
+        }
+    }
+    for len(data) > 0 {
+        var key = varint()
+        switch key & 7 {
+        case wireVarint:
+            fields = append(fields, [2]any { int(key >> 3), varint() })
+        case wireBytes:
+            var length = varint()
+            fields = append(fields, [2]any { int(key >> 3), data[:length] })
+            data = data[length:]
+        default:
+            t.Fatalf("Unexpected wire type %d", key & 7)
+        }
+    }
+    return fields
+}
+
+func TestPprof(t *testing.T) {
+    var profile = profileSource(t, source)
+    var out bytes.Buffer
+    if err := profile.WritePprof(&out, "test.mk"); err != nil { t.Fatal(err) }
+
+    var reader, err = gzip.NewReader(&out)
+    if err != nil { t.Fatalf("Expected a gzipped profile: %s", err) }
+    var data, _ = io.ReadAll(reader)
+
+    var strs = []string {}
+    var counts = map[int] int {}
+    for _, field := range readFields(t, data) {
+        counts[field[0].(int)]++
+        if field[0].(int) == profileStringTable { strs = append(strs, string(field[1].([]byte))) }
+    }
+
+    if len(strs) == 0 || strs[0] != "" { t.Errorf("Expected the string table to start with the empty string but got %q", strs) }
+    for _, expected := range []string { "samples", "count", "cpu", "nanoseconds", "fib", "main", "test.mk" } {
+        var found = false
+        for _, str := range strs { found = found || str == expected }
+        if !found { t.Errorf("Expected %q in the string table %q", expected, strs) }
+    }
+    if counts[profileSampleType] != 2 || counts[profileSample] != len(profile.samples) || counts[profileLocation] == 0 {
+        t.Errorf("Unexpected number of fields %v", counts)
+    }
+}
@@ -0,0 +1,75 @@
+// monkey/profiler/report.go
+
+package profiler
+
+import (
+    "fmt"
+    "io"
+    "sort"
+    "time"
+)
+
+func (this *Profiler) Duration() time.Duration {
+    return this.duration
+}
+
+func (this *Profiler) Samples() int {
+    return this.total
+}
+
+// The functions that were called or sampled, the most expensive first
+func (this *Profiler) Functions() []*Function {
+    var all = []*Function { this.main }
+    for _, function := range this.functions {
+        all = append(all, function)
+    }
+    for _, function := range all {
+        function.Flat, function.Cum = 0, 0
+    }
+
+    for _, current := range this.samples {
+        current.stack[0].function.Flat += current.time
+        var seen = map[*Function] bool {} // The recursive calls count once
+        for _, frame := range current.stack {
+            if seen[frame.function] { continue }
+            seen[frame.function] = true
+            frame.function.Cum += current.time
+        }
+    }
+
+    sort.SliceStable(all, func (i int, j int) bool {
+        if all[i].Flat != all[j].Flat { return all[i].Flat > all[j].Flat }
+        if all[i].Cum != all[j].Cum { return all[i].Cum > all[j].Cum }
+        return all[i].id < all[j].id
+    })
+    return all
+}
+
+// Of all the samples, it can be a bit less than the Duration
+func (this *Profiler) sampledTime() time.Duration {
+    var total time.Duration
+    for _, current := range this.samples {
+        total += current.time
+    }
+    return total
+}
+
+func percent(part time.Duration, total time.Duration) float64 {
+    if total == 0 { return 0 }
+    return float64(part) * 100 / float64(total)
+}
+
+// Writes the flat report, a line for each function with the time it ran by itself (flat) and
+// with the calls it made (cum)
+func (this *Profiler) WriteReport(out io.Writer) {
+    var functions = this.Functions()
+    var total = this.sampledTime()
+    fmt.Fprintf(out, "Duration: %s, %d samples taken every %s\n", this.duration.Round(time.Millisecond), this.total, this.Interval)
+    fmt.Fprintf(out, "%10s %7s %10s %7s %9s  %s\n", "flat", "flat%", "cum", "cum%", "calls", "function")
+    for _, function := range functions {
+        fmt.Fprintf(out, "%10s %6.1f%% %10s %6.1f%% %9d  %s (line %d)\n",
+            function.Flat.Round(time.Microsecond), percent(function.Flat, total),
+            function.Cum.Round(time.Microsecond), percent(function.Cum, total),
+            function.Calls, function.Name, function.Line)
+    }
+}
@@ -0,0 +1,96 @@
+// monkey/readline/history.go
+
+package readline
+
+import (
+    "bufio"
+    "os"
+    "strings"
+)
+
+// The entered lines, oldest first. When it has a path every new line is appended to that file so
+// the history survives between sessions. The file is written again with the newest max lines when
+// it has more
+type History struct {
+    lines []string
+    max   int
+    path  string
+    saved int // The lines in the file
+}
+
+func NewHistory(max int) *History {
+    return &History { lines: []string {}, max: max }
+}
+
+// Loads the lines of the file and keeps appending to it from now on. A missing file is not an error
+func (this *History) LoadFile(path string) error {
+    this.path = path
+
+    var file, err = os.Open(path)
+    if os.IsNotExist(err) { return nil }
+    if err != nil { return err }
+    defer file.Close()
+
+    var scanner = bufio.NewScanner(file)
+    for scanner.Scan() {
+        this.push(scanner.Text())
+        this.saved++
+    }
+    if err := scanner.Err(); err != nil { return err }
+
+    if this.max > 0 && this.saved > this.max { return this.rewrite() }
+    return nil
+}
+
+// Writes the file again with the lines kept in memory
+func (this *History) rewrite() error {
+    var content = strings.Join(this.lines, "\n")
+    if len(this.lines) > 0 { content += "\n" }
+    if err := os.WriteFile(this.path, []byte(content), 0600); err != nil { return err }
+    this.saved = len(this.lines)
+    return nil
+}
+
+func (this *History) push(line string) {
+    this.lines = append(this.lines, line)
+    if this.max > 0 && len(this.lines) > this.max {
+        this.lines = this.lines[len(this.lines) - this.max:]
+    }
+}
+
+// Adds the line unless it is empty or the same as the last one
+func (this *History) Add(line string) error {
+    line = strings.TrimSpace(line)
+    if line == "" { return nil }
+    if len(this.lines) > 0 && this.lines[len(this.lines) - 1] == line { return nil }
+
+    this.push(line)
+
+    if this.path == "" { return nil }
+    if this.max > 0 && this.saved >= this.max { return this.rewrite() }
+
+    var file, err = os.OpenFile(this.path, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0600)
+    if err != nil { return err }
+    defer file.Close()
+    if _, err = file.WriteString(line + "\n"); err != nil { return err }
+    this.saved++
+    return nil
+}
+
+func (this *History) Len() int {
+    return len(this.lines)
+}
+
+// Returns the line at the index, 0 being the oldest
+func (this *History) At(index int) string {
+    return this.lines[index]
+}
+
+// Looks for the newest line containing the query starting at the index and going back in time.
+// Returns -1 when nothing is found
+func (this *History) SearchBackward(query string, from int) int {
+    for i := min(from, len(this.lines) - 1); i >= 0; i-- {
+        if strings.Contains(this.lines[i], query) { return i }
+    }
+    return -1
+}
@@ -0,0 +1,427 @@
+// monkey/readline/readline.go
+/*
+    A small line editor for the REPLs. On a terminal it reads the keys in raw mode to support
+    cursor movement, history navigation and reverse search. On anything else (pipes, files) it
+    just reads whole lines
+*/
+
+package readline
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+)
+
+// Returned by ReadLine when the user presses Ctrl-C, the current input is discarded
+var ErrInterrupted = errors.New("interrupted")
+
+// Keys after decoding the escape sequences
+const (
+    keyNone = iota
+    keyChar
+    keyEnter
+    keyBackspace
+    keyDelete
+    keyLeft
+    keyRight
+    keyUp
+    keyDown
+    keyHome
+    keyEnd
+    keyKillToEnd    // Ctrl-K
+    keyKillToStart  // Ctrl-U
+    keyKillWord     // Ctrl-W
+    keyClearScreen  // Ctrl-L
+    keyReverseSearch // Ctrl-R
+    keyCancel       // Ctrl-G
+    keyInterrupt    // Ctrl-C
+    keyEndOfInput   // Ctrl-D
+    keyTab
+    keyEscape
+)
+
+type key struct {
+    kind int
+    ch   byte
+}
+
+// Receives the line and the cursor position and returns where the word being completed starts
+// and the words that can replace it
+type Completer func (line string, cursor int) (int, []string)
+
+type Editor struct {
+    History   *History
+    Completer Completer // Tab only indents when there is none
+
+    in       *bufio.Reader
+    out      io.Writer
+    fd       int
+    terminal bool
+
+    // State of the line being edited
+    prompt string
+    buffer []byte
+    cursor int
+    historyIndex int    // Position while navigating the history, History.Len() is the line being edited
+    pending      []byte // The line being edited when the navigation started
+
+    // State of the reverse search
+    searching   bool
+    searchQuery string
+    searchIndex int
+}
+
+// Creates the editor over stdin and stdout
+func NewEditor() *Editor {
+    var fd = int(os.Stdin.Fd())
+    return newEditor(os.Stdin, os.Stdout, fd, isTerminal(fd))
+}
+
+// Creates the editor over stdin read through the reader. For the programs that also read stdin in
+// other places, sharing the reader keeps each one from buffering input the other needs
+func NewEditorReading(in *bufio.Reader) *Editor {
+    var fd = int(os.Stdin.Fd())
+    return newEditor(in, os.Stdout, fd, isTerminal(fd))
+}
+
+// bufio.NewReader gives back the reader itself when it is already a *bufio.Reader
+func newEditor(in io.Reader, out io.Writer, fd int, terminal bool) *Editor {
+    return &Editor {
+        History:  NewHistory(1000),
+        in:       bufio.NewReader(in),
+        out:      out,
+        fd:       fd,
+        terminal: terminal,
+    }
+}
+
+func (this *Editor) IsTerminal() bool {
+    return this.terminal
+}
+
+// Reads one line showing the prompt. Returns io.EOF when the input ends (or Ctrl-D on an empty
+// line) and ErrInterrupted when Ctrl-C is pressed
+func (this *Editor) ReadLine(prompt string) (string, error) {
+    if !this.terminal {
+        return this.readSimpleLine(prompt)
+    }
+
+    var state, err = makeRaw(this.fd)
+    if err != nil {
+        return this.readSimpleLine(prompt)
+    }
+    defer restore(this.fd, state)
+
+    return this.readEditedLine(prompt)
+}
+
+func (this *Editor) readSimpleLine(prompt string) (string, error) {
+    fmt.Fprint(this.out, prompt)
+
+    var line, err = this.in.ReadString('\n')
+    if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+        return "", err
+    }
+    line = strings.TrimSuffix(line, "\n")
+    return strings.TrimSuffix(line, "\r"), nil
+}
+
+func (this *Editor) readEditedLine(prompt string) (string, error) {
+    this.prompt = prompt
+    this.buffer = []byte {}
+    this.cursor = 0
+    this.historyIndex = this.History.Len()
+    this.pending = nil
+    this.searching = false
+    this.refresh()
+
+    for {
+        var k, err = this.readKey()
+        if err != nil { return "", err }
+
+        var line, done, errKey = this.handleKey(k)
+        if done {
+            fmt.Fprint(this.out, "\n")
+            return line, errKey
+        }
+    }
+}
+
+func (this *Editor) readKey() (key, error) {
+    var ch, err = this.in.ReadByte()
+    if err != nil { return key {}, err }
+
+    switch ch {
+    case '\r', '\n':
+        return key { kind: keyEnter }, nil
+    case 127, 8:
+        return key { kind: keyBackspace }, nil
+    case 1:
+        return key { kind: keyHome }, nil
+    case 2:
+        return key { kind: keyLeft }, nil
+    case 3:
+        return key { kind: keyInterrupt }, nil
+    case 4:
+        return key { kind: keyEndOfInput }, nil
+    case 5:
+        return key { kind: keyEnd }, nil
+    case 6:
+        return key { kind: keyRight }, nil
+    case 7:
+        return key { kind: keyCancel }, nil
+    case 9:
+        return key { kind: keyTab }, nil
+    case 11:
+        return key { kind: keyKillToEnd }, nil
+    case 12:
+        return key { kind: keyClearScreen }, nil
+    case 14:
+        return key { kind: keyDown }, nil
+    case 16:
+        return key { kind: keyUp }, nil
+    case 18:
+        return key { kind: keyReverseSearch }, nil
+    case 21:
+        return key { kind: keyKillToStart }, nil
+    case 23:
+        return key { kind: keyKillWord }, nil
+    case 27:
+        return this.readEscapeSequence()
+    }
+
+    if ch < 32 { return key { kind: keyNone }, nil }
+    return key { kind: keyChar, ch: ch }, nil
+}
+
+// Decodes the sequences sent by the arrows, home, end and delete keys: ESC [ x, ESC O x or ESC [ n ~
+func (this *Editor) readEscapeSequence() (key, error) {
+    if this.in.Buffered() == 0 { return key { kind: keyEscape }, nil } // A lone escape key
+
+    var kind, err = this.in.ReadByte()
+    if err != nil { return key {}, err }
+    if kind != '[' && kind != 'O' { return key { kind: keyNone }, nil }
+
+    var code byte
+    if code, err = this.in.ReadByte(); err != nil { return key {}, err }
+
+    switch code {
+    case 'A':
+        return key { kind: keyUp }, nil
+    case 'B':
+        return key { kind: keyDown }, nil
+    case 'C':
+        return key { kind: keyRight }, nil
+    case 'D':
+        return key { kind: keyLeft }, nil
+    case 'H':
+        return key { kind: keyHome }, nil
+    case 'F':
+        return key { kind: keyEnd }, nil
+    }
+
+    if code >= '0' && code <= '9' {
+        var tilde, errTilde = this.in.ReadByte()
+        if errTilde != nil { return key {}, errTilde }
+        if tilde != '~' { return key { kind: keyNone }, nil }
+        switch code {
+        case '1', '7':
+            return key { kind: keyHome }, nil
+        case '4', '8':
+            return key { kind: keyEnd }, nil
+        case '3':
+            return key { kind: keyDelete }, nil
+        }
+    }
+    return key { kind: keyNone }, nil
+}
+
+// Applies the key to the line. Returns done when the line was submitted or cancelled
+func (this *Editor) handleKey(k key) (string, bool, error) {
+    if this.searching {
+        return this.handleSearchKey(k)
+    }
+
+    switch k.kind {
+    case keyChar:
+        this.insert([]byte { k.ch })
+    case keyTab:
+        this.complete()
+    case keyEnter:
+        return string(this.buffer), true, nil
+    case keyInterrupt:
+        fmt.Fprint(this.out, "^C")
+        return "", true, ErrInterrupted
+    case keyEndOfInput:
+        if len(this.buffer) == 0 { return "", true, io.EOF }
+        this.deleteAt(this.cursor)
+    case keyBackspace:
+        if this.cursor > 0 {
+            this.cursor--
+            this.deleteAt(this.cursor)
+        }
+    case keyDelete:
+        this.deleteAt(this.cursor)
+    case keyLeft:
+        if this.cursor > 0 { this.cursor-- }
+    case keyRight:
+        if this.cursor < len(this.buffer) { this.cursor++ }
+    case keyHome:
+        this.cursor = 0
+    case keyEnd:
+        this.cursor = len(this.buffer)
+    case keyKillToEnd:
+        this.buffer = this.buffer[:this.cursor]
+    case keyKillToStart:
+        this.buffer = append([]byte {}, this.buffer[this.cursor:]...)
+        this.cursor = 0
+    case keyKillWord:
+        var start = this.cursor
+        for start > 0 && this.buffer[start - 1] == ' ' { start-- }
+        for start > 0 && this.buffer[start - 1] != ' ' { start-- }
+        this.buffer = append(this.buffer[:start], this.buffer[this.cursor:]...)
+        this.cursor = start
+    case keyUp:
+        this.moveInHistory(-1)
+    case keyDown:
+        this.moveInHistory(1)
+    case keyClearScreen:
+        fmt.Fprint(this.out, "\x1b[H\x1b[2J")
+    case keyReverseSearch:
+        this.searching = true
+        this.searchQuery = ""
+        this.searchIndex = this.History.Len() - 1
+    }
+
+    this.refresh()
+    return "", false, nil
+}
+
+func (this *Editor) handleSearchKey(k key) (string, bool, error) {
+    switch k.kind {
+    case keyChar:
+        this.searchQuery += string(k.ch)
+        this.searchIndex = this.History.SearchBackward(this.searchQuery, this.History.Len() - 1)
+    case keyBackspace:
+        if len(this.searchQuery) > 0 {
+            this.searchQuery = this.searchQuery[:len(this.searchQuery) - 1]
+            this.searchIndex = this.History.SearchBackward(this.searchQuery, this.History.Len() - 1)
+        }
+    case keyReverseSearch: // Next older match
+        if this.searchIndex > 0 {
+            var next = this.History.SearchBackward(this.searchQuery, this.searchIndex - 1)
+            if next != -1 { this.searchIndex = next }
+        }
+    case keyCancel, keyInterrupt:
+        this.searching = false
+    case keyEnter:
+        this.searching = false
+        this.acceptSearch()
+        return string(this.buffer), true, nil
+    default: // Any other key leaves the search with the match to be edited
+        this.searching = false
+        this.acceptSearch()
+        return this.handleKey(k)
+    }
+
+    this.refresh()
+    return "", false, nil
+}
+
+func commonPrefix(words []string) string {
+    var prefix = words[0]
+    for _, word := range words[1:] {
+        for !strings.HasPrefix(word, prefix) {
+            prefix = prefix[:len(prefix) - 1]
+        }
+    }
+    return prefix
+}
+
+// Extends the word under the cursor as far as the candidates agree. When they do not agree on
+// anything more all of them are listed below the line
+func (this *Editor) complete() {
+    if this.Completer == nil {
+        this.insert([]byte("    "))
+        return
+    }
+    var start, candidates = this.Completer(string(this.buffer), this.cursor)
+
+    var word = string(this.buffer[start:this.cursor])
+    if len(candidates) == 0 {
+        if strings.TrimSpace(word) == "" { this.insert([]byte("    ")) } // Nothing to complete, so indents
+        return
+    }
+
+    var prefix = commonPrefix(candidates)
+    if len(prefix) > len(word) {
+        var rest = append([]byte {}, this.buffer[this.cursor:]...)
+        this.buffer = append(append(this.buffer[:start], prefix...), rest...)
+        this.cursor = start + len(prefix)
+        return
+    }
+
+    if len(candidates) > 1 {
+        fmt.Fprintf(this.out, "\n%s\n", strings.Join(candidates, "  "))
+    }
+}
+
+func (this *Editor) acceptSearch() {
+    if this.searchIndex < 0 || this.searchIndex >= this.History.Len() { return }
+    this.buffer = []byte(this.History.At(this.searchIndex))
+    this.cursor = len(this.buffer)
+}
+
+func (this *Editor) insert(text []byte) {
+    var rest = append([]byte {}, this.buffer[this.cursor:]...)
+    this.buffer = append(append(this.buffer[:this.cursor], text...), rest...)
+    this.cursor += len(text)
+}
+
+func (this *Editor) deleteAt(index int) {
+    if index < 0 || index >= len(this.buffer) { return }
+    this.buffer = append(this.buffer[:index], this.buffer[index + 1:]...)
+}
+
+func (this *Editor) moveInHistory(direction int) {
+    var next = this.historyIndex + direction
+    if next < 0 || next > this.History.Len() { return }
+
+    if this.historyIndex == this.History.Len() {
+        this.pending = append([]byte {}, this.buffer...) // Saves what was being typed
+    }
+    this.historyIndex = next
+
+    if next == this.History.Len() {
+        this.buffer = append([]byte {}, this.pending...)
+    } else {
+        this.buffer = []byte(this.History.At(next))
+    }
+    this.cursor = len(this.buffer)
+}
+
+// Redraws the current line and puts the cursor back in place
+func (this *Editor) refresh() {
+    var prompt = this.prompt
+    var text = string(this.buffer)
+    var cursor = this.cursor
+
+    if this.searching {
+        var match = ""
+        if this.searchIndex >= 0 && this.searchIndex < this.History.Len() {
+            match = this.History.At(this.searchIndex)
+        }
+        prompt = fmt.Sprintf("(reverse-i-search)`%s': ", this.searchQuery)
+        text = match
+        cursor = len(match)
+    }
+
+    fmt.Fprintf(this.out, "\r%s%s\x1b[K\r", prompt, text)
+    if len(prompt) + cursor > 0 {
+        fmt.Fprintf(this.out, "\x1b[%dC", len(prompt) + cursor)
+    }
+}
@@ -0,0 +1,205 @@
+// monkey/readline/readline_test.go
+
+package readline
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// Editor that reads the keys from the input as if it was a terminal in raw mode
+func newTestEditor(input string, history ...string) *Editor {
+    var editor = newEditor(strings.NewReader(input), &bytes.Buffer {}, -1, true)
+    for _, line := range history {
+        editor.History.Add(line)
+    }
+    return editor
+}
+
+func TestEditingKeys(t *testing.T) {
+    var tests = []struct {
+        name string; input string; expected string
+    } {
+        { "plain text",         "let x = 1;\r",             "let x = 1;"   },
+        { "backspace",          "abd\x7fc\r",               "abc"          },
+        { "left and insert",    "ac\x1b[Db\r",              "abc"          },
+        { "home and end",       "bc\x01a\x05d\r",           "abcd"         },
+        { "home sequence",      "bc\x1b[Ha\x1b[Fd\r",       "abcd"         },
+        { "delete key",         "abxc\x1b[D\x1b[D\x1b[3~\r", "abc"         },
+        { "kill to end",        "abc def\x01\x1b[C\x1b[C\x1b[C\x0b\r", "abc" },
+        { "kill to start",      "abc def\x1b[D\x1b[D\x1b[D\x15\r", "def"   },
+        { "kill word",          "let foo bar\x17\r",        "let foo "     },
+    }
+
+    for _, test := range tests {
+        var editor = newTestEditor(test.input)
+        var line, err = editor.readEditedLine(">> ")
+        if err != nil {
+            t.Errorf("[%s] Unexpected error: %s", test.name, err)
+            continue
+        }
+        if line != test.expected {
+            t.Errorf("[%s] Expected line to be %q but got %q instead", test.name, test.expected, line)
+        }
+    }
+}
+
+func TestHistoryNavigation(t *testing.T) {
+    var editor = newTestEditor("typing\x1b[A\x1b[A\r", "first", "second")
+    var line, _ = editor.readEditedLine(">> ")
+    if line != "first" {
+        t.Errorf("Expected two ups to be %q but got %q instead", "first", line)
+    }
+
+    // Going down past the newest entry brings back what was being typed
+    editor = newTestEditor("typing\x1b[A\x1b[B\r", "first", "second")
+    line, _ = editor.readEditedLine(">> ")
+    if line != "typing" {
+        t.Errorf("Expected up and down to be %q but got %q instead", "typing", line)
+    }
+}
+
+func TestReverseSearch(t *testing.T) {
+    var history = []string { "let add = fn (a, b) { a + b }", "puts(1)", "add(1, 2)" }
+
+    var editor = newTestEditor("\x12add\r", history...)
+    var line, _ = editor.readEditedLine(">> ")
+    if line != "add(1, 2)" {
+        t.Errorf("Expected the newest match %q but got %q instead", "add(1, 2)", line)
+    }
+
+    // Ctrl-R again goes to the older match and an arrow leaves the search to edit it
+    editor = newTestEditor("\x12add\x12\x1b[C!\r", history...)
+    line, _ = editor.readEditedLine(">> ")
+    if line != "let add = fn (a, b) { a + b }!" {
+        t.Errorf("Expected the older match to be edited but got %q instead", line)
+    }
+}
+
+func TestInterruptAndEndOfInput(t *testing.T) {
+    var editor = newTestEditor("half typed\x03")
+    var _, err = editor.readEditedLine(">> ")
+    if !errors.Is(err, ErrInterrupted) {
+        t.Errorf("Expected Ctrl-C to return ErrInterrupted but got %v instead", err)
+    }
+
+    editor = newTestEditor("\x04")
+    _, err = editor.readEditedLine(">> ")
+    if !errors.Is(err, io.EOF) {
+        t.Errorf("Expected Ctrl-D on an empty line to return io.EOF but got %v instead", err)
+    }
+}
+
+func TestTabCompletion(t *testing.T) {
+    var words = []string { "filter", "find", "first", "let" }
+    var completer = func (line string, cursor int) (int, []string) {
+        var start = strings.LastIndexAny(line[:cursor], " (") + 1
+        var found = []string {}
+        for _, word := range words {
+            if start < cursor && strings.HasPrefix(word, line[start:cursor]) { found = append(found, word) }
+        }
+        return start, found
+    }
+
+    var tests = []struct {
+        name string; input string; expected string
+    } {
+        { "single candidate",        "le	 x",           "let x"         },
+        { "common prefix",           "f	",              "fi"            },
+        { "ambiguous keeps the word", "fi	",            "fi"            },
+        { "in the middle",           "(fil)[D	",    "(filter)"      },
+        { "nothing to complete",     "x	",              "x"             },
+        { "indents an empty word",   "	1",              "    1"         },
+    }
+
+    for _, test := range tests {
+        var editor = newTestEditor(test.input)
+        editor.Completer = completer
+        var line, _ = editor.readEditedLine(">> ")
+        if line != test.expected {
+            t.Errorf("[%s] Expected line to be %q but got %q instead", test.name, test.expected, line)
+        }
+    }
+
+    // Ambiguous candidates are listed below the line
+    var out bytes.Buffer
+    var editor = newEditor(strings.NewReader("fi\t\r"), &out, -1, true)
+    editor.Completer = completer
+    editor.readEditedLine(">> ")
+    if !strings.Contains(out.String(), "filter  find  first") {
+        t.Errorf("Expected the candidates to be listed but the output was %q", out.String())
+    }
+
+    // Without a completer tab is indentation
+    editor = newTestEditor("a\tb\r")
+    if line, _ := editor.readEditedLine(">> "); line != "a    b" {
+        t.Errorf("Expected tab without completer to indent but got %q instead", line)
+    }
+}
+
+func TestSimpleLineWhenNotTerminal(t *testing.T) {
+    var editor = newEditor(strings.NewReader("one\r\ntwo"), &bytes.Buffer {}, -1, false)
+    var expectations = []string { "one", "two" }
+    for _, expected := range expectations {
+        var line, err = editor.ReadLine(">> ")
+        if err != nil || line != expected {
+            t.Errorf("Expected line %q but got %q (error %v) instead", expected, line, err)
+        }
+    }
+    if _, err := editor.ReadLine(">> "); !errors.Is(err, io.EOF) {
+        t.Errorf("Expected io.EOF at the end of the input but got %v instead", err)
+    }
+}
+
+// The editor and the other readers of the input take turns on the same buffer
+func TestSharedReader(t *testing.T) {
+    var in = bufio.NewReader(strings.NewReader("step\nscript input\ncontinue\n"))
+    var editor = newEditor(in, &bytes.Buffer {}, -1, false)
+
+    var first, _ = editor.ReadLine("(debug) ")
+    var other, _ = in.ReadString('\n')
+    var second, _ = editor.ReadLine("(debug) ")
+    if first != "step" || other != "script input\n" || second != "continue" {
+        t.Errorf("Expected the lines in order but got %q, %q and %q", first, other, second)
+    }
+}
+
+func TestHistoryFile(t *testing.T) {
+    var path = filepath.Join(t.TempDir(), "history")
+
+    var history = NewHistory(2)
+    if err := history.LoadFile(path); err != nil {
+        t.Fatalf("Expected a missing history file to not be an error but got %s", err)
+    }
+    history.Add("one")
+    history.Add("one") // Repeated lines are skipped
+    history.Add("  ")
+    history.Add("two")
+
+    var loaded = NewHistory(2)
+    loaded.LoadFile(path)
+    if loaded.Len() != 2 || loaded.At(0) != "one" || loaded.At(1) != "two" {
+        t.Errorf("Expected the loaded history to be [one two] but got %v instead", loaded.lines)
+    }
+
+    loaded.Add("three")
+    if loaded.Len() != 2 || loaded.At(0) != "two" {
+        t.Errorf("Expected the history to keep only the newest %d lines but got %v instead", 2, loaded.lines)
+    }
+    if content, _ := os.ReadFile(path); string(content) != "two\nthree\n" {
+        t.Errorf("Expected the history file to keep only the newest lines but got %q instead", content)
+    }
+
+    // A file written by a history with a greater max is cut when it is loaded
+    os.WriteFile(path, []byte("a\nb\nc\n"), 0600)
+    NewHistory(2).LoadFile(path)
+    if content, _ := os.ReadFile(path); string(content) != "b\nc\n" {
+        t.Errorf("Expected the loaded history file to be cut to b and c but got %q instead", content)
+    }
+}
@@ -0,0 +1,14 @@
+// monkey/readline/term_bsd.go
+
+//go:build darwin || freebsd || netbsd || openbsd
+
+package readline
+
+import (
+    "syscall"
+)
+
+const (
+    ioctlGetTermios = syscall.TIOCGETA
+    ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,14 @@
+// monkey/readline/term_linux.go
+
+//go:build linux
+
+package readline
+
+import (
+    "syscall"
+)
+
+const (
+    ioctlGetTermios = syscall.TCGETS
+    ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,25 @@
+// monkey/readline/term_other.go
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package readline
+
+import (
+    "errors"
+)
+
+// No raw mode on these systems, the editor falls back to reading whole lines
+
+type terminalState struct {}
+
+func isTerminal(fd int) bool {
+    return false
+}
+
+func makeRaw(fd int) (*terminalState, error) {
+    return nil, errors.New("raw mode is not supported on this system")
+}
+
+func restore(fd int, state *terminalState) error {
+    return nil
+}
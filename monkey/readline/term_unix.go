@@ -0,0 +1,55 @@
+// monkey/readline/term_unix.go
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package readline
+
+import (
+    "syscall"
+    "unsafe"
+)
+
+type terminalState struct {
+    termios syscall.Termios
+}
+
+func getTermios(fd int) (syscall.Termios, error) {
+    var termios syscall.Termios
+    var _, _, errno = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlGetTermios, uintptr(unsafe.Pointer(&termios)))
+    if errno != 0 { return termios, errno }
+    return termios, nil
+}
+
+func setTermios(fd int, termios *syscall.Termios) error {
+    var _, _, errno = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlSetTermios, uintptr(unsafe.Pointer(termios)))
+    if errno != 0 { return errno }
+    return nil
+}
+
+func isTerminal(fd int) bool {
+    var _, err = getTermios(fd)
+    return err == nil
+}
+
+// Turns off the echo, the line buffering and the signals (Ctrl-C comes as a byte) so the editor
+// receives every key. The output processing stays on so '\n' still returns the carriage
+func makeRaw(fd int) (*terminalState, error) {
+    var old, err = getTermios(fd)
+    if err != nil { return nil, err }
+
+    var raw = old
+    raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+    raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+    raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+    raw.Cflag |= syscall.CS8
+    raw.Cc[syscall.VMIN] = 1
+    raw.Cc[syscall.VTIME] = 0
+
+    if err = setTermios(fd, &raw); err != nil { return nil, err }
+
+    return &terminalState { termios: old }, nil
+}
+
+func restore(fd int, state *terminalState) error {
+    return setTermios(fd, &state.termios)
+}
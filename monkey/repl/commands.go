@@ -0,0 +1,182 @@
+// monkey/repl/commands.go
+/*
+    Meta-commands of the eval REPL. They start with ':' and cover every stage of the pipeline so
+    there is no need to restart the REPL in the lexer or parser modes
+*/
+
+package repl
+
+import (
+    "fmt"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/token"
+    "os"
+    "strings"
+    "time"
+)
+
+type metaCommand struct {
+    name  string
+    usage string
+    help  string
+    run   func (this *evalSession, arg string) bool // Returns true to end the session
+}
+
+var metaCommands []metaCommand
+
+// Filled on init because :help reads the table itself
+func init() {
+    metaCommands = []metaCommand {
+        { ":tokens",  ":tokens <expr>",           "Prints the tokens of the expression",                    (*evalSession).tokensCommand },
+        { ":ast",     ":ast <expr>",              "Prints the parsed statements of the expression",         (*evalSession).astCommand },
+        { ":type",    ":type <expr>",             "Evaluates the expression and prints its type",           (*evalSession).typeCommand },
+        { ":time",    ":time <expr>",             "Evaluates the expression and prints how long it took",   (*evalSession).timeCommand },
+        { ":env",     ":env",                     "Prints the bindings of the environment",                 (*evalSession).envCommand },
+        { ":load",    ":load <file.mk>",          "Runs the file in the current environment",               (*evalSession).loadCommand },
+        { ":save",    ":save [-final] <file.mk>", "Writes the inputs as a script, -final only the ones that build the final bindings", (*evalSession).saveCommand },
+        { ":restore", ":restore <file.mk>",       "Replays a saved session in the current environment",     (*evalSession).restoreCommand },
+        { ":reset",   ":reset",                   "Drops all the bindings",                                 (*evalSession).resetCommand },
+        { ":help",    ":help",                    "Shows this help",                                        (*evalSession).helpCommand },
+        { ":quit",    ":q, :quit",                "Leaves the REPL",                                        (*evalSession).quitCommand },
+    }
+}
+
+func findMetaCommand(name string) (metaCommand, bool) {
+    if name == ":q" { name = ":quit" }
+    for _, command := range metaCommands {
+        if command.name == name { return command, true }
+    }
+    return metaCommand {}, false
+}
+
+func (this *evalSession) runCommand(line string) bool {
+    var name, arg, _ = strings.Cut(line, " ")
+    arg = strings.TrimSpace(arg)
+
+    var command, found = findMetaCommand(name)
+    if !found {
+        fmt.Fprintf(this.out, "Unknown command %s. Use :help to see the commands\n", name)
+        return false
+    }
+    return command.run(this, arg)
+}
+
+func (this *evalSession) requireArg(arg string, usage string) bool {
+    if arg == "" {
+        fmt.Fprintf(this.out, "Usage: %s\n", usage)
+        return false
+    }
+    return true
+}
+
+func (this *evalSession) tokensCommand(arg string) bool {
+    if !this.requireArg(arg, ":tokens <expr>") { return false }
+
+    var lx = lexer.NewLexer(arg)
+    var i = 0
+    for tk := lx.GetNextToken(); tk.Type != token.Eof; tk = lx.GetNextToken() {
+        fmt.Fprintf(this.out, "[%d] %-8s %q\n", i, tk.Type, tk.Literal)
+        i += 1
+    }
+    return false
+}
+
+func (this *evalSession) astCommand(arg string) bool {
+    if !this.requireArg(arg, ":ast <expr>") { return false }
+
+    var program = this.parse(arg)
+    if program == nil { return false }
+
+    for i, stm := range program.Statements {
+        fmt.Fprintf(this.out, "[%d] %T: %s\n", i, stm, stm.String())
+    }
+    return false
+}
+
+func (this *evalSession) typeCommand(arg string) bool {
+    if !this.requireArg(arg, ":type <expr>") { return false }
+
+    var obj = this.eval(arg)
+    if obj == nil { return false }
+
+    if errObj, isErr := obj.(*object.Error); isErr {
+        fmt.Fprintln(this.out, errObj.Inspect())
+        return false
+    }
+    this.record(arg, obj)
+    fmt.Fprintln(this.out, evaluator.GetMsgTypeFor(obj.Type()))
+    return false
+}
+
+func (this *evalSession) timeCommand(arg string) bool {
+    if !this.requireArg(arg, ":time <expr>") { return false }
+
+    var start = time.Now()
+    var obj = this.eval(arg)
+    var elapsed = time.Since(start)
+    if obj == nil { return false }
+
+    this.record(arg, obj)
+    fmt.Fprintln(this.out, obj.Inspect())
+    fmt.Fprintf(this.out, "took %s\n", elapsed)
+    return false
+}
+
+// Prints the bindings of each environment of the chain, from the innermost to the global one
+func (this *evalSession) envCommand(arg string) bool {
+    var level = 0
+    for env := this.env; env != nil; env = env.Outer() {
+        var names = env.Names()
+        if level > 0 { fmt.Fprintf(this.out, "-- outer %d --\n", level) }
+        if len(names) == 0 {
+            fmt.Fprintln(this.out, "(no bindings)")
+        }
+        for _, name := range names {
+            var value, _ = env.Get(name)
+            fmt.Fprintf(this.out, "%s = %s\n", name, value.Inspect())
+        }
+        level += 1
+    }
+    return false
+}
+
+func (this *evalSession) loadCommand(arg string) bool {
+    if !this.requireArg(arg, ":load <file.mk>") { return false }
+
+    var source, err = os.ReadFile(arg)
+    if err != nil {
+        fmt.Fprintf(this.out, "Could not load the file: %s\n", err)
+        return false
+    }
+
+    var obj = this.eval(string(source))
+    if obj == nil { return false }
+
+    if errObj, isErr := obj.(*object.Error); isErr {
+        fmt.Fprintln(this.out, errObj.Inspect())
+        return false
+    }
+    this.record(string(source), obj)
+    fmt.Fprintf(this.out, "Loaded %s\n", arg)
+    return false
+}
+
+func (this *evalSession) resetCommand(arg string) bool {
+    this.env = object.NewEnvironment()
+    this.inputs = nil
+    fmt.Fprintln(this.out, "Environment reset")
+    return false
+}
+
+func (this *evalSession) helpCommand(arg string) bool {
+    for _, command := range metaCommands {
+        fmt.Fprintf(this.out, "  %-26s %s\n", command.usage, command.help)
+    }
+    return false
+}
+
+func (this *evalSession) quitCommand(arg string) bool {
+    return true
+}
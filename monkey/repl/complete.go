@@ -0,0 +1,86 @@
+// monkey/repl/complete.go
+/*
+    Tab completion of the eval REPL. Completes the bindings of the session environment, the
+    builtins, the keywords and, after a '.', the methods of the receiver
+*/
+
+package repl
+
+import (
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "sort"
+    "strings"
+)
+
+func isIdentChar(ch byte) bool {
+    return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+// Finds where the identifier that ends at the position starts
+func wordStart(line string, end int) int {
+    var start = end
+    for start > 0 && isIdentChar(line[start - 1]) {
+        start--
+    }
+    return start
+}
+
+func filterByPrefix(words []string, prefix string) []string {
+    var seen = map[string]bool {}
+    var result = []string {}
+    for _, word := range words {
+        if strings.HasPrefix(word, prefix) && !seen[word] {
+            seen[word] = true
+            result = append(result, word)
+        }
+    }
+    sort.Strings(result)
+    return result
+}
+
+// Guesses the type of what is before the '.' without evaluating anything: a bound identifier or
+// the closing of a literal
+func (this *evalSession) receiverType(line string, dot int) (object.ObjectType, bool) {
+    if dot == 0 { return "", false }
+
+    switch line[dot - 1] {
+    case '"':
+        return object.StringType, true
+    case ']':
+        return object.ArrayType, true
+    case '}':
+        return object.HashType, true
+    }
+
+    var start = wordStart(line, dot)
+    if start == dot { return "", false }
+
+    var value, found = this.env.Get(line[start:dot])
+    if !found { return "", false }
+    return value.Type(), true
+}
+
+// @Impl readline.Completer
+func (this *evalSession) complete(line string, cursor int) (int, []string) {
+    var start = wordStart(line, cursor)
+    var prefix = line[start:cursor]
+
+    if start > 0 && line[start - 1] == '.' {
+        var objType, known = this.receiverType(line, start - 1)
+        if !known { return start, []string {} }
+        return start, filterByPrefix(evaluator.MethodNames(objType), prefix)
+    }
+
+    if prefix == "" { return start, []string {} }
+
+    var words = []string {}
+    for env := this.env; env != nil; env = env.Outer() {
+        words = append(words, env.Names()...)
+    }
+    words = append(words, evaluator.BuiltinNames()...)
+    words = append(words, lexer.Keywords()...)
+
+    return start, filterByPrefix(words, prefix)
+}
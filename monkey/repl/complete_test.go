@@ -0,0 +1,45 @@
+// monkey/repl/complete_test.go
+
+package repl
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestCompletion(t *testing.T) {
+    var _, session = runSessionInputs(`let counter = 1`, `let count_all = fn (x) { x }`, `let names = ["a"]`, `let s = "x"`)
+
+    var tests = []struct {
+        line string; expected string
+    } {
+        { "cou",               "count_all counter"                     },
+        { "1 + coun",          "count_all counter"                     },
+        { "fil",               "filter"                                },
+        { "json_",             "json_parse json_stringify"             },
+        { "re",                "read_file read_line reduce rest return reverse" },
+        { "fn",                "fn"                                    },
+        { "names.f",           "filter find first flatten"             },
+        { "s.",                "first last len rest reverse"           },
+        { `"abc".re`,          "rest reverse"                          },
+        { "[1].pu",            "push"                                  },
+        { `{"a": 1}.ke`,       "keys"                                  },
+        { "counter.",          ""                                      }, // Integer has no methods
+        { "unknown.le",        ""                                      },
+        { "",                  ""                                      },
+        { "zzz",               ""                                      },
+    }
+
+    for _, test := range tests {
+        var _, candidates = session.complete(test.line, len(test.line))
+        var got = strings.Join(candidates, " ")
+        if got != test.expected {
+            t.Errorf("Expected the completion of %q to be %q but got %q instead", test.line, test.expected, got)
+        }
+    }
+
+    // The start is where the word being completed begins
+    if start, _ := session.complete("let y = names.fi", 16); start != 14 {
+        t.Errorf("Expected the completion to start at 14 but got %d instead", start)
+    }
+}
@@ -0,0 +1,97 @@
+// monkey/repl/input.go
+/*
+    Reads the inputs of the eval REPL, that can take more than one line
+*/
+
+package repl
+
+import (
+    "monkey/lexer"
+    "monkey/readline"
+    "monkey/token"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+const (
+    prompt             = ">> "
+    continuationPrompt = ".. "
+)
+
+// Removes the '//' comments, the lexer tells where they are so the ones inside strings stay
+func stripComments(source string) string {
+    var lx = lexer.NewLexer(source)
+    for tk := lx.GetNextToken(); tk.Type != token.Eof; tk = lx.GetNextToken() {}
+
+    var out strings.Builder
+    var from = 0
+    for _, comment := range lx.Comments() {
+        out.WriteString(source[from:comment.Pos.Offset])
+        from = comment.End.Offset
+    }
+    out.WriteString(source[from:])
+    return out.String()
+}
+
+// Tells if the source still needs more lines to be parsed. It happens when a paren, brace, bracket
+// or string was left open or when the last token is an operator waiting for its right side
+func isIncomplete(source string) bool {
+    if strings.Count(stripComments(source), "\"") % 2 != 0 { return true }
+
+    var lx = lexer.NewLexer(source)
+    var depth = 0
+    var last = token.Token { Type: token.Eof }
+
+    for tk := lx.GetNextToken(); tk.Type != token.Eof; tk = lx.GetNextToken() {
+        switch tk.Type {
+        case token.Lparen, token.Lbrace, token.Lbracket:
+            depth++
+        case token.Rparen, token.Rbrace, token.Rbracket:
+            depth--
+        }
+        last = tk
+    }
+
+    if depth > 0 { return true }
+
+    switch last.Type {
+    case token.Assign, token.Comma, token.Dot, token.Colon:
+        return true
+    default:
+        return token.IsOperator(last)
+    }
+}
+
+// Reads lines until the input is complete. The lines are joined with spaces on the history so a
+// multi-line function comes back as one line that can be edited and run again. The comments are
+// dropped there, or they would hide the lines that came after them
+func readInput(editor *readline.Editor) (string, error) {
+    var lines = []string {}
+    var currentPrompt = prompt
+
+    for {
+        var line, err = editor.ReadLine(currentPrompt)
+        if err != nil { return "", err }
+
+        lines = append(lines, line)
+        var source = strings.Join(lines, "\n")
+        if !isIncomplete(source) {
+            var entry = strings.Split(stripComments(source), "\n")
+            for i := range entry { entry[i] = strings.TrimRight(entry[i], " \t") }
+            editor.History.Add(strings.Join(entry, " "))
+            return source, nil
+        }
+
+        currentPrompt = continuationPrompt
+    }
+}
+
+// The history lives in ~/.monkey_history unless MONKEY_HISTORY points to another file
+func historyPath() string {
+    if path := os.Getenv("MONKEY_HISTORY"); path != "" { return path }
+
+    var home, err = os.UserHomeDir()
+    if err != nil { return "" }
+    return filepath.Join(home, ".monkey_history")
+}
@@ -0,0 +1,52 @@
+// monkey/repl/input_test.go
+
+package repl
+
+import (
+    "testing"
+)
+
+func TestIsIncomplete(t *testing.T) {
+    var tests = []struct {
+        input string; expected bool
+    } {
+        { "let x = 5;",                          false },
+        { "let add = fn (a, b) {",               true  },
+        { "let add = fn (a, b) {\n a + b\n}",    false },
+        { "add(1,",                              true  },
+        { "[1, 2",                               true  },
+        { "{ \"a\": 1",                          true  },
+        { "let x =",                             true  },
+        { "1 +",                                 true  },
+        { "x == ",                               true  },
+        { "\"open string",                       true  },
+        { "\"closed\"",                          false },
+        { "}",                                   false },
+        { "",                                    false },
+        { "let x = 1; // it's \"quoted",           false },
+        { "let f = fn (x) { // body",            true  },
+        { "1 + // the rest",                     true  },
+    }
+
+    for _, test := range tests {
+        if isIncomplete(test.input) != test.expected {
+            t.Errorf("Expected isIncomplete(%q) to be %t", test.input, test.expected)
+        }
+    }
+}
+
+func TestStripComments(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "let x = 1; // one",                   "let x = 1; "              },
+        { "// a\nlet y = 2;",                     "\nlet y = 2;"             },
+        { "\"not // a comment\"",                "\"not // a comment\""     },
+    }
+
+    for _, test := range tests {
+        if got := stripComments(test.input); got != test.expected {
+            t.Errorf("Expected stripComments(%q) to be %q but got %q instead", test.input, test.expected, got)
+        }
+    }
+}
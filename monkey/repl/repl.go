@@ -4,13 +4,15 @@ package repl
 
 import (
     "bufio"
+    "errors"
     "fmt"
+    "io"
     "log"
     "os"
-    "monkey/evaluator"
+    "monkey/ast"
     "monkey/lexer"
-    "monkey/object"
     "monkey/parser"
+    "monkey/readline"
 )
 
 func lexerRepl() {
@@ -52,52 +54,58 @@ func parserRepl() {
             parser.PrintErrors()
         } else {
             program.PrintStatements()
+            ast.WriteTree(os.Stdout, program)
         }
     }
 }
 
-func evalRepl() {
+// The session file is replayed before the first input when there is one
+func evalRepl(sessionPath string) {
     fmt.Println("Tokenize then Parse and then Eval your input")
-    scanner := bufio.NewScanner(os.Stdin)
-    var env = object.NewEnvironment()
-    for {
-        fmt.Printf(">> ")
-
-        scanner.Scan()
-        var err = scanner.Err()
-        if err != nil { log.Fatal(err) }
-
-        var line = scanner.Text()
-        if line == ":q" || line == ":quit" { break }
-
-        var lexer = lexer.NewLexer(line)
-        var parser = parser.NewParser(lexer)
-        var program = parser.ParseProgram()
-
-        if len(parser.Errors()) > 0 {
-            parser.PrintErrors()
-        }
+    var editor = readline.NewEditor()
+    if path := historyPath(); path != "" {
+        var err = editor.History.LoadFile(path)
+        if err != nil { fmt.Printf("WARN: Could not load the history: %s\n", err) }
+    }
 
-        var obj = evaluator.Eval(program, env)
-        if obj != nil {
-            fmt.Println(obj.Inspect())
+    var session = newEvalSession(os.Stdout)
+    editor.Completer = session.complete
+    if sessionPath != "" {
+        session.sessionPath = sessionPath
+        if _, err := os.Stat(sessionPath); err == nil {
+            session.restore(sessionPath)
         } else {
-            fmt.Println("WARN: Evaluation result is nil")
+            fmt.Printf("New session, use :save to write it to %s\n", sessionPath)
         }
     }
+    for {
+        var input, err = readInput(editor)
+        if errors.Is(err, readline.ErrInterrupted) { continue } // Ctrl-C only drops the current input
+        if errors.Is(err, io.EOF) { break }
+        if err != nil { log.Fatal(err) }
+
+        if quit := session.handle(input); quit { break }
+    }
+    if session.exited { os.Exit(session.exitCode) }
 }
 
 func Execute(replType string) {
-    fmt.Println("Monkey REPL. [:q or :quit to quit]")
+    fmt.Println("Monkey REPL. [:q or :quit to quit, :help for the other commands]")
     switch replType {
     case "lexer":
         lexerRepl()
     case "parser":
         parserRepl()
     case "eval":
-        evalRepl()
+        evalRepl("")
     default:
         fmt.Println("You need to pass what kind of REPL you want as argument.")
         fmt.Println("Options are: 'lexer' and 'parser'.")
     }
 }
+
+// Starts the eval REPL on the session file, see :save and :restore
+func ExecuteSession(sessionPath string) {
+    fmt.Println("Monkey REPL. [:q or :quit to quit, :help for the other commands]")
+    evalRepl(sessionPath)
+}
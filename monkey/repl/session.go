@@ -0,0 +1,89 @@
+// monkey/repl/session.go
+/*
+    State of the eval REPL: the environment with the bindings and the interpreter. Keeps the
+    handling of the inputs away from the terminal so it can be tested
+*/
+
+package repl
+
+import (
+    "fmt"
+    "io"
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "strings"
+)
+
+type evalSession struct {
+    out         io.Writer
+    env         *object.Environment
+    interpreter *evaluator.Interpreter
+
+    inputs      []string // The inputs that evaluated without errors, for :save
+    sessionPath string   // Set by --session, the default file of :save and :restore
+    exited      bool     // Set when an input called exit, the session then ends with exitCode
+    exitCode    int
+}
+
+func newEvalSession(out io.Writer) *evalSession {
+    var interpreter = evaluator.NewInterpreter()
+    interpreter.Out = out
+    interpreter.Capabilities = evaluator.Capabilities { FileRoot: ".", Env: true, Exit: true }
+
+    return &evalSession {
+        out:         out,
+        env:         object.NewEnvironment(),
+        interpreter: interpreter,
+    }
+}
+
+// Parses the source printing the errors. Returns nil when there are errors
+func (this *evalSession) parse(source string) *ast.Program {
+    var parser = parser.NewParser(lexer.NewLexer(source))
+    var program = parser.ParseProgram()
+
+    if len(parser.Errors()) > 0 {
+        for i, err := range parser.Errors() {
+            fmt.Fprintf(this.out, "[%d] %s\n", i, err)
+        }
+        return nil
+    }
+    return program
+}
+
+// Parses and evaluates the source on the session environment. Returns nil on syntax errors
+func (this *evalSession) eval(source string) object.Object {
+    var program = this.parse(source)
+    if program == nil { return nil }
+
+    // The macros stay in the environment for the next inputs
+    if err := this.interpreter.ExpandMacros(program, this.env); err != nil {
+        return &object.Error { Message: err.Error() }
+    }
+
+    var obj = this.interpreter.Eval(program, this.env)
+    if obj == nil { return evaluator.ObjNull } // Empty programs
+    this.exitCode, this.exited = evaluator.ExitCode(obj)
+    return obj
+}
+
+// Runs one input of the REPL. Returns true when the session should end
+func (this *evalSession) handle(input string) bool {
+    var line = strings.TrimSpace(input)
+    if line == "" { return false }
+
+    if strings.HasPrefix(line, ":") {
+        return this.runCommand(line) || this.exited
+    }
+
+    var obj = this.eval(input)
+    if this.exited { return true }
+    if obj != nil {
+        fmt.Fprintln(this.out, obj.Inspect())
+    }
+    this.record(input, obj)
+    return false
+}
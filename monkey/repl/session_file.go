@@ -0,0 +1,182 @@
+// monkey/repl/session_file.go
+/*
+    Saving and restoring the eval REPL sessions. A session file is a plain monkey script with the
+    inputs that evaluated without errors, in order, so it can also be run with 'monkey run'
+*/
+
+package repl
+
+import (
+    "fmt"
+    "monkey/ast"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "monkey/token"
+    "os"
+    "slices"
+    "strings"
+)
+
+// Names bound by the let statements at the top level of the input
+func definedNames(input string) []string {
+    var program = parser.NewParser(lexer.NewLexer(input)).ParseProgram()
+
+    var names = []string {}
+    for _, stm := range program.Statements {
+        if letStm, isLet := stm.(*ast.LetStatement); isLet {
+            names = append(names, letStm.Identifier)
+        }
+    }
+    return names
+}
+
+// Every identifier the input reads. The names right after a 'let' are skipped because they are
+// written, not read
+func referencedNames(input string) []string {
+    var lx = lexer.NewLexer(input)
+    var names = []string {}
+    var afterLet = false
+    for tk := lx.GetNextToken(); tk.Type != token.Eof; tk = lx.GetNextToken() {
+        if tk.Type == token.Ident && !afterLet {
+            names = append(names, tk.Literal)
+        }
+        afterLet = tk.Type == token.Let
+    }
+    return names
+}
+
+// Keeps only the inputs needed to rebuild the final bindings. Goes from the last input to the
+// first one keeping an input when it has the last definition of a name or a definition read by
+// an input kept after it. The inputs without let statements, like push(arr, 1), are kept when
+// they read a binding that is kept, since they may change its value. The others are dropped
+func finalDefinitions(inputs []string) []string {
+    var bound = map[string]bool {} // Names defined by any input, the others are builtins
+    for _, input := range inputs {
+        for _, name := range definedNames(input) { bound[name] = true }
+    }
+
+    var kept = []string {}
+    var defined = map[string]bool {} // Names whose last definition was already kept
+    var read = map[string]bool {}    // Names read by the kept inputs that still need a definition
+
+    for i := len(inputs) - 1; i >= 0; i-- {
+        var names = definedNames(inputs[i])
+
+        var needed = false
+        for _, name := range names {
+            if !defined[name] || read[name] { needed = true }
+        }
+        if len(names) == 0 {
+            for _, name := range referencedNames(inputs[i]) {
+                if bound[name] && (!defined[name] || read[name]) { needed = true }
+            }
+        }
+        if !needed { continue }
+
+        for _, name := range names {
+            defined[name] = true
+            delete(read, name)
+        }
+        for _, name := range referencedNames(inputs[i]) {
+            read[name] = true
+        }
+        kept = append(kept, inputs[i])
+    }
+
+    slices.Reverse(kept)
+    return kept
+}
+
+// Joins the inputs as a script, one per line. Each one is closed with ';' so the next input
+// cannot be read as a call or an index of the previous one. The ';' goes on its own line when the
+// last line of the input may end with a comment, where it would be commented out
+func sessionScript(inputs []string) string {
+    var out strings.Builder
+    for _, input := range inputs {
+        var trimmed = strings.TrimSpace(input)
+        out.WriteString(trimmed)
+        if needsSemicolon(trimmed) {
+            var lastLine = trimmed[strings.LastIndex(trimmed, "\n") + 1:]
+            if strings.Contains(lastLine, "//") { out.WriteString("\n") }
+            out.WriteString(";")
+        }
+        out.WriteString("\n")
+    }
+    return out.String()
+}
+
+// Whether the last token of the input is not a ';' already. The comments are not tokens
+func needsSemicolon(input string) bool {
+    var lx = lexer.NewLexer(input)
+    var last = ""
+    for tk := lx.GetNextToken(); tk.Type != token.Eof; tk = lx.GetNextToken() {
+        last = tk.Type
+    }
+    return last != "" && last != token.Semicolon
+}
+
+// Records the input when it evaluated without errors
+func (this *evalSession) record(input string, obj object.Object) {
+    if obj == nil { return }
+    if _, isErr := obj.(*object.Error); isErr { return }
+    this.inputs = append(this.inputs, input)
+}
+
+// Picks the path of the argument or the one of the --session flag
+func (this *evalSession) sessionFilePath(arg string, usage string) (string, bool) {
+    if arg != "" { return arg, true }
+    if this.sessionPath != "" { return this.sessionPath, true }
+    fmt.Fprintf(this.out, "Usage: %s\n", usage)
+    return "", false
+}
+
+func (this *evalSession) saveCommand(arg string) bool {
+    var final = false
+    if arg == "-final" || strings.HasPrefix(arg, "-final ") {
+        final = true
+        arg = strings.TrimSpace(strings.TrimPrefix(arg, "-final"))
+    }
+
+    var path, ok = this.sessionFilePath(arg, ":save [-final] <file.mk>")
+    if !ok { return false }
+
+    var inputs = this.inputs
+    if final { inputs = finalDefinitions(inputs) }
+
+    var err = os.WriteFile(path, []byte(sessionScript(inputs)), 0644)
+    if err != nil {
+        fmt.Fprintf(this.out, "Could not save the session: %s\n", err)
+        return false
+    }
+    fmt.Fprintf(this.out, "Saved %d inputs to %s\n", len(inputs), path)
+    return false
+}
+
+func (this *evalSession) restoreCommand(arg string) bool {
+    var path, ok = this.sessionFilePath(arg, ":restore <file.mk>")
+    if !ok { return false }
+
+    this.restore(path)
+    return false
+}
+
+// Replays the session file on the environment. Returns false when it could not be replayed
+func (this *evalSession) restore(path string) bool {
+    var source, err = os.ReadFile(path)
+    if err != nil {
+        fmt.Fprintf(this.out, "Could not restore the session: %s\n", err)
+        return false
+    }
+
+    var obj = this.eval(string(source))
+    if obj == nil { return false }
+
+    if errObj, isErr := obj.(*object.Error); isErr {
+        fmt.Fprintln(this.out, errObj.Inspect())
+        return false
+    }
+    this.record(string(source), obj)
+    fmt.Fprintf(this.out, "Restored %s\n", path)
+    return true
+}
@@ -0,0 +1,141 @@
+// monkey/repl/session_file_test.go
+
+package repl
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestSaveAndRestoreSession(t *testing.T) {
+    var file = filepath.Join(t.TempDir(), "session.mk")
+
+    var output, _ = runSessionInputs(
+        `let xs = [1, 2]`,
+        `let ys = push(xs, 3`,  // Syntax error, not saved
+        `undefined_name`,       // Runtime error, not saved
+        `let double = fn (x) { x * 2 }`,
+        `[1, 2]`,
+        `double(21)`,
+        ":save " + file,
+    )
+    if output != "Saved 4 inputs to " + file + "\n" {
+        t.Errorf("Unexpected output of :save: %q", output)
+    }
+
+    var content, _ = os.ReadFile(file)
+    var expected = "let xs = [1, 2];\nlet double = fn (x) { x * 2 };\n[1, 2];\ndouble(21);\n"
+    if string(content) != expected {
+        t.Errorf("Expected the session file to be %q but got %q instead", expected, string(content))
+    }
+
+    output, _ = runSessionInputs(":restore " + file, "double(len(xs))")
+    if output != "4\n" {
+        t.Errorf("Expected the restored bindings to work but got %q instead", output)
+    }
+
+    output, _ = runSessionInputs(":restore " + filepath.Join(t.TempDir(), "missing.mk"))
+    if !strings.HasPrefix(output, "Could not restore the session: ") {
+        t.Errorf("Expected a missing file to be reported but got %q instead", output)
+    }
+
+    output, _ = runSessionInputs(":save")
+    if output != "Usage: :save [-final] <file.mk>\n" {
+        t.Errorf("Expected :save without a file to print the usage but got %q instead", output)
+    }
+}
+
+func TestSaveTypeAndTimeInputs(t *testing.T) {
+    var file = filepath.Join(t.TempDir(), "session.mk")
+    runSessionInputs(":type let a = [1]", ":time let b = len(a)", ":time missing", ":save " + file)
+
+    var content, _ = os.ReadFile(file)
+    if string(content) != "let a = [1];\nlet b = len(a);\n" {
+        t.Errorf("Expected the lets of :type and :time to be saved but got %q instead", string(content))
+    }
+}
+
+func TestSessionPathIsTheDefault(t *testing.T) {
+    var file = filepath.Join(t.TempDir(), "session.mk")
+
+    var out bytes.Buffer
+    var session = newEvalSession(&out)
+    session.sessionPath = file
+    session.handle("let a = 1")
+    session.handle(":save")
+
+    var other = newEvalSession(&out)
+    other.sessionPath = file
+    other.handle(":restore")
+    out.Reset()
+    other.handle("a + 1")
+    if out.String() != "2\n" {
+        t.Errorf("Expected :save and :restore to use the session file but got %q instead", out.String())
+    }
+}
+
+func TestFinalDefinitions(t *testing.T) {
+    var tests = []struct {
+        inputs []string; expected []string
+    } {
+        {
+            []string { "let a = 1", "puts(a)", "let a = 2" },
+            []string { "let a = 2" },
+        },
+        {
+            // b was built with the first a, so that one is still needed
+            []string { "let a = 1", "let b = a + 1", "let a = 5" },
+            []string { "let a = 1", "let b = a + 1", "let a = 5" },
+        },
+        {
+            []string { "let a = 1", "let a = a + 1", "let b = 2", "let b = 3" },
+            []string { "let a = 1", "let a = a + 1", "let b = 3" },
+        },
+        {
+            []string { "let f = fn (x) { x }", "f(1)", "let f = fn (x) { x * 2 }; let g = 1", "let g = 2" },
+            []string { "let f = fn (x) { x * 2 }; let g = 1", "let g = 2" },
+        },
+        {
+            // The changes to the final bindings are kept, the ones to bindings defined again are not
+            []string { "let arr = []", "push(arr, 1)", "let h = { \"a\": 1 }", "delete(h, \"a\")", "puts(1)", "len(arr)" },
+            []string { "let arr = []", "push(arr, 1)", "let h = { \"a\": 1 }", "delete(h, \"a\")", "len(arr)" },
+        },
+        {
+            []string { "let arr = []", "push(arr, 1)", "let arr = [2]", "push(arr, 3)" },
+            []string { "let arr = [2]", "push(arr, 3)" },
+        },
+    }
+
+    for _, test := range tests {
+        var kept = finalDefinitions(test.inputs)
+        if strings.Join(kept, " | ") != strings.Join(test.expected, " | ") {
+            t.Errorf("Expected the final definitions of %q to be %q but got %q instead", test.inputs, test.expected, kept)
+        }
+    }
+}
+
+func TestSessionScriptWithComments(t *testing.T) {
+    var inputs = []string {
+        "let a = 1 // the first",
+        "let b = fn (x) {\n    x + a\n} // adds a",
+        "let c = 3; // already closed",
+        "// only a comment",
+        "b(c)",
+    }
+    var expected = "let a = 1 // the first\n;\nlet b = fn (x) {\n    x + a\n} // adds a\n;\nlet c = 3; // already closed\n// only a comment\nb(c);\n"
+
+    var script = sessionScript(inputs)
+    if script != expected {
+        t.Errorf("Expected the script to be %q but got %q instead", expected, script)
+    }
+
+    var file = filepath.Join(t.TempDir(), "session.mk")
+    os.WriteFile(file, []byte(script), 0644)
+    var output, _ = runSessionInputs(":restore " + file, "b(c) + a")
+    if output != "5\n" {
+        t.Errorf("Expected the script with comments to restore but got %q instead", output)
+    }
+}
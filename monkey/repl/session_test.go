@@ -0,0 +1,72 @@
+// monkey/repl/session_test.go
+
+package repl
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// Runs the inputs on a new session and returns the output of the last one
+func runSessionInputs(inputs ...string) (string, *evalSession) {
+    var out bytes.Buffer
+    var session = newEvalSession(&out)
+    for _, input := range inputs {
+        out.Reset()
+        session.handle(input)
+    }
+    return out.String(), session
+}
+
+func TestSessionMetaCommands(t *testing.T) {
+    var file = filepath.Join(t.TempDir(), "lib.mk")
+    os.WriteFile(file, []byte("let double = fn (x) { x * 2 };"), 0644)
+
+    var tests = []struct {
+        inputs []string; expected string
+    } {
+        { []string { "1 + 2" },                                 "3\n"                                       },
+        { []string { ":type 1" },                               "Integer\n"                                 },
+        { []string { ":type [1]" },                             "Array\n"                                   },
+        { []string { ":type fn (x) { x }" },                    "Function\n"                                },
+        { []string { ":tokens a + 1" },                         "[0] IDENT    \"a\"\n[1] +        \"+\"\n[2] INT      \"1\"\n" },
+        { []string { ":ast -a * b" },                           "[0] *ast.ExpressionStatement: ((-a) * b)\n" },
+        { []string { "let b = 2", "let a = [1]", ":env" },      "a = [1]\nb = 2\n"                          },
+        { []string { "let a = 1", ":reset", ":env" },           "(no bindings)\n"                           },
+        { []string { ":load " + file, "double(4)" },            "8\n"                                       },
+        { []string { ":tokens" },                               "Usage: :tokens <expr>\n"                   },
+        { []string { ":what" },                                 "Unknown command :what. Use :help to see the commands\n" },
+        { []string { "let x = fn (a) { a" },                    "[0] Expected } but reached the end of the input\n" },
+    }
+
+    for _, test := range tests {
+        var output, _ = runSessionInputs(test.inputs...)
+        if output != test.expected {
+            t.Errorf("Expected %q to output %q but got %q instead", test.inputs, test.expected, output)
+        }
+    }
+}
+
+func TestSessionTimeAndQuit(t *testing.T) {
+    var output, session = runSessionInputs(":time 2 * 3")
+    if !strings.HasPrefix(output, "6\ntook ") {
+        t.Errorf("Expected :time to print the result and the duration but got %q instead", output)
+    }
+
+    if !session.handle(":q") || !session.handle(":quit") {
+        t.Errorf("Expected :q and :quit to end the session")
+    }
+}
+
+func TestSessionExit(t *testing.T) {
+    var _, session = runSessionInputs("let a = 1")
+    if !session.handle("if (a > 0) { exit(5) }") {
+        t.Errorf("Expected exit to end the session")
+    }
+    if !session.exited || session.exitCode != 5 {
+        t.Errorf("Expected the session to end with code 5 but got %v, %d", session.exited, session.exitCode)
+    }
+}
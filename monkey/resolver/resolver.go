@@ -0,0 +1,256 @@
+// monkey/resolver/resolver.go
+/*
+    Static pass over a program before it runs. It reports the names that are not defined anywhere,
+    the bindings that shadow others and the ones never used, and annotates the identifiers bound
+    inside functions with their depth and slot (see ast.Identifier) so the evaluator reads them
+    without looking up the names. Each function gets a frame with a slot for its parameters and
+    the lets of its body (see ast.ScopeLets), the lets of the same name share one.
+
+    The globals stay in the map of their environment, because the REPL, the debugger and the
+    host (e.g. the args of the scripts) add names to it that the resolver does not see
+*/
+
+package resolver
+
+import (
+    "fmt"
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/token"
+    "monkey/utils"
+    "sort"
+    "strings"
+)
+
+const (
+    Undefined = iota
+    Shadowing
+    Unused
+    UnusedParameter
+)
+
+type Warning struct {
+    Kind    int
+    Pos     token.Position
+    End     token.Position
+    Message string
+}
+
+func (this Warning) String() string {
+    return fmt.Sprintf("%s: %s", this.Pos, this.Message)
+}
+
+var builtins = map[string] bool {}
+
+func init() {
+    for _, name := range evaluator.BuiltinNames() {
+        builtins[name] = true
+    }
+}
+
+func isBuiltin(name string) bool {
+    return builtins[name]
+}
+
+type binding struct {
+    name      string
+    pos       token.Position // Of the name, the zero value for the globals of the host
+    end       token.Position
+    slot      int
+    parameter bool
+    used      bool
+}
+
+type scope struct {
+    outer    *scope
+    function *ast.FunctionLiteral // Nil for the program, the macros and the arms of a match
+    bindings map[string] *binding // The lets of the same name share the first binding
+    order    []*binding
+}
+
+type resolver struct {
+    warnings []Warning
+}
+
+// Resolves the program and returns its warnings sorted by position. The globals are the names the
+// host defines before running it
+func Resolve(program *ast.Program, globals ...string) []Warning {
+    var this = &resolver {}
+    var global = &scope { bindings: map[string] *binding {} }
+    for _, name := range globals {
+        this.declare(global, name, token.Position {}, false)
+    }
+
+    ast.ScopeLets(program.Statements, this.declareLet(global))
+    this.walkStatements(global, program.Statements)
+
+    sort.SliceStable(this.warnings, func (i int, j int) bool {
+        return this.warnings[i].Pos.Offset < this.warnings[j].Pos.Offset
+    })
+    return this.warnings
+}
+
+func (this *resolver) warn(kind int, pos token.Position, end token.Position, format string, args ...any) {
+    this.warnings = append(this.warnings, Warning { Kind: kind, Pos: pos, End: end, Message: fmt.Sprintf(format, args...) })
+}
+
+// Finds the scope that binds the name and how many functions out it is
+func (this *scope) lookup(name string) (*binding, *scope, int) {
+    var depth = 0
+    for sc := this; sc != nil; sc = sc.outer {
+        if bind, found := sc.bindings[name]; found { return bind, sc, depth }
+        depth++
+    }
+    return nil, nil, 0
+}
+
+func (this *resolver) declare(sc *scope, name string, pos token.Position, parameter bool) {
+    if _, found := sc.bindings[name]; found { return }
+
+    var end = ast.NameEnd(pos, name)
+    if pos.Line > 0 {
+        if shadowed, _, _ := sc.outer.lookup(name); shadowed != nil {
+            if shadowed.pos.Line > 0 {
+                this.warn(Shadowing, pos, end, "'%s' shadows the one of line %d", name, shadowed.pos.Line)
+            } else {
+                this.warn(Shadowing, pos, end, "'%s' shadows the global of the same name", name)
+            }
+        } else if isBuiltin(name) {
+            this.warn(Shadowing, pos, end, "'%s' shadows the builtin of the same name", name)
+        }
+    }
+
+    var bind = &binding { name: name, pos: pos, end: end, slot: len(sc.order), parameter: parameter }
+    sc.bindings[name] = bind
+    sc.order = append(sc.order, bind)
+}
+
+// The lets get their slots before the statements are walked, so a function can use the names
+// bound after it in its scope
+func (this *resolver) declareLet(sc *scope) func (let *ast.LetStatement) {
+    return func (let *ast.LetStatement) { this.declare(sc, let.Identifier, let.NamePos, false) }
+}
+
+func (this *resolver) reference(sc *scope, ident *ast.Identifier) {
+    ident.Resolved, ident.Depth, ident.Slot = false, 0, 0
+
+    var bind, owner, depth = sc.lookup(ident.Value)
+    if bind == nil {
+        if !isBuiltin(ident.Value) {
+            this.warn(Undefined, ident.Pos(), ident.End(), "undefined name '%s'", ident.Value)
+        }
+        return
+    }
+
+    bind.used = true
+    if owner.function != nil { ident.Resolved, ident.Depth, ident.Slot = true, depth, bind.slot }
+}
+
+func (this *resolver) walkStatements(sc *scope, stms []ast.Statement) {
+    for _, stm := range stms {
+        this.walk(sc, stm)
+    }
+}
+
+func (this *resolver) walkFunction(sc *scope, fn *ast.FunctionLiteral) {
+    var inner = &scope { outer: sc, function: fn, bindings: map[string] *binding {} }
+    for _, param := range fn.Parameters {
+        this.declare(inner, param.Value, param.Pos(), true)
+    }
+    ast.ScopeLets(fn.Body.Statements, this.declareLet(inner))
+    this.walkStatements(inner, fn.Body.Statements)
+
+    fn.Frame = make([]string, len(inner.order))
+    for i, bind := range inner.order {
+        fn.Frame[i] = bind.name
+    }
+    this.warnUnused(inner)
+}
+
+func (this *resolver) warnUnused(sc *scope) {
+    for _, bind := range sc.order {
+        if bind.used || strings.HasPrefix(bind.name, "_") { continue }
+        if bind.parameter {
+            this.warn(UnusedParameter, bind.pos, bind.end, "parameter '%s' is not used", bind.name)
+        } else {
+            this.warn(Unused, bind.pos, bind.end, "'%s' is declared but not used", bind.name)
+        }
+    }
+}
+
+// The body of a macro runs during the expansion on an environment without slots
+func (this *resolver) walkMacro(sc *scope, macro *ast.MacroLiteral) {
+    var inner = &scope { outer: sc, bindings: map[string] *binding {} }
+    for _, param := range macro.Parameters {
+        this.declare(inner, param.Value, param.Pos(), true)
+    }
+    ast.ScopeLets(macro.Body.Statements, this.declareLet(inner))
+    this.walkStatements(inner, macro.Body.Statements)
+    this.warnUnused(inner)
+}
+
+// The code of a quote is not evaluated where it is written, only the arguments of its unquotes
+func (this *resolver) walkQuote(sc *scope, quote *ast.CallExpression) {
+    for _, param := range quote.Parameters {
+        ast.Inspect(param, func (node ast.Node) bool {
+            var call, isCall = node.(*ast.CallExpression)
+            if !isCall || call.Expression.String() != "unquote" { return true }
+            for _, arg := range call.Parameters {
+                this.walk(sc, arg)
+            }
+            return false
+        })
+    }
+}
+
+// Each arm binds the names of its pattern in a scope of its own, like the environment the
+// evaluator gives it. Those names are looked up by name, the scope has no slots
+func (this *resolver) walkMatch(sc *scope, match *ast.MatchExpression) {
+    this.walk(sc, match.Value)
+    for _, arm := range match.Arms {
+        var inner = &scope { outer: sc, bindings: map[string] *binding {} }
+        ast.Inspect(arm.Pattern, func (node ast.Node) bool {
+            if binding, isBinding := node.(*ast.BindingPattern); isBinding {
+                this.declare(inner, binding.Name, binding.Pos(), false)
+            }
+            return true
+        })
+        ast.IfLets(arm.Body, this.declareLet(inner))
+        if arm.Guard != nil { this.walk(inner, arm.Guard) }
+        this.walk(inner, arm.Body)
+        this.warnUnused(inner)
+    }
+}
+
+// Resolves the identifiers under the node. The nodes with a scope of their own, and the ones where
+// an identifier is not a reference, are handled apart
+func (this *resolver) walk(sc *scope, node ast.Node) {
+    if utils.IsNill(node) { return }
+    ast.Inspect(node, func (node ast.Node) bool {
+        switch node := node.(type) {
+        case *ast.Identifier:
+            this.reference(sc, node)
+        case *ast.FunctionLiteral:
+            this.walkFunction(sc, node)
+            return false
+        case *ast.MacroLiteral:
+            this.walkMacro(sc, node)
+            return false
+        case *ast.MatchExpression:
+            this.walkMatch(sc, node)
+            return false
+        case *ast.CallExpression:
+            if node.Expression.String() == "quote" {
+                this.walkQuote(sc, node)
+                return false
+            }
+        case *ast.MethodExpression: // The name of the method is not a reference
+            this.walk(sc, node.Expression)
+            for _, param := range node.Call.Parameters {
+                this.walk(sc, param)
+            }
+            return false
+        }
+        return true
+    })
+}
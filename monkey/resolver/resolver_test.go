@@ -0,0 +1,119 @@
+// monkey/resolver/resolver_test.go
+
+package resolver
+
+import (
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "strings"
+    "testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+    t.Helper()
+    var p = parser.NewParser(lexer.NewLexer(input))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { t.Fatalf("Unexpected parser errors: %v", p.Errors()) }
+    return program
+}
+
+func TestWarnings(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `let a = 1; a + b`,                              "1:16: undefined name 'b'" },
+        { `let f = fn () { g() }; let g = fn () { 1 };`,   "" },
+        { `args; puts(len(args))`,                         "" },
+        { `let x = 1; let f = fn (x) { x }; f(x)`,         "1:24: 'x' shadows the one of line 1" },
+        { `let f = fn (args) { args }; f(1)`,              "1:13: 'args' shadows the global of the same name" },
+        { `let len = fn (x) { x }; len(1)`,                "1:5: 'len' shadows the builtin of the same name" },
+        { `let f = fn (a, _b) { let c = 1; a }; f(1, 2)`,  "1:26: 'c' is declared but not used" },
+        { `let f = fn (a, b) { a }; f(1, 2)`,              "1:16: parameter 'b' is not used" },
+        { `let unused = 1; let f = fn () { 1 };`,          "" },
+        { `[1].map(fn (x) { x + y })`,                     "1:22: undefined name 'y'" },
+        { `let f = fn (n) { if (n) { let m = n }; m }; f(1)`, "" },
+
+        // The quoted code is not evaluated where it is written, only the unquotes
+        { `let m = macro (x, y) { quote(a + unquote(x)) }`, "1:19: parameter 'y' is not used" },
+        { `quote(a + unquote(b))`,                         "1:19: undefined name 'b'" },
+
+        // The names of a pattern are only seen by its arm
+        { `match (1) { n => 2, _ => 3 }`,                  "1:13: 'n' is declared but not used" },
+        { `match ([1]) { [a, ..._r] if a > 0 => a }; a`,   "1:43: undefined name 'a'" },
+        { `let f = fn (x) { match (x) { [x] => x } }; f(1)`, "1:31: 'x' shadows the one of line 1" },
+    }
+
+    for _, test := range tests {
+        var warnings = []string {}
+        for _, warning := range Resolve(parse(t, test.input), "args") {
+            warnings = append(warnings, warning.String())
+        }
+        if got := strings.Join(warnings, ", "); got != test.expected {
+            t.Errorf("Expected the warnings of '%s' to be '%s' but got '%s'", test.input, test.expected, got)
+        }
+    }
+}
+
+func TestAnnotations(t *testing.T) {
+    var program = parse(t, `let g = 1;
+let outer = fn (a) {
+    let b = 2;
+    fn (c) { a + b + c + g }
+};`)
+    Resolve(program)
+
+    var outer = program.Statements[1].(*ast.LetStatement).Expression.(*ast.FunctionLiteral)
+    if got := strings.Join(outer.Frame, " "); got != "a b" { t.Errorf("Expected the frame a b but got %s", got) }
+
+    var inner = outer.Body.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+    if got := strings.Join(inner.Frame, " "); got != "c" { t.Errorf("Expected the frame c but got %s", got) }
+
+    // ((a + b) + c) + g
+    var sum = inner.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+    var left = sum.Left.(*ast.InfixExpression)
+    var tests = []struct {
+        ident *ast.Identifier; resolved bool; depth int; slot int
+    } {
+        { left.Left.(*ast.InfixExpression).Left.(*ast.Identifier),  true,  1, 0 },
+        { left.Left.(*ast.InfixExpression).Right.(*ast.Identifier), true,  1, 1 },
+        { left.Right.(*ast.Identifier),                             true,  0, 0 },
+        { sum.Right.(*ast.Identifier),                              false, 0, 0 },
+    }
+    for _, test := range tests {
+        var ident = test.ident
+        if ident.Resolved != test.resolved || ident.Depth != test.depth || ident.Slot != test.slot {
+            t.Errorf("Expected %s to be resolved=%t at depth %d slot %d but got %t %d %d",
+                ident.Value, test.resolved, test.depth, test.slot, ident.Resolved, ident.Depth, ident.Slot)
+        }
+    }
+}
+
+// The resolved programs give the same results than the ones that look up every name
+func TestEvaluation(t *testing.T) {
+    var tests = []string {
+        `let fib = fn (n) { if (n < 2) { return n }; fib(n - 1) + fib(n - 2) }; fib(15)`,
+        `let adder = fn (a) { fn (b) { a + b } }; let addTwo = adder(2); [addTwo(1), addTwo(10)]`,
+        `let x = 1; let f = fn () { let y = x; let x = 10; [y, x] }; f()`,
+        `let f = fn (n) { if (n > 0) { let m = n * 2 }; m }; f(0)`,
+        `let f = fn (n) { if (n > 0) { let m = n * 2 }; m }; f(4)`,
+        `let later = fn () { after }; let after = 3; later()`,
+        `let f = fn (xs) { xs.map(fn (x) { x * len(xs) }) }; f([1, 2])`,
+        `let f = fn (h) { h["k"] + h.len() }; f({ "k": 1 })`,
+        `let sum = fn (xs) { match (xs) { [] => 0, [x, ...rest] => x + sum(rest) } }; sum([1, 2, 3])`,
+        `let f = fn (v, k) { match (v) { { "a": [x] } if x > k => fn (y) { x + y + k }(1), _ => k } }; f({ "a": [5] }, 2)`,
+    }
+
+    for _, input := range tests {
+        var expected = evaluator.Eval(parse(t, input), object.NewEnvironment())
+
+        var program = parse(t, input)
+        Resolve(program)
+        var got = evaluator.Eval(program, object.NewEnvironment())
+        if got.Inspect() != expected.Inspect() {
+            t.Errorf("Expected '%s' to evaluate to %s when resolved but got %s", input, expected.Inspect(), got.Inspect())
+        }
+    }
+}
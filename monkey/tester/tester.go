@@ -0,0 +1,195 @@
+// monkey/tester/tester.go
+/*
+    Runs the tests written in monkey. The tests are the functions without parameters bound by a
+    top level let whose name starts with test_, in files named *_test.mk. The top level of a file
+    is evaluated once, then the tests are called in the order they are written, each one in an
+    environment of its own enclosed in the top level. The values the tests change, like an array
+    they push to, are seen by the tests that come after them. A test fails when it returns an
+    error, usually from the assert builtins
+*/
+
+package tester
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "monkey/ast"
+    "monkey/coverage"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "monkey/resolver"
+    "regexp"
+    "strings"
+    "time"
+)
+
+const (
+    FileSuffix = "_test.mk"
+    TestPrefix = "test_"
+)
+
+type Result struct {
+    Name     string
+    Error    *object.Error // Nil when the test passed
+    Line     int           // Where the error appeared, 0 when unknown
+    Duration time.Duration
+}
+
+func (this Result) Passed() bool {
+    return this.Error == nil
+}
+
+type Runner struct {
+    Out          io.Writer      // Where the report is written
+    ProgramOut   io.Writer      // Where puts and print of the tests write
+    Capabilities evaluator.Capabilities
+    Filter       *regexp.Regexp // Runs only the tests whose name matches, all of them when nil
+    Verbose      bool           // Reports the tests that pass too
+    Cover        bool           // Collects the coverage of each file
+
+    Coverage     []*coverage.Coverage // Of the files run, when Cover is set
+}
+
+// The names of the tests of the program in the order they are written
+func Discover(program *ast.Program) []string {
+    var names = []string {}
+    for _, stm := range program.Statements {
+        var let, isLet = stm.(*ast.LetStatement)
+        if !isLet || !strings.HasPrefix(let.Identifier, TestPrefix) { continue }
+        if fn, isFn := let.Expression.(*ast.FunctionLiteral); isFn && len(fn.Parameters) == 0 {
+            names = append(names, let.Identifier)
+        }
+    }
+    return names
+}
+
+// Remembers where the last error appeared
+type errorLocator struct {
+    evaluator.BaseObserver
+    err  *object.Error
+    node ast.Node
+}
+
+// @Impl
+func (this *errorLocator) Error(err *object.Error, node ast.Node) {
+    this.err, this.node = err, node
+}
+
+func (this *errorLocator) line(err *object.Error) int {
+    if err != this.err || this.node == nil { return 0 }
+    return this.node.Pos().Line
+}
+
+// The file evaluated once, where its tests are called
+type suite struct {
+    interpreter *evaluator.Interpreter
+    locator     *errorLocator
+    env         *object.Environment
+    err         *object.Error // Of the top level, it fails every test
+    line        int
+}
+
+func (this *Runner) newSuite(program *ast.Program, cover *coverage.Coverage) *suite {
+    var locator = &errorLocator {}
+    var interpreter = evaluator.NewInterpreter()
+    interpreter.Out = this.ProgramOut
+    interpreter.Capabilities = this.Capabilities
+    interpreter.Capabilities.Exit = false
+    if cover != nil {
+        interpreter.Observer = evaluator.Observe(locator, cover)
+    } else {
+        interpreter.Observer = locator
+    }
+
+    var s = &suite { interpreter: interpreter, locator: locator, env: object.NewEnvironment() }
+    if errObj, isErr := interpreter.Eval(program, s.env).(*object.Error); isErr {
+        s.err, s.line = errObj, locator.line(errObj)
+    }
+    return s
+}
+
+// Calls the test in an environment enclosed in the top level
+func (this *suite) run(name string) Result {
+    if this.err != nil { return Result { Name: name, Error: this.err, Line: this.line } }
+
+    var start = time.Now()
+    var value, _ = this.env.Get(name)
+    if fn, isFn := value.(*object.Function); isFn {
+        var enclosed = *fn
+        enclosed.Env = object.NewEnclosedEnvironment(fn.Env)
+        value = &enclosed
+    }
+    var result = this.interpreter.Apply(value)
+    var testResult = Result { Name: name, Duration: time.Since(start) }
+    if errObj, isErr := result.(*object.Error); isErr {
+        testResult.Error, testResult.Line = errObj, this.locator.line(errObj)
+    }
+    return testResult
+}
+
+// Runs the tests of one file and reports them. The error is for the files that do not parse
+func (this *Runner) RunFile(file string, source string) ([]Result, error) {
+    var p = parser.NewParser(lexer.NewLexer(source))
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 {
+        var messages = []string {}
+        for _, msg := range p.Errors() {
+            messages = append(messages, fmt.Sprintf("%s: syntax error: %s", file, msg))
+        }
+        return nil, errors.New(strings.Join(messages, "\n"))
+    }
+
+    // The macros are expanded once, each test evaluates the expanded program
+    var expander = evaluator.NewInterpreter()
+    expander.Out = this.ProgramOut
+    if err := expander.ExpandMacros(program, object.NewEnvironment()); err != nil {
+        return nil, fmt.Errorf("%s:%s", file, err)
+    }
+    resolver.Resolve(program)
+
+    var cover *coverage.Coverage
+    if this.Cover {
+        cover = coverage.NewCoverage(file, source, program)
+        this.Coverage = append(this.Coverage, cover)
+    }
+
+    var results = []Result {}
+    var failed = 0
+    var start = time.Now()
+    var tests *suite // The top level is only evaluated when a test runs
+    for _, name := range Discover(program) {
+        if this.Filter != nil && !this.Filter.MatchString(name) { continue }
+
+        if tests == nil { tests = this.newSuite(program, cover) }
+        var result = tests.run(name)
+        results = append(results, result)
+        if result.Passed() {
+            if this.Verbose { fmt.Fprintf(this.Out, "--- PASS: %s (%s)\n", name, formatDuration(result.Duration)) }
+            continue
+        }
+
+        failed++
+        var location = file
+        if result.Line > 0 { location = fmt.Sprintf("%s:%d", file, result.Line) }
+        fmt.Fprintf(this.Out, "--- FAIL: %s (%s)\n", name, formatDuration(result.Duration))
+        fmt.Fprintf(this.Out, "    %s: %s\n", location, strings.ReplaceAll(result.Error.Message, "\n", "\n    "))
+    }
+
+    var elapsed = formatDuration(time.Since(start))
+    switch {
+    case len(results) == 0:
+        fmt.Fprintf(this.Out, "?    %s [no tests to run]\n", file)
+    case failed > 0:
+        fmt.Fprintf(this.Out, "FAIL %s %s (%d of %d failed)\n", file, elapsed, failed, len(results))
+    default:
+        fmt.Fprintf(this.Out, "ok   %s %s (%d tests)\n", file, elapsed, len(results))
+    }
+    return results, nil
+}
+
+func formatDuration(duration time.Duration) string {
+    return fmt.Sprintf("%.3fs", duration.Seconds())
+}
@@ -0,0 +1,114 @@
+// monkey/tester/tester_test.go
+
+package tester
+
+import (
+    "bytes"
+    "monkey/lexer"
+    "monkey/parser"
+    "regexp"
+    "strings"
+    "testing"
+)
+
+const source = `let items = [];
+let add = fn (x) { push(items, x) };
+
+let test_first = fn () {
+    add(1);
+    assert_eq(items, [1]);
+};
+let test_shared = fn () {
+    add(2);
+    assert_eq(items, [1, 2], "the items of test_first are kept");
+};
+let test_fails = fn () {
+    assert(true);
+    assert_eq(len(items), 1);
+};
+let test_with_param = fn (x) { x };
+let helper_test = fn () { 1 };
+let test_not_fn = 1;
+puts("top level");`
+
+var durations = regexp.MustCompile(`\d+\.\d{3}s`)
+
+func runFile(t *testing.T, runner *Runner, input string) ([]Result, string) {
+    t.Helper()
+    var out bytes.Buffer
+    runner.Out, runner.ProgramOut = &out, &out
+    var results, err = runner.RunFile("list_test.mk", input)
+    if err != nil { t.Fatalf("Unexpected error: %s", err) }
+    return results, durations.ReplaceAllString(out.String(), "0.000s")
+}
+
+func TestDiscover(t *testing.T) {
+    var program = parser.NewParser(lexer.NewLexer(source)).ParseProgram()
+    var got = strings.Join(Discover(program), " ")
+    if got != "test_first test_shared test_fails" {
+        t.Errorf("Expected the tests test_first test_shared test_fails but got %s", got)
+    }
+}
+
+func TestRunFile(t *testing.T) {
+    var results, out = runFile(t, &Runner {}, source)
+
+    // The top level runs once, before the first test
+    var expected = `top level
+--- FAIL: test_fails (0.000s)
+    list_test.mk:14: assert_eq failed
+        expected: 1
+        actual:   2
+FAIL list_test.mk 0.000s (1 of 3 failed)
+`
+    if out != expected { t.Errorf("Expected the report\n%s\nbut got\n%s", expected, out) }
+
+    var passed = []bool {}
+    for _, result := range results { passed = append(passed, result.Passed()) }
+    if len(results) != 3 || !passed[0] || !passed[1] || passed[2] || results[2].Line != 14 {
+        t.Errorf("Unexpected results %+v", results)
+    }
+}
+
+func TestFilterAndVerbose(t *testing.T) {
+    var runner = &Runner { Filter: regexp.MustCompile("first|shared"), Verbose: true }
+    var _, out = runFile(t, runner, source)
+
+    var expected = `top level
+--- PASS: test_first (0.000s)
+--- PASS: test_shared (0.000s)
+ok   list_test.mk 0.000s (2 tests)
+`
+    if out != expected { t.Errorf("Expected the report\n%s\nbut got\n%s", expected, out) }
+
+    runner.Filter = regexp.MustCompile("missing")
+    _, out = runFile(t, runner, source)
+    if out != "?    list_test.mk [no tests to run]\n" { t.Errorf("Expected no tests to run but got %s", out) }
+}
+
+func TestFileErrors(t *testing.T) {
+    // An error at the top level fails every test, where it appeared
+    var _, out = runFile(t, &Runner {}, "let x = 1 + \"a\";\nlet test_one = fn () { puts(\"never\") };")
+    if !strings.Contains(out, "--- FAIL: test_one") || !strings.Contains(out, "list_test.mk:1: type mismatch: Integer + String") {
+        t.Errorf("Expected the error of the file in the report but got\n%s", out)
+    }
+    if strings.Contains(out, "never") { t.Errorf("Expected the test not to run but got\n%s", out) }
+
+    var _, err = (&Runner {}).RunFile("bad_test.mk", "let = 1;")
+    if err == nil || !strings.HasPrefix(err.Error(), "bad_test.mk: syntax error: ") {
+        t.Errorf("Expected a syntax error but got %v", err)
+    }
+}
+
+func TestCoverage(t *testing.T) {
+    var runner = &Runner { Cover: true, Filter: regexp.MustCompile("first") }
+    runFile(t, runner, source)
+    if len(runner.Coverage) != 1 { t.Fatalf("Expected the coverage of one file but got %d", len(runner.Coverage)) }
+
+    var run = 0
+    for _, stm := range runner.Coverage[0].Statements() {
+        if stm.Count > 0 { run++ }
+    }
+    // The 9 top level statements and the statements of add and test_first
+    if run != 12 { t.Errorf("Expected 12 statements run but got %d", run) }
+}
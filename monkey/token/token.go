@@ -2,6 +2,8 @@
 
 package token
 
+import "strconv"
+
 const (
     // Special types
     Illegal    = "ILLEGAL"
@@ -34,6 +36,9 @@ const (
 
     Dot        = "."
     Colon      = ":"
+    Arrow      = "->" // Before the return type of a function
+    FatArrow   = "=>" // Between the pattern and the result of an arm of a match
+    Ellipsis   = "..." // Before the name of the rest of an array pattern
 
     // Grouping
     Lparen     = "("
@@ -51,11 +56,33 @@ const (
     If         = "IF"
     Else       = "ELSE"
     Return     = "RETURN"
+    Macro      = "MACRO"
+    Match      = "MATCH"
 )
 
+// A place in the input. Line and Column start at 1 and the Column counts bytes
+type Position struct {
+    Offset int
+    Line   int
+    Column int
+}
+
+func (this Position) String() string {
+    return strconv.Itoa(this.Line) + ":" + strconv.Itoa(this.Column)
+}
+
+// Comments are not tokens, the lexer keeps them on the side for the tools that need them
+type Comment struct {
+    Text string // With the leading '//'
+    Pos  Position
+    End  Position
+}
+
 type Token struct {
     Type string
     Literal string
+    Pos Position // First character
+    End Position // Right after the last character
 }
 
 func NewToken(tokenType string, value byte) Token {
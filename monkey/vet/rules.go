@@ -0,0 +1,265 @@
+// monkey/vet/rules.go
+
+package vet
+
+import (
+    "fmt"
+    "monkey/ast"
+    "monkey/evaluator"
+    "monkey/resolver"
+    "strings"
+)
+
+type reporter = func (node ast.Node, format string, args ...any)
+
+// The rules in the order they are documented
+func Rules() []Rule {
+    return []Rule {
+        { "unreachable", "statements after a return of the same block", checkUnreachable },
+        { "constant-condition", "if conditions made only of literals", checkConstantConditions },
+        { "builtin-arity", "builtins called with a wrong number of arguments", checkBuiltinArity },
+        { "type-mismatch", "operators between values of different literal types", checkTypeMismatch },
+        { "unused-parameter", "parameters the function never reads, the ones named _x are skipped", checkUnusedParameters },
+        { "duplicate-key", "hash literals with the same literal key twice", checkDuplicateKeys },
+    }
+}
+
+// Calls visit with every node of the program, the parents before their children
+func inspect(node ast.Node, visit func (node ast.Node)) {
+    ast.Inspect(node, func (node ast.Node) bool {
+        visit(node)
+        return true
+    })
+}
+
+func checkUnreachable(program *ast.Program, report reporter) {
+    var checkBlock = func (stms []ast.Statement) {
+        for i, stm := range stms {
+            if _, isReturn := stm.(*ast.ReturnStatement); isReturn && i + 1 < len(stms) {
+                report(stms[i + 1], "unreachable code after the return of line %d", stm.Pos().Line)
+                return
+            }
+        }
+    }
+
+    checkBlock(program.Statements)
+    inspect(program, func (node ast.Node) {
+        if block, isBlock := node.(*ast.StatementsBlock); isBlock { checkBlock(block.Statements) }
+    })
+}
+
+// Literals, or operators between literals
+func isConstant(exp ast.Expression) bool {
+    switch exp := exp.(type) {
+    case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+        return true
+    case *ast.PrefixExpression:
+        return isConstant(exp.Value)
+    case *ast.InfixExpression:
+        return isConstant(exp.Left) && isConstant(exp.Right)
+    default:
+        return false
+    }
+}
+
+func checkConstantConditions(program *ast.Program, report reporter) {
+    inspect(program, func (node ast.Node) {
+        var ifExp, isIf = node.(*ast.IfExpression)
+        if !isIf || !isConstant(ifExp.Condition) { return }
+
+        var branch = "the same branch"
+        if _, isString := ifExp.Condition.(*ast.StringLiteral); isString { branch = "no branch, only booleans and integers do" }
+        report(ifExp.Condition, "the condition is a constant, the if always takes %s", branch)
+    })
+}
+
+// The number of arguments a builtin takes, from the signature of its doc. The optional
+// parameters end with '?' and the variadic ones with '...'. The max is -1 when there is no max
+func builtinArity(name string) (int, int, bool) {
+    var doc, found = evaluator.GetBuiltinDoc(name)
+    if !found { return 0, 0, false }
+
+    var minArgs, maxArgs = -1, 0
+    // Each alternative of the signature: range(end) | range(start, end, step?)
+    var depth, start = 0, 0
+    for i, ch := range doc.Signature {
+        switch ch {
+        case '(':
+            if depth == 0 { start = i + 1 }
+            depth++
+        case ')':
+            depth--
+            if depth > 0 { continue }
+
+            var required, total, variadic = 0, 0, false
+            for _, param := range splitParameters(doc.Signature[start:i]) {
+                switch {
+                case strings.Contains(param, "..."):
+                    variadic = true
+                case strings.Contains(param, "?"):
+                    total++
+                default:
+                    required++
+                    total++
+                }
+            }
+            if minArgs < 0 || required < minArgs { minArgs = required }
+            if maxArgs >= 0 { maxArgs = max(maxArgs, total) }
+            if variadic { maxArgs = -1 }
+        }
+    }
+    return max(minArgs, 0), maxArgs, true
+}
+
+// The parameters of a signature, without splitting the ones of the function parameters
+func splitParameters(params string) []string {
+    var result = []string {}
+    var depth, start = 0, 0
+    for i, ch := range params + "," {
+        switch {
+        case ch == '(':
+            depth++
+        case ch == ')':
+            depth--
+        case ch == ',' && depth == 0:
+            if param := strings.TrimSpace(params[start:min(i, len(params))]); param != "" { result = append(result, param) }
+            start = i + 1
+        }
+    }
+    return result
+}
+
+func describeArity(minArgs int, maxArgs int) string {
+    var count = func (n int) string {
+        if n == 1 { return "1 argument" }
+        return fmt.Sprintf("%d arguments", n)
+    }
+    switch {
+    case maxArgs < 0:
+        return "at least " + count(minArgs)
+    case minArgs == maxArgs:
+        return count(minArgs)
+    default:
+        return fmt.Sprintf("%d to %s", minArgs, count(maxArgs))
+    }
+}
+
+// The names bound anywhere in the program, they hide the builtins of the same name
+func boundNames(program *ast.Program) map[string] bool {
+    var names = map[string] bool {}
+    inspect(program, func (node ast.Node) {
+        switch node := node.(type) {
+        case *ast.LetStatement:
+            names[node.Identifier] = true
+        case *ast.FunctionLiteral:
+            for _, param := range node.Parameters { names[param.Value] = true }
+        case *ast.MacroLiteral:
+            for _, param := range node.Parameters { names[param.Value] = true }
+        case *ast.BindingPattern:
+            names[node.Name] = true
+        }
+    })
+    return names
+}
+
+func checkBuiltinArity(program *ast.Program, report reporter) {
+    var bound = boundNames(program)
+    var check = func (node ast.Node, name string, args int) {
+        if bound[name] { return }
+        var minArgs, maxArgs, found = builtinArity(name)
+        if !found || (args >= minArgs && (maxArgs < 0 || args <= maxArgs)) { return }
+        report(node, "%s takes %s but got %d", name, describeArity(minArgs, maxArgs), args)
+    }
+
+    var methodCalls = map[*ast.CallExpression] bool {} // Visited after their method
+    inspect(program, func (node ast.Node) {
+        switch node := node.(type) {
+        case *ast.CallExpression:
+            if methodCalls[node] { return }
+            if ident, isIdent := node.Expression.(*ast.Identifier); isIdent { check(node, ident.Value, len(node.Parameters)) }
+        case *ast.MethodExpression: // The receiver is the first argument
+            methodCalls[node.Call] = true
+            check(node, node.Call.Expression.String(), len(node.Call.Parameters) + 1)
+        }
+    })
+}
+
+// The type of the value of the expression when it is obvious from the source, else ""
+func literalType(exp ast.Expression) string {
+    switch exp := exp.(type) {
+    case *ast.IntegerLiteral:
+        return "Integer"
+    case *ast.StringLiteral:
+        return "String"
+    case *ast.Boolean:
+        return "Boolean"
+    case *ast.ArrayLiteral:
+        return "Array"
+    case *ast.HashLiteral:
+        return "Hash"
+    case *ast.FunctionLiteral:
+        return "Function"
+    case *ast.PrefixExpression:
+        if exp.Operator == "-" { return "Integer" }
+        return "Boolean"
+    case *ast.InfixExpression:
+        switch exp.Operator {
+        case "<", ">", "==", "!=":
+            return "Boolean"
+        }
+        var left, right = literalType(exp.Left), literalType(exp.Right)
+        if left == right { return left }
+    }
+    return ""
+}
+
+func checkTypeMismatch(program *ast.Program, report reporter) {
+    inspect(program, func (node ast.Node) {
+        var infix, isInfix = node.(*ast.InfixExpression)
+        if !isInfix { return }
+        var left, right = literalType(infix.Left), literalType(infix.Right)
+        if left == "" || right == "" || left == right { return }
+        report(infix, "%s %s %s always fails with a type mismatch", left, infix.Operator, right)
+    })
+}
+
+func checkUnusedParameters(program *ast.Program, report reporter) {
+    for _, warning := range resolver.Resolve(program) {
+        if warning.Kind != resolver.UnusedParameter { continue }
+        var node = &ast.Identifier {}
+        node.SetSpan(warning.Pos, warning.End)
+        report(node, "%s", warning.Message)
+    }
+}
+
+// The literal as it is written, to compare it with the others. "" when it is not a literal
+func literalKey(exp ast.Expression) string {
+    switch exp := exp.(type) {
+    case *ast.IntegerLiteral:
+        return fmt.Sprintf("%d", exp.Value)
+    case *ast.StringLiteral:
+        return fmt.Sprintf("%q", exp.Value)
+    case *ast.Boolean:
+        return fmt.Sprintf("%t", exp.Value)
+    default:
+        return ""
+    }
+}
+
+func checkDuplicateKeys(program *ast.Program, report reporter) {
+    inspect(program, func (node ast.Node) {
+        var hash, isHash = node.(*ast.HashLiteral)
+        if !isHash { return }
+
+        var seen = map[string] ast.Expression {}
+        for _, key := range hash.Keys {
+            var literal = literalKey(key)
+            if literal == "" { continue }
+            if first, found := seen[literal]; found {
+                report(key, "the key %s is already in the hash at %s", literal, first.Pos())
+                continue
+            }
+            seen[literal] = key
+        }
+    })
+}
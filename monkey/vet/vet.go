@@ -0,0 +1,139 @@
+// monkey/vet/vet.go
+/*
+    Finds the mistakes that parse but fail or do nothing when the script runs. Each rule can be
+    turned off in a JSON config file:
+
+        { "rules": { "unused-parameter": false } }
+
+    and a '// vet:ignore' comment hides the findings of its line, or of the next line when it is
+    alone in its line. It can name the rules to hide: '// vet:ignore constant-condition, unreachable'
+*/
+
+package vet
+
+import (
+    "encoding/json"
+    "fmt"
+    "monkey/ast"
+    "monkey/lexer"
+    "monkey/parser"
+    "monkey/token"
+    "os"
+    "sort"
+    "strings"
+    "unicode"
+)
+
+const ignoreComment = "vet:ignore"
+
+type Finding struct {
+    Rule    string
+    Pos     token.Position
+    End     token.Position
+    Message string
+}
+
+func (this Finding) String() string {
+    return fmt.Sprintf("%s: %s (%s)", this.Pos, this.Message, this.Rule)
+}
+
+type Rule struct {
+    Name    string
+    Summary string
+    check   func (program *ast.Program, report func (node ast.Node, format string, args ...any))
+}
+
+// The rules that are on, by name. The missing ones are on
+type Config struct {
+    Rules map[string] bool `json:"rules"`
+}
+
+func DefaultConfig() Config {
+    return Config { Rules: map[string] bool {} }
+}
+
+func (this Config) enabled(rule string) bool {
+    var on, found = this.Rules[rule]
+    return !found || on
+}
+
+func findRule(name string) (Rule, bool) {
+    for _, rule := range Rules() {
+        if rule.Name == name { return rule, true }
+    }
+    return Rule {}, false
+}
+
+// Reads the config file, the rules it names must exist
+func LoadConfig(path string) (Config, error) {
+    var data, err = os.ReadFile(path)
+    if err != nil { return Config {}, err }
+
+    var config = DefaultConfig()
+    if err := json.Unmarshal(data, &config); err != nil { return Config {}, fmt.Errorf("%s: %s", path, err) }
+    for name := range config.Rules {
+        if _, found := findRule(name); !found { return Config {}, fmt.Errorf("%s: unknown rule '%s'", path, name) }
+    }
+    if config.Rules == nil { config.Rules = map[string] bool {} }
+    return config, nil
+}
+
+// The rules hidden at each line by the ignore comments, an empty list hides all of them
+func ignoredLines(comments []token.Comment, source string) map[int] []string {
+    var lines = strings.Split(source, "\n")
+    var ignored = map[int] []string {}
+    for _, comment := range comments {
+        var text = strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+        var list, isIgnore = strings.CutPrefix(text, ignoreComment)
+        if !isIgnore || (list != "" && !unicode.IsSpace(rune(list[0]))) { continue } // Not vet:ignoreXYZ
+
+        var rules = []string {}
+        for _, name := range strings.Split(list, ",") {
+            if name = strings.TrimSpace(name); name != "" { rules = append(rules, name) }
+        }
+
+        // Alone in its line it is about the next one
+        var line = comment.Pos.Line
+        var before = lines[line - 1][:comment.Pos.Column - 1]
+        if strings.TrimSpace(before) == "" { line++ }
+        if all, found := ignored[line]; found && len(all) == 0 { continue } // Hides all of them already
+        if len(rules) == 0 {
+            ignored[line] = []string {}
+        } else {
+            ignored[line] = append(ignored[line], rules...)
+        }
+    }
+    return ignored
+}
+
+func isIgnored(ignored map[int] []string, finding Finding) bool {
+    var rules, found = ignored[finding.Pos.Line]
+    if !found { return false }
+    if len(rules) == 0 { return true }
+    for _, rule := range rules {
+        if rule == finding.Rule { return true }
+    }
+    return false
+}
+
+// Checks the source with the rules the config enables, the findings are sorted by position.
+// Returns a *parser.SyntaxError when it does not parse
+func Check(source string, config Config) ([]Finding, error) {
+    var lx = lexer.NewLexer(source)
+    var p = parser.NewParser(lx)
+    var program = p.ParseProgram()
+    if len(p.Errors()) > 0 { return nil, &parser.SyntaxError { Messages: p.Errors() } }
+
+    var ignored = ignoredLines(lx.Comments(), source)
+    var findings = []Finding {}
+    for _, rule := range Rules() {
+        if !config.enabled(rule.Name) { continue }
+        rule.check(program, func (node ast.Node, format string, args ...any) {
+            var finding = Finding { Rule: rule.Name, Pos: node.Pos(), End: node.End(), Message: fmt.Sprintf(format, args...) }
+            if !isIgnored(ignored, finding) { findings = append(findings, finding) }
+        })
+    }
+
+    sort.SliceStable(findings, func (i int, j int) bool { return findings[i].Pos.Offset < findings[j].Pos.Offset })
+    return findings, nil
+}
@@ -0,0 +1,138 @@
+// monkey/vet/vet_test.go
+
+package vet
+
+import (
+    "errors"
+    "io"
+    "monkey/evaluator"
+    "monkey/lexer"
+    "monkey/object"
+    "monkey/parser"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func check(t *testing.T, input string, config Config) string {
+    t.Helper()
+    var findings, err = Check(input, config)
+    if err != nil { t.Fatalf("Unexpected error checking '%s': %s", input, err) }
+
+    var lines = []string {}
+    for _, finding := range findings {
+        lines = append(lines, finding.String())
+    }
+    return strings.Join(lines, ", ")
+}
+
+func TestRules(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { `let f = fn (x) { return x; puts(x) }; f(1)`,  "1:28: unreachable code after the return of line 1 (unreachable)" },
+        { `let f = fn (x) { puts(x); return x }; f(1)`,  "" },
+        { `if (true) { 1 } else { 2 }`,                  "1:5: the condition is a constant, the if always takes the same branch (constant-condition)" },
+        { `if ("yes") { 1 }`,                            "1:5: the condition is a constant, the if always takes no branch, only booleans and integers do (constant-condition)" },
+        { `let x = 1; if (x > 1) { 1 }`,                 "" },
+        { `len(1, 2)`,                                   "1:1: len takes 1 argument but got 2 (builtin-arity)" },
+        { `range()`,                                     "1:1: range takes 1 to 3 arguments but got 0 (builtin-arity)" },
+        { `zip([1])`,                                    "1:1: zip takes at least 2 arguments but got 1 (builtin-arity)" },
+        { `puts(); puts(1, 2, 3); range(1, 10, 2)`,      "" },
+        { `[1].push()`,                                  "1:1: push takes 2 arguments but got 1 (builtin-arity)" },
+        { `let len = fn (a, b) { a + b }; len(1, 2)`,    "" },
+        { `1 + "a"`,                                     "1:1: Integer + String always fails with a type mismatch (type-mismatch)" },
+        { `[1] + (2 * 3)`,                               "1:1: Array + Integer always fails with a type mismatch (type-mismatch)" },
+        { `let x = "a"; x + 1; 1 == 1`,                  "" },
+        { `let f = fn (a, b) { a }; f(1, 2)`,            "1:16: parameter 'b' is not used (unused-parameter)" },
+        { `let f = fn (a, _b) { a }; f(1, 2)`,           "" },
+        { `{ "a": 1, "b": 2, "a": 3 }`,                  `1:19: the key "a" is already in the hash at 1:3 (duplicate-key)` },
+        { `{ 1: 1, true: 2, "1": 3 }`,                   "" },
+    }
+
+    for _, test := range tests {
+        if got := check(t, test.input, DefaultConfig()); got != test.expected {
+            t.Errorf("Expected the findings of '%s' to be '%s' but got '%s'", test.input, test.expected, got)
+        }
+    }
+}
+
+func TestIgnoreComments(t *testing.T) {
+    var tests = []struct {
+        input string; expected string
+    } {
+        { "len(1, 2) // vet:ignore",                                    "" },
+        { "len(1, 2) // vet:ignore builtin-arity",                      "" },
+        { "len(1, 2) // vet:ignore unreachable",                        "1:1: len takes 1 argument but got 2 (builtin-arity)" },
+        { "// vet:ignore\nlen(1, 2);\nlen(1, 2)",                       "3:1: len takes 1 argument but got 2 (builtin-arity)" },
+        { "    // vet:ignore type-mismatch, builtin-arity\nlen(1 + \"a\", 2)", "" },
+        { "len(1, 2) // vet:ignoreXYZ",                                 "1:1: len takes 1 argument but got 2 (builtin-arity)" },
+        { "// vet:ignore\nlen(1, 2) // vet:ignore unreachable",         "" },
+        { "// vet:ignore unreachable\nlen(1, 2) // vet:ignore",         "" },
+        { "// a comment\nlen(1, 2)",                                    "2:1: len takes 1 argument but got 2 (builtin-arity)" },
+    }
+
+    for _, test := range tests {
+        if got := check(t, test.input, DefaultConfig()); got != test.expected {
+            t.Errorf("Expected the findings of '%s' to be '%s' but got '%s'", test.input, test.expected, got)
+        }
+    }
+}
+
+func TestConfig(t *testing.T) {
+    var dir = t.TempDir()
+    var write = func (name string, content string) string {
+        var path = filepath.Join(dir, name)
+        if err := os.WriteFile(path, []byte(content), 0644); err != nil { t.Fatal(err) }
+        return path
+    }
+
+    var config, err = LoadConfig(write("vet.json", `{ "rules": { "type-mismatch": false, "unreachable": true } }`))
+    if err != nil { t.Fatalf("Unexpected error: %s", err) }
+    if got := check(t, `1 + "a"; len()`, config); got != "1:10: len takes 1 argument but got 0 (builtin-arity)" {
+        t.Errorf("Expected only the arity finding but got '%s'", got)
+    }
+
+    _, err = LoadConfig(write("unknown.json", `{ "rules": { "no-such-rule": false } }`))
+    if err == nil || !strings.Contains(err.Error(), "unknown rule 'no-such-rule'") {
+        t.Errorf("Expected an unknown rule error but got %v", err)
+    }
+
+    _, err = LoadConfig(write("invalid.json", `{ "rules": `))
+    if err == nil { t.Errorf("Expected an error for the invalid JSON") }
+}
+
+func TestSyntaxError(t *testing.T) {
+    var _, err = Check(`let = 1`, DefaultConfig())
+    var syntaxErr *parser.SyntaxError
+    if !errors.As(err, &syntaxErr) || len(syntaxErr.Messages) == 0 {
+        t.Errorf("Expected a syntax error but got %v", err)
+    }
+}
+
+// The arity of the docs is the one the builtins check when they run
+func TestBuiltinArityMatchesTheBuiltins(t *testing.T) {
+    for _, name := range evaluator.BuiltinNames() {
+        var minArgs, maxArgs, found = builtinArity(name)
+        if !found {
+            t.Errorf("The builtin %s has no doc", name)
+            continue
+        }
+
+        for args := 0; args <= 4; args++ {
+            var params = strings.TrimSuffix(strings.Repeat("0, ", args), ", ")
+            var p = parser.NewParser(lexer.NewLexer(name + "(" + params + ")"))
+            var interpreter = evaluator.NewInterpreter()
+            interpreter.Out = io.Discard
+            var result = interpreter.Eval(p.ParseProgram(), object.NewEnvironment())
+
+            var wrongCount = false
+            if err, isErr := result.(*object.Error); isErr { wrongCount = strings.HasPrefix(err.Message, "wrong number of arguments") }
+            var expected = args < minArgs || (maxArgs >= 0 && args > maxArgs)
+            if wrongCount != expected {
+                t.Errorf("Expected %s with %d arguments to be wrong=%t as its doc says but got %s", name, args, expected, result.Inspect())
+            }
+        }
+    }
+}